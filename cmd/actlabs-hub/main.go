@@ -1,18 +1,24 @@
 package main
 
 import (
+	"actlabs-hub/internal/app"
 	"actlabs-hub/internal/auth"
 	"actlabs-hub/internal/config"
+	"actlabs-hub/internal/entity"
 	"actlabs-hub/internal/handler"
+	"actlabs-hub/internal/health"
+	"actlabs-hub/internal/helper"
 	"actlabs-hub/internal/logger"
+	"actlabs-hub/internal/metrics"
 	"actlabs-hub/internal/middleware"
 	"actlabs-hub/internal/mise"
 	"actlabs-hub/internal/miseadapter"
-	"actlabs-hub/internal/redis"
+	"actlabs-hub/internal/openapi"
 	"actlabs-hub/internal/repository"
-	"actlabs-hub/internal/service"
+	"actlabs-hub/internal/tracing"
 	"actlabs/ratelimit"
 	"context"
+	"errors"
 	"log/slog"
 	"net/http"
 	"os"
@@ -24,16 +30,15 @@ import (
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	goredis "github.com/redis/go-redis/v9"
 )
 
 func main() {
-	// Create root application context with cancellation
-	ctx, cancel := context.WithCancel(context.Background())
+	// The root context is cancelled on SIGINT or SIGTERM, which stops the
+	// background monitors and starts the graceful shutdown.
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
-	// Setup graceful shutdown
-	setupGracefulShutdown(cancel)
-
 	// Load environment variables from .env file
 	err := godotenv.Load()
 	if err != nil {
@@ -47,82 +52,112 @@ func main() {
 	}
 
 	logger.SetupLogger(ctx)
+
 	appConfig, err := config.NewConfig(ctx)
 	if err != nil {
 		logger.LogError(ctx, "error initializing config", "error", err)
 		panic(err)
 	}
 
-	rdb, err := redis.NewRedisClient(ctx)
+	if len(os.Args) > 1 && os.Args[1] == "migrate-deployment-keys" {
+		if err := runMigrateDeploymentKeys(ctx, appConfig, os.Args[2:]); err != nil {
+			logger.LogError(ctx, "deployment key migration failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	shutdownTracing, err := tracing.Setup(ctx, appConfig.OtelTracesExporter)
 	if err != nil {
-		logger.LogError(ctx, "error initializing redis", "error", err)
+		logger.LogError(ctx, "error initializing tracing", "error", err)
 		panic(err)
 	}
+	defer shutdownTracing(context.Background())
 
-	auth, err := auth.NewAuth(ctx, appConfig)
+	hub, err := app.New(ctx, appConfig)
 	if err != nil {
-		logger.LogError(ctx, "error initializing auth", "error", err)
+		logger.LogError(ctx, "error initializing app", "error", err)
 		panic(err)
 	}
 
 	// mise
 	miseServer := mise.Server{
-		ContainerClient: miseadapter.NewMISEAdapter(ctx, http.DefaultClient, appConfig.MiseEndpoint),
+		ContainerClient: miseadapter.NewMISEAdapter(ctx, &http.Client{Transport: tracing.Transport(nil)}, appConfig.MiseEndpoint),
 		VerboseLogging:  appConfig.MiseVerboseLogging,
 	}
 
-	eventRepository, err := repository.NewEventRepository(ctx, auth)
+	authenticator, err := auth.NewAuthenticatorChain(appConfig, miseServer)
 	if err != nil {
-		logger.LogError(ctx, "error initializing event repository", "error", err)
+		logger.LogError(ctx, "error initializing authenticators", "error", err)
 		panic(err)
 	}
 
-	serverRepository, err := repository.NewServerRepository(appConfig, auth, rdb)
-	if err != nil {
-		logger.LogError(ctx, "error initializing server repository", "error", err)
-		panic(err)
-	}
-	labRepository, err := repository.NewLabRepository(auth, appConfig, rdb)
-	if err != nil {
-		logger.LogError(ctx, "error initializing lab repository", "error", err)
-		panic(err)
-	}
-	assignmentRepository, err := repository.NewAssignmentRepository(auth, appConfig, rdb)
-	if err != nil {
-		logger.LogError(ctx, "error initializing assignment repository", "error", err)
-		panic(err)
+	// Runtime settings are applied now and again whenever an admin changes
+	// them on any instance. Until the saved ones load, the configured values
+	// are used.
+	if err := hub.SettingsService.LoadSettings(ctx); err != nil {
+		logger.LogError(ctx, "error loading runtime settings, using configured values", "error", err)
 	}
-	challengeRepository, err := repository.NewChallengeRepository(auth, appConfig, rdb)
-	if err != nil {
-		logger.LogError(ctx, "error initializing challenge repository", "error", err)
-		panic(err)
+	hub.SettingsService.Subscribe(ctx, "logger", func(ctx context.Context, settings entity.RuntimeSettings) {
+		if err := logger.SetLevel(settings.LogLevel); err != nil {
+			logger.LogError(ctx, "error setting log level", "log_level", settings.LogLevel, "error", err)
+		}
+	})
+	hub.SettingsService.Subscribe(ctx, "deployments poller", hub.DeploymentService.ApplySettings)
+	hub.Tasks.Go("WatchSettings", func() { hub.SettingsService.WatchSettings(ctx) })
+
+	if appConfig.ActlabsHubMonitorAndAutoDestroyDeployments {
+		logger.LogInfo(ctx, "auto deploy of auto-destroyed servers to destroy pending deployments is enabled")
+		hub.Tasks.Go("MonitorAndAutoDestroyDeployments", func() { hub.DeploymentService.MonitorAndAutoDestroyDeployments(ctx) })
 	}
-	authRepository, err := repository.NewAuthRepository(auth, appConfig, rdb)
-	if err != nil {
-		logger.LogError(ctx, "error initializing auth repository", "error", err)
-		panic(err)
+
+	if appConfig.ActlabsHubMonitorSchedules {
+		logger.LogInfo(ctx, "scheduled deployments are enabled")
+		hub.Tasks.Go("MonitorSchedules", func() { hub.ScheduleService.MonitorSchedules(ctx) })
 	}
-	deploymentRepository, err := repository.NewDeploymentRepository(auth, rdb, appConfig)
-	if err != nil {
-		logger.LogError(ctx, "error initializing deployment repository", "error", err)
-		panic(err)
+
+	if appConfig.ActlabsHubCollectSupportingDocuments {
+		logger.LogInfo(ctx, "collection of orphaned supporting documents is enabled")
+		hub.Tasks.Go("MonitorSupportingDocuments", func() { hub.LabService.MonitorSupportingDocuments(ctx) })
 	}
 
-	eventService := service.NewEventService(eventRepository)
-	serverService := service.NewServerService(serverRepository, appConfig, eventService)
-	labService := service.NewLabService(labRepository)
-	assignmentService := service.NewAssignmentService(assignmentRepository, labService)
-	challengeService := service.NewChallengeService(challengeRepository, labService)
-	authService := service.NewAuthService(authRepository)
-	deploymentService := service.NewDeploymentService(deploymentRepository, serverService, eventService, appConfig)
+	if appConfig.ActlabsHubMonitorStuckDeployments {
+		logger.LogInfo(ctx, "reconciliation of stuck deployments is enabled")
+		hub.Tasks.Go("MonitorStuckDeployments", func() { hub.DeploymentService.MonitorStuckDeployments(ctx) })
+	}
 
-	if appConfig.ActlabsHubMonitorAndAutoDestroyDeployments {
-		logger.LogInfo(ctx, "auto deploy of auto-destroyed servers to destroy pending deployments is enabled")
-		go deploymentService.MonitorAndAutoDestroyDeployments(ctx)
+	if appConfig.ActlabsHubMonitorServerAuthorization {
+		logger.LogInfo(ctx, "periodic verification of server owners' subscription access is enabled")
+		hub.Tasks.Go("MonitorServerAuthorization", func() { hub.ServerService.MonitorServerAuthorization(ctx) })
 	}
 
-	// add in ratelimiter for user calls
-	rateLimiter := ratelimit.NewLimiter(rdb, ratelimit.DefaultConfig())
+	checkTimeout := time.Duration(appConfig.ActlabsHubReadinessCheckTimeoutSeconds) * time.Second
+	readiness := health.NewChecker(checkTimeout, time.Duration(appConfig.ActlabsHubReadinessCacheSeconds)*time.Second, readinessChecks(ctx, appConfig, hub.Auth, hub.Redis)...)
+	// A loop iteration calling slow actlabs servers can outlast a few
+	// heartbeats, liveness only fails for loops stuck far longer than that.
+	liveness := health.NewChecker(checkTimeout, 0, health.HeartbeatCheck(health.LivenessMissedBeats))
+
+	// Rate limiters are rebuilt when their runtime settings change.
+	userRateLimit := &middleware.Reloadable{}
+	apiKeyRateLimit := &middleware.Reloadable{}
+	hub.SettingsService.Subscribe(ctx, "rate limiter", func(ctx context.Context, settings entity.RuntimeSettings) {
+		window := time.Duration(settings.RateLimit.WindowSeconds) * time.Second
+
+		// add in ratelimiter for user calls
+		userConfig := ratelimit.DefaultConfig()
+		userConfig.Window = window
+		userConfig.MaxRequests = settings.RateLimit.MaxRequests
+		userRateLimit.Set(ratelimit.Middleware(ratelimit.NewLimiter(hub.Redis, userConfig), userRateLimitKey))
+
+		apiKeyRateLimit.Set(ratelimit.Middleware(ratelimit.NewLimiter(hub.Redis, ratelimit.Config{
+			Window:            window,
+			MaxRequests:       settings.RateLimit.APIKeyMaxRequests, // Higher limit for API key authenticated routes
+			InitialBackoff:    1 * time.Second,
+			MaxBackoff:        60 * time.Second,
+			BackoffMultiplier: 2.0,
+			KeyPrefix:         "api_key_ratelimit",
+		}), apiKeyRateLimitKey))
+	})
 
 	// Disable Gin's default logging since we use structured logging
 	middleware.DisableGinDefaultLogging()
@@ -132,14 +167,23 @@ func main() {
 	// Add recovery middleware (since we're using gin.New() instead of gin.Default())
 	router.Use(gin.Recovery())
 
+	// Count in-flight requests so shutdown can report the ones it abandons
+	inFlight := &middleware.InFlight{}
+	router.Use(inFlight.Middleware())
+
 	router.SetTrustedProxies(nil)
 
-	config := cors.DefaultConfig()
-	config.AllowOrigins = strings.Split(appConfig.CorsAllowOrigins, ",")
-	config.AllowMethods = strings.Split(appConfig.CorsAllowMethods, ",")
-	config.AllowHeaders = strings.Split(appConfig.CorsAllowHeaders, ",")
+	// Allowed origins are a runtime setting, methods and headers aren't.
+	corsMiddleware := &middleware.Reloadable{}
+	hub.SettingsService.Subscribe(ctx, "cors", func(ctx context.Context, settings entity.RuntimeSettings) {
+		config := cors.DefaultConfig()
+		config.AllowOrigins = settings.CorsAllowOrigins
+		config.AllowMethods = strings.Split(appConfig.CorsAllowMethods, ",")
+		config.AllowHeaders = strings.Split(appConfig.CorsAllowHeaders, ",")
+		corsMiddleware.Set(cors.New(config))
+	})
 
-	router.Use(cors.New(config))
+	router.Use(corsMiddleware.Middleware())
 
 	// Add context middleware to generate trace IDs and manage context
 	router.Use(middleware.ContextMiddleware())
@@ -147,89 +191,213 @@ func main() {
 	// Add structured logging middleware
 	router.Use(middleware.GinLoggerWithTraceID())
 
+	// Record request durations for /metrics
+	router.Use(metrics.GinMiddleware())
+
+	// Write errors from handlers and middleware as problem+json
+	router.Use(middleware.ErrorHandler())
+
 	authRouter := router.Group("/")
-	authRouter.Use(middleware.Auth(miseServer, *appConfig))
-	authRouter.Use(ratelimit.Middleware(rateLimiter, func(c *gin.Context) string {
-		// logger.UserIDKey is hub's internal contextKey type
-		if uid, ok := c.Request.Context().Value(logger.UserIDKey).(string); ok {
-			return uid
-		}
-		return ""
-	}))
+	authRouter.Use(middleware.Auth(authenticator))
+	authRouter.Use(metrics.CountRateLimited("user", userRateLimit.Middleware()))
 
 	apiKeyAuthRouter := router.Group("/")
 	apiKeyAuthRouter.Use(middleware.APIKeyAuthRequired(*appConfig))
 
-	apiKeyRateLimiter := ratelimit.NewLimiter(rdb, ratelimit.Config{
-		Window:            1 * time.Minute,
-		MaxRequests:       200, // Higher limit for API key authenticated routes
-		InitialBackoff:    1 * time.Second,
-		MaxBackoff:        60 * time.Second,
-		BackoffMultiplier: 2.0,
-		KeyPrefix:         "api_key_ratelimit",
-	})
+	apiKeyAuthRouter.Use(metrics.CountRateLimited("api_key", apiKeyRateLimit.Middleware()))
 
-	apiKeyAuthRouter.Use(ratelimit.Middleware(apiKeyRateLimiter, func(c *gin.Context) string {
-		// For API key authenticated routes, we can use the API key itself as the identifier for rate limiting
-		apiKey := c.GetHeader("x-api-key")
-		if apiKey != "" {
-			return apiKey
-		}
-		return ""
-	}))
+	// Register handlers through the route table so /openapi.json knows how
+	// each route is authenticated. Handlers are registered on validated groups
+	// so a caller without the role gets a 403 before the body is looked at.
+	api := openapi.NewRouteTable(router)
+
+	api.Register(openapi.Public, func() {
+		handler.NewHealthzHandler(router.Group("/"))
+		handler.NewProbeHandler(router.Group("/"), readiness, liveness)
+		handler.NewOpenAPIHandler(router.Group("/"), api)
+	})
 
-	handler.NewHealthzHandler(router.Group("/"))
-	handler.NewServerHandler(authRouter.Group("/"), serverService)
-	handler.NewAssignmentHandler(authRouter.Group("/"), assignmentService, appConfig)
-	handler.NewAssignmentAPIKeyHandler(apiKeyAuthRouter.Group("/"), assignmentService, appConfig)
-	handler.NewChallengeHandler(authRouter.Group("/"), challengeService, appConfig)
-	handler.NewChallengeAPIKeyHandler(apiKeyAuthRouter.Group("/"), challengeService, appConfig)
-	handler.NewAuthHandler(authRouter.Group("/"), authService)
+	api.Register(openapi.User, func() {
+		handler.NewServerHandler(validated(authRouter), hub.ServerService)
+		handler.NewAssignmentHandler(validated(authRouter), hub.AssignmentService, appConfig)
+		handler.NewChallengeHandler(validated(authRouter), hub.ChallengeService, appConfig)
+		handler.NewAuthHandler(validated(authRouter), hub.AuthService)
+		handler.NewDeploymentUserHandler(validated(authRouter), hub.DeploymentService)
+		handler.NewQuotaHandler(validated(authRouter), hub.QuotaService)
+	})
 
-	handler.NewDeploymentHandler(apiKeyAuthRouter.Group("/"), deploymentService)
-	handler.NewServerHandlerArmToken(apiKeyAuthRouter.Group("/"), serverService)
+	api.Register(openapi.APIKey, func() {
+		handler.NewAssignmentAPIKeyHandler(validated(apiKeyAuthRouter), hub.AssignmentService, appConfig)
+		handler.NewChallengeAPIKeyHandler(validated(apiKeyAuthRouter), hub.ChallengeService, appConfig)
+		handler.NewDeploymentHandler(validated(apiKeyAuthRouter), hub.DeploymentService)
+		handler.NewServerHandlerArmToken(validated(apiKeyAuthRouter), hub.ServerService)
+	})
 
 	adminRouter := authRouter.Group("/")
-	adminRouter.Use(middleware.AdminRequired(authService))
-	handler.NewAdminAuthHandler(adminRouter, authService)
-	handler.NewAdminServerHandler(adminRouter, serverService)
+	adminRouter.Use(middleware.AdminRequired(hub.AuthService))
+	api.Register(openapi.Role("admin"), func() {
+		handler.NewAdminAuthHandler(validated(adminRouter), hub.AuthService)
+		handler.NewAdminServerHandler(validated(adminRouter), hub.ServerService)
+		handler.NewAdminDeploymentHandler(validated(adminRouter), hub.DeploymentService)
+		handler.NewAdminQuotaHandler(validated(adminRouter), hub.QuotaService)
+		handler.NewAdminSettingsHandler(validated(adminRouter), hub.SettingsService)
+	})
 
 	mentorRouter := authRouter.Group("/")
-	mentorRouter.Use(middleware.MentorRequired(authService))
-	handler.NewAssignmentHandlerMentorRequired(mentorRouter, assignmentService)
+	mentorRouter.Use(middleware.MentorRequired(hub.AuthService))
+	api.Register(openapi.Role("mentor"), func() {
+		handler.NewAssignmentHandlerMentorRequired(validated(mentorRouter), hub.AssignmentService)
+		handler.NewScheduleHandlerMentorRequired(validated(mentorRouter), hub.ScheduleService)
 
-	mentorRouter.Use(middleware.UpdateCredits())
-	handler.NewLabHandlerMentorRequired(mentorRouter, labService)
+		mentorRouter.Use(middleware.UpdateCredits())
+		handler.NewLabHandlerMentorRequired(validated(mentorRouter), hub.LabService, hub.CostService)
+	})
 
 	labRouter := authRouter.Group("/")
 	labRouter.Use(middleware.UpdateCredits())
-	handler.NewLabHandler(labRouter, labService, appConfig)
+	api.Register(openapi.User, func() {
+		handler.NewLabHandler(validated(labRouter), hub.LabService, hub.CostService, appConfig)
+	})
 
 	contributorRouter := labRouter.Group("/")
-	contributorRouter.Use(middleware.ContributorRequired(authService)).Use(middleware.UpdateCredits())
-	handler.NewLabHandlerContributorRequired(contributorRouter, labService)
+	contributorRouter.Use(middleware.ContributorRequired(hub.AuthService)).Use(middleware.UpdateCredits())
+	api.Register(openapi.Role("contributor"), func() {
+		handler.NewLabHandlerContributorRequired(validated(contributorRouter), hub.LabService)
+	})
 
-	handler.NewLabHandlerAPIKey(apiKeyAuthRouter, labService, appConfig)
+	api.Register(openapi.APIKey, func() {
+		handler.NewLabHandlerAPIKey(validated(apiKeyAuthRouter), hub.LabService, appConfig)
+	})
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8883"
+	for _, route := range api.Undocumented() {
+		logger.LogWarning(ctx, "route missing from the OpenAPI spec", "route", route)
 	}
-	router.Run(":" + port)
-}
 
-// setupGracefulShutdown sets up signal handling for graceful shutdown
-func setupGracefulShutdown(cancel context.CancelFunc) {
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	server := &http.Server{
+		Addr:    ":" + appConfig.Port,
+		Handler: router,
+	}
 
+	serverErr := make(chan error, 1)
 	go func() {
-		sig := <-sigCh
-		logger.LogInfo(context.Background(), "received shutdown signal, initiating graceful shutdown", "signal", sig.String())
-		cancel() // Cancel the root context to stop all background services
+		serverErr <- server.ListenAndServe()
+	}()
 
-		// Give background services time to shut down gracefully
-		time.Sleep(5 * time.Second)
-		os.Exit(0)
+	// Metrics are served on their own port, which isn't exposed publicly, so
+	// only the scraper can read them.
+	metricsRouter := gin.New()
+	metricsRouter.Use(gin.Recovery())
+	handler.NewMetricsHandler(metricsRouter.Group("/"))
+	metricsServer := &http.Server{
+		Addr:    ":" + appConfig.MetricsPort,
+		Handler: metricsRouter,
+	}
+	go func() {
+		if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.LogError(ctx, "metrics server stopped", "error", err)
+		}
 	}()
+	// Closed last so shutdown can still be watched.
+	defer metricsServer.Close()
+
+	select {
+	case <-ctx.Done():
+		logger.LogInfo(context.Background(), "received shutdown signal, initiating graceful shutdown")
+	case err := <-serverErr:
+		logger.LogError(context.Background(), "http server stopped", "error", err)
+		cancel()
+	}
+
+	shutdown(server, readiness, inFlight, hub.Tasks, hub.Redis,
+		time.Duration(appConfig.ActlabsHubShutdownDrainSeconds)*time.Second,
+		time.Duration(appConfig.ActlabsHubShutdownTimeoutSeconds)*time.Second,
+	)
+}
+
+// validated returns a group of r that checks JSON bodies against the OpenAPI
+// spec after r's own middleware has run.
+func validated(r *gin.RouterGroup) *gin.RouterGroup {
+	return r.Group("/", openapi.ValidateRequest())
+}
+
+func userRateLimitKey(c *gin.Context) string {
+	// logger.UserIDKey is hub's internal contextKey type
+	if uid, ok := c.Request.Context().Value(logger.UserIDKey).(string); ok {
+		return uid
+	}
+	return ""
+}
+
+func apiKeyRateLimitKey(c *gin.Context) string {
+	// For API key authenticated routes, we can use the API key itself as the identifier for rate limiting
+	apiKey := c.GetHeader("x-api-key")
+	if apiKey != "" {
+		return apiKey
+	}
+	return ""
+}
+
+// shutdown fails readiness for drain so no new requests are routed here, then
+// stops accepting connections, drains in-flight requests and waits for
+// background tasks, all within timeout, then closes Redis. Whatever didn't
+// finish in time is reported and abandoned.
+func shutdown(server *http.Server, readiness *health.Checker, inFlight *middleware.InFlight, tasks *helper.TaskGroup, rdb *goredis.Client, drain time.Duration, timeout time.Duration) {
+	readiness.Drain()
+	logger.LogInfo(context.Background(), "failing readiness before shutting down the server", "drain", drain.String())
+	time.Sleep(drain)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		logger.LogError(ctx, "in-flight requests didn't finish before the shutdown deadline",
+			"abandoned_requests", inFlight.Count(),
+			"error", err,
+		)
+	}
+
+	// Requests are drained and the monitors are stopping, so the tasks left
+	// are the last ones.
+	if abandoned := tasks.Wait(ctx); len(abandoned) > 0 {
+		logger.LogError(ctx, "background tasks didn't finish before the shutdown deadline",
+			"abandoned_tasks", strings.Join(abandoned, ", "),
+			"count", len(abandoned),
+		)
+	}
+
+	if err := rdb.Close(); err != nil {
+		logger.LogError(ctx, "failed to close redis client", "error", err)
+	}
+
+	logger.LogInfo(ctx, "shutdown complete")
+}
+
+// readinessChecks checks everything the hub can't serve requests without.
+func readinessChecks(ctx context.Context, appConfig *config.Config, azureAuth *auth.Auth, rdb *goredis.Client) []health.Check {
+	checks := []health.Check{
+		health.RedisCheck(rdb),
+		health.HeartbeatCheck(health.ReadinessMissedBeats),
+	}
+
+	for name, client := range azureAuth.TableClients() {
+		checks = append(checks, health.TableCheck(name, client))
+	}
+
+	for _, containerName := range repository.LabContainers() {
+		client, err := azureAuth.GetBlobContainerClient(appConfig.ActlabsHubStorageAccount, containerName)
+		if err != nil {
+			logger.LogError(ctx, "error initializing blob container client for readiness", "container", containerName, "error", err)
+			panic(err)
+		}
+		checks = append(checks, health.BlobContainerCheck(containerName, client))
+	}
+
+	for _, name := range appConfig.AuthAuthenticators {
+		if strings.EqualFold(strings.TrimSpace(name), auth.AuthMethodMISE) {
+			checks = append(checks, health.TCPCheck("mise", appConfig.MiseEndpoint))
+			break
+		}
+	}
+
+	return checks
 }
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"flag"
+
+	"actlabs-hub/internal/actlabsserver"
+	"actlabs-hub/internal/auth"
+	"actlabs-hub/internal/config"
+	"actlabs-hub/internal/logger"
+	"actlabs-hub/internal/redis"
+	"actlabs-hub/internal/repository"
+)
+
+// runMigrateDeploymentKeys rewrites deployments stored under a row key other
+// than their canonical entity.DeploymentKey. Run it with -dry-run first to see
+// what would change.
+func runMigrateDeploymentKeys(ctx context.Context, appConfig *config.Config, args []string) error {
+	flags := flag.NewFlagSet("migrate-deployment-keys", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "only log the deployments that would be migrated")
+	flags.Parse(args)
+
+	rdb, err := redis.NewRedisClient(ctx, appConfig)
+	if err != nil {
+		return err
+	}
+	defer rdb.Close()
+
+	auth, err := auth.NewAuth(ctx, appConfig)
+	if err != nil {
+		return err
+	}
+
+	deploymentRepository, err := repository.NewDeploymentRepository(auth, rdb, appConfig, actlabsserver.NewClientFromConfig(appConfig))
+	if err != nil {
+		return err
+	}
+
+	migrated, err := deploymentRepository.MigrateDeploymentKeys(ctx, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	logger.LogInfo(ctx, "deployment key migration finished", "migrated", migrated, "dry_run", *dryRun)
+	return nil
+}
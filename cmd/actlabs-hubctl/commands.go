@@ -0,0 +1,403 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"actlabs-hub/internal/app"
+	"actlabs-hub/internal/entity"
+)
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, hub *app.App, out *printer, args []string) error
+	// Commands that only read the configuration get a hub without
+	// connections, so they work where storage isn't reachable.
+	configOnly bool
+}
+
+var commands = map[string]command{
+	"profiles grant":      {"<userPrincipal> <role>", grantRole, false},
+	"profiles revoke":     {"<userPrincipal> <role>", revokeRole, false},
+	"servers list":        {"", listServers, false},
+	"servers unregister":  {"<userPrincipalName>", unregisterServer, false},
+	"assignments export":  {"[-file path]", exportAssignments, false},
+	"assignments import":  {"<file|->", importAssignments, false},
+	"labs export":         {"[-type typeOfLab] [-file path]", exportLabs, false},
+	"labs import":         {"<file|->", importLabs, false},
+	"deployments list":    {"[-user userPrincipalName]", listDeployments, false},
+	"deployments extend":  {"[-by duration] <userPrincipalName> <subscriptionId> <workspace>", extendDeployment, false},
+	"deployments destroy": {"<userPrincipalName> <subscriptionId> <workspace>", destroyDeployment, false},
+	"events tail":         {"[-n count] [-f] [-interval duration]", tailEvents, false},
+	"config print":        {"", printConfig, true},
+}
+
+// printConfig writes the configuration the hub would run with and where each
+// setting came from. Secrets are redacted.
+func printConfig(ctx context.Context, hub *app.App, out *printer, args []string) error {
+	if _, err := parse(newFlags("config print"), args); err != nil {
+		return err
+	}
+
+	settings := hub.Config.Settings()
+	values := map[string]any{}
+	rows := [][]string{}
+	for _, setting := range settings {
+		values[setting.Name] = setting.Value
+
+		value, ok := setting.Value.(string)
+		if !ok {
+			b, err := json.Marshal(setting.Value)
+			if err != nil {
+				return err
+			}
+			value = string(b)
+		}
+		rows = append(rows, []string{setting.Name, value, cmp.Or(setting.Source, "unset")})
+	}
+	return out.table(values, []string{"NAME", "VALUE", "SOURCE"}, rows)
+}
+
+// parse parses a command's flags and checks it got exactly the named
+// positional arguments.
+func parse(flags *flag.FlagSet, args []string, names ...string) ([]string, error) {
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() != len(names) {
+		usage := flags.Name()
+		for _, name := range names {
+			usage += " <" + name + ">"
+		}
+		return nil, fmt.Errorf("usage: %s", usage)
+	}
+	return flags.Args(), nil
+}
+
+func newFlags(name string) *flag.FlagSet {
+	return flag.NewFlagSet(name, flag.ContinueOnError)
+}
+
+func grantRole(ctx context.Context, hub *app.App, out *printer, args []string) error {
+	args, err := parse(newFlags("profiles grant"), args, "userPrincipal", "role")
+	if err != nil {
+		return err
+	}
+	if err := hub.AuthService.AddRole(ctx, args[0], args[1]); err != nil {
+		return err
+	}
+	return out.status("role %s granted to %s", args[1], args[0])
+}
+
+func revokeRole(ctx context.Context, hub *app.App, out *printer, args []string) error {
+	args, err := parse(newFlags("profiles revoke"), args, "userPrincipal", "role")
+	if err != nil {
+		return err
+	}
+	if err := hub.AuthService.DeleteRole(ctx, args[0], args[1]); err != nil {
+		return err
+	}
+	return out.status("role %s revoked from %s", args[1], args[0])
+}
+
+func listServers(ctx context.Context, hub *app.App, out *printer, args []string) error {
+	if _, err := parse(newFlags("servers list"), args); err != nil {
+		return err
+	}
+
+	servers, err := hub.ServerService.GetAllServers(ctx)
+	if err != nil {
+		return err
+	}
+	slices.SortFunc(servers, func(a, b entity.Server) int { return cmp.Compare(a.UserPrincipalName, b.UserPrincipalName) })
+
+	rows := [][]string{}
+	for _, server := range servers {
+		rows = append(rows, []string{server.UserPrincipalName, server.SubscriptionId, string(server.Status), server.Region, server.LastUserActivityTime})
+	}
+	return out.table(servers, []string{"USER", "SUBSCRIPTION", "STATUS", "REGION", "LAST ACTIVITY"}, rows)
+}
+
+func unregisterServer(ctx context.Context, hub *app.App, out *printer, args []string) error {
+	args, err := parse(newFlags("servers unregister"), args, "userPrincipalName")
+	if err != nil {
+		return err
+	}
+	if err := hub.ServerService.Unregister(ctx, args[0]); err != nil {
+		return err
+	}
+	return out.status("server of %s unregistered", args[0])
+}
+
+// Exports are always JSON, so they can be imported again.
+
+func exportAssignments(ctx context.Context, hub *app.App, out *printer, args []string) error {
+	flags := newFlags("assignments export")
+	file := flags.String("file", "", "write to file instead of stdout")
+	if _, err := parse(flags, args); err != nil {
+		return err
+	}
+
+	assignments, err := hub.AssignmentService.GetAllAssignments(ctx)
+	if err != nil {
+		return err
+	}
+	return export(out, *file, assignments, len(assignments))
+}
+
+// importAssignments writes the assignments as they are, keeping their ids and
+// status, so an export can be restored into another environment. Nothing is
+// written unless every assignment is valid.
+func importAssignments(ctx context.Context, hub *app.App, out *printer, args []string) error {
+	args, err := parse(newFlags("assignments import"), args, "file")
+	if err != nil {
+		return err
+	}
+
+	assignments := []entity.Assignment{}
+	if err := load(args[0], &assignments); err != nil {
+		return err
+	}
+
+	imported, err := hub.AssignmentService.ImportAssignments(ctx, assignments)
+	if err != nil {
+		return fmt.Errorf("imported %d of %d assignments: %w", imported, len(assignments), err)
+	}
+	return out.status("imported %d assignments", imported)
+}
+
+func exportLabs(ctx context.Context, hub *app.App, out *printer, args []string) error {
+	flags := newFlags("labs export")
+	typeOfLab := flags.String("type", "", "only export labs of this type")
+	file := flags.String("file", "", "write to file instead of stdout")
+	if _, err := parse(flags, args); err != nil {
+		return err
+	}
+
+	types := labTypes()
+	if *typeOfLab != "" {
+		if !slices.Contains(types, *typeOfLab) {
+			return fmt.Errorf("unknown lab type %q, use one of %s", *typeOfLab, strings.Join(types, ", "))
+		}
+		types = []string{*typeOfLab}
+	}
+
+	// An export missing labs it couldn't read would silently lose them.
+	labs := []entity.LabType{}
+	for _, typeOfLab := range types {
+		some, err := hub.LabService.GetAllLabs(ctx, typeOfLab)
+		if err != nil {
+			return err
+		}
+		labs = append(labs, some...)
+	}
+	return export(out, *file, labs, len(labs))
+}
+
+// importLabs writes the labs as they are, skipping the ownership checks of
+// LabService.UpsertLab. Each lab becomes the current version of its id. Nothing
+// is written unless every lab is valid.
+func importLabs(ctx context.Context, hub *app.App, out *printer, args []string) error {
+	args, err := parse(newFlags("labs import"), args, "file")
+	if err != nil {
+		return err
+	}
+
+	labs := []entity.LabType{}
+	if err := load(args[0], &labs); err != nil {
+		return err
+	}
+
+	imported, err := hub.LabService.ImportLabs(ctx, labs)
+	if err != nil {
+		return fmt.Errorf("imported %d of %d labs: %w", imported, len(labs), err)
+	}
+	return out.status("imported %d labs", imported)
+}
+
+func labTypes() []string {
+	return slices.Concat(entity.PrivateLab, entity.PublicLab, entity.ProtectedLabs)
+}
+
+func listDeployments(ctx context.Context, hub *app.App, out *printer, args []string) error {
+	flags := newFlags("deployments list")
+	user := flags.String("user", "", "only list this user's deployments")
+	if _, err := parse(flags, args); err != nil {
+		return err
+	}
+
+	var deployments []entity.Deployment
+	var err error
+	if *user != "" {
+		deployments, err = hub.DeploymentService.GetUserDeployments(ctx, *user)
+	} else {
+		deployments, err = hub.DeploymentService.GetAllDeployments(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	slices.SortFunc(deployments, func(a, b entity.Deployment) int { return cmp.Compare(a.DeploymentId, b.DeploymentId) })
+
+	rows := [][]string{}
+	for _, deployment := range deployments {
+		autoDelete := "never"
+		if deployment.DeploymentAutoDelete && deployment.DeploymentAutoDeleteUnixTime != 0 {
+			autoDelete = time.Unix(deployment.DeploymentAutoDeleteUnixTime, 0).UTC().Format(time.RFC3339)
+		}
+		rows = append(rows, []string{
+			deployment.DeploymentUserId,
+			deployment.DeploymentSubscriptionId,
+			deployment.DeploymentWorkspace,
+			string(deployment.DeploymentStatus),
+			autoDelete,
+		})
+	}
+	return out.table(deployments, []string{"USER", "SUBSCRIPTION", "WORKSPACE", "STATUS", "AUTO DELETE"}, rows)
+}
+
+func extendDeployment(ctx context.Context, hub *app.App, out *printer, args []string) error {
+	flags := newFlags("deployments extend")
+	by := flags.Duration("by", time.Hour, "how much longer the deployment lives")
+	args, err := parse(flags, args, "userPrincipalName", "subscriptionId", "workspace")
+	if err != nil {
+		return err
+	}
+
+	deployment, err := hub.DeploymentService.ExtendDeployment(ctx, args[0], args[1], args[2], *by)
+	if err != nil {
+		return err
+	}
+	return out.status("deployment %s will be deleted at %s", deployment.DeploymentId, time.Unix(deployment.DeploymentAutoDeleteUnixTime, 0).UTC().Format(time.RFC3339))
+}
+
+func destroyDeployment(ctx context.Context, hub *app.App, out *printer, args []string) error {
+	args, err := parse(newFlags("deployments destroy"), args, "userPrincipalName", "subscriptionId", "workspace")
+	if err != nil {
+		return err
+	}
+
+	key := entity.DeploymentKey{UserId: args[0], SubscriptionId: args[1], Workspace: args[2]}
+	if err := hub.DeploymentService.DestroyDeployment(ctx, key); err != nil {
+		return err
+	}
+	return out.status("destroy of deployment %s requested", key.RowKey())
+}
+
+// tailEvents prints the latest events, oldest first, and with -f keeps
+// printing new ones until interrupted. JSON output is one event per line.
+func tailEvents(ctx context.Context, hub *app.App, out *printer, args []string) error {
+	flags := newFlags("events tail")
+	n := flags.Int("n", 20, "number of events to print first")
+	follow := flags.Bool("f", false, "keep printing new events")
+	interval := flags.Duration("interval", 10*time.Second, "how often to look for new events with -f")
+	if _, err := parse(flags, args); err != nil {
+		return err
+	}
+
+	seen := map[string]bool{}
+	first := true
+	for {
+		events, err := hub.EventService.GetEvents(ctx)
+		if err != nil {
+			return err
+		}
+		slices.SortStableFunc(events, func(a, b entity.Event) int {
+			return cmp.Or(cmp.Compare(a.TimeStamp, b.TimeStamp), cmp.Compare(a.RowKey, b.RowKey))
+		})
+
+		fresh := []entity.Event{}
+		for _, event := range events {
+			key := event.PartitionKey + "/" + event.RowKey
+			if !seen[key] {
+				seen[key] = true
+				fresh = append(fresh, event)
+			}
+		}
+		if first && len(fresh) > *n {
+			fresh = fresh[len(fresh)-*n:]
+		}
+		if err := writeEvents(out, fresh, first); err != nil {
+			return err
+		}
+		first = false
+
+		if !*follow {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(*interval):
+		}
+	}
+}
+
+func writeEvents(out *printer, events []entity.Event, header bool) error {
+	if out.json {
+		encoder := json.NewEncoder(out.w)
+		for _, event := range events {
+			if err := encoder.Encode(event); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(out.w, 0, 0, 2, ' ', 0)
+	if header {
+		fmt.Fprintln(tw, "TIME\tTYPE\tREASON\tOBJECT\tMESSAGE")
+	}
+	for _, event := range events {
+		fmt.Fprintln(tw, strings.Join([]string{event.TimeStamp, event.Type, event.Reason, event.Object, event.Message}, "\t"))
+	}
+	return tw.Flush()
+}
+
+// export writes the count items in v as JSON to file, or to stdout when file
+// is empty.
+func export(out *printer, file string, v any, count int) error {
+	if file == "" {
+		return writeJSON(out.w, v)
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := writeJSON(f, v); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return out.status("exported %d items to %s", count, file)
+}
+
+// load reads JSON from file into v, or from stdin when file is -.
+func load(file string, v any) error {
+	var r io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	if err := json.NewDecoder(r).Decode(v); err != nil {
+		return fmt.Errorf("reading %s: %w", file, err)
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"actlabs-hub/internal/app"
+	"actlabs-hub/internal/config"
+	"actlabs-hub/internal/entity"
+	"actlabs-hub/internal/logger"
+	"actlabs-hub/internal/service"
+)
+
+type fakeLabRepository struct {
+	entity.LabRepository
+	labs       map[string]map[string]entity.LabType
+	unreadable string
+}
+
+func newFakeLabRepository() *fakeLabRepository {
+	return &fakeLabRepository{labs: map[string]map[string]entity.LabType{}}
+}
+
+func (r *fakeLabRepository) ListBlobs(ctx context.Context, typeOfLab string) ([]entity.Blob, error) {
+	blobs := []entity.Blob{}
+	for id := range r.labs[typeOfLab] {
+		blobs = append(blobs, entity.Blob{Name: id, IsCurrentVersion: true})
+	}
+	return blobs, nil
+}
+
+func (r *fakeLabRepository) GetLab(ctx context.Context, typeOfLab string, labId string) (entity.LabType, error) {
+	if labId == r.unreadable {
+		return entity.LabType{}, errors.New("blob unreadable")
+	}
+	return r.labs[typeOfLab][labId], nil
+}
+
+func (r *fakeLabRepository) UpsertLab(ctx context.Context, labId string, lab string, typeOfLab string) error {
+	l := entity.LabType{}
+	if err := json.Unmarshal([]byte(lab), &l); err != nil {
+		return err
+	}
+	if r.labs[typeOfLab] == nil {
+		r.labs[typeOfLab] = map[string]entity.LabType{}
+	}
+	r.labs[typeOfLab][labId] = l
+	return nil
+}
+
+type fakeEventService struct {
+	entity.EventService
+	events []entity.Event
+}
+
+func (s *fakeEventService) GetEvents(ctx context.Context) ([]entity.Event, error) {
+	return s.events, nil
+}
+
+func (s *fakeEventService) CreateEvent(ctx context.Context, event entity.Event) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+func newFakeHub(repo *fakeLabRepository, events *fakeEventService) *app.App {
+	return &app.App{LabService: service.NewLabService(repo, events, &config.Config{})}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "positional arguments", args: []string{"-by", "2", "alice", "sub"}, want: []string{"alice", "sub"}},
+		{name: "missing argument", args: []string{"alice"}, wantErr: true},
+		{name: "extra argument", args: []string{"alice", "sub", "more"}, wantErr: true},
+		{name: "unknown flag", args: []string{"-nope", "alice", "sub"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags := newFlags("test")
+			flags.SetOutput(io.Discard)
+			flags.Int("by", 1, "")
+
+			got, err := parse(flags, tt.args, "user", "subscription")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	flags := newFlags("test")
+	flags.SetOutput(io.Discard)
+	if _, err := parse(flags, []string{"-h"}); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("parse(-h) error = %v, want flag.ErrHelp", err)
+	}
+}
+
+func TestLabsExportImportRoundTrip(t *testing.T) {
+	ctx := logger.WithUserID(context.Background(), "admin@example.com")
+	file := filepath.Join(t.TempDir(), "labs.json")
+
+	source := newFakeLabRepository()
+	source.labs["publiclab"] = map[string]entity.LabType{
+		"lab-1": {Id: "lab-1", Name: "one", Type: "publiclab", Category: "public", Owners: []string{"owner@example.com"}},
+	}
+	source.labs["readinesslab"] = map[string]entity.LabType{
+		"lab-2": {Id: "lab-2", Name: "two", Type: "readinesslab", Category: "protected", Owners: []string{"owner@example.com"}},
+	}
+
+	var buf bytes.Buffer
+	out, _ := newPrinter(&buf, "table")
+	if err := exportLabs(ctx, newFakeHub(source, &fakeEventService{}), out, []string{"-file", file}); err != nil {
+		t.Fatalf("exportLabs() = %v", err)
+	}
+	if buf.String() != "exported 2 items to "+file+"\n" {
+		t.Errorf("exportLabs() output = %q", buf.String())
+	}
+
+	target := newFakeLabRepository()
+	events := &fakeEventService{}
+	buf.Reset()
+	if err := importLabs(ctx, newFakeHub(target, events), out, []string{file}); err != nil {
+		t.Fatalf("importLabs() = %v", err)
+	}
+	if buf.String() != "imported 2 labs\n" {
+		t.Errorf("importLabs() output = %q", buf.String())
+	}
+
+	for typeOfLab, labs := range source.labs {
+		for id, lab := range labs {
+			got := target.labs[typeOfLab][id]
+			if got.Name != lab.Name || got.Type != lab.Type {
+				t.Errorf("imported %s/%s = %+v, want %+v", typeOfLab, id, got, lab)
+			}
+		}
+	}
+	if len(events.events) != 1 || events.events[0].Reason != "LabsImported" {
+		t.Errorf("events = %+v, want one LabsImported", events.events)
+	}
+}
+
+func TestLabsImportRejectsInvalidLabs(t *testing.T) {
+	ctx := logger.WithUserID(context.Background(), "admin@example.com")
+	file := filepath.Join(t.TempDir(), "labs.json")
+
+	var buf bytes.Buffer
+	out, _ := newPrinter(&buf, "json")
+	if err := export(out, file, []entity.LabType{{Id: "lab-1", Type: "publiclab"}, {Name: "no id", Type: "publiclab"}}, 2); err != nil {
+		t.Fatalf("export() = %v", err)
+	}
+
+	target := newFakeLabRepository()
+	if err := importLabs(ctx, newFakeHub(target, &fakeEventService{}), out, []string{file}); err == nil {
+		t.Fatal("importLabs() = nil, want validation error")
+	}
+	if len(target.labs) != 0 {
+		t.Errorf("importLabs() wrote %v, want nothing written", target.labs)
+	}
+}
+
+func TestLabsExportFailsOnUnreadableLab(t *testing.T) {
+	ctx := context.Background()
+
+	source := newFakeLabRepository()
+	source.labs["publiclab"] = map[string]entity.LabType{
+		"lab-1": {Id: "lab-1", Type: "publiclab", Category: "public"},
+		"lab-2": {Id: "lab-2", Type: "publiclab", Category: "public"},
+	}
+	source.unreadable = "lab-2"
+
+	var buf bytes.Buffer
+	out, _ := newPrinter(&buf, "json")
+	if err := exportLabs(ctx, newFakeHub(source, &fakeEventService{}), out, []string{"-type", "publiclab"}); err == nil {
+		t.Fatal("exportLabs() = nil, want error for the unreadable lab")
+	}
+	if buf.Len() != 0 {
+		t.Errorf("exportLabs() wrote %q, want nothing", buf.String())
+	}
+}
+
+func TestEventsTail(t *testing.T) {
+	events := &fakeEventService{events: []entity.Event{
+		{PartitionKey: "alice", RowKey: "2", TimeStamp: "2026-01-01T00:00:02Z", Reason: "Second"},
+		{PartitionKey: "alice", RowKey: "1", TimeStamp: "2026-01-01T00:00:01Z", Reason: "First"},
+		{PartitionKey: "bob", RowKey: "1", TimeStamp: "2026-01-01T00:00:03Z", Reason: "Third"},
+	}}
+
+	var buf bytes.Buffer
+	out, _ := newPrinter(&buf, "json")
+	if err := tailEvents(context.Background(), &app.App{EventService: events}, out, []string{"-n", "2"}); err != nil {
+		t.Fatalf("tailEvents() = %v", err)
+	}
+
+	reasons := []string{}
+	decoder := json.NewDecoder(&buf)
+	for decoder.More() {
+		event := entity.Event{}
+		if err := decoder.Decode(&event); err != nil {
+			t.Fatal(err)
+		}
+		reasons = append(reasons, event.Reason)
+	}
+	if strings.Join(reasons, ",") != "Second,Third" {
+		t.Errorf("tailEvents() printed %v, want the last 2 events oldest first", reasons)
+	}
+}
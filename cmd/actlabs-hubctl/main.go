@@ -0,0 +1,118 @@
+// actlabs-hubctl runs administrative operations against the hub's storage
+// through the same services the server uses.
+//
+//	actlabs-hubctl [-o table|json] [-config file] [-as userPrincipal] <resource> <verb> [flags] [args]
+//
+// Configuration is read from the config file, the environment, .env and
+// .env.local exactly as the server reads it. Events of changes made are
+// recorded as made by the -as user.
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"actlabs-hub/internal/app"
+	"actlabs-hub/internal/config"
+	"actlabs-hub/internal/logger"
+
+	"github.com/joho/godotenv"
+)
+
+// tasksTimeout is how long to wait for background writes a command started,
+// such as deployment operation entries, before exiting.
+const tasksTimeout = 30 * time.Second
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	if err := run(ctx, os.Args[1:]); err != nil {
+		// The command's usage was already printed.
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("actlabs-hubctl", flag.ExitOnError)
+	output := flags.String("o", "table", "output format, table or json")
+	configFile := flags.String("config", "", "config file, defaults to $"+config.ConfigFileEnv)
+	as := flags.String("as", "actlabs-hubctl", "user principal events of changes are recorded for")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: actlabs-hubctl [-o table|json] [-config file] [-as userPrincipal] <resource> <verb> [flags] [args]")
+		fmt.Fprintln(flags.Output())
+		for _, name := range slices.Sorted(maps.Keys(commands)) {
+			fmt.Fprintln(flags.Output(), " ", strings.TrimSpace(name+" "+commands[name].usage))
+		}
+	}
+	flags.Parse(args)
+
+	if flags.NArg() < 2 {
+		flags.Usage()
+		return fmt.Errorf("missing command")
+	}
+	name := flags.Arg(0) + " " + flags.Arg(1)
+	cmd, ok := commands[name]
+	if !ok {
+		flags.Usage()
+		return fmt.Errorf("unknown command %q", name)
+	}
+
+	out, err := newPrinter(os.Stdout, *output)
+	if err != nil {
+		return err
+	}
+
+	// Same as the server, .env.local overrides .env.
+	if err := godotenv.Load(); err != nil {
+		slog.DebugContext(ctx, "no .env file loaded", "error", err)
+	}
+	if err := godotenv.Load(".env.local"); err != nil {
+		slog.DebugContext(ctx, "no .env.local file loaded", "error", err)
+	}
+
+	// Logs go to stderr so stdout is only the command's output.
+	logger.SetupLoggerTo(ctx, os.Stderr)
+
+	ctx = logger.WithUserID(ctx, *as)
+
+	appConfig, err := config.Load(ctx, cmp.Or(*configFile, os.Getenv(config.ConfigFileEnv)))
+	if err != nil {
+		return fmt.Errorf("error initializing config: %w", err)
+	}
+
+	if cmd.configOnly {
+		return cmd.run(ctx, &app.App{Config: appConfig}, out, flags.Args()[2:])
+	}
+
+	hub, err := app.New(ctx, appConfig)
+	if err != nil {
+		return err
+	}
+	defer hub.Close()
+
+	err = cmd.run(ctx, hub, out, flags.Args()[2:])
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), tasksTimeout)
+	defer waitCancel()
+	if abandoned := hub.Tasks.Wait(waitCtx); len(abandoned) > 0 {
+		logger.LogWarning(ctx, "background tasks didn't finish before exiting", "abandoned_tasks", abandoned)
+	}
+
+	return err
+}
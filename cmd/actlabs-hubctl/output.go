@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// printer writes command results as a table or as JSON.
+type printer struct {
+	w    io.Writer
+	json bool
+}
+
+func newPrinter(w io.Writer, format string) (*printer, error) {
+	switch format {
+	case "table":
+		return &printer{w: w}, nil
+	case "json":
+		return &printer{w: w, json: true}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q, use table or json", format)
+}
+
+// table writes v as JSON, or header and rows aligned in columns.
+func (p *printer) table(v any, header []string, rows [][]string) error {
+	if p.json {
+		return writeJSON(p.w, v)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// status writes the outcome of a command that changed something.
+func (p *printer) status(format string, args ...any) error {
+	message := fmt.Sprintf(format, args...)
+	if p.json {
+		return writeJSON(p.w, map[string]string{"status": message})
+	}
+	_, err := fmt.Fprintln(p.w, message)
+	return err
+}
+
+func writeJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
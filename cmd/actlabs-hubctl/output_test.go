@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestPrinter(t *testing.T) {
+	rows := []map[string]string{{"name": "one"}}
+
+	var buf bytes.Buffer
+	out, err := newPrinter(&buf, "table")
+	if err != nil {
+		t.Fatalf("newPrinter(table) = %v", err)
+	}
+	if err := out.table(rows, []string{"NAME", "STATUS"}, [][]string{{"one", "Running"}, {"longer-name", "Stopped"}}); err != nil {
+		t.Fatalf("table() = %v", err)
+	}
+	want := "NAME         STATUS\none          Running\nlonger-name  Stopped\n"
+	if buf.String() != want {
+		t.Errorf("table output = %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := out.status("imported %d labs", 2); err != nil {
+		t.Fatalf("status() = %v", err)
+	}
+	if buf.String() != "imported 2 labs\n" {
+		t.Errorf("status output = %q", buf.String())
+	}
+
+	buf.Reset()
+	out, err = newPrinter(&buf, "json")
+	if err != nil {
+		t.Fatalf("newPrinter(json) = %v", err)
+	}
+	if err := out.table(rows, []string{"NAME"}, [][]string{{"one"}}); err != nil {
+		t.Fatalf("table() = %v", err)
+	}
+	got := []map[string]string{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil || len(got) != 1 || got[0]["name"] != "one" {
+		t.Errorf("json table output = %q, %v", buf.String(), err)
+	}
+
+	buf.Reset()
+	if err := out.status("imported %d labs", 2); err != nil {
+		t.Fatalf("status() = %v", err)
+	}
+	status := map[string]string{}
+	if err := json.Unmarshal(buf.Bytes(), &status); err != nil || status["status"] != "imported 2 labs" {
+		t.Errorf("json status output = %q, %v", buf.String(), err)
+	}
+
+	if _, err := newPrinter(&buf, "yaml"); err == nil {
+		t.Error("newPrinter(yaml) = nil error, want unknown format")
+	}
+}
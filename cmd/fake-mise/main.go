@@ -0,0 +1,63 @@
+// fake-mise serves a fake MISE container for local development. Point
+// MISE_ENDPOINT at http://localhost:5000/ValidateRequest and mint tokens with
+// POST /token. It's its own binary so the hub never ships a token minter.
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"actlabs-hub/internal/fakemise"
+	"actlabs-hub/internal/logger"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	// The audience and issuer default to the hub's, read the way it reads them.
+	if err := godotenv.Load(); err != nil {
+		slog.WarnContext(ctx, "Error loading .env file")
+	}
+	if err := godotenv.Load(".env.local"); err != nil {
+		slog.WarnContext(ctx, "No .env.local file found or error loading it")
+	}
+
+	logger.SetupLogger(ctx)
+
+	if err := run(ctx, os.Args[1:]); err != nil {
+		logger.LogError(ctx, "fake mise stopped", "error", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("fake-mise", flag.ExitOnError)
+	addr := flags.String("addr", ":5000", "address to listen on")
+	keyFile := flags.String("key-file", "fake-mise.pem", "RSA signing key, created if missing")
+	audience := flags.String("aud", os.Getenv("AUTH_TOKEN_AUD"), "expected token audience")
+	issuer := flags.String("iss", os.Getenv("AUTH_TOKEN_ISS"), "expected token issuer")
+	statusCode := flags.Int("status", 0, "status code to return for every request, 0 to validate tokens")
+	flags.Parse(args)
+
+	key, err := fakemise.LoadOrCreateKey(*keyFile)
+	if err != nil {
+		return err
+	}
+
+	server := fakemise.New(key, fakemise.Options{
+		Audience:   *audience,
+		Issuer:     *issuer,
+		StatusCode: *statusCode,
+	})
+
+	logger.LogInfo(ctx, "fake mise listening", "addr", *addr, "key_file", *keyFile)
+	return http.ListenAndServe(*addr, server)
+}
@@ -0,0 +1,84 @@
+package actlabsserver
+
+import (
+	"sync"
+	"time"
+)
+
+// breakers keeps a circuit breaker per user's server, so one server that's
+// down doesn't block calls to the others.
+type breakers struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+func newBreakers(threshold int, cooldown time.Duration) *breakers {
+	return &breakers{
+		threshold: threshold,
+		cooldown:  cooldown,
+		breakers:  map[string]*breaker{},
+	}
+}
+
+func (b *breakers) get(server string) *breaker {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.breakers[server]; !ok {
+		b.breakers[server] = &breaker{threshold: b.threshold, cooldown: b.cooldown}
+	}
+	return b.breakers[server]
+}
+
+// breaker opens after threshold consecutive failures. Once the cooldown is
+// over a single trial request is let through; it closes the breaker if it
+// succeeds and opens it again if it fails. A threshold of zero disables it.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	trial     bool
+}
+
+func (b *breaker) allow() bool {
+	if b.threshold <= 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+	if time.Now().Before(b.openUntil) || b.trial {
+		return false
+	}
+	b.trial = true
+	return true
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.trial = false
+}
+
+func (b *breaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.trial = false
+	if b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
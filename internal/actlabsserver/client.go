@@ -0,0 +1,302 @@
+// Package actlabsserver is the hub's client for the users' actlabs servers.
+// Every server sits behind the shared internal endpoint, which routes each
+// request to the user's server by the x-user-id header.
+package actlabsserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"actlabs-hub/internal/config"
+	"actlabs-hub/internal/entity"
+	"actlabs-hub/internal/logger"
+	"actlabs-hub/internal/tracing"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrCircuitOpen is returned without calling the server while its circuit
+// breaker is open.
+var ErrCircuitOpen = errors.New("actlabs server circuit breaker is open")
+
+// StatusError is returned when the server answers with an unexpected status.
+type StatusError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("actlabs server %s %s returned %d: %s", e.Method, e.Path, e.StatusCode, e.Body)
+}
+
+// retryable is true for statuses that may succeed when tried again.
+func (e *StatusError) retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
+}
+
+// retryable is true when a request that failed with err can be sent again.
+// Idempotent requests always can; others only when they never reached the
+// server, as a timeout or 5xx may come after the server acted on them.
+func retryable(method string, err error) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+type Options struct {
+	BaseURL string
+	APIKey  string
+	Timeout time.Duration
+	// Attempts after the first one. Only network errors, 429 and 5xx are
+	// retried, and POSTs only when the connection couldn't be made.
+	MaxRetries     int
+	RetryBaseDelay time.Duration
+	// Consecutive failures that open a server's breaker, and how long it
+	// stays open before a single trial request is let through.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+type Client struct {
+	options    Options
+	httpClient *http.Client
+	breakers   *breakers
+}
+
+func NewClient(options Options) *Client {
+	return &Client{
+		options:    options,
+		httpClient: &http.Client{Timeout: options.Timeout, Transport: tracing.Transport(nil)},
+		breakers:   newBreakers(options.BreakerThreshold, options.BreakerCooldown),
+	}
+}
+
+// NewClientFromConfig returns a client for the internal endpoint of the
+// actlabs servers.
+func NewClientFromConfig(appConfig *config.Config) *Client {
+	return NewClient(Options{
+		BaseURL:          appConfig.ActlabsServerEndpointInternal,
+		APIKey:           appConfig.ActlabsServerApiKey,
+		Timeout:          time.Duration(appConfig.ActlabsServerRequestTimeoutSeconds) * time.Second,
+		MaxRetries:       appConfig.ActlabsServerMaxRetries,
+		RetryBaseDelay:   time.Duration(appConfig.ActlabsServerRetryBaseDelayMilliseconds) * time.Millisecond,
+		BreakerThreshold: appConfig.ActlabsServerCircuitBreakerThreshold,
+		BreakerCooldown:  time.Duration(appConfig.ActlabsServerCircuitBreakerCooldownSeconds) * time.Second,
+	})
+}
+
+// TerraformResponse identifies the operation the server accepted.
+type TerraformResponse struct {
+	OperationId string `json:"operationId"`
+}
+
+// StatusResponse is what the server reports on its readiness probe.
+type StatusResponse struct {
+	Status  string `json:"status"`
+	Version string `json:"version,omitempty"`
+}
+
+// OperationStatus is what the server reports about the terraform operation of
+// a workspace.
+type OperationStatus struct {
+	Running     bool   `json:"running"`
+	OperationId string `json:"operationId,omitempty"`
+	Action      string `json:"action,omitempty"`
+}
+
+// Apply asks the user's server to deploy the deployment's lab.
+func (c *Client) Apply(ctx context.Context, userPrincipalName string, deployment entity.Deployment) (TerraformResponse, error) {
+	return c.terraform(ctx, "apply", userPrincipalName, deployment)
+}
+
+// Destroy asks the user's server to destroy the deployment.
+func (c *Client) Destroy(ctx context.Context, userPrincipalName string, deployment entity.Deployment) (TerraformResponse, error) {
+	return c.terraform(ctx, "destroy", userPrincipalName, deployment)
+}
+
+func (c *Client) terraform(ctx context.Context, action string, userPrincipalName string, deployment entity.Deployment) (TerraformResponse, error) {
+	body, err := json.Marshal(deployment)
+	if err != nil {
+		return TerraformResponse{}, err
+	}
+
+	// The operation id is generated once so a retry after a failed connection
+	// is the same operation.
+	response := TerraformResponse{OperationId: uuid.New().String()}
+	path := "/api/terraform/" + action + "/" + response.OperationId
+
+	if _, err := c.do(ctx, http.MethodPost, path, userPrincipalName, body, http.StatusAccepted); err != nil {
+		return TerraformResponse{}, err
+	}
+
+	return response, nil
+}
+
+// OperationStatus asks the user's server whether a terraform operation is
+// still running in the deployment's workspace.
+func (c *Client) OperationStatus(ctx context.Context, userPrincipalName string, deployment entity.Deployment) (OperationStatus, error) {
+	path := "/api/terraform/status/" + url.PathEscape(deployment.DeploymentSubscriptionId) + "/" + url.PathEscape(deployment.DeploymentWorkspace)
+
+	body, err := c.do(ctx, http.MethodGet, path, userPrincipalName, nil, http.StatusOK)
+	if err != nil {
+		return OperationStatus{}, err
+	}
+
+	status := OperationStatus{}
+	if err := json.Unmarshal(body, &status); err != nil {
+		return OperationStatus{}, fmt.Errorf("not able to read operation status: %w", err)
+	}
+
+	return status, nil
+}
+
+// Status calls the user's server readiness probe at path.
+func (c *Client) Status(ctx context.Context, userPrincipalName string, path string) (StatusResponse, error) {
+	body, err := c.do(ctx, http.MethodGet, path, userPrincipalName, nil, http.StatusOK)
+	if err != nil {
+		return StatusResponse{}, err
+	}
+
+	status := StatusResponse{}
+	// Older servers answer with plain text, being up is all that matters then.
+	if err := json.Unmarshal(body, &status); err != nil || status.Status == "" {
+		status.Status = strings.TrimSpace(string(body))
+	}
+
+	return status, nil
+}
+
+func (c *Client) do(ctx context.Context, method string, path string, userPrincipalName string, body []byte, expectedStatus int) (responseBody []byte, err error) {
+	// Each attempt is a child span of this one.
+	ctx, span := tracing.Start(ctx, "actlabsserver "+method,
+		trace.WithAttributes(attribute.String("actlabsserver.path", path)),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	breaker := c.breakers.get(userPrincipalName)
+	if !breaker.allow() {
+		return nil, ErrCircuitOpen
+	}
+
+	for attempt := 0; attempt <= c.options.MaxRetries; attempt++ {
+		if attempt > 0 {
+			if waitErr := sleep(ctx, backoff(c.options.RetryBaseDelay, attempt)); waitErr != nil {
+				break
+			}
+			logger.LogDebug(ctx, "retrying actlabs server request",
+				"method", method,
+				"path", path,
+				"attempt", attempt,
+				"error", err,
+			)
+		}
+
+		responseBody, err = c.send(ctx, method, path, userPrincipalName, body, expectedStatus)
+		if err == nil {
+			breaker.success()
+			return responseBody, nil
+		}
+
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && !statusErr.retryable() {
+			// The server answered, it's up even if it didn't like the request.
+			breaker.success()
+			return nil, err
+		}
+		if ctx.Err() != nil || !retryable(method, err) {
+			break
+		}
+	}
+
+	breaker.failure()
+	logger.LogError(ctx, "actlabs server request failed",
+		"method", method,
+		"path", path,
+		"requested_user_id", userPrincipalName,
+		"error", err,
+	)
+
+	return nil, err
+}
+
+func (c *Client) send(ctx context.Context, method string, path string, userPrincipalName string, body []byte, expectedStatus int) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.options.BaseURL, "/")+path, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("x-api-key", c.options.APIKey)
+	req.Header.Set("x-user-id", userPrincipalName)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != expectedStatus {
+		return nil, &StatusError{
+			Method:     method,
+			Path:       path,
+			StatusCode: resp.StatusCode,
+			Body:       strings.TrimSpace(string(responseBody)),
+		}
+	}
+
+	return responseBody, nil
+}
+
+// backoff is exponential with full jitter, so retries from many callers
+// don't arrive together.
+func backoff(base time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+	return rand.N(base << (attempt - 1))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,151 @@
+package actlabsserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"actlabs-hub/internal/entity"
+)
+
+func newTestClient(fake *FakeServer) *Client {
+	return NewClient(Options{
+		BaseURL:          fake.URL,
+		APIKey:           "key",
+		Timeout:          time.Second,
+		MaxRetries:       2,
+		RetryBaseDelay:   time.Millisecond,
+		BreakerThreshold: 2,
+		BreakerCooldown:  time.Hour,
+	})
+}
+
+func TestDestroy(t *testing.T) {
+	fake := NewFakeServer()
+	defer fake.Close()
+
+	client := newTestClient(fake)
+	deployment := entity.Deployment{DeploymentWorkspace: "ws"}
+
+	response, err := client.Destroy(context.Background(), "user@microsoft.com", deployment)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	requests := fake.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(requests))
+	}
+	request := requests[0]
+	if request.Path != "/api/terraform/destroy/"+response.OperationId || request.UserId != "user@microsoft.com" || request.APIKey != "key" || request.Deployment.DeploymentWorkspace != "ws" {
+		t.Errorf("unexpected request: %+v", request)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	fake := NewFakeServer()
+	defer fake.Close()
+
+	client := newTestClient(fake)
+
+	// Server errors of idempotent requests are retried.
+	fake.FailNext(http.StatusBadGateway, http.StatusServiceUnavailable)
+	if _, err := client.Status(context.Background(), "user@microsoft.com", "/status"); err != nil {
+		t.Fatalf("expected retries to succeed, got %v", err)
+	}
+	if len(fake.Requests()) != 3 {
+		t.Errorf("expected 3 attempts, got %d", len(fake.Requests()))
+	}
+
+	// The server may have acted on a POST that failed, so it isn't retried.
+	fake.FailNext(http.StatusBadGateway)
+	_, err := client.Apply(context.Background(), "user@microsoft.com", entity.Deployment{})
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("expected bad gateway status error, got %v", err)
+	}
+	if len(fake.Requests()) != 4 {
+		t.Errorf("expected apply not to be retried, got %d requests", len(fake.Requests()))
+	}
+
+	// Client errors aren't retried either.
+	fake.FailNext(http.StatusBadRequest)
+	_, err = client.Status(context.Background(), "user@microsoft.com", "/status")
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected bad request status error, got %v", err)
+	}
+	if len(fake.Requests()) != 5 {
+		t.Errorf("expected bad request not to be retried, got %d requests", len(fake.Requests()))
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	dialErr := &url.Error{Op: "Post", URL: "http://server", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}
+	readErr := &url.Error{Op: "Post", URL: "http://server", Err: &net.OpError{Op: "read", Err: errors.New("connection reset")}}
+
+	tests := []struct {
+		method string
+		err    error
+		want   bool
+	}{
+		{http.MethodGet, readErr, true},
+		{http.MethodPost, dialErr, true},
+		{http.MethodPost, readErr, false},
+		{http.MethodPost, context.DeadlineExceeded, false},
+		{http.MethodPost, &StatusError{StatusCode: http.StatusServiceUnavailable}, false},
+	}
+	for _, tt := range tests {
+		if got := retryable(tt.method, tt.err); got != tt.want {
+			t.Errorf("retryable(%s, %v) = %v, want %v", tt.method, tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	fake := NewFakeServer()
+	defer fake.Close()
+
+	client := newTestClient(fake)
+	fake.FailUser("down@microsoft.com", http.StatusServiceUnavailable)
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.Status(context.Background(), "down@microsoft.com", "/status"); err == nil {
+			t.Fatalf("expected error from server that is down")
+		}
+	}
+
+	requests := len(fake.Requests())
+	if _, err := client.Status(context.Background(), "down@microsoft.com", "/status"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected open circuit, got %v", err)
+	}
+	if len(fake.Requests()) != requests {
+		t.Errorf("expected no request while the circuit is open")
+	}
+
+	// Other servers aren't affected.
+	status, err := client.Status(context.Background(), "user@microsoft.com", "/status")
+	if err != nil || status.Status != "OK" {
+		t.Errorf("expected other server to be up, got %+v, %v", status, err)
+	}
+}
+
+func TestOperationStatus(t *testing.T) {
+	fake := NewFakeServer()
+	defer fake.Close()
+
+	client := newTestClient(fake)
+	deployment := entity.Deployment{DeploymentSubscriptionId: "sub", DeploymentWorkspace: "ws"}
+	fake.SetRunning("ws", true)
+
+	status, err := client.OperationStatus(context.Background(), "user@microsoft.com", deployment)
+	if err != nil || !status.Running {
+		t.Fatalf("expected the operation to be running, got %+v, %v", status, err)
+	}
+	if path := fake.Requests()[0].Path; path != "/api/terraform/status/sub/ws" {
+		t.Errorf("unexpected path %s", path)
+	}
+}
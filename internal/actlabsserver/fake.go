@@ -0,0 +1,112 @@
+package actlabsserver
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+
+	"actlabs-hub/internal/entity"
+)
+
+// FakeRequest is a request the fake server received.
+type FakeRequest struct {
+	Method     string
+	Path       string
+	UserId     string
+	APIKey     string
+	Deployment entity.Deployment
+}
+
+// FakeServer stands in for the actlabs servers in tests. It accepts every
+// terraform request, reports no operation running unless told otherwise with
+// SetRunning and answers the readiness probe at /status, unless told to fail
+// with FailNext or FailUser.
+type FakeServer struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	requests  []FakeRequest
+	failNext  []int
+	failUsers map[string]int
+	running   map[string]bool
+}
+
+func NewFakeServer() *FakeServer {
+	f := &FakeServer{failUsers: map[string]int{}, running: map[string]bool{}}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.handle))
+	return f
+}
+
+// FailNext answers the next requests with the given statuses, in order.
+func (f *FakeServer) FailNext(statuses ...int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failNext = append(f.failNext, statuses...)
+}
+
+// FailUser answers every request for the user's server with status.
+func (f *FakeServer) FailUser(userId string, status int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failUsers[userId] = status
+}
+
+// SetRunning reports whether an operation is running in workspace.
+func (f *FakeServer) SetRunning(workspace string, running bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.running[workspace] = running
+}
+
+// Requests returns the requests received so far.
+func (f *FakeServer) Requests() []FakeRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]FakeRequest{}, f.requests...)
+}
+
+func (f *FakeServer) handle(w http.ResponseWriter, r *http.Request) {
+	request := FakeRequest{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		UserId: r.Header.Get("x-user-id"),
+		APIKey: r.Header.Get("x-api-key"),
+	}
+	if body, err := io.ReadAll(r.Body); err == nil && len(body) > 0 {
+		json.Unmarshal(body, &request.Deployment)
+	}
+
+	f.mu.Lock()
+	f.requests = append(f.requests, request)
+	status, failing := f.failUsers[request.UserId]
+	if !failing && len(f.failNext) > 0 {
+		status, failing = f.failNext[0], true
+		f.failNext = f.failNext[1:]
+	}
+	f.mu.Unlock()
+
+	if failing {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/api/terraform/status/"):
+		f.mu.Lock()
+		running := f.running[path.Base(r.URL.Path)]
+		f.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(OperationStatus{Running: running})
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/api/terraform/"):
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == http.MethodGet && r.URL.Path == "/status":
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"OK"}`))
+	default:
+		http.NotFound(w, r)
+	}
+}
@@ -0,0 +1,174 @@
+// Package app wires the hub's repositories and services. The server and
+// actlabs-hubctl both build on it so they always act on the same storage the
+// same way.
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"actlabs-hub/internal/actlabsserver"
+	"actlabs-hub/internal/auth"
+	"actlabs-hub/internal/config"
+	"actlabs-hub/internal/entity"
+	"actlabs-hub/internal/helper"
+	"actlabs-hub/internal/identity"
+	"actlabs-hub/internal/logger"
+	"actlabs-hub/internal/notifier"
+	"actlabs-hub/internal/redis"
+	"actlabs-hub/internal/repository"
+	"actlabs-hub/internal/service"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+type App struct {
+	Config *config.Config
+	Redis  *goredis.Client
+	Auth   *auth.Auth
+
+	// Background tasks started by services, waited for on shutdown.
+	Tasks *helper.TaskGroup
+
+	EventRepository      entity.EventRepository
+	ServerRepository     entity.ServerRepository
+	LabRepository        entity.LabRepository
+	AssignmentRepository entity.AssignmentRepository
+	ChallengeRepository  entity.ChallengeRepository
+	AuthRepository       entity.AuthRepository
+	DeploymentRepository entity.DeploymentRepository
+	QuotaRepository      entity.QuotaRepository
+	ScheduleRepository   entity.ScheduleRepository
+	SettingsRepository   entity.SettingsRepository
+
+	EventService      entity.EventService
+	ServerService     entity.ServerService
+	LabService        entity.LabService
+	AssignmentService entity.AssignmentService
+	ChallengeService  entity.ChallengeService
+	AuthService       entity.AuthService
+	QuotaService      entity.QuotaService
+	CostService       entity.CostService
+	DeploymentService entity.DeploymentService
+	ScheduleService   entity.ScheduleService
+	SettingsService   entity.SettingsService
+}
+
+// New connects to Redis and storage and builds every repository and service.
+// Close releases the connections.
+func New(ctx context.Context, appConfig *config.Config) (*App, error) {
+	a := &App{Config: appConfig, Tasks: helper.NewTaskGroup()}
+
+	var err error
+
+	a.Redis, err = redis.NewRedisClient(ctx, appConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing redis: %w", err)
+	}
+
+	if err := a.build(ctx); err != nil {
+		a.Close()
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func (a *App) build(ctx context.Context) error {
+	appConfig := a.Config
+
+	var err error
+
+	a.Auth, err = auth.NewAuth(ctx, appConfig)
+	if err != nil {
+		return fmt.Errorf("error initializing auth: %w", err)
+	}
+
+	// The identity may only be allowed to use existing tables, readiness
+	// reports the ones that are missing.
+	if err := a.Auth.CreateTables(ctx); err != nil {
+		logger.LogWarning(ctx, "not able to create tables", "error", err)
+	}
+
+	a.EventRepository, err = repository.NewEventRepository(ctx, a.Auth)
+	if err != nil {
+		return fmt.Errorf("error initializing event repository: %w", err)
+	}
+	a.ServerRepository, err = repository.NewServerRepository(appConfig, a.Auth, a.Redis)
+	if err != nil {
+		return fmt.Errorf("error initializing server repository: %w", err)
+	}
+	a.LabRepository, err = repository.NewLabRepository(a.Auth, appConfig, a.Redis)
+	if err != nil {
+		return fmt.Errorf("error initializing lab repository: %w", err)
+	}
+	a.AssignmentRepository, err = repository.NewAssignmentRepository(a.Auth, appConfig, a.Redis)
+	if err != nil {
+		return fmt.Errorf("error initializing assignment repository: %w", err)
+	}
+	a.ChallengeRepository, err = repository.NewChallengeRepository(a.Auth, appConfig, a.Redis)
+	if err != nil {
+		return fmt.Errorf("error initializing challenge repository: %w", err)
+	}
+	a.AuthRepository, err = repository.NewAuthRepository(a.Auth, appConfig, a.Redis)
+	if err != nil {
+		return fmt.Errorf("error initializing auth repository: %w", err)
+	}
+
+	actlabsServerClient := actlabsserver.NewClientFromConfig(appConfig)
+
+	a.DeploymentRepository, err = repository.NewDeploymentRepository(a.Auth, a.Redis, appConfig, actlabsServerClient)
+	if err != nil {
+		return fmt.Errorf("error initializing deployment repository: %w", err)
+	}
+	a.QuotaRepository, err = repository.NewQuotaRepository(a.Auth, a.Redis)
+	if err != nil {
+		return fmt.Errorf("error initializing quota repository: %w", err)
+	}
+	a.ScheduleRepository, err = repository.NewScheduleRepository(a.Auth)
+	if err != nil {
+		return fmt.Errorf("error initializing schedule repository: %w", err)
+	}
+
+	a.SettingsRepository, err = repository.NewSettingsRepository(a.Redis)
+	if err != nil {
+		return fmt.Errorf("error initializing settings repository: %w", err)
+	}
+
+	priceSheetRepository, err := repository.NewPriceSheetRepository(appConfig.ActlabsHubPriceSheetFile)
+	if err != nil {
+		return fmt.Errorf("error initializing price sheet repository: %w", err)
+	}
+
+	deploymentNotifier, err := notifier.NewNotifier(appConfig)
+	if err != nil {
+		return fmt.Errorf("error initializing notifier: %w", err)
+	}
+
+	directory := identity.NewDirectory(appConfig.ActlabsHubTenants)
+
+	// Role assignments can't be read locally, so every user is authorized.
+	var authorizationChecker entity.ServerAuthorizationChecker = a.ServerRepository
+	if appConfig.ActlabsEnvironmentName == "local" {
+		authorizationChecker = repository.NewLocalAuthorizationChecker()
+	}
+
+	a.EventService = service.NewEventService(a.EventRepository)
+	a.ServerService = service.NewServerService(a.ServerRepository, appConfig, a.EventService, directory, authorizationChecker, a.DeploymentRepository)
+	a.LabService = service.NewLabService(a.LabRepository, a.EventService, appConfig)
+	a.AssignmentService = service.NewAssignmentService(a.AssignmentRepository, a.LabService, a.EventService, directory)
+	a.ChallengeService = service.NewChallengeService(a.ChallengeRepository, a.LabService, directory)
+	a.AuthService = service.NewAuthService(a.AuthRepository)
+	a.QuotaService = service.NewQuotaService(a.QuotaRepository, a.DeploymentRepository, appConfig)
+	a.CostService = service.NewCostService(priceSheetRepository)
+	a.DeploymentService = service.NewDeploymentService(a.DeploymentRepository, a.ServerService, a.EventService, a.AuthService, deploymentNotifier, a.QuotaService, a.CostService, repository.NewServerStatusClient(appConfig, actlabsServerClient), a.Tasks, appConfig)
+	a.SettingsService = service.NewSettingsService(a.SettingsRepository, a.EventService, appConfig)
+	a.ScheduleService = service.NewScheduleService(a.ScheduleRepository, a.DeploymentRepository, a.DeploymentService, a.LabService, a.ServerService, a.EventService, appConfig)
+
+	return nil
+}
+
+// Close closes the Redis client. Background tasks must have stopped first.
+func (a *App) Close() error {
+	return a.Redis.Close()
+}
@@ -2,12 +2,18 @@ package auth
 
 import (
 	"actlabs-hub/internal/config"
+	"actlabs-hub/internal/metrics"
+	"actlabs-hub/internal/tracing"
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/data/aztables"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
 )
 
 type Auth struct {
@@ -20,6 +26,8 @@ type Auth struct {
 	ActlabsDeploymentsTableClient          *aztables.Client
 	ActlabsEventsTableClient               *aztables.Client
 	ActlabSDeploymentOperationsTableClient *aztables.Client
+	ActlabsQuotasTableClient               *aztables.Client
+	ActlabsSchedulesTableClient            *aztables.Client
 }
 
 func NewAuth(ctx context.Context, appConfig *config.Config) (*Auth, error) {
@@ -108,6 +116,24 @@ func NewAuth(ctx context.Context, appConfig *config.Config) (*Auth, error) {
 		return nil, fmt.Errorf("not able to create table client %w", err)
 	}
 
+	actlabsQuotasTableClient, err := GetTableClient(
+		cred,
+		appConfig.ActlabsHubStorageAccount,
+		appConfig.ActlabsHubQuotasTableName,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("not able to create table client %w", err)
+	}
+
+	actlabsSchedulesTableClient, err := GetTableClient(
+		cred,
+		appConfig.ActlabsHubStorageAccount,
+		appConfig.ActlabsHubSchedulesTableName,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("not able to create table client %w", err)
+	}
+
 	return &Auth{
 		Cred:           cred,
 		FdpoCredential: fdpoCredential,
@@ -119,6 +145,8 @@ func NewAuth(ctx context.Context, appConfig *config.Config) (*Auth, error) {
 		ActlabsDeploymentsTableClient:          actlabsDeploymentsTableClient,
 		ActlabsEventsTableClient:               actlabsEventsTableClient,
 		ActlabSDeploymentOperationsTableClient: actlabsDeploymentOperationsTableClient,
+		ActlabsQuotasTableClient:               actlabsQuotasTableClient,
+		ActlabsSchedulesTableClient:            actlabsSchedulesTableClient,
 	}, nil
 }
 
@@ -130,5 +158,54 @@ func GetTableClient(cred azcore.TokenCredential, storageAccountName string, tabl
 		tableUrl = "https://127.0.0.1:10002/" + storageAccountName + "/" + tableName
 	}
 
-	return aztables.NewClient(tableUrl, cred, nil)
+	return aztables.NewClient(tableUrl, cred, &aztables.ClientOptions{
+		ClientOptions: azcore.ClientOptions{
+			PerCallPolicies: []policy.Policy{metrics.StoragePolicy("table"), tracing.StoragePolicy("table")},
+		},
+	})
+}
+
+// TableClients returns every table client by a short name, for health checks.
+func (a *Auth) TableClients() map[string]*aztables.Client {
+	return map[string]*aztables.Client{
+		"servers":              a.ActlabsServersTableClient,
+		"readiness":            a.ActlabsReadinessTableClient,
+		"challenges":           a.ActlabsChallengesTableClient,
+		"profiles":             a.ActlabsProfilesTableClient,
+		"deployments":          a.ActlabsDeploymentsTableClient,
+		"events":               a.ActlabsEventsTableClient,
+		"deploymentOperations": a.ActlabSDeploymentOperationsTableClient,
+		"quotas":               a.ActlabsQuotasTableClient,
+		"schedules":            a.ActlabsSchedulesTableClient,
+	}
+}
+
+// CreateTables creates the tables that don't exist yet, so tables added in a
+// release are there before readiness checks them.
+func (a *Auth) CreateTables(ctx context.Context) error {
+	var errs []error
+	for name, client := range a.TableClients() {
+		_, err := client.CreateTable(ctx, nil)
+
+		var responseErr *azcore.ResponseError
+		if err != nil && !(errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusConflict) {
+			errs = append(errs, fmt.Errorf("creating %s table: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (a *Auth) GetBlobContainerClient(storageAccountName string, containerName string) (*container.Client, error) {
+	containerUrl := "https://" + storageAccountName + ".blob.core.windows.net/" + containerName
+
+	// Use this for local emulator
+	if storageAccountName == "devstoreaccount1" {
+		containerUrl = "https://localhost:10000/" + storageAccountName + "/" + containerName
+	}
+
+	return container.NewClient(containerUrl, a.Cred, &container.ClientOptions{
+		ClientOptions: azcore.ClientOptions{
+			PerCallPolicies: []policy.Policy{metrics.StoragePolicy("blob"), tracing.StoragePolicy("blob")},
+		},
+	})
 }
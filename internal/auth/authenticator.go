@@ -0,0 +1,296 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"actlabs-hub/internal/config"
+	"actlabs-hub/internal/entity"
+	"actlabs-hub/internal/logger"
+	"actlabs-hub/internal/mise"
+)
+
+const (
+	AuthMethodMISE     = "mise"
+	AuthMethodJWT      = "jwt"
+	AuthMethodAPIKey   = "apikey"
+	AuthMethodDevToken = "devtoken"
+)
+
+type principalContextKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the authenticated principal.
+func WithPrincipal(ctx context.Context, principal entity.Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+// GetPrincipal returns the principal stored in ctx by the auth middleware.
+func GetPrincipal(ctx context.Context) (entity.Principal, bool) {
+	principal, ok := ctx.Value(principalContextKey{}).(entity.Principal)
+	return principal, ok
+}
+
+// AuthenticatorChain tries each authenticator in order and returns the first
+// principal produced.
+type AuthenticatorChain []entity.Authenticator
+
+func (chain AuthenticatorChain) Name() string {
+	names := make([]string, 0, len(chain))
+	for _, authenticator := range chain {
+		names = append(names, authenticator.Name())
+	}
+	return strings.Join(names, ",")
+}
+
+func (chain AuthenticatorChain) Authenticate(ctx context.Context, r *http.Request) (entity.Principal, error) {
+	var errs []error
+	for _, authenticator := range chain {
+		principal, err := authenticator.Authenticate(ctx, r)
+		if err == nil {
+			return principal, nil
+		}
+		if errors.Is(err, entity.ErrNoCredentials) {
+			continue
+		}
+
+		logger.LogDebug(ctx, "authenticator rejected request",
+			"authenticator", authenticator.Name(),
+			"error", err,
+		)
+		errs = append(errs, fmt.Errorf("%s: %w", authenticator.Name(), err))
+	}
+
+	if len(errs) == 0 {
+		return entity.Principal{}, entity.ErrNoCredentials
+	}
+	return entity.Principal{}, errors.Join(errs...)
+}
+
+// NewAuthenticatorChain builds the chain configured in AUTH_AUTHENTICATORS.
+func NewAuthenticatorChain(appConfig *config.Config, miseServer mise.Server) (AuthenticatorChain, error) {
+	chain := AuthenticatorChain{}
+	for _, name := range appConfig.AuthAuthenticators {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case AuthMethodMISE:
+			chain = append(chain, &MISEAuthenticator{Server: miseServer})
+		case AuthMethodJWT:
+			chain = append(chain, &JWTAuthenticator{
+				JWKSURL:  appConfig.AuthJwksURL,
+				Audience: appConfig.AuthTokenAud,
+				Issuer:   appConfig.AuthTokenIss,
+			})
+		case AuthMethodAPIKey:
+			chain = append(chain, &APIKeyAuthenticator{APIKey: appConfig.ActlabsServerApiKey})
+		case AuthMethodDevToken:
+			if appConfig.ActlabsEnvironmentName != "local" {
+				return nil, fmt.Errorf("authenticator %s is only allowed when ACTLABS_ENVIRONMENT_NAME is local", AuthMethodDevToken)
+			}
+			if appConfig.AuthDevStaticToken == "" || appConfig.AuthDevUserId == "" {
+				return nil, fmt.Errorf("authenticator %s requires AUTH_DEV_STATIC_TOKEN and AUTH_DEV_USER_ID", AuthMethodDevToken)
+			}
+			chain = append(chain, &DevTokenAuthenticator{
+				Token:  appConfig.AuthDevStaticToken,
+				UserId: appConfig.AuthDevUserId,
+			})
+		default:
+			return nil, fmt.Errorf("unknown authenticator %q", name)
+		}
+	}
+
+	if len(chain) == 0 {
+		return nil, errors.New("no authenticators configured")
+	}
+
+	return chain, nil
+}
+
+// MISEAuthenticator delegates token validation to the MISE container.
+type MISEAuthenticator struct {
+	Server mise.Server
+}
+
+func (a *MISEAuthenticator) Name() string {
+	return AuthMethodMISE
+}
+
+func (a *MISEAuthenticator) Authenticate(ctx context.Context, r *http.Request) (entity.Principal, error) {
+	authHeader := r.Header.Get("Authorization")
+	if _, ok := bearerToken(authHeader); !ok {
+		return entity.Principal{}, entity.ErrNoCredentials
+	}
+
+	result, err := a.Server.DelegateAuthToContainer(ctx, authHeader, r.URL.String(), r.Method, clientIP(r))
+	if err != nil {
+		return entity.Principal{}, err
+	}
+
+	userPrincipal := firstClaim(result.SubjectClaims, "preferred_username")
+	if userPrincipal == "" {
+		return entity.Principal{}, errors.New("preferred_username claim missing or empty")
+	}
+
+	return entity.Principal{
+		UserId:     userPrincipal,
+		TenantId:   firstClaim(result.SubjectClaims, "tid"),
+		AuthMethod: AuthMethodMISE,
+		Claims:     result.SubjectClaims,
+	}, nil
+}
+
+// JWTAuthenticator verifies bearer tokens in-process against a JWKS endpoint.
+type JWTAuthenticator struct {
+	JWKSURL  string
+	Audience string
+	Issuer   string
+}
+
+func (a *JWTAuthenticator) Name() string {
+	return AuthMethodJWT
+}
+
+func (a *JWTAuthenticator) Authenticate(ctx context.Context, r *http.Request) (entity.Principal, error) {
+	tokenString, ok := bearerToken(r.Header.Get("Authorization"))
+	if !ok {
+		return entity.Principal{}, entity.ErrNoCredentials
+	}
+
+	token, err := ParseTokenWithKeySet(ctx, tokenString, a.JWKSURL)
+	if err != nil {
+		return entity.Principal{}, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return entity.Principal{}, errors.New("invalid claims")
+	}
+
+	if err := verifyClaims(claims, a.Audience, a.Issuer); err != nil {
+		return entity.Principal{}, err
+	}
+
+	principalClaims := flattenClaims(claims)
+
+	userPrincipal := firstClaim(principalClaims, "upn")
+	if userPrincipal == "" {
+		userPrincipal = firstClaim(principalClaims, "preferred_username")
+	}
+	if userPrincipal == "" {
+		return entity.Principal{}, errors.New("user principal name not found in token")
+	}
+
+	return entity.Principal{
+		UserId:     userPrincipal,
+		TenantId:   firstClaim(principalClaims, "tid"),
+		AuthMethod: AuthMethodJWT,
+		Claims:     principalClaims,
+	}, nil
+}
+
+// APIKeyAuthenticator authenticates trusted services (actlabs-server) that
+// call on behalf of the user named in the x-user-id header.
+type APIKeyAuthenticator struct {
+	APIKey string
+}
+
+func (a *APIKeyAuthenticator) Name() string {
+	return AuthMethodAPIKey
+}
+
+func (a *APIKeyAuthenticator) Authenticate(ctx context.Context, r *http.Request) (entity.Principal, error) {
+	reqApiKey := r.Header.Get("x-api-key")
+	if reqApiKey == "" {
+		return entity.Principal{}, entity.ErrNoCredentials
+	}
+
+	if a.APIKey == "" || subtle.ConstantTimeCompare([]byte(reqApiKey), []byte(a.APIKey)) != 1 {
+		return entity.Principal{}, errors.New("invalid api key")
+	}
+
+	reqUserPrincipal := r.Header.Get("x-user-id")
+	if reqUserPrincipal == "" {
+		return entity.Principal{}, errors.New("no user principal provided")
+	}
+
+	return entity.Principal{
+		UserId:     reqUserPrincipal,
+		AuthMethod: AuthMethodAPIKey,
+		Claims:     map[string][]string{},
+	}, nil
+}
+
+// DevTokenAuthenticator accepts a single static bearer token and maps it to a
+// fixed user. It is meant for local development only.
+type DevTokenAuthenticator struct {
+	Token  string
+	UserId string
+}
+
+func (a *DevTokenAuthenticator) Name() string {
+	return AuthMethodDevToken
+}
+
+func (a *DevTokenAuthenticator) Authenticate(ctx context.Context, r *http.Request) (entity.Principal, error) {
+	tokenString, ok := bearerToken(r.Header.Get("Authorization"))
+	if !ok || subtle.ConstantTimeCompare([]byte(tokenString), []byte(a.Token)) != 1 {
+		// Not ours; let the next authenticator look at the token.
+		return entity.Principal{}, entity.ErrNoCredentials
+	}
+
+	return entity.Principal{
+		UserId:     a.UserId,
+		AuthMethod: AuthMethodDevToken,
+		Claims: map[string][]string{
+			"upn": {a.UserId},
+		},
+	}, nil
+}
+
+func bearerToken(authHeader string) (string, bool) {
+	token, found := strings.CutPrefix(authHeader, "Bearer ")
+	if !found || token == "" {
+		return "", false
+	}
+	return token, true
+}
+
+func firstClaim(claims map[string][]string, name string) string {
+	if values := claims[name]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// flattenClaims converts JWT claims into the multi-valued shape MISE returns.
+func flattenClaims(claims jwt.MapClaims) map[string][]string {
+	flattened := make(map[string][]string, len(claims))
+	for name, value := range claims {
+		switch v := value.(type) {
+		case string:
+			flattened[name] = []string{v}
+		case []interface{}:
+			for _, item := range v {
+				flattened[name] = append(flattened[name], fmt.Sprint(item))
+			}
+		default:
+			flattened[name] = []string{fmt.Sprint(v)}
+		}
+	}
+	return flattened
+}
+
+// clientIP mirrors gin's ClientIP with no trusted proxies, which is how the
+// router is configured.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(strings.TrimSpace(r.RemoteAddr))
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
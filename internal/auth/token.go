@@ -34,13 +34,25 @@ func GetClaimFromToken(ctx context.Context, tokenString string, claim string) (s
 	return value, nil
 }
 
+// DefaultJWKSURL is the Entra ID key set used to verify access tokens.
+const DefaultJWKSURL = "https://login.microsoftonline.com/common/discovery/v2.0/keys"
+
 func ParseToken(ctx context.Context, tokenString string) (*jwt.Token, error) {
+	return ParseTokenWithKeySet(ctx, tokenString, DefaultJWKSURL)
+}
+
+// ParseTokenWithKeySet parses and verifies the signature of the token using
+// the keys published at jwksURL.
+func ParseTokenWithKeySet(ctx context.Context, tokenString string, jwksURL string) (*jwt.Token, error) {
 	// Drop the Bearer prefix if it exists
 	if strings.HasPrefix(tokenString, "Bearer ") {
 		tokenString = strings.Split(tokenString, "Bearer ")[1]
 	}
 
-	keySet, err := jwk.Fetch(ctx, "https://login.microsoftonline.com/common/discovery/v2.0/keys")
+	keySet, err := jwk.Fetch(ctx, jwksURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch key set: %w", err)
+	}
 
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		if token.Method.Alg() != jwa.RS256.String() {
@@ -91,34 +103,43 @@ func VerifyToken(ctx context.Context, tokenString string) (bool, error) {
 		return false, errors.New("invalid claims")
 	}
 
+	if err := verifyClaims(claims, os.Getenv("AUTH_TOKEN_AUD"), os.Getenv("AUTH_TOKEN_ISS")); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// verifyClaims checks the audience, issuer and expiration of the token.
+func verifyClaims(claims jwt.MapClaims, expectedAud string, expectedIss string) error {
 	// check the audience
 	aud, ok := claims["aud"].(string)
 	if !ok {
-		return false, errors.New("not able to get audience from claims")
+		return errors.New("not able to get audience from claims")
 	}
-	if aud != os.Getenv("AUTH_TOKEN_AUD") {
-		return false, errors.New("unexpected audience, expected " + os.Getenv("AUTH_TOKEN_AUD") + " but got " + aud)
+	if aud != expectedAud {
+		return errors.New("unexpected audience, expected " + expectedAud + " but got " + aud)
 	}
 
 	// Check the issuer
 	iss, ok := claims["iss"].(string)
 	if !ok {
-		return false, errors.New("not able to get issuer from claims")
+		return errors.New("not able to get issuer from claims")
 	}
-	if iss != os.Getenv("AUTH_TOKEN_ISS") {
-		return false, errors.New("unexpected issuer, expected " + os.Getenv("AUTH_TOKEN_ISS") + " but got " + iss)
+	if iss != expectedIss {
+		return errors.New("unexpected issuer, expected " + expectedIss + " but got " + iss)
 	}
 
 	// Check the expiration time
 	exp, ok := claims["exp"].(float64)
 	if !ok {
-		return false, errors.New("invalid expiration time")
+		return errors.New("invalid expiration time")
 	}
 	if time.Now().Unix() > int64(exp) {
-		return false, errors.New("token has expired")
+		return errors.New("token has expired")
 	}
 
-	return true, nil
+	return nil
 }
 
 func GetTokenJSON(ctx context.Context, token string) (map[string]interface{}, error) {
@@ -198,47 +219,3 @@ func VerifyUserPrincipalName(ctx context.Context, userPrincipalName string, toke
 	userPrincipalNameInToken, _ := GetUserPrincipalFromToken(ctx, token)
 	return userPrincipalName == userPrincipalNameInToken
 }
-
-func VerifyArmToken(ctx context.Context, tokenString string) (bool, error) {
-
-	token, err := ParseToken(ctx, tokenString)
-	if err != nil {
-		return false, err
-	}
-
-	// Get the claims from the token
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return false, errors.New("invalid claims")
-	}
-
-	// check the audience
-	aud, ok := claims["aud"].(string)
-	if !ok {
-		return false, errors.New("not able to get audience from claims")
-	}
-	if aud != "https://management.azure.com" && aud != "https://management.core.windows.net/" {
-		return false, errors.New("unexpected audience, expected https://management.azure.com or https://management.core.windows.net/ but got " + aud)
-	}
-
-	// Check the issuer
-	iss, ok := claims["iss"].(string)
-	if !ok {
-		return false, errors.New("not able to get issuer from claims")
-	}
-
-	if iss != "https://sts.windows.net/"+os.Getenv("TENANT_ID")+"/" && iss != "https://sts.windows.net/"+os.Getenv("FDPO_TENANT_ID")+"/" {
-		return false, errors.New("unexpected issuer, expected https://sts.windows.net/" + os.Getenv("TENANT_ID") + "/ or https://sts.windows.net/" + os.Getenv("FDPO_TENANT_ID") + "/ but got " + iss)
-	}
-
-	// Check the expiration time
-	exp, ok := claims["exp"].(float64)
-	if !ok {
-		return false, errors.New("invalid expiration time")
-	}
-	if time.Now().Unix() > int64(exp) {
-		return false, errors.New("token has expired")
-	}
-
-	return true, nil
-}
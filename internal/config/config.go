@@ -1,503 +1,306 @@
 package config
 
 import (
+	"actlabs-hub/internal/entity"
 	"actlabs-hub/internal/logger"
 	"context"
+	"errors"
 	"fmt"
 	"os"
-	"strconv"
+	"reflect"
 )
 
+// ConfigFileEnv names the YAML or JSON file settings are read from before
+// the environment, which overrides it.
+const ConfigFileEnv = "ACTLABS_HUB_CONFIG_FILE"
+
+// Config is loaded as described in load.go. The env names are also the keys
+// of the config file.
 type Config struct {
-	ActlabsAppGatewayName                                    string
-	ActlabsEnvironmentName                                   string
-	ActlabsFQDN                                              string
-	ActlabsHubClientID                                       string
-	ActlabsHubManagedServersTableName                        string
-	ActlabsHubReadinessAssignmentsTableName                  string
-	ActlabsHubChallengesTableName                            string
-	ActlabsHubProfilesTableName                              string
-	ActlabsHubDeploymentsTableName                           string
-	ActlabsHubEventsTableName                                string
-	ActlabsHubDeploymentOperationsTableName                  string
-	ActlabsHubManagedIdentityResourceId                      string
-	ActlabsHubResourceGroup                                  string
-	ActlabsHubStorageAccount                                 string
-	ActlabsHubSubscriptionID                                 string
-	ActlabsHubURL                                            string
-	ActlabsHubAutoDestroyPollingIntervalSeconds              int32
-	ActlabsHubAutoDestroyIdleTimeSeconds                     int32
-	ActlabsHubDeploymentsPollingIntervalSeconds              int32
-	ActlabsHubMonitorAndDestroyInactiveServers               bool
-	ActlabsHubMonitorAndAutoDestroyDeployments               bool
-	ActlabsServerCaddyCPU                                    float64
-	ActlabsServerCaddyMemory                                 float64
-	ActlabsServerCPU                                         float64
-	ActlabsServerMemory                                      float64
-	ActlabsServerImage                                       string
-	ActlabsServerPort                                        int32
-	ActlabsServerReadinessProbeFailureThreshold              int32
-	ActlabsServerReadinessProbeInitialDelaySeconds           int32
-	ActlabsServerReadinessProbePath                          string
-	ActlabsServerReadinessProbePeriodSeconds                 int32
-	ActlabsServerReadinessProbeSuccessThreshold              int32
-	ActlabsServerReadinessProbeTimeoutSeconds                int32
-	ActlabsServerRootDir                                     string
-	ActlabsServerUPWaitTimeSeconds                           string
-	ActlabsServerManagedEnvironmentId                        string
-	ActlabsServerResourceGroup                               string
-	AuthTokenAud                                             string
-	AuthTokenIss                                             string
-	HttpPort                                                 int32
-	HttpsPort                                                int32
-	TenantID                                                 string
-	ActlabsServerApiKey                                      string
-	ActlabsServerEndpointExternal                            string
-	ActlabsServerEndpointInternal                            string
-	ActlabsServerUseMsi                                      bool
-	ActlabsServerUseServicePrincipal                         bool
-	ActlabsServerServicePrincipalClientId                    string
-	ActlabsServerServicePrincipalObjectId                    string
-	ActlabsServerServicePrincipalClientSecretKeyvaultURL     string
-	ActlabsServerFdpoServicePrincipalClientId                string
-	ActlabsServerFdpoServicePrincipalObjectId                string
-	ActlabsServerFdpoServicePrincipalSecret                  string
-	FdpoTenantID                                             string
-	ActlabsServerFdpoServicePrincipalClientSecretKeyvaultURL string
-	ActlabsHubUseMsi                                         bool
-	ActlabsHubUseUserAuth                                    bool
-	MiseEndpoint                                             string
-	MiseVerboseLogging                                       bool
-	AuthVerifyMode                                           string
-	CorsAllowOrigins                                         string
-	CorsAllowMethods                                         string
-	CorsAllowHeaders                                         string
-	ActlabsServerAroRpFirstPartySpID                         string
-	ActlabsServerAppSettingWebsiteSiteName                   string
-	ActlabsServerArmMsiApiVersion                            string
-	ActlabsServerArmMsiApiProxyPort                          string
-	ActlabsServerAPIKey                                      string
+	ActlabsAppGatewayName                   string `env:"ACTLABS_APP_GATEWAY_NAME,required"`
+	ActlabsEnvironmentName                  string `env:"ACTLABS_ENVIRONMENT_NAME,required"`
+	ActlabsFQDN                             string `env:"ACTLABS_FQDN,required"`
+	ActlabsHubClientID                      string `env:"ACTLABS_HUB_CLIENT_ID,required"`
+	ActlabsHubManagedServersTableName       string `env:"ACTLABS_HUB_MANAGED_SERVERS_TABLE_NAME,required"`
+	ActlabsHubReadinessAssignmentsTableName string `env:"ACTLABS_HUB_READINESS_ASSIGNMENTS_TABLE_NAME,required"`
+	ActlabsHubChallengesTableName           string `env:"ACTLABS_HUB_CHALLENGES_TABLE_NAME,required"`
+	ActlabsHubProfilesTableName             string `env:"ACTLABS_HUB_PROFILES_TABLE_NAME,required"`
+	ActlabsHubDeploymentsTableName          string `env:"ACTLABS_HUB_DEPLOYMENTS_TABLE_NAME,required"`
+	ActlabsHubEventsTableName               string `env:"ACTLABS_HUB_EVENTS_TABLE_NAME,required"`
+	ActlabsHubDeploymentOperationsTableName string `env:"ACTLABS_HUB_DEPLOYMENT_OPERATIONS_TABLE_NAME,required"`
+	ActlabsHubQuotasTableName               string `env:"ACTLABS_HUB_QUOTAS_TABLE_NAME" default:"Quotas"`
+	ActlabsHubSchedulesTableName            string `env:"ACTLABS_HUB_SCHEDULES_TABLE_NAME" default:"Schedules"`
+	ActlabsHubManagedIdentityResourceId     string `env:"ACTLABS_HUB_MANAGED_IDENTITY_RESOURCE_ID,required"`
+	ActlabsHubResourceGroup                 string `env:"ACTLABS_HUB_RESOURCE_GROUP_NAME,required"`
+	ActlabsHubStorageAccount                string `env:"ACTLABS_HUB_STORAGE_ACCOUNT_NAME,required"`
+	ActlabsHubSubscriptionID                string `env:"ACTLABS_HUB_SUBSCRIPTION_ID,required"`
+	ActlabsHubURL                           string `env:"ACTLABS_HUB_URL,required"`
+
+	// Port the hub listens on.
+	Port string `env:"PORT" default:"8883"`
+	// Port /metrics is served on. It's not exposed publicly like Port.
+	MetricsPort string `env:"METRICS_PORT" default:"9090"`
+
+	RedisHostname string `env:"REDIS_HOSTNAME" default:"localhost"`
+	RedisPort     string `env:"REDIS_PORT" default:"6379"`
+	RedisPassword string `env:"REDIS_PASSWORD,secret"`
+	RedisDB       int    `env:"REDIS_DB" default:"0"`
+
+	ActlabsHubAutoDestroyPollingIntervalSeconds int32 `env:"ACTLABS_HUB_AUTO_DESTROY_POLLING_INTERVAL_SECONDS" default:"600"`
+	ActlabsHubAutoDestroyIdleTimeSeconds        int32 `env:"ACTLABS_HUB_AUTO_DESTROY_IDLE_TIME_SECONDS" default:"3600"`
+	ActlabsHubDeploymentsPollingIntervalSeconds int32 `env:"ACTLABS_HUB_DEPLOYMENTS_POLLING_INTERVAL_SECONDS" default:"30"`
+	ActlabsHubMonitorAndDestroyInactiveServers  bool  `env:"ACTLABS_HUB_MONITOR_AND_DESTROY_INACTIVE_SERVERS" default:"true"`
+	ActlabsHubMonitorAndAutoDestroyDeployments  bool  `env:"ACTLABS_HUB_MONITOR_AUTO_DESTROY_DEPLOYMENTS" default:"true"`
+	ActlabsHubMonitorServerAuthorization        bool  `env:"ACTLABS_HUB_MONITOR_SERVER_AUTHORIZATION" default:"true"`
+	ActlabsHubServerVerificationIntervalSeconds int32 `env:"ACTLABS_HUB_SERVER_VERIFICATION_INTERVAL_SECONDS" default:"21600"`
+	ActlabsHubMonitorStuckDeployments           bool  `env:"ACTLABS_HUB_MONITOR_STUCK_DEPLOYMENTS" default:"false"`
+	ActlabsHubMonitorSchedules                  bool  `env:"ACTLABS_HUB_MONITOR_SCHEDULES" default:"false"`
+
+	// MIME types are sniffed from the document's content, not taken from the upload.
+	ActlabsHubSupportingDocumentAllowedTypes       []string `env:"ACTLABS_HUB_SUPPORTING_DOCUMENT_ALLOWED_TYPES" default:"application/pdf"`
+	ActlabsHubSupportingDocumentMaxSizeBytes       int64    `env:"ACTLABS_HUB_SUPPORTING_DOCUMENT_MAX_SIZE_BYTES" default:"10485760"`
+	ActlabsHubCollectSupportingDocuments           bool     `env:"ACTLABS_HUB_COLLECT_SUPPORTING_DOCUMENTS" default:"false"`
+	ActlabsHubSupportingDocumentsGCIntervalSeconds int32    `env:"ACTLABS_HUB_SUPPORTING_DOCUMENTS_GC_INTERVAL_SECONDS" default:"86400"`
+	// Documents younger than this are kept, they may be uploaded for a lab that isn't saved yet.
+	ActlabsHubSupportingDocumentsGCGraceHours int64 `env:"ACTLABS_HUB_SUPPORTING_DOCUMENTS_GC_GRACE_HOURS" default:"24"`
+
+	ActlabsHubSchedulesPollingIntervalSeconds        int32 `env:"ACTLABS_HUB_SCHEDULES_POLLING_INTERVAL_SECONDS" default:"60"`
+	ActlabsHubStuckDeploymentsPollingIntervalSeconds int32 `env:"ACTLABS_HUB_STUCK_DEPLOYMENTS_POLLING_INTERVAL_SECONDS" default:"300"`
+	// A deployment in progress is stuck once its status hasn't changed for this
+	// long. It's only reconciled if its server isn't running the operation.
+	ActlabsHubStuckDeploymentThresholdMinutes int64 `env:"ACTLABS_HUB_STUCK_DEPLOYMENT_THRESHOLD_MINUTES" default:"240"`
+	// Times a stuck destroy is sent to the server again before it's marked failed.
+	ActlabsHubStuckDeploymentMaxRetries int `env:"ACTLABS_HUB_STUCK_DEPLOYMENT_MAX_RETRIES" default:"1"`
+
+	// How far out a deployment's auto delete time can be pushed when extending it.
+	ActlabsHubDeploymentMaxLifespanSecondsUser   int64 `env:"ACTLABS_HUB_DEPLOYMENT_MAX_LIFESPAN_SECONDS_USER" default:"28800"`
+	ActlabsHubDeploymentMaxLifespanSecondsMentor int64 `env:"ACTLABS_HUB_DEPLOYMENT_MAX_LIFESPAN_SECONDS_MENTOR" default:"86400"`
+	// Users are warned this many minutes before their deployment is auto deleted.
+	ActlabsHubDeploymentExpiryWarningMinutes []int `env:"ACTLABS_HUB_DEPLOYMENT_EXPIRY_WARNING_MINUTES" default:"30,5"`
+
+	ActlabsHubNotifier           string `env:"ACTLABS_HUB_NOTIFIER" default:"log"`
+	ActlabsHubNotifierWebhookURL string `env:"ACTLABS_HUB_NOTIFIER_WEBHOOK_URL,secret"`
+
+	// Built in prices are used when no price sheet file is set.
+	ActlabsHubPriceSheetFile string `env:"ACTLABS_HUB_PRICE_SHEET_FILE"`
+
+	// Default deployment quota, admins can override it per user. Zero means no limit.
+	ActlabsHubQuotaMaxConcurrentDeployments int   `env:"ACTLABS_HUB_QUOTA_MAX_CONCURRENT_DEPLOYMENTS" default:"3"`
+	ActlabsHubQuotaMaxWorkspaces            int   `env:"ACTLABS_HUB_QUOTA_MAX_WORKSPACES" default:"10"`
+	ActlabsHubQuotaMaxLifespanHoursPerWeek  int64 `env:"ACTLABS_HUB_QUOTA_MAX_LIFESPAN_HOURS_PER_WEEK" default:"80"`
+	// The three above.
+	ActlabsHubDefaultDeploymentQuota entity.DeploymentQuota
+
+	ActlabsServerCaddyCPU                          float64 `env:"ACTLABS_SERVER_CADDY_CPU" default:"0.5"`
+	ActlabsServerCaddyMemory                       float64 `env:"ACTLABS_SERVER_CADDY_MEMORY" default:"0.5"`
+	ActlabsServerCPU                               float64 `env:"ACTLABS_SERVER_CPU" default:"0.5"`
+	ActlabsServerMemory                            float64 `env:"ACTLABS_SERVER_MEMORY" default:"0.5"`
+	ActlabsServerImage                             string  `env:"ACTLABS_SERVER_IMAGE" default:"actlabs.azurecr.io/actlabs-server:latest"`
+	ActlabsServerPort                              int32   `env:"ACTLABS_SERVER_PORT" default:"8881"`
+	ActlabsServerReadinessProbeFailureThreshold    int32   `env:"ACTLABS_SERVER_READINESS_PROBE_FAILURE_THRESHOLD" default:"20"`
+	ActlabsServerReadinessProbeInitialDelaySeconds int32   `env:"ACTLABS_SERVER_READINESS_PROBE_INITIAL_DELAY_SECONDS" default:"10"`
+	ActlabsServerReadinessProbePath                string  `env:"ACTLABS_SERVER_READINESS_PROBE_PATH" default:"/status"`
+	ActlabsServerReadinessProbePeriodSeconds       int32   `env:"ACTLABS_SERVER_READINESS_PROBE_PERIOD_SECONDS" default:"10"`
+	ActlabsServerReadinessProbeSuccessThreshold    int32   `env:"ACTLABS_SERVER_READINESS_PROBE_SUCCESS_THRESHOLD" default:"1"`
+	ActlabsServerReadinessProbeTimeoutSeconds      int32   `env:"ACTLABS_SERVER_READINESS_PROBE_TIMEOUT_SECONDS" default:"5"`
+	ActlabsServerRootDir                           string  `env:"ACTLABS_SERVER_ROOT_DIR,required"`
+	ActlabsServerUPWaitTimeSeconds                 string  `env:"ACTLABS_SERVER_UP_WAIT_TIME_SECONDS" default:"180"`
+	ActlabsServerManagedEnvironmentId              string  `env:"ACTLABS_SERVER_MANAGED_ENVIRONMENT_ID,required"`
+	ActlabsServerResourceGroup                     string  `env:"ACTLABS_SERVER_RESOURCE_GROUP,required"`
+
+	AuthTokenAud string `env:"AUTH_TOKEN_AUD,required"`
+	AuthTokenIss string `env:"AUTH_TOKEN_ISS,required"`
+	HttpPort     int32  `env:"HTTP_PORT" default:"80"`
+	HttpsPort    int32  `env:"HTTPS_PORT" default:"443"`
+	TenantID     string `env:"TENANT_ID,required"`
+
+	ActlabsServerApiKey           string `env:"ACTLABS_SERVER_API_KEY,required,secret"`
+	ActlabsServerEndpointExternal string `env:"ACTLABS_SERVER_ENDPOINT_EXTERNAL,required"`
+	ActlabsServerEndpointInternal string `env:"ACTLABS_SERVER_ENDPOINT_INTERNAL,required"`
+
+	// Calls from the hub to the actlabs servers.
+	ActlabsServerRequestTimeoutSeconds      int32 `env:"ACTLABS_SERVER_REQUEST_TIMEOUT_SECONDS" default:"30"`
+	ActlabsServerMaxRetries                 int   `env:"ACTLABS_SERVER_MAX_RETRIES" default:"2"`
+	ActlabsServerRetryBaseDelayMilliseconds int   `env:"ACTLABS_SERVER_RETRY_BASE_DELAY_MILLISECONDS" default:"500"`
+	// Consecutive failed calls to a server before calls to it are stopped for the cooldown.
+	ActlabsServerCircuitBreakerThreshold       int   `env:"ACTLABS_SERVER_CIRCUIT_BREAKER_THRESHOLD" default:"5"`
+	ActlabsServerCircuitBreakerCooldownSeconds int32 `env:"ACTLABS_SERVER_CIRCUIT_BREAKER_COOLDOWN_SECONDS" default:"60"`
+
+	ActlabsServerUseMsi              bool `env:"ACTLABS_SERVER_USE_MSI" default:"false"`
+	ActlabsServerUseServicePrincipal bool `env:"ACTLABS_SERVER_USE_SERVICE_PRINCIPAL" default:"false"`
+	// Required with ACTLABS_SERVER_USE_SERVICE_PRINCIPAL.
+	ActlabsServerServicePrincipalClientId                    string `env:"ACTLABS_SERVER_SERVICE_PRINCIPAL_CLIENT_ID"`
+	ActlabsServerServicePrincipalObjectId                    string `env:"ACTLABS_SERVER_SERVICE_PRINCIPAL_OBJECT_ID"`
+	ActlabsServerServicePrincipalClientSecretKeyvaultURL     string `env:"ACTLABS_SERVER_SERVICE_PRINCIPAL_CLIENT_SECRET_KEYVAULT_URL"`
+	ActlabsServerFdpoServicePrincipalClientId                string `env:"ACTLABS_SERVER_FDPO_SERVICE_PRINCIPAL_CLIENT_ID,required"`
+	ActlabsServerFdpoServicePrincipalObjectId                string `env:"ACTLABS_SERVER_FDPO_SERVICE_PRINCIPAL_OBJECT_ID,required"`
+	ActlabsServerFdpoServicePrincipalSecret                  string `env:"ACTLABS_SERVER_FDPO_SERVICE_PRINCIPAL_SECRET,required,secret"`
+	FdpoTenantID                                             string `env:"FDPO_TENANT_ID,required"`
+	ActlabsServerFdpoServicePrincipalClientSecretKeyvaultURL string `env:"ACTLABS_SERVER_FDPO_SERVICE_PRINCIPAL_CLIENT_SECRET_KEYVAULT_URL,required"`
+
+	ActlabsHubUseMsi      bool `env:"ACTLABS_HUB_USE_MSI" default:"false"`
+	ActlabsHubUseUserAuth bool `env:"ACTLABS_HUB_USE_USER_AUTH" default:"false"`
+
+	MiseEndpoint       string `env:"MISE_ENDPOINT,required"`
+	MiseVerboseLogging bool   `env:"MISE_VERBOSE_LOGGING" default:"false"`
+	AuthVerifyMode     string `env:"AUTH_VERIFY_MODE" default:"Custom"`
+	// Authenticators are tried in the order listed. Without an explicit list
+	// it's what AUTH_VERIFY_MODE used to select.
+	AuthAuthenticators []string `env:"AUTH_AUTHENTICATORS"`
+	AuthJwksURL        string   `env:"AUTH_JWKS_URL" default:"https://login.microsoftonline.com/common/discovery/v2.0/keys"`
+	// Only honored when ACTLABS_ENVIRONMENT_NAME is local.
+	AuthDevStaticToken string `env:"AUTH_DEV_STATIC_TOKEN,secret"`
+	AuthDevUserId      string `env:"AUTH_DEV_USER_ID"`
+
+	// Domains and tenants the hub serves, as JSON. The first domain is used
+	// for user IDs given without one. Defaults to the corp tenant (V2) and
+	// FDPO (V3).
+	ActlabsHubTenants []entity.Tenant `env:"ACTLABS_HUB_TENANTS"`
+	// Owners given to labs that have neither an owner nor a creator.
+	ActlabsHubDefaultLabOwners []string `env:"ACTLABS_HUB_DEFAULT_LAB_OWNERS" default:"ashisverma@microsoft.com,ericlucier@microsoft.com"`
+
+	CorsAllowOrigins string `env:"CORS_ALLOW_ORIGINS,required"`
+	CorsAllowMethods string `env:"CORS_ALLOW_METHODS,required"`
+	CorsAllowHeaders string `env:"CORS_ALLOW_HEADERS,required"`
+
+	// The OTLP exporter reads its endpoint and headers from the standard
+	// OTEL_EXPORTER_OTLP_* variables.
+	OtelTracesExporter string `env:"OTEL_TRACES_EXPORTER" default:"none" oneof:"otlp stdout none"`
+
+	// Starting values of the runtime settings, admins can change them while
+	// the hub is running.
+	ActlabsHubLogLevel                   string `env:"ACTLABS_HUB_LOG_LEVEL" default:"INFO"`
+	ActlabsHubRateLimitWindowSeconds     int    `env:"ACTLABS_HUB_RATE_LIMIT_WINDOW_SECONDS" default:"60"`
+	ActlabsHubRateLimitMaxRequests       int    `env:"ACTLABS_HUB_RATE_LIMIT_MAX_REQUESTS" default:"100"`
+	ActlabsHubAPIKeyRateLimitMaxRequests int    `env:"ACTLABS_HUB_API_KEY_RATE_LIMIT_MAX_REQUESTS" default:"200"`
+
+	ActlabsHubReadinessCheckTimeoutSeconds int32 `env:"ACTLABS_HUB_READINESS_CHECK_TIMEOUT_SECONDS" default:"5"`
+	ActlabsHubReadinessCacheSeconds        int32 `env:"ACTLABS_HUB_READINESS_CACHE_SECONDS" default:"10"`
+	// Readiness fails this long before the hub stops accepting requests, at
+	// least one readiness probe period so load balancers notice first.
+	ActlabsHubShutdownDrainSeconds int32 `env:"ACTLABS_HUB_SHUTDOWN_DRAIN_SECONDS" default:"10"`
+	// In-flight requests and background tasks get this long to finish.
+	ActlabsHubShutdownTimeoutSeconds int32 `env:"ACTLABS_HUB_SHUTDOWN_TIMEOUT_SECONDS" default:"30"`
+
+	ActlabsServerAroRpFirstPartySpID       string `env:"ACTLABS_SERVER_AZURE_RED_HAT_OPENSHIFT_RP_FIRST_PARTY_SP_ID,required"`
+	ActlabsServerAppSettingWebsiteSiteName string `env:"ACTLABS_SERVER_APPSETTING_WEBSITE_SITE_NAME" default:"dummy"`
+	ActlabsServerArmMsiApiVersion          string `env:"ACTLABS_SERVER_ARM_MSI_API_VERSION" default:"2019-08-01"`
+	ActlabsServerArmMsiApiProxyPort        string `env:"ACTLABS_SERVER_ARM_MSI_API_PROXY_PORT" default:"42300"`
+	ActlabsServerAPIKey                    string
 	// Add other configuration fields as needed
+
+	// Where each setting came from, by env name.
+	sources map[string]string
 }
 
+// NewConfig loads the file named by ACTLABS_HUB_CONFIG_FILE, if any, and the
+// environment.
 func NewConfig(ctx context.Context) (*Config, error) {
+	return Load(ctx, os.Getenv(ConfigFileEnv))
+}
 
-	actlabsAppGatewayName := getEnv(ctx, "ACTLABS_APP_GATEWAY_NAME")
-	if actlabsAppGatewayName == "" {
-		return nil, fmt.Errorf("ACTLABS_APP_GATEWAY_NAME not set")
-	}
-
-	actlabsEnvironmentName := getEnv(ctx, "ACTLABS_ENVIRONMENT_NAME")
-	if actlabsEnvironmentName == "" {
-		return nil, fmt.Errorf("ACTLABS_ENVIRONMENT_NAME not set")
-	}
-
-	actlabsFQDN := getEnv(ctx, "ACTLABS_FQDN")
-	if actlabsFQDN == "" {
-		return nil, fmt.Errorf("ACTLABS_FQDN not set")
-	}
-
-	authTokenAud := getEnv(ctx, "AUTH_TOKEN_AUD")
-	if authTokenAud == "" {
-		return nil, fmt.Errorf("AUTH_TOKEN_AUD not set")
-	}
-
-	authTokenIss := getEnv(ctx, "AUTH_TOKEN_ISS")
-	if authTokenIss == "" {
-		return nil, fmt.Errorf("AUTH_TOKEN_ISS not set")
-	}
-
-	actlabsServerRootDir := getEnv(ctx, "ACTLABS_SERVER_ROOT_DIR")
-	if actlabsServerRootDir == "" {
-		return nil, fmt.Errorf("ACTLABS_SERVER_ROOT_DIR not set")
-	}
-
-	actlabsServerApiKey := getEnv(ctx, "ACTLABS_SERVER_API_KEY")
-	if actlabsServerApiKey == "" {
-		return nil, fmt.Errorf("ACTLABS_SERVER_API_KEY not set")
-	}
-
-	actlabsServerEndpointExternal := getEnv(ctx, "ACTLABS_SERVER_ENDPOINT_EXTERNAL")
-	if actlabsServerEndpointExternal == "" {
-		return nil, fmt.Errorf("ACTLABS_SERVER_ENDPOINT_EXTERNAL not set")
-	}
-
-	actlabsServerEndpointInternal := getEnv(ctx, "ACTLABS_SERVER_ENDPOINT_INTERNAL")
-	if actlabsServerEndpointInternal == "" {
-		return nil, fmt.Errorf("ACTLABS_SERVER_ENDPOINT_INTERNAL not set")
-	}
-
-	actlabsServerUseMsi, err := strconv.ParseBool(getEnvWithDefault(ctx, "ACTLABS_SERVER_USE_MSI", "false"))
-	if err != nil {
-		return nil, err
-	}
-
-	actlabsServerUseServicePrincipal, err := strconv.ParseBool(getEnvWithDefault(ctx, "ACTLABS_SERVER_USE_SERVICE_PRINCIPAL", "false"))
-	if err != nil {
-		return nil, err
-	}
-
-	actlabsServerServicePrincipalClientId := getEnv(ctx, "ACTLABS_SERVER_SERVICE_PRINCIPAL_CLIENT_ID")
-	if actlabsServerServicePrincipalClientId == "" && actlabsServerUseServicePrincipal {
-		return nil, fmt.Errorf("ACTLABS_SERVER_SERVICE_PRINCIPAL_CLIENT_ID not set")
-	}
-
-	actlabsServerServicePrincipalObjectId := getEnv(ctx, "ACTLABS_SERVER_SERVICE_PRINCIPAL_OBJECT_ID")
-	if actlabsServerServicePrincipalObjectId == "" && actlabsServerUseServicePrincipal {
-		return nil, fmt.Errorf("ACTLABS_SERVER_SERVICE_PRINCIPAL_OBJECT_ID not set")
-	}
-
-	actlabsServerServicePrincipalClientSecretKeyvaultURL := getEnv(ctx, "ACTLABS_SERVER_SERVICE_PRINCIPAL_CLIENT_SECRET_KEYVAULT_URL")
-	if actlabsServerServicePrincipalClientSecretKeyvaultURL == "" && actlabsServerUseServicePrincipal {
-		return nil, fmt.Errorf("ACTLABS_SERVER_SERVICE_PRINCIPAL_CLIENT_SECRET_KEYVAULT_URL not set")
-	}
-
-	actlabsServerFdpoServicePrincipalClientId := getEnv(ctx, "ACTLABS_SERVER_FDPO_SERVICE_PRINCIPAL_CLIENT_ID")
-	if actlabsServerFdpoServicePrincipalClientId == "" {
-		return nil, fmt.Errorf("ACTLABS_SERVER_FDPO_SERVICE_PRINCIPAL_CLIENT_ID not set")
-	}
-
-	actlabsServerFdpoServicePrincipalObjectId := getEnv(ctx, "ACTLABS_SERVER_FDPO_SERVICE_PRINCIPAL_OBJECT_ID")
-	if actlabsServerFdpoServicePrincipalObjectId == "" {
-		return nil, fmt.Errorf("ACTLABS_SERVER_FDPO_SERVICE_PRINCIPAL_OBJECT_ID not set")
-	}
-
-	actlabsServerFdpoServicePrincipalSecret := getEnv(ctx, "ACTLABS_SERVER_FDPO_SERVICE_PRINCIPAL_SECRET")
-	if actlabsServerFdpoServicePrincipalSecret == "" {
-		return nil, fmt.Errorf("ACTLABS_SERVER_FDPO_SERVICE_PRINCIPAL_SECRET not set")
-	}
-
-	fdpoTenantID := getEnv(ctx, "FDPO_TENANT_ID")
-	if fdpoTenantID == "" {
-		return nil, fmt.Errorf("FDPO_TENANT_ID not set")
-	}
-
-	actlabsServerFdpoServicePrincipalClientSecretKeyvaultURL := getEnv(ctx, "ACTLABS_SERVER_FDPO_SERVICE_PRINCIPAL_CLIENT_SECRET_KEYVAULT_URL")
-	if actlabsServerFdpoServicePrincipalClientSecretKeyvaultURL == "" {
-		return nil, fmt.Errorf("ACTLABS_SERVER_FDPO_SERVICE_PRINCIPAL_CLIENT_SECRET_KEYVAULT_URL not set")
-	}
-
-	actlabsHubUseMsi, err := strconv.ParseBool(getEnvWithDefault(ctx, "ACTLABS_HUB_USE_MSI", "false"))
-	if err != nil {
-		return nil, err
-	}
-
-	actlabsHubUseUserAuth, err := strconv.ParseBool(getEnvWithDefault(ctx, "ACTLABS_HUB_USE_USER_AUTH", "false"))
-	if err != nil {
-		return nil, err
-	}
-
-	actlabsServerUPWaitTimeSeconds := getEnvWithDefault(ctx, "ACTLABS_SERVER_UP_WAIT_TIME_SECONDS", "180")
-	if actlabsServerUPWaitTimeSeconds == "" {
-		return nil, fmt.Errorf("ACTLABS_SERVER_UP_WAIT_TIME_SECONDS not set")
-	}
-
-	actlabsServerManagedEnvironmentId := getEnv(ctx, "ACTLABS_SERVER_MANAGED_ENVIRONMENT_ID")
-	if actlabsServerManagedEnvironmentId == "" {
-		return nil, fmt.Errorf("ACTLABS_SERVER_MANAGED_ENVIRONMENT_ID not set")
-	}
-
-	actlabsServerResourceGroup := getEnv(ctx, "ACTLABS_SERVER_RESOURCE_GROUP")
-	if actlabsServerResourceGroup == "" {
-		return nil, fmt.Errorf("ACTLABS_SERVER_RESOURCE_GROUP not set")
-	}
-
-	actlabsServerPort, err := strconv.ParseInt(getEnvWithDefault(ctx, "ACTLABS_SERVER_PORT", "8881"), 10, 32)
-	if err != nil {
-		return nil, fmt.Errorf("ACTLABS_SERVER_PORT not set")
-	}
-
-	actlabsServerImage := getEnvWithDefault(ctx, "ACTLABS_SERVER_IMAGE", "actlabs.azurecr.io/actlabs-server:latest")
-	if actlabsServerImage == "" {
-		return nil, fmt.Errorf("ACTLABS_SERVER_IMAGE not set")
-	}
-
-	actlabsHubURL := getEnv(ctx, "ACTLABS_HUB_URL")
-	if actlabsHubURL == "" {
-		return nil, fmt.Errorf("ACTLABS_HUB_URL not set")
-	}
-
-	httpPort, err := strconv.Atoi(getEnvWithDefault(ctx, "HTTP_PORT", "80"))
-	if err != nil {
-		return nil, fmt.Errorf("HTTP_PORT not set")
-	}
-
-	httpsPort, err := strconv.Atoi(getEnvWithDefault(ctx, "HTTPS_PORT", "443"))
-	if err != nil {
-		return nil, fmt.Errorf("HTTPS_PORT not set")
-	}
-
-	actlabsServerReadinessProbePath := getEnvWithDefault(ctx, "ACTLABS_SERVER_READINESS_PROBE_PATH", "/status")
-
-	tenantID := getEnv(ctx, "TENANT_ID")
-	if tenantID == "" {
-		return nil, fmt.Errorf("TENANT_ID not set")
-	}
-
-	actlabsServerCPUFloat, err := strconv.ParseFloat(getEnvWithDefault(ctx, "ACTLABS_SERVER_CPU", "0.5"), 32)
-	if err != nil {
-		return nil, err
-	}
-
-	actlabsServerMemoryFloat, err := strconv.ParseFloat(getEnvWithDefault(ctx, "ACTLABS_SERVER_MEMORY", "0.5"), 32)
-	if err != nil {
-		return nil, err
-	}
-
-	actlabsServerCaddyCPUFloat, err := strconv.ParseFloat(getEnvWithDefault(ctx, "ACTLABS_SERVER_CADDY_CPU", "0.5"), 32)
-	if err != nil {
-		return nil, err
-	}
-
-	actlabsServerCaddyMemoryFloat, err := strconv.ParseFloat(getEnvWithDefault(ctx, "ACTLABS_SERVER_CADDY_MEMORY", "0.5"), 32)
-	if err != nil {
-		return nil, err
-	}
-
-	actlabsServerReadinessProbeInitialDelaySecondsInt, err := strconv.ParseInt(getEnvWithDefault(ctx, "ACTLABS_SERVER_READINESS_PROBE_INITIAL_DELAY_SECONDS", "10"), 10, 32)
-	if err != nil {
-		return nil, err
-	}
-
-	actlabsServerReadinessProbeTimeoutSecondsInt, err := strconv.ParseInt(getEnvWithDefault(ctx, "ACTLABS_SERVER_READINESS_PROBE_TIMEOUT_SECONDS", "5"), 10, 32)
-	if err != nil {
-		return nil, err
-	}
-
-	actlabsServerReadinessProbePeriodSecondsInt, err := strconv.ParseInt(getEnvWithDefault(ctx, "ACTLABS_SERVER_READINESS_PROBE_PERIOD_SECONDS", "10"), 10, 32)
-	if err != nil {
-		return nil, err
-	}
-
-	actlabsServerReadinessProbeSuccessThresholdInt, err := strconv.ParseInt(getEnvWithDefault(ctx, "ACTLABS_SERVER_READINESS_PROBE_SUCCESS_THRESHOLD", "1"), 10, 32)
-	if err != nil {
-		return nil, err
-	}
-
-	actlabsServerReadinessProbeFailureThresholdInt, err := strconv.ParseInt(getEnvWithDefault(ctx, "ACTLABS_SERVER_READINESS_PROBE_FAILURE_THRESHOLD", "20"), 10, 32)
-	if err != nil {
-		return nil, err
-	}
-
-	actlabsHubClientID := getEnv(ctx, "ACTLABS_HUB_CLIENT_ID")
-	if actlabsHubClientID == "" {
-		return nil, fmt.Errorf("ACTLABS_HUB_CLIENT_ID not set")
-	}
-
-	actlabsHubSubscriptionID := getEnv(ctx, "ACTLABS_HUB_SUBSCRIPTION_ID")
-	if actlabsHubSubscriptionID == "" {
-		return nil, fmt.Errorf("ACTLABS_HUB_SUBSCRIPTION_ID not set")
-	}
-
-	actlabsHubResourceGroup := getEnv(ctx, "ACTLABS_HUB_RESOURCE_GROUP_NAME")
-	if actlabsHubResourceGroup == "" {
-		return nil, fmt.Errorf("ACTLABS_HUB_RESOURCE_GROUP_NAME not set")
-	}
-
-	actlabsHubStorageAccount := getEnv(ctx, "ACTLABS_HUB_STORAGE_ACCOUNT_NAME")
-	if actlabsHubStorageAccount == "" {
-		return nil, fmt.Errorf("ACTLABS_HUB_STORAGE_ACCOUNT_NAME not set")
-	}
-
-	actlabsHubManagedServersTableName := getEnv(ctx, "ACTLABS_HUB_MANAGED_SERVERS_TABLE_NAME")
-	if actlabsHubManagedServersTableName == "" {
-		return nil, fmt.Errorf("ACTLABS_HUB_MANAGED_SERVERS_TABLE_NAME not set")
-	}
-
-	actlabsHubReadinessAssignmentsTableName := getEnv(ctx, "ACTLABS_HUB_READINESS_ASSIGNMENTS_TABLE_NAME")
-	if actlabsHubReadinessAssignmentsTableName == "" {
-		return nil, fmt.Errorf("ACTLABS_HUB_READINESS_ASSIGNMENTS_TABLE_NAME not set")
-	}
-
-	actlabsHubChallengesTableName := getEnv(ctx, "ACTLABS_HUB_CHALLENGES_TABLE_NAME")
-	if actlabsHubChallengesTableName == "" {
-		return nil, fmt.Errorf("ACTLABS_HUB_CHALLENGES_TABLE_NAME not set")
-	}
-
-	actlabsHubProfilesTableName := getEnv(ctx, "ACTLABS_HUB_PROFILES_TABLE_NAME")
-	if actlabsHubProfilesTableName == "" {
-		return nil, fmt.Errorf("ACTLABS_HUB_PROFILES_TABLE_NAME not set")
-	}
-
-	actlabsHubDeploymentsTableName := getEnv(ctx, "ACTLABS_HUB_DEPLOYMENTS_TABLE_NAME")
-	if actlabsHubDeploymentsTableName == "" {
-		return nil, fmt.Errorf("ACTLABS_HUB_DEPLOYMENTS_TABLE_NAME not set")
-	}
-
-	actlabsHubEventsTableName := getEnv(ctx, "ACTLABS_HUB_EVENTS_TABLE_NAME")
-	if actlabsHubEventsTableName == "" {
-		return nil, fmt.Errorf("ACTLABS_HUB_EVENTS_TABLE_NAME not set")
-	}
-
-	actlabsHubDeploymentOperationsTableName := getEnv(ctx, "ACTLABS_HUB_DEPLOYMENT_OPERATIONS_TABLE_NAME")
-	if actlabsHubDeploymentOperationsTableName == "" {
-		return nil, fmt.Errorf("ACTLABS_HUB_DEPLOYMENT_OPERATIONS_TABLE_NAME not set")
-	}
-
-	actlabsHubManagedIdentityResourceId := getEnv(ctx, "ACTLABS_HUB_MANAGED_IDENTITY_RESOURCE_ID")
-	if actlabsHubManagedIdentityResourceId == "" {
-		return nil, fmt.Errorf("ACTLABS_HUB_MANAGED_IDENTITY_RESOURCE_ID not set")
-	}
-
-	actlabsHubAutoDestroyPollingIntervalSeconds, err := strconv.ParseInt(getEnvWithDefault(ctx, "ACTLABS_HUB_AUTO_DESTROY_POLLING_INTERVAL_SECONDS", "600"), 10, 32)
-	if err != nil {
-		return nil, err
-	}
-
-	actlabsHubAutoDestroyIdleTimeSeconds, err := strconv.ParseInt(getEnvWithDefault(ctx, "ACTLABS_HUB_AUTO_DESTROY_IDLE_TIME_SECONDS", "3600"), 10, 32)
-	if err != nil {
-		return nil, err
-	}
+// Load reads settings from their defaults, then file if it isn't empty, then
+// the environment. Every problem found is returned together.
+func Load(ctx context.Context, file string) (*Config, error) {
+	c := &Config{}
 
-	actlabsHubDeploymentsPollingIntervalSeconds, err := strconv.ParseInt(getEnvWithDefault(ctx, "ACTLABS_HUB_DEPLOYMENTS_POLLING_INTERVAL_SECONDS", "30"), 10, 32)
-	if err != nil {
+	sources, err := load(c, file, os.LookupEnv)
+	c.sources = sources
+	if err := errors.Join(err, c.complete()); err != nil {
 		return nil, err
 	}
 
-	miseEndpoint := getEnv(ctx, "MISE_ENDPOINT")
-	if miseEndpoint == "" {
-		return nil, fmt.Errorf("MISE_ENDPOINT not set")
-	}
-
-	miseVerboseLogging, err := strconv.ParseBool(getEnvWithDefault(ctx, "MISE_VERBOSE_LOGGING", "false"))
-	if err != nil {
-		return nil, fmt.Errorf("MISE_VERBOSE_LOGGING not set or invalid: %w", err)
+	logger.LogInfo(ctx, "configuration loaded", "file", file)
+	for _, setting := range c.Settings() {
+		logger.LogDebug(ctx, "setting", "name", setting.Name, "value", setting.Value, "from", setting.Source)
 	}
 
-	authVerifyMode := getEnvWithDefault(ctx, "AUTH_VERIFY_MODE", "Custom")
-
-	corsAllowOrigins := getEnv(ctx, "CORS_ALLOW_ORIGINS")
-	if corsAllowOrigins == "" {
-		return nil, fmt.Errorf("CORS_ALLOW_ORIGINS not set")
-	}
+	return c, nil
+}
 
-	corsAllowMethods := getEnv(ctx, "CORS_ALLOW_METHODS")
-	if corsAllowMethods == "" {
-		return nil, fmt.Errorf("CORS_ALLOW_METHODS not set")
-	}
+// complete fills in the fields derived from others and checks what the tags
+// can't express.
+func (c *Config) complete() error {
+	errs := []error{}
 
-	corsAllowHeaders := getEnv(ctx, "CORS_ALLOW_HEADERS")
-	if corsAllowHeaders == "" {
-		return nil, fmt.Errorf("CORS_ALLOW_HEADERS not set")
+	if c.ActlabsServerUseServicePrincipal {
+		for name, value := range map[string]string{
+			"ACTLABS_SERVER_SERVICE_PRINCIPAL_CLIENT_ID":                  c.ActlabsServerServicePrincipalClientId,
+			"ACTLABS_SERVER_SERVICE_PRINCIPAL_OBJECT_ID":                  c.ActlabsServerServicePrincipalObjectId,
+			"ACTLABS_SERVER_SERVICE_PRINCIPAL_CLIENT_SECRET_KEYVAULT_URL": c.ActlabsServerServicePrincipalClientSecretKeyvaultURL,
+		} {
+			if value == "" {
+				errs = append(errs, fmt.Errorf("%s not set", name))
+			}
+		}
 	}
 
-	actlabsServerAroRpFirstPartySpID := getEnv(ctx, "ACTLABS_SERVER_AZURE_RED_HAT_OPENSHIFT_RP_FIRST_PARTY_SP_ID")
-	if actlabsServerAroRpFirstPartySpID == "" {
-		return nil, fmt.Errorf("ACTLABS_SERVER_AZURE_RED_HAT_OPENSHIFT_RP_FIRST_PARTY_SP_ID not set")
+	for _, minutes := range c.ActlabsHubDeploymentExpiryWarningMinutes {
+		if minutes <= 0 {
+			errs = append(errs, fmt.Errorf("ACTLABS_HUB_DEPLOYMENT_EXPIRY_WARNING_MINUTES has invalid value %d", minutes))
+		}
 	}
 
-	actlabsServerAppSettingWebsiteSiteName := getEnvWithDefault(ctx, "ACTLABS_SERVER_APPSETTING_WEBSITE_SITE_NAME", "dummy")
-	if actlabsServerAppSettingWebsiteSiteName == "" {
-		return nil, fmt.Errorf("ACTLABS_SERVER_APPSETTING_WEBSITE_SITE_NAME not set")
+	if _, err := logger.ParseLevel(c.ActlabsHubLogLevel); err != nil {
+		errs = append(errs, fmt.Errorf("ACTLABS_HUB_LOG_LEVEL has invalid value %q", c.ActlabsHubLogLevel))
 	}
 
-	actlabsServerArmMsiApiVersion := getEnvWithDefault(ctx, "ACTLABS_SERVER_ARM_MSI_API_VERSION", "2019-08-01")
-	if actlabsServerArmMsiApiVersion == "" {
-		return nil, fmt.Errorf("ACTLABS_SERVER_ARM_MSI_API_VERSION not set")
+	c.ActlabsHubDefaultDeploymentQuota = entity.DeploymentQuota{
+		MaxConcurrentDeployments: c.ActlabsHubQuotaMaxConcurrentDeployments,
+		MaxWorkspaces:            c.ActlabsHubQuotaMaxWorkspaces,
+		MaxLifespanHoursPerWeek:  c.ActlabsHubQuotaMaxLifespanHoursPerWeek,
 	}
 
-	actlabsServerArmMsiApiProxyPort := getEnvWithDefault(ctx, "ACTLABS_SERVER_ARM_MSI_API_PROXY_PORT", "42300")
-	if actlabsServerArmMsiApiProxyPort == "" {
-		return nil, fmt.Errorf("ACTLABS_SERVER_ARM_MSI_API_PROXY_PORT not set")
+	if len(c.AuthAuthenticators) == 0 {
+		c.sources["AUTH_AUTHENTICATORS"] = SourceDefault
+		c.AuthAuthenticators = []string{"jwt"}
+		if c.AuthVerifyMode == "MISE" {
+			c.AuthAuthenticators = []string{"mise"}
+		}
 	}
 
-	actlabsHubMonitorAndDestroyInactiveServers, err := strconv.ParseBool(getEnvWithDefault(ctx, "ACTLABS_HUB_MONITOR_AND_DESTROY_INACTIVE_SERVERS", "true"))
-	if err != nil {
-		return nil, err
+	if c.sources["ACTLABS_HUB_TENANTS"] == "" {
+		c.sources["ACTLABS_HUB_TENANTS"] = SourceDefault
+		c.ActlabsHubTenants = []entity.Tenant{
+			{Domain: "microsoft.com", TenantId: c.TenantID, ServerVersion: "V2"},
+			{Domain: "microsoft.com", TenantId: c.FdpoTenantID, ServerVersion: "V3"},
+		}
+	} else if len(c.ActlabsHubTenants) == 0 {
+		errs = append(errs, fmt.Errorf("ACTLABS_HUB_TENANTS must list at least one tenant"))
 	}
-
-	actlabsHubMonitorAndAutoDestroyDeployments, err := strconv.ParseBool(getEnvWithDefault(ctx, "ACTLABS_HUB_MONITOR_AUTO_DESTROY_DEPLOYMENTS", "true"))
-	if err != nil {
-		return nil, err
+	for _, tenant := range c.ActlabsHubTenants {
+		if tenant.Domain == "" || tenant.TenantId == "" || (tenant.ServerVersion != "V2" && tenant.ServerVersion != "V3") {
+			errs = append(errs, fmt.Errorf("ACTLABS_HUB_TENANTS entries need domain, tenantId and serverVersion V2 or V3"))
+			break
+		}
 	}
 
-	// Retrieve other environment variables and check them as needed
-
-	return &Config{
-		ActlabsAppGatewayName:                                    actlabsAppGatewayName,
-		ActlabsEnvironmentName:                                   actlabsEnvironmentName,
-		ActlabsFQDN:                                              actlabsFQDN,
-		ActlabsHubClientID:                                       actlabsHubClientID,
-		ActlabsHubManagedServersTableName:                        actlabsHubManagedServersTableName,
-		ActlabsHubReadinessAssignmentsTableName:                  actlabsHubReadinessAssignmentsTableName,
-		ActlabsHubChallengesTableName:                            actlabsHubChallengesTableName,
-		ActlabsHubProfilesTableName:                              actlabsHubProfilesTableName,
-		ActlabsHubDeploymentsTableName:                           actlabsHubDeploymentsTableName,
-		ActlabsHubEventsTableName:                                actlabsHubEventsTableName,
-		ActlabsHubDeploymentOperationsTableName:                  actlabsHubDeploymentOperationsTableName,
-		ActlabsHubManagedIdentityResourceId:                      actlabsHubManagedIdentityResourceId,
-		ActlabsHubResourceGroup:                                  actlabsHubResourceGroup,
-		ActlabsHubStorageAccount:                                 actlabsHubStorageAccount,
-		ActlabsHubSubscriptionID:                                 actlabsHubSubscriptionID,
-		ActlabsHubURL:                                            actlabsHubURL,
-		ActlabsHubAutoDestroyPollingIntervalSeconds:              int32(actlabsHubAutoDestroyPollingIntervalSeconds),
-		ActlabsHubAutoDestroyIdleTimeSeconds:                     int32(actlabsHubAutoDestroyIdleTimeSeconds),
-		ActlabsHubDeploymentsPollingIntervalSeconds:              int32(actlabsHubDeploymentsPollingIntervalSeconds),
-		ActlabsServerCaddyCPU:                                    actlabsServerCaddyCPUFloat,
-		ActlabsServerCaddyMemory:                                 actlabsServerCaddyMemoryFloat,
-		ActlabsServerCPU:                                         actlabsServerCPUFloat,
-		ActlabsServerMemory:                                      actlabsServerMemoryFloat,
-		ActlabsServerPort:                                        int32(actlabsServerPort),
-		ActlabsServerImage:                                       actlabsServerImage,
-		ActlabsServerReadinessProbeFailureThreshold:              int32(actlabsServerReadinessProbeFailureThresholdInt),
-		ActlabsServerReadinessProbeInitialDelaySeconds:           int32(actlabsServerReadinessProbeInitialDelaySecondsInt),
-		ActlabsServerReadinessProbePath:                          actlabsServerReadinessProbePath,
-		ActlabsServerReadinessProbePeriodSeconds:                 int32(actlabsServerReadinessProbePeriodSecondsInt),
-		ActlabsServerReadinessProbeSuccessThreshold:              int32(actlabsServerReadinessProbeSuccessThresholdInt),
-		ActlabsServerReadinessProbeTimeoutSeconds:                int32(actlabsServerReadinessProbeTimeoutSecondsInt),
-		ActlabsServerRootDir:                                     actlabsServerRootDir,
-		ActlabsServerUPWaitTimeSeconds:                           actlabsServerUPWaitTimeSeconds,
-		ActlabsServerManagedEnvironmentId:                        actlabsServerManagedEnvironmentId,
-		ActlabsServerResourceGroup:                               actlabsServerResourceGroup,
-		ActlabsHubMonitorAndDestroyInactiveServers:               actlabsHubMonitorAndDestroyInactiveServers,
-		ActlabsHubMonitorAndAutoDestroyDeployments:               actlabsHubMonitorAndAutoDestroyDeployments,
-		AuthTokenAud:                                             authTokenAud,
-		AuthTokenIss:                                             authTokenIss,
-		HttpPort:                                                 int32(httpPort),
-		HttpsPort:                                                int32(httpsPort),
-		TenantID:                                                 tenantID,
-		ActlabsServerApiKey:                                      actlabsServerApiKey,
-		ActlabsServerEndpointExternal:                            actlabsServerEndpointExternal,
-		ActlabsServerEndpointInternal:                            actlabsServerEndpointInternal,
-		ActlabsServerUseMsi:                                      actlabsServerUseMsi,
-		ActlabsServerUseServicePrincipal:                         actlabsServerUseServicePrincipal,
-		ActlabsServerServicePrincipalClientId:                    actlabsServerServicePrincipalClientId,
-		ActlabsServerServicePrincipalObjectId:                    actlabsServerServicePrincipalObjectId,
-		ActlabsServerServicePrincipalClientSecretKeyvaultURL:     actlabsServerServicePrincipalClientSecretKeyvaultURL,
-		ActlabsServerFdpoServicePrincipalClientId:                actlabsServerFdpoServicePrincipalClientId,
-		ActlabsServerFdpoServicePrincipalObjectId:                actlabsServerFdpoServicePrincipalObjectId,
-		ActlabsServerFdpoServicePrincipalSecret:                  actlabsServerFdpoServicePrincipalSecret,
-		FdpoTenantID:                                             fdpoTenantID,
-		ActlabsServerFdpoServicePrincipalClientSecretKeyvaultURL: actlabsServerFdpoServicePrincipalClientSecretKeyvaultURL,
-		ActlabsHubUseMsi:                                         actlabsHubUseMsi,
-		ActlabsHubUseUserAuth:                                    actlabsHubUseUserAuth,
-		MiseEndpoint:                                             miseEndpoint,
-		MiseVerboseLogging:                                       miseVerboseLogging,
-		AuthVerifyMode:                                           authVerifyMode,
-		CorsAllowOrigins:                                         corsAllowOrigins,
-		CorsAllowMethods:                                         corsAllowMethods,
-		CorsAllowHeaders:                                         corsAllowHeaders,
-		ActlabsServerAroRpFirstPartySpID:                         actlabsServerAroRpFirstPartySpID,
-		ActlabsServerAppSettingWebsiteSiteName:                   actlabsServerAppSettingWebsiteSiteName,
-		ActlabsServerArmMsiApiVersion:                            actlabsServerArmMsiApiVersion,
-		ActlabsServerArmMsiApiProxyPort:                          actlabsServerArmMsiApiProxyPort,
-		// Set other fields
-	}, nil
+	return errors.Join(errs...)
 }
 
-// Helper function to retrieve the value and log it
-func getEnv(ctx context.Context, env string) string {
-	value := os.Getenv(env)
-	logger.LogDebug(ctx, "environment variable", "name", env, "value", value)
-	return value
+// Setting is one loaded setting, with secrets redacted.
+type Setting struct {
+	Name   string `json:"name"`
+	Value  any    `json:"value"`
+	Source string `json:"source"`
+	Secret bool   `json:"secret"`
 }
 
-// Helper function to retrieve the value, if none found, set default and log it
-func getEnvWithDefault(ctx context.Context, env string, defaultValue string) string {
-	value := os.Getenv(env)
-	if value == "" {
-		value = defaultValue
-	}
-	logger.LogInfo(ctx, "environment variable", "name", env, "value", value)
-	return value
+// Settings lists every setting in the order of Config's fields.
+func (c *Config) Settings() []Setting {
+	v := reflect.ValueOf(c).Elem()
+	settings := []Setting{}
+	for _, f := range fields(v.Type()) {
+		setting := Setting{
+			Name:   f.name,
+			Value:  v.Field(f.index).Interface(),
+			Source: c.sources[f.name],
+			Secret: f.secret,
+		}
+		if f.secret && !v.Field(f.index).IsZero() {
+			setting.Value = Redacted
+		}
+		settings = append(settings, setting)
+	}
+	return settings
 }
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// requiredEnv sets every required setting, and whatever is in extra.
+func requiredEnv(extra map[string]string) func(string) (string, bool) {
+	env := map[string]string{}
+	for _, f := range fields(reflect.TypeFor[Config]()) {
+		if f.required {
+			env[f.name] = "set"
+		}
+	}
+	for name, value := range extra {
+		env[name] = value
+	}
+	return func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestLoadReportsEveryError(t *testing.T) {
+	c := &Config{}
+	_, err := load(c, "", func(name string) (string, bool) {
+		if name == "ACTLABS_SERVER_PORT" {
+			return "not a number", true
+		}
+		return "", false
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	for _, want := range []string{"ACTLABS_FQDN not set", "ACTLABS_SERVER_API_KEY not set", "ACTLABS_SERVER_PORT is not valid"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+}
+
+func TestLoadEnvironmentOverridesFile(t *testing.T) {
+	file := writeFile(t, `
+ACTLABS_SERVER_PORT: 9000
+ACTLABS_HUB_NOTIFIER: webhook
+ACTLABS_HUB_DEPLOYMENT_EXPIRY_WARNING_MINUTES: [60, 10]
+`)
+
+	c := &Config{}
+	sources, err := load(c, file, requiredEnv(map[string]string{"ACTLABS_HUB_NOTIFIER": "log"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.ActlabsServerPort != 9000 || sources["ACTLABS_SERVER_PORT"] != SourceFile {
+		t.Errorf("expected port 9000 from the file, got %d from %s", c.ActlabsServerPort, sources["ACTLABS_SERVER_PORT"])
+	}
+	if c.ActlabsHubNotifier != "log" || sources["ACTLABS_HUB_NOTIFIER"] != SourceEnv {
+		t.Errorf("expected notifier log from the environment, got %s from %s", c.ActlabsHubNotifier, sources["ACTLABS_HUB_NOTIFIER"])
+	}
+	if !reflect.DeepEqual(c.ActlabsHubDeploymentExpiryWarningMinutes, []int{60, 10}) {
+		t.Errorf("expected warnings at 60 and 10 minutes, got %v", c.ActlabsHubDeploymentExpiryWarningMinutes)
+	}
+	if c.RedisHostname != "localhost" || sources["REDIS_HOSTNAME"] != SourceDefault {
+		t.Errorf("expected the default redis hostname, got %s from %s", c.RedisHostname, sources["REDIS_HOSTNAME"])
+	}
+}
+
+func TestLoadRejectsUnknownFileSettings(t *testing.T) {
+	file := writeFile(t, `{"ACTLABS_SERVER_PROT": 9000}`)
+
+	_, err := load(&Config{}, file, requiredEnv(nil))
+	if err == nil || !strings.Contains(err.Error(), "unknown setting ACTLABS_SERVER_PROT") {
+		t.Errorf("expected the misspelled setting to be rejected, got %v", err)
+	}
+}
+
+func TestSettingsRedactsSecrets(t *testing.T) {
+	c := &Config{}
+	sources, err := load(c, "", requiredEnv(map[string]string{"ACTLABS_SERVER_API_KEY": "hunter2"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.sources = sources
+
+	for _, setting := range c.Settings() {
+		switch setting.Name {
+		case "ACTLABS_SERVER_API_KEY":
+			if setting.Value != Redacted {
+				t.Errorf("expected the api key to be redacted, got %v", setting.Value)
+			}
+		case "REDIS_PASSWORD":
+			// Unset secrets show as empty so it's clear they're missing.
+			if setting.Value != "" {
+				t.Errorf("expected the unset redis password to be empty, got %v", setting.Value)
+			}
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Fields of Config are loaded from the env tag:
+//
+//	env:"NAME"           environment variable, and key in the config file
+//	env:"NAME,required"  must be set to something other than empty
+//	env:"NAME,secret"    redacted in logs and config print
+//	default:"value"      used when neither the file nor the environment set it
+//	oneof:"a b c"        the only values allowed
+//
+// Values are parsed the same way from the environment and from strings in the
+// file. Lists are comma separated, anything else that isn't a scalar is JSON.
+// Fields without an env tag are derived from the others in complete.
+
+// Where a setting's value came from.
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+)
+
+// Redacted replaces the value of secrets that are set.
+const Redacted = "[redacted]"
+
+type field struct {
+	index    int
+	name     string
+	required bool
+	secret   bool
+	def      string
+	hasDef   bool
+	oneOf    []string
+}
+
+func fields(t reflect.Type) []field {
+	fields := []field{}
+	for i := 0; i < t.NumField(); i++ {
+		tag, ok := t.Field(i).Tag.Lookup("env")
+		if !ok {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		f := field{
+			index:    i,
+			name:     name,
+			required: slices.Contains(strings.Split(options, ","), "required"),
+			secret:   slices.Contains(strings.Split(options, ","), "secret"),
+		}
+		f.def, f.hasDef = t.Field(i).Tag.Lookup("default")
+		if oneOf := t.Field(i).Tag.Get("oneof"); oneOf != "" {
+			f.oneOf = strings.Fields(oneOf)
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// load sets c's tagged fields from their default, then the file if there is
+// one, then the environment. It returns where each set field came from and
+// every problem found, not just the first.
+func load(c *Config, file string, lookupEnv func(string) (string, bool)) (map[string]string, error) {
+	v := reflect.ValueOf(c).Elem()
+	sources := map[string]string{}
+	errs := []error{}
+
+	fromFile := map[string]any{}
+	if file != "" {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return sources, fmt.Errorf("not able to read config file: %w", err)
+		}
+		// YAML is a superset of JSON, so this reads both.
+		if err := yaml.Unmarshal(b, &fromFile); err != nil {
+			return sources, fmt.Errorf("config file %s is not valid YAML or JSON: %w", file, err)
+		}
+	}
+
+	known := map[string]bool{}
+	for _, f := range fields(v.Type()) {
+		known[f.name] = true
+		target := v.Field(f.index)
+
+		if f.hasDef {
+			if err := setString(target, f.def); err != nil {
+				errs = append(errs, fmt.Errorf("%s has invalid default %q: %w", f.name, f.def, err))
+			}
+			sources[f.name] = SourceDefault
+		}
+
+		if value, ok := fromFile[f.name]; ok && value != nil {
+			if err := setAny(target, value); err != nil {
+				errs = append(errs, fmt.Errorf("%s in %s is not valid: %w", f.name, file, err))
+			}
+			sources[f.name] = SourceFile
+		}
+
+		// An empty variable counts as not set, as it always has.
+		if value, ok := lookupEnv(f.name); ok && value != "" {
+			if err := setString(target, value); err != nil {
+				errs = append(errs, fmt.Errorf("%s is not valid: %w", f.name, err))
+			}
+			sources[f.name] = SourceEnv
+		}
+
+		if f.required && target.IsZero() {
+			errs = append(errs, fmt.Errorf("%s not set", f.name))
+		}
+
+		if len(f.oneOf) > 0 && !slices.Contains(f.oneOf, target.String()) {
+			errs = append(errs, fmt.Errorf("%s must be one of %s, got %q", f.name, strings.Join(f.oneOf, ", "), target.String()))
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(fromFile)) {
+		if !known[name] {
+			errs = append(errs, fmt.Errorf("unknown setting %s in %s", name, file))
+		}
+	}
+
+	return sources, errors.Join(errs...)
+}
+
+// setString parses value into target by target's type.
+func setString(target reflect.Value, value string) error {
+	switch target.Kind() {
+	case reflect.String:
+		target.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		target.SetBool(b)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, target.Type().Bits())
+		if err != nil {
+			return err
+		}
+		target.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return err
+		}
+		target.SetFloat(f)
+	case reflect.Slice:
+		if kind := target.Type().Elem().Kind(); kind != reflect.String && kind != reflect.Int {
+			return json.Unmarshal([]byte(value), target.Addr().Interface())
+		}
+		items := reflect.MakeSlice(target.Type(), 0, 0)
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			element := reflect.New(target.Type().Elem()).Elem()
+			if err := setString(element, item); err != nil {
+				return err
+			}
+			items = reflect.Append(items, element)
+		}
+		target.Set(items)
+	default:
+		return fmt.Errorf("unsupported type %s", target.Type())
+	}
+	return nil
+}
+
+// setAny sets target from a value decoded from the config file. Scalars are
+// parsed like environment variables, lists and objects as JSON.
+func setAny(target reflect.Value, value any) error {
+	switch value := value.(type) {
+	case string:
+		return setString(target, value)
+	case bool, int, int64, uint64, float64:
+		return setString(target, fmt.Sprint(value))
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	// Replace, don't merge into, the default.
+	target.Set(reflect.Zero(target.Type()))
+	return json.Unmarshal(b, target.Addr().Interface())
+}
@@ -46,6 +46,9 @@ type AssignmentService interface {
 	// Returns an array of assignments and any error encountered.
 	GetAllAssignments(ctx context.Context) ([]Assignment, error)
 
+	// ListAssignments returns a page of all assignments.
+	ListAssignments(ctx context.Context, query ListQuery) (Page[Assignment], error)
+
 	// GetAssignmentsByLabId retrieves assignments associated with a specific lab.
 	// labId: The ID of the lab.
 	// Returns an array of assignments and any error encountered.
@@ -74,6 +77,11 @@ type AssignmentService interface {
 	// assignmentIds: The IDs of the assignments to delete.
 	// Returns any error encountered.
 	DeleteAssignments(ctx context.Context, assignmentIds []string, userPrincipal string) error
+
+	// ImportAssignments writes the assignments as they are, keeping their ids
+	// and status, and records an event. Nothing is written unless every
+	// assignment is valid. Returns how many assignments were written.
+	ImportAssignments(ctx context.Context, assignments []Assignment) (int, error)
 }
 
 type AssignmentRepository interface {
@@ -81,6 +89,9 @@ type AssignmentRepository interface {
 	// Returns an array of assignments and any error encountered.
 	GetAllAssignments(ctx context.Context) ([]Assignment, error)
 
+	// ListAssignments returns a page of all assignments.
+	ListAssignments(ctx context.Context, query ListQuery) (Page[Assignment], error)
+
 	// GetAssignmentsByLabId retrieves assignments associated with a specific lab.
 	// labId: The ID of the lab.
 	// Returns an array of assignments and any error encountered.
@@ -2,6 +2,8 @@ package entity
 
 import (
 	"context"
+	"errors"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
@@ -31,6 +33,31 @@ type ProfileRecord struct {
 	Roles         string `json:"Roles"`
 }
 
+// Principal is the authenticated caller of a request. Every authenticator
+// produces the same shape so that downstream code doesn't need to know how
+// the caller was authenticated.
+type Principal struct {
+	UserId     string              `json:"userId"`
+	TenantId   string              `json:"tenantId"`
+	AuthMethod string              `json:"authMethod"`
+	Claims     map[string][]string `json:"claims"`
+}
+
+// ErrNoCredentials is returned by an Authenticator when the request doesn't
+// carry the kind of credentials it handles, so the next one can be tried.
+var ErrNoCredentials = errors.New("no credentials for authenticator")
+
+// Authenticator verifies the credentials presented with a request.
+type Authenticator interface {
+	// Name of the authenticator as used in the AUTH_AUTHENTICATORS setting.
+	Name() string
+
+	// Authenticate returns the principal for the request, ErrNoCredentials
+	// when the request has nothing for this authenticator, or any other error
+	// when the credentials were presented but are not valid.
+	Authenticate(ctx context.Context, r *http.Request) (Principal, error)
+}
+
 type AuthService interface {
 	// Create a new user profile.
 	// Privilege: User (can only create own profile)
@@ -50,6 +77,10 @@ type AuthService interface {
 	// Privilege: Admin
 	GetAllProfiles(ctx context.Context) ([]Profile, error)
 
+	// Get a page of all profiles
+	// Privilege: Admin
+	ListProfiles(ctx context.Context, query ListQuery) (Page[Profile], error)
+
 	// deletes the role from the user.
 	// if the user has no roles left, then the user is deleted.
 	// Privilege: Admin
@@ -76,6 +107,9 @@ type AuthRepository interface {
 	// Get all profiles from the table.
 	GetAllProfiles(ctx context.Context) ([]Profile, error)
 
+	// Get a page of profiles from the table.
+	ListProfiles(ctx context.Context, query ListQuery) (Page[Profile], error)
+
 	// This method is used to delete the record for UserPrincipal from the table.
 	// This is used only when the last role is removed from the user.
 	DeleteProfile(ctx context.Context, userPrincipal string) error
@@ -43,6 +43,9 @@ type ChallengeService interface {
 	// Returns an array of challenges and any error encountered.
 	GetAllChallenges(ctx context.Context) ([]Challenge, error)
 
+	// ListChallenges returns a page of all challenges.
+	ListChallenges(ctx context.Context, query ListQuery) (Page[Challenge], error)
+
 	// GetChallengeById retrieves a specific challenge by its ID.
 	// challengeId: The ID of the challenge to retrieve.
 	// Returns the Challenge and any error encountered.
@@ -87,6 +90,9 @@ type ChallengeRepository interface {
 	// Returns an array of challenges and any error encountered.
 	GetAllChallenges(ctx context.Context) ([]Challenge, error)
 
+	// ListChallenges returns a page of all challenges.
+	ListChallenges(ctx context.Context, query ListQuery) (Page[Challenge], error)
+
 	// GetChallengeByUserIdAndLabId retrieves a specific challenge by its user ID and lab ID.
 	// userId: The ID of the user.
 	// labId: The ID of the lab.
@@ -0,0 +1,54 @@
+package entity
+
+import "context"
+
+// PriceSheet holds the hourly prices used to estimate what a lab costs while
+// deployed. Prices are in Currency.
+type PriceSheet struct {
+	Currency string `json:"currency"`
+
+	// VM prices by size, and the price used for sizes not listed.
+	VmHourly        map[string]float64 `json:"vmHourly"`
+	DefaultVmHourly float64            `json:"defaultVmHourly"`
+
+	// Size assumed when the template doesn't set one.
+	DefaultNodeVmSize       string `json:"defaultNodeVmSize"`
+	DefaultJumpserverVmSize string `json:"defaultJumpserverVmSize"`
+	DefaultAroWorkerVmSize  string `json:"defaultAroWorkerVmSize"`
+
+	KubernetesClusterHourly float64 `json:"kubernetesClusterHourly"`
+	AroClusterHourly        float64 `json:"aroClusterHourly"`
+	AppGatewayHourly        float64 `json:"appGatewayHourly"`
+	ContainerRegistryHourly float64 `json:"containerRegistryHourly"`
+
+	// Firewall prices by SKU tier (Basic, Standard, Premium).
+	FirewallHourly        map[string]float64 `json:"firewallHourly"`
+	DefaultFirewallHourly float64            `json:"defaultFirewallHourly"`
+}
+
+type CostItem struct {
+	Resource   string  `json:"resource"`
+	Sku        string  `json:"sku"`
+	Quantity   int     `json:"quantity"`
+	HourlyCost float64 `json:"hourlyCost"`
+}
+
+// CostEstimate is the estimated cost of a lab while it's deployed.
+type CostEstimate struct {
+	Currency   string     `json:"currency"`
+	HourlyCost float64    `json:"hourlyCost"`
+	Items      []CostItem `json:"items"`
+	// LifespanCost is HourlyCost for the deployment's lifespan, when it has one.
+	LifespanCost float64 `json:"lifespanCost,omitempty"`
+}
+
+type CostService interface {
+	EstimateLabCost(ctx context.Context, lab LabType) (CostEstimate, error)
+
+	// Estimate for the deployment's lab over its lifespan.
+	EstimateDeploymentCost(ctx context.Context, deployment Deployment) (CostEstimate, error)
+}
+
+type PriceSheetRepository interface {
+	GetPriceSheet(ctx context.Context) (PriceSheet, error)
+}
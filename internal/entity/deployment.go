@@ -2,6 +2,7 @@ package entity
 
 import (
 	"context"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/data/aztables"
 )
@@ -35,6 +36,54 @@ type Deployment struct {
 	DeploymentAutoDelete         bool             `json:"deploymentAutoDelete"`
 	DeploymentLifespan           int64            `json:"deploymentLifespan"`
 	DeploymentAutoDeleteUnixTime int64            `json:"deploymentAutoDeleteUnixTime"`
+	// Estimated from the lab when the deployment is upserted. The estimated
+	// cost covers the lifespan and is only set for auto deleted deployments.
+	DeploymentEstimatedHourlyCost float64 `json:"deploymentEstimatedHourlyCost,omitempty"`
+	DeploymentEstimatedCost       float64 `json:"deploymentEstimatedCost,omitempty"`
+}
+
+// DeploymentKey identifies a deployment and builds every key it's stored
+// under, so table rows and cache entries always agree.
+type DeploymentKey struct {
+	UserId         string
+	SubscriptionId string
+	Workspace      string
+}
+
+func NewDeploymentKey(deployment Deployment) DeploymentKey {
+	return DeploymentKey{
+		UserId:         deployment.DeploymentUserId,
+		SubscriptionId: deployment.DeploymentSubscriptionId,
+		Workspace:      deployment.DeploymentWorkspace,
+	}
+}
+
+func (k DeploymentKey) PartitionKey() string {
+	return k.UserId
+}
+
+// RowKey is also the deployment's DeploymentId.
+func (k DeploymentKey) RowKey() string {
+	return k.UserId + "-" + k.Workspace + "-" + k.SubscriptionId
+}
+
+// CacheKey is the Redis key of the deployment.
+func (k DeploymentKey) CacheKey() string {
+	return "deployment-" + k.RowKey()
+}
+
+// UserCacheKey is the Redis key of all of the user's deployments.
+func (k DeploymentKey) UserCacheKey() string {
+	return k.UserId + "-deployments"
+}
+
+// LegacyCacheKeys are keys the deployment was cached under before
+// DeploymentKey. They're only deleted.
+func (k DeploymentKey) LegacyCacheKeys() []string {
+	return []string{
+		k.UserId + "-" + k.SubscriptionId + "-" + k.Workspace,
+		k.UserId + "-" + k.Workspace + "-" + k.SubscriptionId,
+	}
 }
 
 type DeploymentEntry struct {
@@ -55,22 +104,115 @@ type OperationEntry struct {
 	DeploymentLab                string           `json:"DeploymentLab"`
 }
 
+// DeploymentOperation is one recorded status change of a deployment, with the
+// lab as it was at that point.
+type DeploymentOperation struct {
+	Status               DeploymentStatus `json:"status"`
+	Timestamp            string           `json:"timestamp"`
+	SincePreviousSeconds int64            `json:"sincePreviousSeconds"`
+	AutoDelete           bool             `json:"autoDelete"`
+	Lifespan             int64            `json:"lifespan"`
+	AutoDeleteUnixTime   int64            `json:"autoDeleteUnixTime"`
+	Lab                  LabType          `json:"lab"`
+}
+
+// DeploymentPhase is how long one of the Init, Plan, Deployment or Destroy
+// phases took, from its "In Progress" status to the status that ended it.
+type DeploymentPhase struct {
+	Phase           string           `json:"phase"`
+	Result          DeploymentStatus `json:"result"`
+	StartedAt       string           `json:"startedAt"`
+	EndedAt         string           `json:"endedAt"`
+	DurationSeconds int64            `json:"durationSeconds"`
+}
+
+// DeploymentHistory is a page of a deployment's timeline, oldest first.
+type DeploymentHistory struct {
+	UserId         string                `json:"userId"`
+	SubscriptionId string                `json:"subscriptionId"`
+	Workspace      string                `json:"workspace"`
+	Operations     []DeploymentOperation `json:"operations"`
+	Phases         []DeploymentPhase     `json:"phases"`
+	NextToken      string                `json:"nextToken,omitempty"`
+}
+
+var (
+	ErrDeploymentNotFound       = NewNotFoundError("deployment_not_found", "deployment not found")
+	ErrDeploymentNotAutoDeleted = NewValidationError("deployment_not_auto_deleted", "deployment is not set to auto delete")
+	ErrLifespanExceedsPolicy    = NewForbiddenError("lifespan_exceeds_policy", "requested extension exceeds the maximum lifespan allowed")
+	ErrDeploymentExpired        = NewConflictError("deployment_expired", "deployment has expired and is being deleted")
+	ErrDeploymentNotExtendable  = NewConflictError("deployment_not_extendable", "only completed or failed deployments can be extended")
+)
+
+// ExtendDeploymentRequest pushes a deployment's auto delete time out by
+// DurationSeconds.
+type ExtendDeploymentRequest struct {
+	DurationSeconds int64 `json:"durationSeconds"`
+}
+
+// StuckDeployment is a deployment whose in progress status hasn't changed for
+// longer than the stuck threshold.
+type StuckDeployment struct {
+	Deployment   Deployment `json:"deployment"`
+	StatusSince  string     `json:"statusSince"`
+	StuckMinutes int64      `json:"stuckMinutes"`
+	// Times the operation was already sent to the server again.
+	Retries int `json:"retries"`
+}
+
+// ServerStatusClient asks a user's actlabs server whether it's up, and whether
+// it's still running a deployment's operation.
+type ServerStatusClient interface {
+	IsServerUp(ctx context.Context, userPrincipalName string) (bool, error)
+	IsOperationRunning(ctx context.Context, userPrincipalName string, deployment Deployment) (bool, error)
+}
+
 type DeploymentService interface {
 	GetAllDeployments(ctx context.Context) ([]Deployment, error)
 	GetUserDeployments(ctx context.Context, userPrincipalName string) ([]Deployment, error)
 	UpsertDeployment(ctx context.Context, deployment Deployment) error
-	DeleteDeployment(ctx context.Context, userPrincipalName string, subscriptionId string, workspace string) error
+	DeleteDeployment(ctx context.Context, key DeploymentKey) error
+	GetDeploymentHistory(ctx context.Context, userPrincipalName string, subscriptionId string, workspace string, limit int32, nextToken string) (DeploymentHistory, error)
+
+	// Extend the deployment's auto delete time. The remaining lifespan after
+	// extending can't exceed the maximum for the user's role.
+	ExtendDeployment(ctx context.Context, userPrincipalName string, subscriptionId string, workspace string, duration time.Duration) (Deployment, error)
+
+	// Asks the user's actlabs server to destroy the deployment now, as if its
+	// auto delete time had passed.
+	DestroyDeployment(ctx context.Context, key DeploymentKey) error
 
 	MonitorAndAutoDestroyDeployments(ctx context.Context)
+	// Takes the polling interval from the runtime settings.
+	ApplySettings(ctx context.Context, settings RuntimeSettings)
+
+	GetStuckDeployments(ctx context.Context) ([]StuckDeployment, error)
+	// Retries stuck destroys while the server is up and retries are left,
+	// everything else stuck is marked failed.
+	ReconcileStuckDeployments(ctx context.Context) error
+	MonitorStuckDeployments(ctx context.Context)
 }
 
 type DeploymentRepository interface {
 	GetAllDeployments(ctx context.Context) ([]Deployment, error)
 	GetUserDeployments(ctx context.Context, userPrincipalName string) ([]Deployment, error)
-	GetDeployment(ctx context.Context, userPrincipalName string, workspace string, subscriptionId string) (Deployment, error)
+	GetDeployment(ctx context.Context, key DeploymentKey) (Deployment, error)
 	UpsertDeployment(ctx context.Context, deployment Deployment) error
 	DeploymentOperationEntry(ctx context.Context, deployment Deployment) error
-	DeleteDeployment(ctx context.Context, userPrincipalName string, subscriptionId string, workspace string) error
+	// Operations oldest first. nextToken continues from a previous page and
+	// the returned token is empty on the last page.
+	GetDeploymentOperations(ctx context.Context, userPrincipalName string, subscriptionId string, workspace string, limit int32, nextToken string) ([]DeploymentOperation, string, error)
+	DeleteDeployment(ctx context.Context, key DeploymentKey) error
+
+	// Rewrites rows not stored under their canonical DeploymentKey. Returns
+	// the number of rows that were (or, with dryRun, would be) migrated.
+	MigrateDeploymentKeys(ctx context.Context, dryRun bool) (int, error)
 
 	AutoDestroyDeployment(ctx context.Context, userPrincipalName string, deployment Deployment) error
+	// Asks the user's actlabs server to deploy the deployment's lab.
+	DeployDeployment(ctx context.Context, userPrincipalName string, deployment Deployment) error
+
+	// Records that the expiry warning for windowMinutes was sent for the
+	// deployment's current auto delete time. Returns false if it already was.
+	MarkExpiryWarningSent(ctx context.Context, deployment Deployment, windowMinutes int) (bool, error)
 }
@@ -0,0 +1,84 @@
+package entity
+
+import "errors"
+
+// ErrorKind says what went wrong in terms a client can act on. The error
+// handler middleware maps each kind to an HTTP status.
+type ErrorKind string
+
+const (
+	ErrorKindNotFound             ErrorKind = "not_found"
+	ErrorKindUnauthenticated      ErrorKind = "unauthenticated"
+	ErrorKindForbidden            ErrorKind = "forbidden"
+	ErrorKindConflict             ErrorKind = "conflict"
+	ErrorKindValidation           ErrorKind = "validation"
+	ErrorKindTooLarge             ErrorKind = "too_large"
+	ErrorKindUnsupportedMediaType ErrorKind = "unsupported_media_type"
+	// A dependency such as storage, Redis or a user's server failed.
+	ErrorKindUpstream ErrorKind = "upstream"
+)
+
+// Error is a domain error returned by services. Code is stable and
+// machine-readable, renaming or removing one needs a new version of the
+// problem contract. Message is safe to show to the user. Err is the cause,
+// it's logged but never sent to the client.
+type Error struct {
+	Kind    ErrorKind
+	Code    string
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches errors with the same code, so a sentinel matches any error
+// created with its code.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+func NewNotFoundError(code string, message string) *Error {
+	return &Error{Kind: ErrorKindNotFound, Code: code, Message: message}
+}
+
+func NewUnauthenticatedError(code string, message string) *Error {
+	return &Error{Kind: ErrorKindUnauthenticated, Code: code, Message: message}
+}
+
+func NewForbiddenError(code string, message string) *Error {
+	return &Error{Kind: ErrorKindForbidden, Code: code, Message: message}
+}
+
+func NewConflictError(code string, message string) *Error {
+	return &Error{Kind: ErrorKindConflict, Code: code, Message: message}
+}
+
+func NewValidationError(code string, message string) *Error {
+	return &Error{Kind: ErrorKindValidation, Code: code, Message: message}
+}
+
+func NewUpstreamError(code string, message string, err error) *Error {
+	return &Error{Kind: ErrorKindUpstream, Code: code, Message: message, Err: err}
+}
+
+// NewStorageError is returned when reading or writing storage failed.
+func NewStorageError(message string, err error) *Error {
+	return NewUpstreamError("storage_unavailable", message, err)
+}
+
+// ErrorKindOf is the kind of the first *Error in err's chain, empty if
+// there's none.
+func ErrorKindOf(err error) ErrorKind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return ""
+}
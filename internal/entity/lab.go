@@ -32,6 +32,26 @@ var (
 	ProtectedLabs = labentity.ProtectedLabs
 )
 
+// SupportingDocument is the metadata stored with a supporting document.
+// Documents uploaded before metadata was stored only have Id, ContentType,
+// Size and UploadedAt.
+type SupportingDocument struct {
+	Id          string   `json:"id"`
+	FileName    string   `json:"fileName"`
+	ContentType string   `json:"contentType"`
+	Size        int64    `json:"size"`
+	UploadedBy  string   `json:"uploadedBy"`
+	UploadedAt  string   `json:"uploadedAt"`
+	LabIds      []string `json:"labIds"`
+}
+
+var (
+	ErrLabNotFound                      = NewNotFoundError("lab_not_found", "lab not found")
+	ErrSupportingDocumentNotFound       = NewNotFoundError("supporting_document_not_found", "supporting document not found")
+	ErrSupportingDocumentTooLarge       = &Error{Kind: ErrorKindTooLarge, Code: "supporting_document_too_large", Message: "supporting document is too large"}
+	ErrSupportingDocumentTypeNotAllowed = &Error{Kind: ErrorKindUnsupportedMediaType, Code: "supporting_document_type_not_allowed", Message: "supporting document type is not allowed"}
+)
+
 type LabService interface {
 	// Private Labs
 	// Role: user
@@ -63,15 +83,32 @@ type LabService interface {
 	// Shared functions
 	GetLabByIdAndType(ctx context.Context, typeOfLab string, labId string) (LabType, error)
 	GetLabs(ctx context.Context, typeOfLab string) ([]LabType, error)
+	// GetAllLabs is GetLabs failing when any lab can't be read, for callers
+	// that act on the whole set, like collecting orphaned documents.
+	GetAllLabs(ctx context.Context, typeOfLab string) ([]LabType, error)
+	// A page of the labs of typeOfLab userId can see: their own private
+	// labs, all public labs and protected labs redacted as in GetProtectedLabs.
+	ListLabs(ctx context.Context, typeOfLab string, userId string, query ListQuery) (Page[LabType], error)
 	GetLabVersions(ctx context.Context, typeOfLab string, labId string) ([]LabType, error)
 	UpsertLab(ctx context.Context, lab LabType) (LabType, error)
 	DeleteLab(ctx context.Context, typeOfLab string, labId string) error
+	// ImportLabs writes each lab as the current version of its id, without
+	// the ownership checks of UpsertLab, and records an event. Nothing is
+	// written unless every lab is valid. Returns how many labs were written.
+	ImportLabs(ctx context.Context, labs []LabType) (int, error)
 
 	// Supporting Documents
-	UpsertSupportingDocument(ctx context.Context, supportingDocument multipart.File) (string, error)
+	// Validates the document's type and size before saving it.
+	UpsertSupportingDocument(ctx context.Context, supportingDocument multipart.File, header *multipart.FileHeader, uploadedBy string) (string, error)
 	DeleteSupportingDocument(ctx context.Context, supportingDocumentId string) error
 	GetSupportingDocument(ctx context.Context, supportingDocumentId string) (io.ReadCloser, error)
+	GetSupportingDocumentMetadata(ctx context.Context, supportingDocumentId string) (SupportingDocument, error)
 	DoesSupportingDocumentExist(ctx context.Context, supportingDocumentId string) bool
+
+	// Deletes supporting documents no current lab version references. Returns
+	// the number of documents deleted.
+	CollectSupportingDocuments(ctx context.Context) (int, error)
+	MonitorSupportingDocuments(ctx context.Context)
 }
 
 type LabRepository interface {
@@ -89,8 +126,12 @@ type LabRepository interface {
 	DeleteLab(ctx context.Context, typeOfLab string, labId string) error
 
 	// Supporting Documents
-	UpsertSupportingDocument(ctx context.Context, supportingDocument multipart.File) (string, error)
+	UpsertSupportingDocument(ctx context.Context, supportingDocument io.Reader, metadata SupportingDocument) (string, error)
 	DeleteSupportingDocument(ctx context.Context, supportingDocumentId string) error
 	GetSupportingDocument(ctx context.Context, supportingDocumentId string) (io.ReadCloser, error)
+	GetSupportingDocumentMetadata(ctx context.Context, supportingDocumentId string) (SupportingDocument, error)
+	// Replaces the labs referencing the document.
+	SetSupportingDocumentLabIds(ctx context.Context, supportingDocumentId string, labIds []string) error
+	ListSupportingDocuments(ctx context.Context) ([]SupportingDocument, error)
 	DoesSupportingDocumentExist(ctx context.Context, supportingDocumentId string) bool
 }
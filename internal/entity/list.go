@@ -0,0 +1,27 @@
+package entity
+
+// ListQuery selects a page of a list endpoint's results.
+type ListQuery struct {
+	// Limit is the page size. Zero returns everything.
+	Limit int32
+	// NextToken continues from the page that returned it.
+	NextToken string
+	// Sort orders the items by these JSON fields. Table storage returns pages
+	// in key order, so sorted lists aren't paged.
+	Sort []SortField
+	// Fields limits the JSON fields returned, all of them when empty.
+	Fields []string
+}
+
+type SortField struct {
+	Field      string
+	Descending bool
+}
+
+// Page is one page of a list. NextToken is empty on the last page.
+type Page[T any] struct {
+	Items     []T    `json:"items"`
+	NextToken string `json:"nextToken,omitempty"`
+}
+
+var ErrInvalidNextToken = NewValidationError("invalid_next_token", "nextToken is not valid")
@@ -0,0 +1,15 @@
+package entity
+
+import "context"
+
+type Notification struct {
+	UserId  string `json:"userId"`
+	Reason  string `json:"reason"`
+	Subject string `json:"subject"`
+	Message string `json:"message"`
+}
+
+// Notifier delivers notifications to users outside of the hub's own events.
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification) error
+}
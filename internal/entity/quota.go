@@ -0,0 +1,91 @@
+package entity
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/aztables"
+)
+
+// DeploymentQuota limits a user's deployments. Zero means no limit.
+type DeploymentQuota struct {
+	MaxConcurrentDeployments int   `json:"maxConcurrentDeployments"`
+	MaxWorkspaces            int   `json:"maxWorkspaces"`
+	MaxLifespanHoursPerWeek  int64 `json:"maxLifespanHoursPerWeek"`
+}
+
+// DeploymentQuotaOverride replaces the default quota for a user.
+type DeploymentQuotaOverride struct {
+	UserId string          `json:"userId"`
+	Quota  DeploymentQuota `json:"quota"`
+}
+
+type DeploymentQuotaOverrideEntry struct {
+	aztables.Entity
+	Quota string
+}
+
+// DeploymentQuotaUsage is a user's quota and how much of it is used.
+type DeploymentQuotaUsage struct {
+	UserId                string          `json:"userId"`
+	Quota                 DeploymentQuota `json:"quota"`
+	Overridden            bool            `json:"overridden"`
+	ConcurrentDeployments int             `json:"concurrentDeployments"`
+	Workspaces            int             `json:"workspaces"`
+	LifespanHoursThisWeek float64         `json:"lifespanHoursThisWeek"`
+}
+
+const (
+	QuotaLimitConcurrentDeployments = "maxConcurrentDeployments"
+	QuotaLimitWorkspaces            = "maxWorkspaces"
+	QuotaLimitLifespanHoursPerWeek  = "maxLifespanHoursPerWeek"
+)
+
+// QuotaExceededError is returned when a deployment would take the user over
+// one of their limits.
+type QuotaExceededError struct {
+	Limit     string  `json:"limit"`
+	Max       int64   `json:"max"`
+	Current   float64 `json:"current"`
+	Requested float64 `json:"requested"`
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("quota exceeded: %s is %d, currently using %g and requested %g", e.Limit, e.Max, e.Current, e.Requested)
+}
+
+type QuotaService interface {
+	// The user's quota and current usage.
+	GetQuotaUsage(ctx context.Context, userId string) (DeploymentQuotaUsage, error)
+
+	// Returns a *QuotaExceededError if creating or starting deployment would
+	// exceed the user's quota, otherwise the lifespan in seconds the upsert
+	// hands out, to be recorded with RecordLifespan once it succeeds. Updates
+	// of deployments already starting are never rejected. Quotas fail open,
+	// storage errors are logged and the deployment is allowed.
+	CheckDeployment(ctx context.Context, deployment Deployment) (int64, error)
+
+	// Returns a *QuotaExceededError if handing out lifespanSeconds more would
+	// exceed the user's weekly lifespan quota. Fails open like CheckDeployment.
+	CheckLifespan(ctx context.Context, userId string, lifespanSeconds int64) error
+
+	// Records lifespan handed out to the user this week.
+	RecordLifespan(ctx context.Context, userId string, lifespanSeconds int64) error
+
+	// Overrides are managed by admins.
+	GetQuotaOverrides(ctx context.Context) ([]DeploymentQuotaOverride, error)
+	UpsertQuotaOverride(ctx context.Context, override DeploymentQuotaOverride) error
+	DeleteQuotaOverride(ctx context.Context, userId string) error
+}
+
+type QuotaRepository interface {
+	// Returns false if the user has no override.
+	GetQuotaOverride(ctx context.Context, userId string) (DeploymentQuota, bool, error)
+	GetQuotaOverrides(ctx context.Context) ([]DeploymentQuotaOverride, error)
+	UpsertQuotaOverride(ctx context.Context, override DeploymentQuotaOverride) error
+	DeleteQuotaOverride(ctx context.Context, userId string) error
+
+	// Lifespan seconds handed out to the user in the given week, e.g. "2024-W18".
+	GetWeeklyLifespan(ctx context.Context, userId string, week string) (int64, error)
+	AddWeeklyLifespan(ctx context.Context, userId string, week string, lifespanSeconds int64) error
+}
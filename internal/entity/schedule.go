@@ -0,0 +1,76 @@
+package entity
+
+import (
+	"context"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/aztables"
+)
+
+type ScheduleStatus string
+
+const (
+	ScheduleScheduled ScheduleStatus = "Scheduled"
+	ScheduleDeployed  ScheduleStatus = "Deployed"
+	ScheduleCompleted ScheduleStatus = "Completed"
+)
+
+const (
+	ScheduleActionDeploy  = "deploy"
+	ScheduleActionDestroy = "destroy"
+)
+
+var (
+	ErrScheduleNotFound = NewNotFoundError("schedule_not_found", "schedule not found")
+	ErrInvalidSchedule  = NewValidationError("invalid_schedule", "invalid schedule")
+	// The user already has a deployment in the schedule's workspace.
+	ErrScheduleWorkspaceInUse = NewConflictError("schedule_workspace_in_use", "workspace already has a deployment")
+)
+
+// Schedule deploys a lab to a set of users' workspaces at StartTime and
+// destroys it at EndTime. Times are RFC3339. Only the lab's id and type are
+// taken from requests, the lab itself is read from storage.
+type Schedule struct {
+	Id        string         `json:"id"`
+	CreatedBy string         `json:"createdBy"`
+	UserIds   []string       `json:"userIds"`
+	Lab       LabType        `json:"lab"`
+	Workspace string         `json:"workspace"`
+	StartTime string         `json:"startTime"`
+	EndTime   string         `json:"endTime"`
+	Status    ScheduleStatus `json:"status"`
+	Runs      []ScheduleRun  `json:"runs"`
+}
+
+// ScheduleRun is the result of deploying or destroying the lab for one user.
+type ScheduleRun struct {
+	UserId    string `json:"userId"`
+	Action    string `json:"action"`
+	Time      string `json:"time"`
+	Succeeded bool   `json:"succeeded"`
+	Error     string `json:"error,omitempty"`
+}
+
+type ScheduleEntry struct {
+	aztables.Entity
+	Schedule string
+}
+
+type ScheduleService interface {
+	GetSchedules(ctx context.Context) ([]Schedule, error)
+	GetSchedule(ctx context.Context, scheduleId string) (Schedule, error)
+	CreateSchedule(ctx context.Context, schedule Schedule) (Schedule, error)
+	// Deletes the schedule. Labs it already deployed are destroyed.
+	DeleteSchedule(ctx context.Context, scheduleId string) error
+
+	// Deploys and destroys the labs of schedules whose time has come. A
+	// schedule stays deployed until every destroy succeeded.
+	RunSchedules(ctx context.Context) error
+	MonitorSchedules(ctx context.Context)
+}
+
+type ScheduleRepository interface {
+	GetSchedules(ctx context.Context) ([]Schedule, error)
+	GetSchedule(ctx context.Context, scheduleId string) (Schedule, error)
+	UpsertSchedule(ctx context.Context, schedule Schedule) error
+	DeleteSchedule(ctx context.Context, scheduleId string) error
+}
@@ -2,6 +2,7 @@ package entity
 
 import (
 	"context"
+	"time"
 )
 
 const OwnerRoleDefinitionId string = "/Microsoft.Authorization/roleDefinitions/8e3af657-a8ff-443c-a75c-2fe8c4bcb635"
@@ -26,6 +27,9 @@ const (
 	ServerStatusUpdating      ServerStatus = "Updating"
 )
 
+// ErrServerNotRegistered is returned when the user has no server registered.
+var ErrServerNotRegistered = NewNotFoundError("server_not_registered", "server is not registered")
+
 type Server struct {
 	PartitionKey                string       `json:"PartitionKey"`
 	RowKey                      string       `json:"RowKey"`
@@ -51,12 +55,92 @@ type Server struct {
 	AutoDestroy                 bool         `json:"autoDestroy"`
 	InactivityDurationInSeconds int          `json:"inactivityDurationInSeconds"`
 	Version                     string       `json:"version"`
+	// Set while the owner has lost access to the subscription, with the status
+	// to restore once access is back.
+	Suspended           bool         `json:"suspended,omitempty"`
+	SuspendedFromStatus ServerStatus `json:"suspendedFromStatus,omitempty"`
+	// LastModifiedTime is the table entity's timestamp, set when reading. It
+	// isn't stored.
+	LastModifiedTime string `json:"lastModifiedTime,omitempty"`
 }
 
 type ManagedServerActionStatus struct {
 	InProgress bool `json:"inProgress"`
 }
 
+// ServerVerificationResult is the outcome of re-checking that a server's
+// owner still has the required role on its subscription.
+type ServerVerificationResult struct {
+	UserPrincipalName string       `json:"userPrincipalName"`
+	SubscriptionId    string       `json:"subscriptionId"`
+	Authorized        bool         `json:"authorized"`
+	Suspended         bool         `json:"suspended"`
+	PreviousStatus    ServerStatus `json:"previousStatus"`
+	Status            ServerStatus `json:"status"`
+	Error             string       `json:"error,omitempty"`
+	CheckedAt         string       `json:"checkedAt"`
+}
+
+// ServerHealth is a registered server with its last verification result, if
+// it has been verified yet.
+type ServerHealth struct {
+	UserPrincipalName string                    `json:"userPrincipalName"`
+	SubscriptionId    string                    `json:"subscriptionId"`
+	TenantID          string                    `json:"tenantId"`
+	Version           string                    `json:"version"`
+	Status            ServerStatus              `json:"status"`
+	LastVerification  *ServerVerificationResult `json:"lastVerification"`
+}
+
+// ServerSummaryOptions are the thresholds used to build a ServerSummary.
+type ServerSummaryOptions struct {
+	IdleThreshold  time.Duration
+	StuckThreshold time.Duration
+	TopUsers       int
+}
+
+// ServerSummaryEntry is a server listed in a ServerSummary, with how long it
+// has been in its current condition.
+type ServerSummaryEntry struct {
+	UserPrincipalName string       `json:"userPrincipalName"`
+	SubscriptionId    string       `json:"subscriptionId"`
+	Status            ServerStatus `json:"status"`
+	Region            string       `json:"region"`
+	Version           string       `json:"version"`
+	Since             string       `json:"since"`
+	DurationMinutes   int64        `json:"durationMinutes"`
+}
+
+// UserDeploymentCount is a user's deployment count joined with their server.
+type UserDeploymentCount struct {
+	UserPrincipalName string       `json:"userPrincipalName"`
+	Deployments       int          `json:"deployments"`
+	ServerStatus      ServerStatus `json:"serverStatus"`
+	ServerRegion      string       `json:"serverRegion"`
+	// Estimated hourly cost of the user's deployed labs.
+	EstimatedHourlyCost float64 `json:"estimatedHourlyCost"`
+}
+
+// ServerSummary aggregates the server fleet for admins.
+type ServerSummary struct {
+	TotalServers int                   `json:"totalServers"`
+	ByStatus     map[ServerStatus]int  `json:"byStatus"`
+	ByVersion    map[string]int        `json:"byVersion"`
+	ByRegion     map[string]int        `json:"byRegion"`
+	ByTenant     map[string]int        `json:"byTenant"`
+	IdleServers  []ServerSummaryEntry  `json:"idleServers"`
+	StuckServers []ServerSummaryEntry  `json:"stuckServers"`
+	TopUsers     []UserDeploymentCount `json:"topUsers"`
+	// Estimated hourly cost of all deployed labs.
+	EstimatedHourlyCost float64 `json:"estimatedHourlyCost"`
+}
+
+// ServerAuthorizationChecker verifies that the owner of a server has the
+// required role assignment on the server's subscription.
+type ServerAuthorizationChecker interface {
+	IsUserAuthorized(ctx context.Context, server Server) (bool, error)
+}
+
 type ServerService interface {
 	RegisterSubscription(ctx context.Context, server Server) error
 	Unregister(ctx context.Context, userPrincipalName string) error
@@ -64,16 +148,34 @@ type ServerService interface {
 	GetServer(ctx context.Context, userPrincipalName string) (Server, error)
 
 	GetAllServers(ctx context.Context) ([]Server, error)
+	ListServers(ctx context.Context, query ListQuery) (Page[Server], error)
 
 	UpdateActivityStatus(ctx context.Context, userPrincipalName string) error
+
+	// Re-verify the owner's access for every registered server. Servers whose
+	// owner lost access are marked Unknown (suspended) until access returns.
+	VerifyServers(ctx context.Context) ([]ServerVerificationResult, error)
+
+	// Runs VerifyServers periodically until ctx is cancelled.
+	MonitorServerAuthorization(ctx context.Context)
+
+	// All registered servers with their last verification result.
+	GetServersHealth(ctx context.Context) ([]ServerHealth, error)
+
+	// Fleet wide counts, idle and stuck servers, and the users with most deployments.
+	GetServersSummary(ctx context.Context, options ServerSummaryOptions) (ServerSummary, error)
 }
 
 type ServerRepository interface {
 	IsUserAuthorized(ctx context.Context, server Server) (bool, error)
 
+	SaveServerVerificationResult(ctx context.Context, result ServerVerificationResult) error
+	GetServerVerificationResults(ctx context.Context) (map[string]ServerVerificationResult, error)
+
 	UpsertServerInDatabase(ctx context.Context, server Server) error
 	GetServerFromDatabase(ctx context.Context, partitionKey string, rowKey string) (Server, error)
 	GetAllServersFromDatabase(ctx context.Context) ([]Server, error)
+	ListServersFromDatabase(ctx context.Context, query ListQuery) (Page[Server], error)
 
 	DeleteServerFromDatabase(ctx context.Context, server Server) error
 }
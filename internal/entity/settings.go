@@ -0,0 +1,44 @@
+package entity
+
+import "context"
+
+// RuntimeSettings are the settings admins can change while the hub is
+// running. Every hub instance applies a change as soon as it's saved.
+type RuntimeSettings struct {
+	DeploymentsPollingIntervalSeconds int32             `json:"deploymentsPollingIntervalSeconds"`
+	CorsAllowOrigins                  []string          `json:"corsAllowOrigins"`
+	LogLevel                          string            `json:"logLevel"`
+	RateLimit                         RateLimitSettings `json:"rateLimit"`
+}
+
+// RateLimitSettings are requests allowed per window, per user and per API key.
+type RateLimitSettings struct {
+	WindowSeconds     int `json:"windowSeconds"`
+	MaxRequests       int `json:"maxRequests"`
+	APIKeyMaxRequests int `json:"apiKeyMaxRequests"`
+}
+
+type SettingsService interface {
+	GetSettings(ctx context.Context) (RuntimeSettings, error)
+	// Validates and saves the settings, and records an event with what changed.
+	UpdateSettings(ctx context.Context, settings RuntimeSettings) (RuntimeSettings, error)
+
+	// Applies the saved settings. Call it before subscribing so subscribers
+	// start with them rather than the configured defaults.
+	LoadSettings(ctx context.Context) error
+	// Subscribe calls apply with the current settings, and again whenever
+	// they change on any instance. apply must not block.
+	Subscribe(ctx context.Context, name string, apply func(ctx context.Context, settings RuntimeSettings))
+	// Applies changes saved by other instances until ctx is done.
+	WatchSettings(ctx context.Context)
+}
+
+type SettingsRepository interface {
+	// Returns false if the settings were never changed from their defaults.
+	GetSettings(ctx context.Context) (RuntimeSettings, bool, error)
+	// Saves the settings and notifies every watcher.
+	UpsertSettings(ctx context.Context, settings RuntimeSettings) error
+	// Calls subscribed once listening for changes, then changed with settings
+	// saved from anywhere until ctx is done.
+	WatchSettings(ctx context.Context, subscribed func(), changed func(settings RuntimeSettings)) error
+}
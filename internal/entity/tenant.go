@@ -0,0 +1,11 @@
+package entity
+
+// Tenant maps an identity domain to the Entra tenant its users sign in from
+// and the actlabs-server version deployed for them. A tenant may own several
+// domains, and a domain may be served from several tenants (e.g. corp and
+// FDPO), so each pair is its own entry.
+type Tenant struct {
+	Domain        string `json:"domain"`
+	TenantId      string `json:"tenantId"`
+	ServerVersion string `json:"serverVersion"`
+}
@@ -0,0 +1,278 @@
+// Package fakemise is a stand-in for the MISE container. It speaks the same
+// request and response headers as miseadapter.ValidateRequest but validates
+// tokens signed with a local RSA key instead of Entra ID, so the hub can run
+// and be tested without the sidecar.
+package fakemise
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	keyID = "fake-mise"
+
+	returnAllSubjectClaimsHeader = "Return-All-Subject-Token-Claims"
+	returnSubjectClaimPrefix     = "Return-Subject-Token-Claim-"
+	subjectClaimPrefix           = "Subject-Token-Claim-"
+	encodedSubjectClaimPrefix    = "Subject-Token-Encoded-Claim-"
+)
+
+// Options control how the fake validates tokens and what it returns.
+type Options struct {
+	// Audience and Issuer are checked when set.
+	Audience string
+	Issuer   string
+
+	// SubjectClaims are returned for every valid token, replacing the claim of
+	// the same name in the token.
+	SubjectClaims map[string][]string
+
+	// StatusCode, when set, is returned for every request regardless of the
+	// token. Useful to simulate MISE rejecting or failing.
+	StatusCode int
+}
+
+// Server is an http.Handler implementing the MISE ValidateRequest contract.
+type Server struct {
+	key *rsa.PrivateKey
+
+	mu      sync.RWMutex
+	options Options
+}
+
+func New(key *rsa.PrivateKey, options Options) *Server {
+	return &Server{
+		key:     key,
+		options: options,
+	}
+}
+
+// NewWithGeneratedKey creates a server with a fresh signing key.
+func NewWithGeneratedKey(options Options) (*Server, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	return New(key, options), nil
+}
+
+// LoadOrCreateKey reads a PEM encoded RSA key from path, creating one if the
+// file doesn't exist, so tokens stay valid across restarts.
+func LoadOrCreateKey(path string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("no PEM data found in %s", path)
+		}
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+
+	data = pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// SetSubjectClaims replaces the claims returned for every valid token.
+func (s *Server) SetSubjectClaims(claims map[string][]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.options.SubjectClaims = claims
+}
+
+// SetStatusCode forces the status code of every response. Zero restores
+// normal validation.
+func (s *Server) SetStatusCode(statusCode int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.options.StatusCode = statusCode
+}
+
+// SignToken returns a token signed with the server's key. The configured
+// audience and issuer, and an expiry one hour out, are added unless present.
+func (s *Server) SignToken(claims jwt.MapClaims) (string, error) {
+	s.mu.RLock()
+	options := s.options
+	s.mu.RUnlock()
+
+	signed := jwt.MapClaims{}
+	for name, value := range claims {
+		signed[name] = value
+	}
+	if _, ok := signed["aud"]; !ok && options.Audience != "" {
+		signed["aud"] = options.Audience
+	}
+	if _, ok := signed["iss"]; !ok && options.Issuer != "" {
+		signed["iss"] = options.Issuer
+	}
+	if _, ok := signed["exp"]; !ok {
+		signed["exp"] = time.Now().Add(time.Hour).Unix()
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, signed)
+	token.Header["kid"] = keyID
+	return token.SignedString(s.key)
+}
+
+// ServeHTTP validates the token in the Authorization header. POST /token mints
+// a token from the JSON claims in the body, for use outside of tests.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.URL.Path == "/token" {
+		s.serveToken(w, r)
+		return
+	}
+
+	s.mu.RLock()
+	options := s.options
+	s.mu.RUnlock()
+
+	if options.StatusCode != 0 && options.StatusCode != http.StatusOK {
+		w.Header().Add("Error-Description", "status code forced by fake mise")
+		w.WriteHeader(options.StatusCode)
+		return
+	}
+
+	claims, err := s.validate(r.Header.Get("Authorization"), options)
+	if err != nil {
+		w.Header().Add("Error-Description", err.Error())
+		w.Header().Add("WWW-Authenticate", `Bearer error="invalid_token"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	for name, values := range options.SubjectClaims {
+		claims[name] = values
+	}
+
+	for name, values := range claims {
+		if !claimRequested(r.Header, name) {
+			continue
+		}
+		for _, value := range values {
+			if isPlainHeaderValue(value) {
+				w.Header().Add(subjectClaimPrefix+name, value)
+			} else {
+				w.Header().Add(encodedSubjectClaimPrefix+name, base64.StdEncoding.EncodeToString([]byte(value)))
+			}
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) serveToken(w http.ResponseWriter, r *http.Request) {
+	claims := jwt.MapClaims{}
+	if err := json.NewDecoder(r.Body).Decode(&claims); err != nil {
+		http.Error(w, "invalid claims: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	token, err := s.SignToken(claims)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"access_token": token})
+}
+
+func (s *Server) validate(authHeader string, options Options) (map[string][]string, error) {
+	tokenString, found := strings.CutPrefix(authHeader, "Bearer ")
+	if !found || tokenString == "" {
+		return nil, errors.New("no bearer token")
+	}
+
+	parserOptions := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		jwt.WithExpirationRequired(),
+	}
+	if options.Audience != "" {
+		parserOptions = append(parserOptions, jwt.WithAudience(options.Audience))
+	}
+	if options.Issuer != "" {
+		parserOptions = append(parserOptions, jwt.WithIssuer(options.Issuer))
+	}
+
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return &s.key.PublicKey, nil
+	}, parserOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	mapClaims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("invalid claims")
+	}
+
+	claims := map[string][]string{}
+	for name, value := range mapClaims {
+		switch v := value.(type) {
+		case string:
+			claims[name] = []string{v}
+		case []interface{}:
+			for _, item := range v {
+				claims[name] = append(claims[name], fmt.Sprint(item))
+			}
+		case float64:
+			claims[name] = []string{fmt.Sprintf("%.0f", v)}
+		default:
+			claims[name] = []string{fmt.Sprint(v)}
+		}
+	}
+
+	return claims, nil
+}
+
+func claimRequested(header http.Header, claim string) bool {
+	if header.Get(returnAllSubjectClaimsHeader) != "" {
+		return true
+	}
+	return header.Get(returnSubjectClaimPrefix+claim) != ""
+}
+
+// isPlainHeaderValue reports whether value can be sent as is; MISE base64
+// encodes claims that aren't printable ASCII.
+func isPlainHeaderValue(value string) bool {
+	for _, r := range value {
+		if r > unicode.MaxASCII || !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -4,10 +4,10 @@ import (
 	"net/http"
 	"strings"
 
-	"actlabs-hub/internal/auth"
 	"actlabs-hub/internal/config"
 	"actlabs-hub/internal/entity"
 	"actlabs-hub/internal/logger"
+	"actlabs-hub/internal/middleware"
 
 	"github.com/gin-gonic/gin"
 )
@@ -57,13 +57,19 @@ func (a *assignmentHandler) GetAllAssignments(c *gin.Context) {
 		"endpoint", "GET /assignment",
 	)
 
-	assignments, err := a.assignmentService.GetAllAssignments(c.Request.Context())
+	query, paged, err := listQuery[entity.Assignment](c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 
-	c.IndentedJSON(http.StatusOK, assignments)
+	page, err := a.assignmentService.ListAssignments(c.Request.Context(), query)
+	if err != nil {
+		middleware.AbortWithError(c, err)
+		return
+	}
+
+	respondWithList(c, query, paged, page)
 }
 
 func (a *assignmentHandler) GetAllLabsRedacted(c *gin.Context) {
@@ -72,15 +78,11 @@ func (a *assignmentHandler) GetAllLabsRedacted(c *gin.Context) {
 		"endpoint", "GET /assignment/labs",
 	)
 
-	// Get the auth token from the request header
-	authToken := c.GetHeader("Authorization")
-	// Remove Bearer from the authToken
-	authToken = strings.Split(authToken, "Bearer ")[1]
-	userId, _ := auth.GetUserPrincipalFromToken(c.Request.Context(), authToken)
+	userId := callingUserPrincipal(c)
 
 	labs, err := a.assignmentService.GetAllLabsRedacted(c.Request.Context(), userId)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 	c.IndentedJSON(http.StatusOK, labs)
@@ -88,17 +90,11 @@ func (a *assignmentHandler) GetAllLabsRedacted(c *gin.Context) {
 
 func (a *assignmentHandler) GetMyAssignedLabsRedacted(c *gin.Context) {
 
-	// Get the auth token from the request header
-	authToken := c.GetHeader("Authorization")
-
-	// Remove Bearer from the authToken
-	authToken = strings.Split(authToken, "Bearer ")[1]
-	//Get the user principal from the auth token
-	userId, _ := auth.GetUserPrincipalFromToken(c.Request.Context(), authToken)
+	userId := callingUserPrincipal(c)
 
 	labs, err := a.assignmentService.GetAssignedLabsRedactedByUserId(c.Request.Context(), userId)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 
@@ -108,18 +104,12 @@ func (a *assignmentHandler) GetMyAssignedLabsRedacted(c *gin.Context) {
 func (a *assignmentHandler) GetAssignedLabsRedactedByUserId(c *gin.Context) {
 	userId := c.Param("userId")
 	if userId == "my" {
-		// Get the auth token from the request header
-		authToken := c.GetHeader("Authorization")
-
-		// Remove Bearer from the authToken
-		authToken = strings.Split(authToken, "Bearer ")[1]
-		//Get the user principal from the auth token
-		userId, _ = auth.GetUserPrincipalFromToken(c.Request.Context(), authToken)
+		userId = callingUserPrincipal(c)
 	}
 
 	labs, err := a.assignmentService.GetAssignedLabsRedactedByUserId(c.Request.Context(), userId)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 
@@ -130,7 +120,7 @@ func (a *assignmentHandler) GetAssignmentsByLabId(c *gin.Context) {
 	labId := c.Param("labId")
 	assignments, err := a.assignmentService.GetAssignmentsByLabId(c.Request.Context(), labId)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 
@@ -141,7 +131,7 @@ func (a *assignmentHandler) GetAssignmentsByUserId(c *gin.Context) {
 	userId := c.Param("userId")
 	assignments, err := a.assignmentService.GetAssignmentsByUserId(c.Request.Context(), userId)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 
@@ -149,17 +139,11 @@ func (a *assignmentHandler) GetAssignmentsByUserId(c *gin.Context) {
 }
 
 func (a *assignmentHandler) GetMyAssignments(c *gin.Context) {
-	// Get the auth token from the request header
-	authToken := c.GetHeader("Authorization")
-
-	// Remove Bearer from the authToken
-	authToken = strings.Split(authToken, "Bearer ")[1]
-	//Get the user principal from the auth token
-	userPrincipal, _ := auth.GetUserPrincipalFromToken(c.Request.Context(), authToken)
+	userPrincipal := callingUserPrincipal(c)
 
 	assignments, err := a.assignmentService.GetAssignmentsByUserId(c.Request.Context(), userPrincipal)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 
@@ -172,22 +156,16 @@ func (a *assignmentHandler) CreateMyAssignments(c *gin.Context) {
 	)
 
 	bulkAssignment := entity.BulkAssignment{}
-	if err := c.Bind(&bulkAssignment); err != nil {
+	if err := c.ShouldBind(&bulkAssignment); err != nil {
 		logger.LogError(c.Request.Context(), "Invalid request payload for create my assignments",
 			"validation_error", err.Error(),
 			"endpoint", "POST /assignment/my",
 		)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, entity.NewValidationError("invalid_request_body", err.Error()))
 		return
 	}
 
-	// Get the auth token from the request header
-	authToken := c.GetHeader("Authorization")
-
-	// Remove Bearer from the authToken
-	authToken = strings.Split(authToken, "Bearer ")[1]
-	//Get the user principal from the auth token
-	userPrincipal, _ := auth.GetUserPrincipalFromToken(c.Request.Context(), authToken)
+	userPrincipal := callingUserPrincipal(c)
 
 	// Sanitizing to make sure that the user is not creating assignments for other users.
 	for _, userId := range bulkAssignment.UserIds {
@@ -198,13 +176,13 @@ func (a *assignmentHandler) CreateMyAssignments(c *gin.Context) {
 				"target_user", userId,
 				"endpoint", "POST /assignment/my",
 			)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "you can only create assignments for yourself"})
+			middleware.AbortWithError(c, entity.NewForbiddenError("assignment_forbidden", "you can only create assignments for yourself"))
 			return
 		}
 	}
 
 	if err := a.assignmentService.CreateAssignments(c.Request.Context(), bulkAssignment.UserIds, bulkAssignment.LabIds, userPrincipal); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 
@@ -217,25 +195,19 @@ func (a *assignmentHandler) CreateAssignments(c *gin.Context) {
 	)
 
 	bulkAssignment := entity.BulkAssignment{}
-	if err := c.Bind(&bulkAssignment); err != nil {
+	if err := c.ShouldBind(&bulkAssignment); err != nil {
 		logger.LogError(c.Request.Context(), "Invalid request payload for create assignments",
 			"validation_error", err.Error(),
 			"endpoint", "POST /assignment",
 		)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, entity.NewValidationError("invalid_request_body", err.Error()))
 		return
 	}
 
-	// Get the auth token from the request header
-	authToken := c.GetHeader("Authorization")
-
-	// Remove Bearer from the authToken
-	authToken = strings.Split(authToken, "Bearer ")[1]
-	//Get the user principal from the auth token
-	userPrincipal, _ := auth.GetUserPrincipalFromToken(c.Request.Context(), authToken)
+	userPrincipal := callingUserPrincipal(c)
 
 	if err := a.assignmentService.CreateAssignments(c.Request.Context(), bulkAssignment.UserIds, bulkAssignment.LabIds, userPrincipal); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 
@@ -255,7 +227,7 @@ func (a *assignmentHandler) UpdateAssignment(c *gin.Context) {
 	)
 
 	if err := a.assignmentService.UpdateAssignment(c.Request.Context(), userId, labId, status); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 
@@ -268,22 +240,16 @@ func (a *assignmentHandler) DeleteMyAssignments(c *gin.Context) {
 	)
 
 	assignments := []string{}
-	if err := c.Bind(&assignments); err != nil {
+	if err := c.ShouldBind(&assignments); err != nil {
 		logger.LogError(c.Request.Context(), "Invalid request payload for delete my assignments",
 			"validation_error", err.Error(),
 			"endpoint", "DELETE /assignment/my",
 		)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, entity.NewValidationError("invalid_request_body", err.Error()))
 		return
 	}
 
-	// Get the auth token from the request header
-	authToken := c.GetHeader("Authorization")
-
-	// Remove Bearer from the authToken
-	authToken = strings.Split(authToken, "Bearer ")[1]
-	//Get the user principal from the auth token
-	userPrincipal, _ := auth.GetUserPrincipalFromToken(c.Request.Context(), authToken)
+	userPrincipal := callingUserPrincipal(c)
 
 	// Sanitizing to make sure that the user is not deleting assignments for other users.
 	for _, assignment := range assignments {
@@ -294,13 +260,13 @@ func (a *assignmentHandler) DeleteMyAssignments(c *gin.Context) {
 				"target_assignment", assignment,
 				"endpoint", "DELETE /assignment/my",
 			)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "you can only delete your own assignments"})
+			middleware.AbortWithError(c, entity.NewForbiddenError("assignment_forbidden", "you can only delete your own assignments"))
 			return
 		}
 	}
 
 	if err := a.assignmentService.DeleteAssignments(c.Request.Context(), assignments, userPrincipal); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 
@@ -313,25 +279,19 @@ func (a *assignmentHandler) DeleteAssignments(c *gin.Context) {
 	)
 
 	assignments := []string{}
-	if err := c.Bind(&assignments); err != nil {
+	if err := c.ShouldBind(&assignments); err != nil {
 		logger.LogError(c.Request.Context(), "Invalid request payload for delete assignments",
 			"validation_error", err.Error(),
 			"endpoint", "DELETE /assignment",
 		)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, entity.NewValidationError("invalid_request_body", err.Error()))
 		return
 	}
 
-	// Get the auth token from the request header
-	authToken := c.GetHeader("Authorization")
-
-	// Remove Bearer from the authToken
-	authToken = strings.Split(authToken, "Bearer ")[1]
-	//Get the user principal from the auth token
-	userPrincipal, _ := auth.GetUserPrincipalFromToken(c.Request.Context(), authToken)
+	userPrincipal := callingUserPrincipal(c)
 
 	if err := a.assignmentService.DeleteAssignments(c.Request.Context(), assignments, userPrincipal); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 
@@ -5,8 +5,8 @@ import (
 	"actlabs-hub/internal/entity"
 	"actlabs-hub/internal/helper"
 	"actlabs-hub/internal/logger"
+	"actlabs-hub/internal/middleware"
 	"net/http"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -45,18 +45,12 @@ func (h *AuthHandler) GetProfile(c *gin.Context) {
 	// My roles
 	if userPrincipal == "my" {
 
-		// Get the auth token from the request header
-		authToken := c.GetHeader("Authorization")
-
-		// Remove Bearer from the authToken
-		authToken = strings.Split(authToken, "Bearer ")[1]
-
-		userPrincipal, _ = auth.GetUserPrincipalFromToken(c.Request.Context(), authToken)
+		userPrincipal = callingUserPrincipal(c)
 	}
 
 	profile, err := h.authService.GetProfile(c.Request.Context(), userPrincipal)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, profile)
@@ -67,7 +61,7 @@ func (h *AuthHandler) GetAllProfilesRedacted(c *gin.Context) {
 
 	profiles, err := h.authService.GetAllProfilesRedacted(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, profiles)
@@ -76,12 +70,18 @@ func (h *AuthHandler) GetAllProfilesRedacted(c *gin.Context) {
 func (h *AuthHandler) GetAllProfiles(c *gin.Context) {
 	logger.LogInfo(c.Request.Context(), "get all profiles request")
 
-	profiles, err := h.authService.GetAllProfiles(c.Request.Context())
+	query, paged, err := listQuery[entity.Profile](c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, profiles)
+
+	page, err := h.authService.ListProfiles(c.Request.Context(), query)
+	if err != nil {
+		middleware.AbortWithError(c, err)
+		return
+	}
+	respondWithList(c, query, paged, page)
 }
 
 func (h *AuthHandler) AddRole(c *gin.Context) {
@@ -95,7 +95,7 @@ func (h *AuthHandler) AddRole(c *gin.Context) {
 
 	err := h.authService.AddRole(c.Request.Context(), userPrincipal, role)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 	c.Status(http.StatusOK)
@@ -106,19 +106,12 @@ func (h *AuthHandler) CreateProfile(c *gin.Context) {
 
 	profile := entity.Profile{}
 
-	// Get the auth token from the request header
-	authToken := c.GetHeader("Authorization")
-
-	// Remove Bearer from the authToken
-	authToken = strings.Split(authToken, "Bearer ")[1]
-
-	userPrincipal, err := auth.GetUserPrincipalFromToken(c.Request.Context(), authToken)
-	if err != nil {
-		logger.LogError(c.Request.Context(), "Failed to extract user principal from token",
+	userPrincipal := callingUserPrincipal(c)
+	if userPrincipal == "" {
+		logger.LogError(c.Request.Context(), "Failed to identify calling user",
 			"endpoint", "POST /profiles",
-			"error", err.Error(),
 		)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "not able to identify user"})
+		middleware.AbortWithError(c, entity.NewUnauthenticatedError("unauthenticated", "not able to identify user"))
 		return
 	}
 
@@ -130,7 +123,7 @@ func (h *AuthHandler) CreateProfile(c *gin.Context) {
 			"endpoint", "POST /profiles",
 			"user_principal", userPrincipal,
 		)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, entity.NewValidationError("invalid_request_body", err.Error()))
 		return
 	}
 
@@ -143,7 +136,7 @@ func (h *AuthHandler) CreateProfile(c *gin.Context) {
 			"endpoint", "POST /profiles",
 		)
 
-		c.JSON(http.StatusBadRequest, gin.H{"error": "userPrincipal in the request body does not match the calling user"})
+		middleware.AbortWithError(c, entity.NewForbiddenError("profile_forbidden", "userPrincipal in the request body does not match the calling user"))
 		return
 	}
 
@@ -152,9 +145,9 @@ func (h *AuthHandler) CreateProfile(c *gin.Context) {
 		profile.Roles = append(profile.Roles, role)
 	}
 
-	err = h.authService.CreateProfile(c.Request.Context(), profile)
+	err := h.authService.CreateProfile(c.Request.Context(), profile)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 	c.Status(http.StatusOK)
@@ -171,8 +164,16 @@ func (h *AuthHandler) DeleteRole(c *gin.Context) {
 
 	err := h.authService.DeleteRole(c.Request.Context(), userPrincipal, role)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 	c.Status(http.StatusOK)
 }
+
+// callingUserPrincipal returns the user ID of the authenticated caller, which
+// the auth middleware puts in the request context, or "" when there's none.
+// Handlers on API key routes take the user from a parameter instead.
+func callingUserPrincipal(c *gin.Context) string {
+	principal, _ := auth.GetPrincipal(c.Request.Context())
+	return principal.UserId
+}
@@ -1,12 +1,11 @@
 package handler
 
 import (
-	"actlabs-hub/internal/auth"
 	"actlabs-hub/internal/config"
 	"actlabs-hub/internal/entity"
 	"actlabs-hub/internal/logger"
+	"actlabs-hub/internal/middleware"
 	"net/http"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -47,20 +46,14 @@ func (ch *challengeHandler) GetAllLabsRedacted(c *gin.Context) {
 
 	labs, err := ch.challengeService.GetAllLabsRedacted(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 	c.IndentedJSON(http.StatusOK, labs)
 }
 
 func (ch *challengeHandler) GetMyChallengeLabsRedacted(c *gin.Context) {
-	// Get the auth token from the request header
-	authToken := c.GetHeader("Authorization")
-
-	// Remove Bearer from the authToken
-	authToken = strings.Split(authToken, "Bearer ")[1]
-	//Get the user principal from the auth token
-	userId, _ := auth.GetUserPrincipalFromToken(c.Request.Context(), authToken)
+	userId := callingUserPrincipal(c)
 
 	logger.LogInfo(c.Request.Context(), "get my challenge labs redacted request",
 		"user_id", userId,
@@ -68,7 +61,7 @@ func (ch *challengeHandler) GetMyChallengeLabsRedacted(c *gin.Context) {
 
 	labs, err := ch.challengeService.GetChallengesLabsRedactedByUserId(c.Request.Context(), userId)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 	c.IndentedJSON(http.StatusOK, labs)
@@ -77,22 +70,22 @@ func (ch *challengeHandler) GetMyChallengeLabsRedacted(c *gin.Context) {
 func (ch *challengeHandler) GetAllChallenges(c *gin.Context) {
 	logger.LogInfo(c.Request.Context(), "get all challenges request")
 
-	challenges, err := ch.challengeService.GetAllChallenges(c.Request.Context())
+	query, paged, err := listQuery[entity.Challenge](c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
-	c.IndentedJSON(http.StatusOK, challenges)
+
+	page, err := ch.challengeService.ListChallenges(c.Request.Context(), query)
+	if err != nil {
+		middleware.AbortWithError(c, err)
+		return
+	}
+	respondWithList(c, query, paged, page)
 }
 
 func (ch *challengeHandler) GetMyChallenges(c *gin.Context) {
-	// Get the auth token from the request header
-	authToken := c.GetHeader("Authorization")
-
-	// Remove Bearer from the authToken
-	authToken = strings.Split(authToken, "Bearer ")[1]
-	//Get the user principal from the auth token
-	userId, _ := auth.GetUserPrincipalFromToken(c.Request.Context(), authToken)
+	userId := callingUserPrincipal(c)
 
 	logger.LogInfo(c.Request.Context(), "get my challenges request",
 		"user_id", userId,
@@ -100,7 +93,7 @@ func (ch *challengeHandler) GetMyChallenges(c *gin.Context) {
 
 	challenges, err := ch.challengeService.GetChallengesByUserId(c.Request.Context(), userId)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 
@@ -116,7 +109,7 @@ func (ch *challengeHandler) GetChallengesByLabId(c *gin.Context) {
 
 	challenges, err := ch.challengeService.GetChallengesByLabId(c.Request.Context(), labId)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 
@@ -127,13 +120,13 @@ func (ch *challengeHandler) UpsertChallenges(c *gin.Context) {
 	logger.LogInfo(c.Request.Context(), "upsert challenges request")
 
 	challenges := []entity.Challenge{}
-	if err := c.BindJSON(&challenges); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := c.ShouldBindJSON(&challenges); err != nil {
+		middleware.AbortWithError(c, entity.NewValidationError("invalid_request_body", err.Error()))
 		return
 	}
 
 	if err := ch.challengeService.UpsertChallenges(c.Request.Context(), challenges); err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to create/update one or more challenges"})
+		middleware.AbortWithError(c, err)
 		return
 	}
 
@@ -152,7 +145,7 @@ func (ch *challengeHandler) UpdateChallenge(c *gin.Context) {
 	)
 
 	if err := ch.challengeService.UpdateChallenge(c.Request.Context(), userId, labId, status); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 
@@ -169,7 +162,7 @@ func (ch *challengeHandler) DeleteChallenge(c *gin.Context) {
 	challengeIds := []string{challengeId}
 
 	if err := ch.challengeService.DeleteChallenges(c.Request.Context(), challengeIds); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 
@@ -6,13 +6,14 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"actlabs-hub/internal/auth"
 	"actlabs-hub/internal/config"
 	"actlabs-hub/internal/entity"
+	"actlabs-hub/internal/middleware"
 
 	"github.com/gin-gonic/gin"
 )
@@ -23,7 +24,10 @@ type mockChallengeService struct {
 	labs       []entity.LabType
 	challenges []entity.Challenge
 	err        error
+	nextToken  string
 	// Track calls for verification
+	lastListQuery  entity.ListQuery
+	lastUserId     string
 	lastDeletedIds []string
 	lastUpserted   []entity.Challenge
 	lastUpdateArgs struct {
@@ -42,6 +46,10 @@ func (m *mockChallengeService) GetChallengesLabsRedactedByUserId(ctx context.Con
 func (m *mockChallengeService) GetAllChallenges(ctx context.Context) ([]entity.Challenge, error) {
 	return m.challenges, m.err
 }
+func (m *mockChallengeService) ListChallenges(ctx context.Context, query entity.ListQuery) (entity.Page[entity.Challenge], error) {
+	m.lastListQuery = query
+	return entity.Page[entity.Challenge]{Items: m.challenges, NextToken: m.nextToken}, m.err
+}
 func (m *mockChallengeService) GetChallengeByUserIdAndLabId(ctx context.Context, userId string, labId string) (entity.Challenge, error) {
 	if len(m.challenges) > 0 {
 		return m.challenges[0], m.err
@@ -52,6 +60,7 @@ func (m *mockChallengeService) GetChallengesByLabId(ctx context.Context, labId s
 	return m.challenges, m.err
 }
 func (m *mockChallengeService) GetChallengesByUserId(ctx context.Context, userId string) ([]entity.Challenge, error) {
+	m.lastUserId = userId
 	return m.challenges, m.err
 }
 func (m *mockChallengeService) UpsertChallenges(ctx context.Context, challenges []entity.Challenge) error {
@@ -74,19 +83,16 @@ func (m *mockChallengeService) DeleteChallenges(ctx context.Context, challengeId
 
 // --- Helpers ---
 
-// makeFakeJWT builds a fake JWT whose payload contains the given claims.
-// The header and signature are dummy values; only the base64-encoded payload matters
-// because auth.GetUserPrincipalFromToken does not verify signatures.
-func makeFakeJWT(claims map[string]interface{}) string {
-	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
-	payload, _ := json.Marshal(claims)
-	payloadB64 := base64.RawURLEncoding.EncodeToString(payload)
-	return fmt.Sprintf("%s.%s.fakesig", header, payloadB64)
+// withPrincipal returns req as the auth middleware would pass it on for the
+// authenticated userId.
+func withPrincipal(req *http.Request, userId string) *http.Request {
+	return req.WithContext(auth.WithPrincipal(req.Context(), entity.Principal{UserId: userId}))
 }
 
 func setupChallengeRouter(svc entity.ChallengeService) *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
+	router.Use(middleware.ErrorHandler())
 	appConfig := &config.Config{}
 	NewChallengeHandler(router.Group("/"), svc, appConfig)
 	NewChallengeAPIKeyHandler(router.Group("/"), svc, appConfig)
@@ -178,6 +184,87 @@ func TestGetAllChallenges_Success(t *testing.T) {
 	}
 }
 
+func TestGetAllChallenges_Paged(t *testing.T) {
+	svc := &mockChallengeService{
+		challenges: []entity.Challenge{
+			{ChallengeId: "user1@microsoft.com+lab1", UserId: "user1@microsoft.com", LabId: "lab1", Status: "challenged"},
+			{ChallengeId: "user2@microsoft.com+lab2", UserId: "user2@microsoft.com", LabId: "lab2", Status: "accepted"},
+		},
+		nextToken: "next",
+	}
+	router := setupChallengeRouter(svc)
+
+	req, _ := http.NewRequest("GET", "/challenge?limit=2&nextToken=this&fields=userId,status", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	query := svc.lastListQuery
+	if query.Limit != 2 || query.NextToken != "this" || len(query.Fields) != 2 {
+		t.Errorf("unexpected query %+v", query)
+	}
+
+	var page entity.Page[map[string]string]
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+	if page.NextToken != "next" || len(page.Items) != 2 {
+		t.Fatalf("unexpected page %+v", page)
+	}
+	if page.Items[0]["userId"] != "user1@microsoft.com" || len(page.Items[0]) != 2 {
+		t.Errorf("expected user1 first with only userId and status, got %v", page.Items[0])
+	}
+}
+
+func TestGetAllChallenges_Sorted(t *testing.T) {
+	svc := &mockChallengeService{
+		challenges: []entity.Challenge{
+			{ChallengeId: "user1@microsoft.com+lab1", UserId: "user1@microsoft.com", LabId: "lab1", Status: "challenged"},
+			{ChallengeId: "user2@microsoft.com+lab2", UserId: "user2@microsoft.com", LabId: "lab2", Status: "accepted"},
+		},
+	}
+	router := setupChallengeRouter(svc)
+
+	req, _ := http.NewRequest("GET", "/challenge?sort=-userId", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// The whole list is sorted, so it's read in a single page.
+	query := svc.lastListQuery
+	if query.Limit != 0 || len(query.Sort) != 1 || !query.Sort[0].Descending {
+		t.Errorf("unexpected query %+v", query)
+	}
+
+	var page entity.Page[entity.Challenge]
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+	if len(page.Items) != 2 || page.Items[0].UserId != "user2@microsoft.com" {
+		t.Errorf("expected user2 first, got %+v", page.Items)
+	}
+}
+
+func TestGetAllChallenges_InvalidListQuery(t *testing.T) {
+	router := setupChallengeRouter(&mockChallengeService{})
+
+	for _, query := range []string{"limit=0", "sort=nope", "fields=nope", "sort=userId&limit=2", "sort=userId&nextToken=next"} {
+		req, _ := http.NewRequest("GET", "/challenge?"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, w.Code)
+		}
+	}
+}
+
 func TestGetAllChallenges_ServiceError(t *testing.T) {
 	svc := &mockChallengeService{err: errors.New("db error")}
 	router := setupChallengeRouter(svc)
@@ -241,9 +328,8 @@ func TestGetMyChallenges_Success(t *testing.T) {
 	}
 	router := setupChallengeRouter(svc)
 
-	token := makeFakeJWT(map[string]interface{}{"upn": "testuser@microsoft.com"})
 	req, _ := http.NewRequest("GET", "/challenge/my", nil)
-	req.Header.Set("Authorization", "Bearer "+token)
+	req = withPrincipal(req, "testuser@microsoft.com")
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
@@ -260,13 +346,30 @@ func TestGetMyChallenges_Success(t *testing.T) {
 	}
 }
 
+func TestGetMyChallenges_UnverifiedTokenIsNotTheCaller(t *testing.T) {
+	svc := &mockChallengeService{lastUserId: "not called"}
+	router := setupChallengeRouter(svc)
+
+	// Without the auth middleware's principal the token's claims aren't
+	// trusted.
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"upn":"someone@microsoft.com"}`))
+	req, _ := http.NewRequest("GET", "/challenge/my", nil)
+	req.Header.Set("Authorization", "Bearer "+header+"."+payload+".fakesig")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if svc.lastUserId != "" {
+		t.Errorf("expected no calling user, got %q", svc.lastUserId)
+	}
+}
+
 func TestGetMyChallenges_ServiceError(t *testing.T) {
 	svc := &mockChallengeService{err: errors.New("db error")}
 	router := setupChallengeRouter(svc)
 
-	token := makeFakeJWT(map[string]interface{}{"upn": "testuser@microsoft.com"})
 	req, _ := http.NewRequest("GET", "/challenge/my", nil)
-	req.Header.Set("Authorization", "Bearer "+token)
+	req = withPrincipal(req, "testuser@microsoft.com")
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
@@ -285,9 +388,8 @@ func TestGetMyChallengeLabsRedacted_Success(t *testing.T) {
 	}
 	router := setupChallengeRouter(svc)
 
-	token := makeFakeJWT(map[string]interface{}{"upn": "testuser@microsoft.com"})
 	req, _ := http.NewRequest("GET", "/challenge/labs/my", nil)
-	req.Header.Set("Authorization", "Bearer "+token)
+	req = withPrincipal(req, "testuser@microsoft.com")
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
@@ -308,9 +410,8 @@ func TestGetMyChallengeLabsRedacted_ServiceError(t *testing.T) {
 	svc := &mockChallengeService{err: errors.New("service error")}
 	router := setupChallengeRouter(svc)
 
-	token := makeFakeJWT(map[string]interface{}{"upn": "testuser@microsoft.com"})
 	req, _ := http.NewRequest("GET", "/challenge/labs/my", nil)
-	req.Header.Set("Authorization", "Bearer "+token)
+	req = withPrincipal(req, "testuser@microsoft.com")
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
@@ -2,9 +2,12 @@ package handler
 
 import (
 	"net/http"
+	"strconv"
+	"time"
 
 	"actlabs-hub/internal/entity"
 	"actlabs-hub/internal/logger"
+	"actlabs-hub/internal/middleware"
 
 	"github.com/gin-gonic/gin"
 )
@@ -21,6 +24,26 @@ func NewDeploymentHandler(r *gin.RouterGroup, service entity.DeploymentService)
 	r.GET("/deployments", handler.GetUserDeployments)
 	r.PUT("/deployments", handler.UpsertDeployment)
 	r.DELETE("/deployments/:subscriptionId/:workspace", handler.DeleteDeployment)
+	r.GET("/deployments/:subscriptionId/:workspace/history", handler.GetDeploymentHistory)
+}
+
+// NewDeploymentUserHandler registers the routes users call directly, with
+// their own token rather than through the actlabs server.
+func NewDeploymentUserHandler(r *gin.RouterGroup, service entity.DeploymentService) {
+	handler := &deploymentHandler{
+		deploymentService: service,
+	}
+
+	r.POST("/deployments/:subscriptionId/:workspace/extend", handler.ExtendDeployment)
+}
+
+func NewAdminDeploymentHandler(r *gin.RouterGroup, service entity.DeploymentService) {
+	handler := &deploymentHandler{
+		deploymentService: service,
+	}
+
+	r.GET("/admin/deployments/:userId/:subscriptionId/:workspace/history", handler.AdminGetDeploymentHistory)
+	r.GET("/admin/deployments/stuck", handler.GetStuckDeployments)
 }
 
 func (d *deploymentHandler) GetUserDeployments(c *gin.Context) {
@@ -30,7 +53,7 @@ func (d *deploymentHandler) GetUserDeployments(c *gin.Context) {
 
 	deployments, err := d.deploymentService.GetUserDeployments(c.Request.Context(), userPrincipal)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 
@@ -41,18 +64,18 @@ func (d *deploymentHandler) UpsertDeployment(c *gin.Context) {
 	logger.LogInfo(c.Request.Context(), "upserting deployment")
 
 	deployment := entity.Deployment{}
-	if err := c.Bind(&deployment); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := c.ShouldBind(&deployment); err != nil {
+		middleware.AbortWithError(c, entity.NewValidationError("invalid_request_body", err.Error()))
 		return
 	}
 
 	userPrincipal := c.GetHeader("x-user-id")
 
-	deployment.DeploymentId = userPrincipal + "-" + deployment.DeploymentWorkspace + "-" + deployment.DeploymentSubscriptionId
 	deployment.DeploymentUserId = userPrincipal
+	deployment.DeploymentId = entity.NewDeploymentKey(deployment).RowKey()
 
 	if err := d.deploymentService.UpsertDeployment(c.Request.Context(), deployment); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 
@@ -67,9 +90,101 @@ func (d *deploymentHandler) DeleteDeployment(c *gin.Context) {
 
 	userPrincipal := c.GetHeader("x-user-id")
 
-	if err := d.deploymentService.DeleteDeployment(c.Request.Context(), userPrincipal, workspace, subscriptionId); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	key := entity.DeploymentKey{
+		UserId:         userPrincipal,
+		SubscriptionId: subscriptionId,
+		Workspace:      workspace,
+	}
+
+	if err := d.deploymentService.DeleteDeployment(c.Request.Context(), key); err != nil {
+		middleware.AbortWithError(c, err)
+		return
 	}
 
 	c.Status(http.StatusNoContent)
 }
+
+func (d *deploymentHandler) GetDeploymentHistory(c *gin.Context) {
+	logger.LogInfo(c.Request.Context(), "getting deployment history")
+
+	d.deploymentHistory(c, c.GetHeader("x-user-id"))
+}
+
+func (d *deploymentHandler) AdminGetDeploymentHistory(c *gin.Context) {
+	logger.LogInfo(c.Request.Context(), "getting deployment history for admin")
+
+	d.deploymentHistory(c, c.Param("userId"))
+}
+
+// deploymentHistory returns a page of the deployment's timeline. Page size is
+// set with limit (default 100, at most 1000) and the next page is requested
+// with the nextToken from the previous response.
+func (d *deploymentHandler) deploymentHistory(c *gin.Context, userPrincipal string) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		middleware.AbortWithError(c, entity.NewValidationError("invalid_limit", "limit must be between 1 and 1000"))
+		return
+	}
+
+	history, err := d.deploymentService.GetDeploymentHistory(
+		c.Request.Context(),
+		userPrincipal,
+		c.Param("subscriptionId"),
+		c.Param("workspace"),
+		int32(limit),
+		c.Query("nextToken"),
+	)
+	if err != nil {
+		middleware.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+func (d *deploymentHandler) ExtendDeployment(c *gin.Context) {
+	logger.LogInfo(c.Request.Context(), "extending deployment")
+
+	request := entity.ExtendDeploymentRequest{}
+	if err := c.ShouldBind(&request); err != nil {
+		middleware.AbortWithError(c, entity.NewValidationError("invalid_request_body", err.Error()))
+		return
+	}
+
+	if request.DurationSeconds <= 0 {
+		middleware.AbortWithError(c, entity.NewValidationError("invalid_duration", "durationSeconds must be positive"))
+		return
+	}
+
+	userPrincipal := callingUserPrincipal(c)
+	if userPrincipal == "" {
+		middleware.AbortWithError(c, entity.NewUnauthenticatedError("unauthenticated", "unauthorized"))
+		return
+	}
+
+	deployment, err := d.deploymentService.ExtendDeployment(
+		c.Request.Context(),
+		userPrincipal,
+		c.Param("subscriptionId"),
+		c.Param("workspace"),
+		time.Duration(request.DurationSeconds)*time.Second,
+	)
+	if err != nil {
+		middleware.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, deployment)
+}
+
+func (d *deploymentHandler) GetStuckDeployments(c *gin.Context) {
+	logger.LogInfo(c.Request.Context(), "getting stuck deployments")
+
+	stuckDeployments, err := d.deploymentService.GetStuckDeployments(c.Request.Context())
+	if err != nil {
+		middleware.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stuckDeployments)
+}
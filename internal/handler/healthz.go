@@ -3,6 +3,8 @@ package handler
 import (
 	"net/http"
 
+	"actlabs-hub/internal/health"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -17,3 +19,38 @@ func NewHealthzHandler(r *gin.RouterGroup) {
 func (h *healthzHandler) Healthz(c *gin.Context) {
 	c.Status(http.StatusOK)
 }
+
+type probeHandler struct {
+	readiness *health.Checker
+	liveness  *health.Checker
+}
+
+// NewProbeHandler serves /readyz, which checks the hub's dependencies and
+// background loops, and /livez, which only fails for loops stuck far longer
+// than readiness tolerates, so neither a dependency outage nor a slow loop
+// restarts the hub.
+func NewProbeHandler(r *gin.RouterGroup, readiness *health.Checker, liveness *health.Checker) {
+	handler := &probeHandler{
+		readiness: readiness,
+		liveness:  liveness,
+	}
+
+	r.GET("/readyz", handler.Readyz)
+	r.GET("/livez", handler.Livez)
+}
+
+func (h *probeHandler) Readyz(c *gin.Context) {
+	respondWithReport(c, h.readiness.Run(c.Request.Context()))
+}
+
+func (h *probeHandler) Livez(c *gin.Context) {
+	respondWithReport(c, h.liveness.Run(c.Request.Context()))
+}
+
+func respondWithReport(c *gin.Context, report health.Report) {
+	if report.Status != health.StatusUp {
+		c.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
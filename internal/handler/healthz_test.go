@@ -1,9 +1,15 @@
 package handler
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
+
+	"actlabs-hub/internal/health"
 
 	"github.com/gin-gonic/gin"
 )
@@ -30,3 +36,38 @@ func TestHealthzHandler_Healthz(t *testing.T) {
 		t.Errorf("Expected response code to be 200, got %d", w.Code)
 	}
 }
+
+func TestProbeHandler_ReadyzReportsFailingCheck(t *testing.T) {
+	router := gin.Default()
+
+	readiness := health.NewChecker(time.Second, 0,
+		health.Check{Name: "redis", Check: func(ctx context.Context) error { return errors.New("connection refused") }},
+		health.Check{Name: "table:servers", Check: func(ctx context.Context) error { return nil }},
+	)
+	liveness := health.NewChecker(time.Second, 0)
+
+	NewProbeHandler(&router.RouterGroup, readiness, liveness)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected response code to be 503, got %d", w.Code)
+	}
+
+	report := health.Report{}
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Checks["redis"].Status != health.StatusDown || report.Checks["table:servers"].Status != health.StatusUp {
+		t.Errorf("Expected only redis to be down, got %+v", report.Checks)
+	}
+
+	// Liveness doesn't depend on redis.
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/livez", nil))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected response code to be 200, got %d", w.Code)
+	}
+}
@@ -2,38 +2,42 @@ package handler
 
 import (
 	"encoding/json"
-	"fmt"
 	"io"
+	"mime"
 	"net/http"
-	"strings"
 
-	"actlabs-hub/internal/auth"
 	"actlabs-hub/internal/config"
 	"actlabs-hub/internal/entity"
+	"actlabs-hub/internal/helper"
+	"actlabs-hub/internal/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
 type labHandler struct {
-	labService entity.LabService
-	appConfig  *config.Config
+	labService  entity.LabService
+	costService entity.CostService
+	appConfig   *config.Config
 }
 
 // Authenticated user.
-func NewLabHandler(r *gin.RouterGroup, labService entity.LabService, appConfig *config.Config) {
+func NewLabHandler(r *gin.RouterGroup, labService entity.LabService, costService entity.CostService, appConfig *config.Config) {
 	handler := &labHandler{
-		labService: labService,
-		appConfig:  appConfig,
+		labService:  labService,
+		costService: costService,
+		appConfig:   appConfig,
 	}
 	// all private lab operations.
 	r.GET("/lab/private/:typeOfLab", handler.GetLabs)
 	r.POST("/lab/private", handler.UpsertLab)
 	r.DELETE("/lab/private/:typeOfLab/:labId", handler.DeleteLab)
 	r.GET("/lab/private/versions/:typeOfLab/:labId", handler.GetLabVersions)
+	r.GET("/lab/private/:typeOfLab/:labId/cost", handler.GetPrivateLabCost)
 
 	// public lab read-only operations.
 	r.GET("/lab/public/:typeOfLab", handler.GetLabs)
 	r.GET("/lab/public/versions/:typeOfLab/:labId", handler.GetLabVersions)
+	r.GET("/lab/public/:typeOfLab/:labId/cost", handler.GetPublicLabCost)
 }
 
 // Authenticated with ARM token and ProtectedLabSecret.
@@ -59,9 +63,10 @@ func NewLabHandlerContributorRequired(r *gin.RouterGroup, labService entity.LabS
 }
 
 // Authenticated user with 'mentor' role.
-func NewLabHandlerMentorRequired(r *gin.RouterGroup, labService entity.LabService) {
+func NewLabHandlerMentorRequired(r *gin.RouterGroup, labService entity.LabService, costService entity.CostService) {
 	handler := &labHandler{
-		labService: labService,
+		labService:  labService,
+		costService: costService,
 	}
 
 	// all protected lab operations.
@@ -70,11 +75,13 @@ func NewLabHandlerMentorRequired(r *gin.RouterGroup, labService entity.LabServic
 	r.GET("/lab/protected/:typeOfLab", handler.GetLabs)
 	r.GET("/lab/protected/versions/:typeOfLab/:labId", handler.GetLabVersions)
 	r.DELETE("/lab/protected/:typeOfLab/:labId", handler.DeleteLab)
+	r.GET("/lab/protected/:typeOfLab/:labId/cost", handler.GetProtectedLabCost)
 
 	// supporting documents testing only
 	r.POST("/lab/protected/supportingDocument", handler.UpsertSupportingDocument)
 	r.DELETE("/lab/protected/supportingDocument/:supportingDocumentId", handler.DeleteSupportingDocument)
 	r.GET("/lab/protected/supportingDocument/:supportingDocumentId", handler.GetSupportingDocument)
+	r.GET("/lab/protected/supportingDocument/:supportingDocumentId/metadata", handler.GetSupportingDocumentMetadata)
 }
 
 func (l *labHandler) GetLabWithAPIKey(c *gin.Context) {
@@ -92,12 +99,12 @@ func (l *labHandler) GetLabWithAPIKey(c *gin.Context) {
 	case validateLabType(typeOfLab, entity.PrivateLab):
 		lab, err = l.labService.GetPrivateLab(c.Request.Context(), typeOfLab, labId)
 	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lab type: " + typeOfLab})
+		middleware.AbortWithError(c, invalidLabType(typeOfLab))
 		return
 	}
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 
@@ -111,11 +118,7 @@ func (l *labHandler) GetLab(c *gin.Context) {
 	var lab entity.LabType
 	var err error
 
-	// Get the auth token from the request header
-	authToken := c.GetHeader("Authorization")
-	// Remove Bearer from the authToken
-	authToken = strings.Split(authToken, "Bearer ")[1]
-	userId, _ := auth.GetUserPrincipalFromToken(c.Request.Context(), authToken)
+	userId := callingUserPrincipal(c)
 
 	switch {
 	case validateLabType(typeOfLab, entity.ProtectedLabs):
@@ -123,12 +126,12 @@ func (l *labHandler) GetLab(c *gin.Context) {
 	case validateLabType(typeOfLab, entity.PrivateLab):
 		lab, err = l.labService.GetPrivateLab(c.Request.Context(), typeOfLab, labId)
 	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lab type: " + typeOfLab})
+		middleware.AbortWithError(c, invalidLabType(typeOfLab))
 		return
 	}
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 
@@ -137,46 +140,31 @@ func (l *labHandler) GetLab(c *gin.Context) {
 
 func (l *labHandler) GetLabs(c *gin.Context) {
 	typeOfLab := c.Param("typeOfLab")
+	if !validateLabType(typeOfLab, entity.PrivateLab) && !validateLabType(typeOfLab, entity.PublicLab) && !validateLabType(typeOfLab, entity.ProtectedLabs) {
+		middleware.AbortWithError(c, invalidLabType(typeOfLab))
+		return
+	}
 
-	var labs []entity.LabType
-	var err error
-
-	// Get the auth token from the request header
-	authToken := c.GetHeader("Authorization")
-	// Remove Bearer from the authToken
-	authToken = strings.Split(authToken, "Bearer ")[1]
-	userId, _ := auth.GetUserPrincipalFromToken(c.Request.Context(), authToken)
-
-	switch {
-	case validateLabType(typeOfLab, entity.PrivateLab):
-		// Get the auth token from the request header
-		authToken := c.GetHeader("Authorization")
-		// Remove Bearer from the authToken
-		authToken = strings.Split(authToken, "Bearer ")[1]
-		userId, _ := auth.GetUserPrincipalFromToken(c.Request.Context(), authToken)
-		labs, err = l.labService.GetPrivateLabs(c.Request.Context(), typeOfLab, userId)
-	case validateLabType(typeOfLab, entity.PublicLab):
-		labs, err = l.labService.GetPublicLabs(c.Request.Context(), typeOfLab)
-	case validateLabType(typeOfLab, entity.ProtectedLabs):
-		labs, err = l.labService.GetProtectedLabs(c.Request.Context(), typeOfLab, userId, false)
-	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lab type: " + typeOfLab})
+	query, paged, err := listQuery[entity.LabType](c)
+	if err != nil {
+		middleware.AbortWithError(c, err)
 		return
 	}
 
+	page, err := l.labService.ListLabs(c.Request.Context(), typeOfLab, callingUserPrincipal(c), query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 
-	c.IndentedJSON(http.StatusOK, labs)
+	respondWithList(c, query, paged, page)
 }
 
 func (l *labHandler) UpsertLab(c *gin.Context) {
 	// Bind the request body to the LabType struct
 	var lab entity.LabType
-	if err := c.Bind(&lab); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+	if err := c.ShouldBind(&lab); err != nil {
+		middleware.AbortWithError(c, entity.NewValidationError("invalid_request_body", "Invalid request body: "+err.Error()))
 		return
 	}
 
@@ -188,19 +176,15 @@ func (l *labHandler) UpsertLab(c *gin.Context) {
 	case validateLabType(lab.Type, entity.PublicLab):
 		lab, upsertErr = l.labService.UpsertPublicLab(c.Request.Context(), lab)
 	case validateLabType(lab.Type, entity.ProtectedLabs):
-		// Get the auth token from the request header
-		authToken := c.GetHeader("Authorization")
-		// Remove Bearer from the authToken
-		authToken = strings.Split(authToken, "Bearer ")[1]
-		userId, _ := auth.GetUserPrincipalFromToken(c.Request.Context(), authToken)
+		userId := callingUserPrincipal(c)
 		lab, upsertErr = l.labService.UpsertProtectedLab(c.Request.Context(), lab, userId)
 	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lab type: " + lab.Type})
+		middleware.AbortWithError(c, invalidLabType(lab.Type))
 		return
 	}
 
 	if upsertErr != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": upsertErr.Error()})
+		middleware.AbortWithError(c, upsertErr)
 		return
 	}
 
@@ -210,37 +194,37 @@ func (l *labHandler) UpsertLab(c *gin.Context) {
 func (l *labHandler) UpsertLabWithSupportingDocument(c *gin.Context) {
 	// Parse the multipart form
 	if err := c.Request.ParseMultipartForm(10 << 20); err != nil { // 10 MB max memory
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse multipart form: " + err.Error()})
+		middleware.AbortWithError(c, entity.NewValidationError("invalid_request_body", "Failed to parse multipart form: "+err.Error()))
 		return
 	}
 
 	// Get the lab field
 	labField := c.Request.FormValue("lab")
 	if labField == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Lab field is required"})
+		middleware.AbortWithError(c, entity.NewValidationError("invalid_request_body", "Lab field is required"))
 		return
 	}
 
 	// Unmarshal the lab field into the LabType struct
 	lab := entity.LabType{}
 	if err := json.Unmarshal([]byte(labField), &lab); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lab field: " + err.Error()})
+		middleware.AbortWithError(c, entity.NewValidationError("invalid_request_body", "Invalid lab field: "+err.Error()))
 		return
 	}
 
 	// Get the supportingDocument field (optional)
-	supportingDocument, _, err := c.Request.FormFile("supportingDocument")
+	supportingDocument, supportingDocumentHeader, err := c.Request.FormFile("supportingDocument")
 	if err != nil && err != http.ErrMissingFile {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Error retrieving supporting document: " + err.Error()})
+		middleware.AbortWithError(c, entity.NewValidationError("invalid_supporting_document", "Error retrieving supporting document: "+err.Error()))
 		return
 	}
 
 	if supportingDocument != nil {
 		defer supportingDocument.Close()
 
-		supportingDocumentId, err := l.labService.UpsertSupportingDocument(c.Request.Context(), supportingDocument)
+		supportingDocumentId, err := l.labService.UpsertSupportingDocument(c.Request.Context(), supportingDocument, supportingDocumentHeader, callingUserPrincipal(c))
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			middleware.AbortWithError(c, err)
 			return
 		}
 
@@ -255,19 +239,15 @@ func (l *labHandler) UpsertLabWithSupportingDocument(c *gin.Context) {
 	case validateLabType(lab.Type, entity.PublicLab):
 		lab, upsertErr = l.labService.UpsertPublicLab(c.Request.Context(), lab)
 	case validateLabType(lab.Type, entity.ProtectedLabs):
-		// Get the auth token from the request header
-		authToken := c.GetHeader("Authorization")
-		// Remove Bearer from the authToken
-		authToken = strings.Split(authToken, "Bearer ")[1]
-		userId, _ := auth.GetUserPrincipalFromToken(c.Request.Context(), authToken)
+		userId := callingUserPrincipal(c)
 		lab, upsertErr = l.labService.UpsertProtectedLab(c.Request.Context(), lab, userId)
 	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lab type: " + lab.Type})
+		middleware.AbortWithError(c, invalidLabType(lab.Type))
 		return
 	}
 
 	if upsertErr != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": upsertErr.Error()})
+		middleware.AbortWithError(c, upsertErr)
 		return
 	}
 
@@ -280,11 +260,7 @@ func (l *labHandler) DeleteLab(c *gin.Context) {
 
 	var err error
 
-	// Get the auth token from the request header
-	authToken := c.GetHeader("Authorization")
-	// Remove Bearer from the authToken
-	authToken = strings.Split(authToken, "Bearer ")[1]
-	userId, _ := auth.GetUserPrincipalFromToken(c.Request.Context(), authToken)
+	userId := callingUserPrincipal(c)
 
 	switch {
 	case validateLabType(typeOfLab, entity.PrivateLab):
@@ -294,12 +270,12 @@ func (l *labHandler) DeleteLab(c *gin.Context) {
 	case validateLabType(typeOfLab, entity.ProtectedLabs):
 		err = l.labService.DeleteProtectedLab(c.Request.Context(), typeOfLab, labId)
 	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lab type: " + typeOfLab})
+		middleware.AbortWithError(c, invalidLabType(typeOfLab))
 		return
 	}
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 
@@ -315,29 +291,93 @@ func (l *labHandler) GetLabVersions(c *gin.Context) {
 
 	switch {
 	case validateLabType(typeOfLab, entity.PrivateLab):
-		// Get the auth token from the request header
-		authToken := c.GetHeader("Authorization")
-		// Remove Bearer from the authToken
-		authToken = strings.Split(authToken, "Bearer ")[1]
-		userId, _ := auth.GetUserPrincipalFromToken(c.Request.Context(), authToken)
+		userId := callingUserPrincipal(c)
 		labs, err = l.labService.GetPrivateLabVersions(c.Request.Context(), typeOfLab, labId, userId)
 	case validateLabType(typeOfLab, entity.PublicLab):
 		labs, err = l.labService.GetPublicLabVersions(c.Request.Context(), typeOfLab, labId)
 	case validateLabType(typeOfLab, entity.ProtectedLabs):
 		labs, err = l.labService.GetProtectedLabVersions(c.Request.Context(), typeOfLab, labId)
 	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lab type: " + typeOfLab})
+		middleware.AbortWithError(c, invalidLabType(typeOfLab))
 		return
 	}
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 
 	c.IndentedJSON(http.StatusOK, labs)
 }
 
+func (l *labHandler) GetPrivateLabCost(c *gin.Context) {
+	typeOfLab := c.Param("typeOfLab")
+	if !validateLabType(typeOfLab, entity.PrivateLab) {
+		middleware.AbortWithError(c, invalidLabType(typeOfLab))
+		return
+	}
+
+	lab, err := l.labService.GetPrivateLab(c.Request.Context(), typeOfLab, c.Param("labId"))
+	if err != nil {
+		middleware.AbortWithError(c, err)
+		return
+	}
+
+	userId := callingUserPrincipal(c)
+	if !helper.Contains(lab.Owners, userId) && !helper.Contains(lab.Editors, userId) && !helper.Contains(lab.Viewers, userId) {
+		middleware.AbortWithError(c, entity.NewForbiddenError("lab_access_denied", "you do not have access to this lab"))
+		return
+	}
+
+	l.labCost(c, lab)
+}
+
+func (l *labHandler) GetPublicLabCost(c *gin.Context) {
+	typeOfLab := c.Param("typeOfLab")
+	if !validateLabType(typeOfLab, entity.PublicLab) {
+		middleware.AbortWithError(c, invalidLabType(typeOfLab))
+		return
+	}
+
+	lab, err := l.labService.GetLabByIdAndType(c.Request.Context(), typeOfLab, c.Param("labId"))
+	if err != nil {
+		middleware.AbortWithError(c, err)
+		return
+	}
+
+	l.labCost(c, lab)
+}
+
+func (l *labHandler) GetProtectedLabCost(c *gin.Context) {
+	typeOfLab := c.Param("typeOfLab")
+	if !validateLabType(typeOfLab, entity.ProtectedLabs) {
+		middleware.AbortWithError(c, invalidLabType(typeOfLab))
+		return
+	}
+
+	lab, err := l.labService.GetProtectedLab(c.Request.Context(), typeOfLab, c.Param("labId"), callingUserPrincipal(c), false)
+	if err != nil {
+		middleware.AbortWithError(c, err)
+		return
+	}
+
+	l.labCost(c, lab)
+}
+
+func (l *labHandler) labCost(c *gin.Context, lab entity.LabType) {
+	estimate, err := l.costService.EstimateLabCost(c.Request.Context(), lab)
+	if err != nil {
+		middleware.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, estimate)
+}
+
+func invalidLabType(typeOfLab string) error {
+	return entity.NewValidationError("invalid_lab_type", "Invalid lab type: "+typeOfLab)
+}
+
 func validateLabType(typeOfLab string, validTypes []string) bool {
 	for _, t := range validTypes {
 		if typeOfLab == t {
@@ -351,24 +391,21 @@ func validateLabType(typeOfLab string, validTypes []string) bool {
 func (l *labHandler) UpsertSupportingDocument(c *gin.Context) {
 	// Parse the multipart form
 	if err := c.Request.ParseMultipartForm(10 << 20); err != nil { // 10 MB max memory
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse multipart form: " + err.Error()})
+		middleware.AbortWithError(c, entity.NewValidationError("invalid_request_body", "Failed to parse multipart form: "+err.Error()))
 		return
 	}
 
 	// Get the supportingDocument field
-	supportingDocument, _, err := c.Request.FormFile("supportingDocument")
+	supportingDocument, supportingDocumentHeader, err := c.Request.FormFile("supportingDocument")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Error retrieving supporting document: " + err.Error()})
+		middleware.AbortWithError(c, entity.NewValidationError("invalid_supporting_document", "Error retrieving supporting document: "+err.Error()))
 		return
 	}
 	defer supportingDocument.Close()
 
-	// Process the file as needed
-	// For example, you can save the file to disk or process it in memory
-
-	supportingDocumentId, err := l.labService.UpsertSupportingDocument(c.Request.Context(), supportingDocument)
+	supportingDocumentId, err := l.labService.UpsertSupportingDocument(c.Request.Context(), supportingDocument, supportingDocumentHeader, callingUserPrincipal(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 
@@ -380,30 +417,54 @@ func (l *labHandler) DeleteSupportingDocument(c *gin.Context) {
 
 	err := l.labService.DeleteSupportingDocument(c.Request.Context(), supportingDocumentId)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 
 	c.Status(http.StatusNoContent)
 }
 
+func (l *labHandler) GetSupportingDocumentMetadata(c *gin.Context) {
+	supportingDocumentId := c.Param("supportingDocumentId")
+
+	supportingDocument, err := l.labService.GetSupportingDocumentMetadata(c.Request.Context(), supportingDocumentId)
+	if err != nil {
+		middleware.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, supportingDocument)
+}
+
 func (l *labHandler) GetSupportingDocument(c *gin.Context) {
 	supportingDocumentId := c.Param("supportingDocumentId")
 
 	supportingDocumentReader, err := l.labService.GetSupportingDocument(c.Request.Context(), supportingDocumentId)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 
 	defer supportingDocumentReader.Close()
 
-	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.pdf\"", supportingDocumentId))
-	c.Header("Content-Type", "application/pdf")
+	// Documents uploaded before metadata was kept are all PDFs.
+	fileName := supportingDocumentId + ".pdf"
+	contentType := "application/pdf"
+	if supportingDocument, err := l.labService.GetSupportingDocumentMetadata(c.Request.Context(), supportingDocumentId); err == nil {
+		if supportingDocument.FileName != "" {
+			fileName = supportingDocument.FileName
+		}
+		if supportingDocument.ContentType != "" {
+			contentType = supportingDocument.ContentType
+		}
+	}
+
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileName}))
+	c.Header("Content-Type", contentType)
 
 	// Stream the file content to the response writer
 	if _, err := io.Copy(c.Writer, supportingDocumentReader); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 }
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"actlabs-hub/internal/entity"
+	"actlabs-hub/internal/helper"
+	"actlabs-hub/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listParameters are the query parameters of list endpoints.
+var listParameters = []string{"limit", "nextToken", "sort", "fields"}
+
+// defaultListLimit is the page size when a list is paged without a limit.
+const defaultListLimit = 100
+
+// listQuery reads a list endpoint's query for items of type T:
+//
+//	limit      page size, 1 to 1000, 100 by default
+//	nextToken  from the previous page
+//	sort       comma separated JSON fields, - in front for descending
+//	fields     comma separated JSON fields to return
+//
+// paged is false when none are given. Those requests get every item as a
+// bare array, as they did before paging. Storage can only order a page, not
+// the whole list, so a sorted list is returned in a single page and sort
+// can't be combined with limit or nextToken.
+func listQuery[T any](c *gin.Context) (query entity.ListQuery, paged bool, err error) {
+	for _, parameter := range listParameters {
+		if _, ok := c.GetQuery(parameter); ok {
+			paged = true
+		}
+	}
+	if !paged {
+		return query, false, nil
+	}
+
+	query.Limit = defaultListLimit
+	if limit, ok := c.GetQuery("limit"); ok {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > 1000 {
+			return query, true, entity.NewValidationError("invalid_limit", "limit must be between 1 and 1000")
+		}
+		query.Limit = int32(n)
+	}
+
+	query.NextToken = c.Query("nextToken")
+
+	for _, field := range splitList(c.Query("sort")) {
+		sort := entity.SortField{Field: field}
+		if name, ok := strings.CutPrefix(field, "-"); ok {
+			sort = entity.SortField{Field: name, Descending: true}
+		}
+		query.Sort = append(query.Sort, sort)
+	}
+
+	if len(query.Sort) > 0 {
+		_, limited := c.GetQuery("limit")
+		if limited || query.NextToken != "" {
+			return query, true, entity.NewValidationError("invalid_list_query", "sort can't be combined with limit or nextToken, sorted lists are returned in a single page")
+		}
+		query.Limit = 0
+	}
+
+	query.Fields = splitList(c.Query("fields"))
+
+	return query, true, helper.CheckListQuery[T](query)
+}
+
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// respondWithList writes page sorted and with the query's fields, or just its
+// items when the request wasn't paged.
+func respondWithList[T any](c *gin.Context, query entity.ListQuery, paged bool, page entity.Page[T]) {
+	if !paged {
+		c.IndentedJSON(http.StatusOK, page.Items)
+		return
+	}
+
+	helper.SortItems(page.Items, query.Sort)
+
+	if len(query.Fields) == 0 {
+		c.JSON(http.StatusOK, page)
+		return
+	}
+
+	items, err := helper.SelectFields(page.Items, query.Fields)
+	if err != nil {
+		middleware.AbortWithError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, entity.Page[map[string]json.RawMessage]{Items: items, NextToken: page.NextToken})
+}
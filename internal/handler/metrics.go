@@ -0,0 +1,13 @@
+package handler
+
+import (
+	"actlabs-hub/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NewMetricsHandler serves /metrics. It belongs on the internal metrics
+// router, not the public one.
+func NewMetricsHandler(r *gin.RouterGroup) {
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+}
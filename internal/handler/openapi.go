@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"net/http"
+	"sync"
+
+	"actlabs-hub/internal/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+type openAPIHandler struct {
+	routes   *openapi.RouteTable
+	once     sync.Once
+	document openapi.Document
+}
+
+// NewOpenAPIHandler serves the spec of the routes in routes. It's built on the
+// first request, once every handler has been registered.
+func NewOpenAPIHandler(r *gin.RouterGroup, routes *openapi.RouteTable) {
+	handler := &openAPIHandler{
+		routes: routes,
+	}
+
+	r.GET("/openapi.json", handler.GetDocument)
+}
+
+func (h *openAPIHandler) GetDocument(c *gin.Context) {
+	h.once.Do(func() {
+		h.document = h.routes.Document()
+	})
+	c.JSON(http.StatusOK, h.document)
+}
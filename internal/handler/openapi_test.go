@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"actlabs-hub/internal/config"
+	"actlabs-hub/internal/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newDocumentedRouter registers every handler the way main does, with no
+// services behind them.
+func newDocumentedRouter() (*gin.Engine, *openapi.RouteTable) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	api := openapi.NewRouteTable(router)
+	appConfig := &config.Config{}
+
+	api.Register(openapi.Public, func() {
+		NewHealthzHandler(router.Group("/"))
+		NewProbeHandler(router.Group("/"), nil, nil)
+		NewOpenAPIHandler(router.Group("/"), api)
+	})
+	api.Register(openapi.User, func() {
+		NewServerHandler(router.Group("/"), nil)
+		NewAssignmentHandler(router.Group("/"), nil, appConfig)
+		NewChallengeHandler(router.Group("/"), nil, appConfig)
+		NewAuthHandler(router.Group("/"), nil)
+		NewDeploymentUserHandler(router.Group("/"), nil)
+		NewQuotaHandler(router.Group("/"), nil)
+		NewLabHandler(router.Group("/"), nil, nil, appConfig)
+	})
+	api.Register(openapi.APIKey, func() {
+		NewAssignmentAPIKeyHandler(router.Group("/"), nil, appConfig)
+		NewChallengeAPIKeyHandler(router.Group("/"), nil, appConfig)
+		NewDeploymentHandler(router.Group("/"), nil)
+		NewServerHandlerArmToken(router.Group("/"), nil)
+		NewLabHandlerAPIKey(router.Group("/"), nil, appConfig)
+	})
+	api.Register(openapi.Role("admin"), func() {
+		NewAdminAuthHandler(router.Group("/"), nil)
+		NewAdminServerHandler(router.Group("/"), nil)
+		NewAdminDeploymentHandler(router.Group("/"), nil)
+		NewAdminQuotaHandler(router.Group("/"), nil)
+	})
+	api.Register(openapi.Role("mentor"), func() {
+		NewAssignmentHandlerMentorRequired(router.Group("/"), nil)
+		NewScheduleHandlerMentorRequired(router.Group("/"), nil)
+		NewLabHandlerMentorRequired(router.Group("/"), nil, nil)
+	})
+	api.Register(openapi.Role("contributor"), func() {
+		NewLabHandlerContributorRequired(router.Group("/"), nil)
+	})
+
+	return router, api
+}
+
+func TestOpenAPIHandler_DocumentsEveryRoute(t *testing.T) {
+	router, api := newDocumentedRouter()
+
+	if undocumented := api.Undocumented(); len(undocumented) != 0 {
+		t.Fatalf("routes missing from internal/openapi/routes.go: %v", undocumented)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	document := openapi.Document{}
+	if err := json.Unmarshal(w.Body.Bytes(), &document); err != nil {
+		t.Fatal(err)
+	}
+
+	operations := 0
+	for _, item := range document.Paths {
+		operations += len(item)
+	}
+	if operations != len(router.Routes()) {
+		t.Errorf("expected %d operations, got %d", len(router.Routes()), operations)
+	}
+
+	register := document.Paths["/arm/server/register"]["put"]
+	if register == nil || len(register.Security) != 1 || register.Security[0]["apiKey"] == nil {
+		t.Errorf("expected PUT /arm/server/register to need an api key, got %+v", register)
+	}
+	if register.RequestBody == nil || register.RequestBody.Content["application/json"].Schema.Ref != "#/components/schemas/Server" {
+		t.Errorf("expected PUT /arm/server/register to take a Server, got %+v", register.RequestBody)
+	}
+
+	if role := document.Paths["/admin/quotas/{userId}"]["put"].RequiredRole; role != "admin" {
+		t.Errorf("expected PUT /admin/quotas/{userId} to need admin, got %q", role)
+	}
+	if security := document.Paths["/healthz"]["get"].Security; len(security) != 0 {
+		t.Errorf("expected /healthz to be public, got %v", security)
+	}
+}
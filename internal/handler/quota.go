@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"net/http"
+
+	"actlabs-hub/internal/entity"
+	"actlabs-hub/internal/logger"
+	"actlabs-hub/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+type quotaHandler struct {
+	quotaService entity.QuotaService
+}
+
+func NewQuotaHandler(r *gin.RouterGroup, quotaService entity.QuotaService) {
+	handler := &quotaHandler{
+		quotaService: quotaService,
+	}
+
+	r.GET("/deployments/quota", handler.GetQuotaUsage)
+}
+
+func NewAdminQuotaHandler(r *gin.RouterGroup, quotaService entity.QuotaService) {
+	handler := &quotaHandler{
+		quotaService: quotaService,
+	}
+
+	r.GET("/admin/quotas", handler.GetQuotaOverrides)
+	r.GET("/admin/quotas/:userId", handler.AdminGetQuotaUsage)
+	r.PUT("/admin/quotas/:userId", handler.UpsertQuotaOverride)
+	r.DELETE("/admin/quotas/:userId", handler.DeleteQuotaOverride)
+}
+
+func (q *quotaHandler) GetQuotaUsage(c *gin.Context) {
+	logger.LogInfo(c.Request.Context(), "getting deployment quota usage")
+
+	userPrincipal := callingUserPrincipal(c)
+	if userPrincipal == "" {
+		middleware.AbortWithError(c, entity.NewUnauthenticatedError("unauthenticated", "unauthorized"))
+		return
+	}
+
+	usage, err := q.quotaService.GetQuotaUsage(c.Request.Context(), userPrincipal)
+	if err != nil {
+		middleware.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, usage)
+}
+
+func (q *quotaHandler) AdminGetQuotaUsage(c *gin.Context) {
+	logger.LogInfo(c.Request.Context(), "getting deployment quota usage for admin")
+
+	usage, err := q.quotaService.GetQuotaUsage(c.Request.Context(), c.Param("userId"))
+	if err != nil {
+		middleware.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, usage)
+}
+
+func (q *quotaHandler) GetQuotaOverrides(c *gin.Context) {
+	logger.LogInfo(c.Request.Context(), "getting quota overrides")
+
+	overrides, err := q.quotaService.GetQuotaOverrides(c.Request.Context())
+	if err != nil {
+		middleware.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, overrides)
+}
+
+func (q *quotaHandler) UpsertQuotaOverride(c *gin.Context) {
+	logger.LogInfo(c.Request.Context(), "upserting quota override")
+
+	quota := entity.DeploymentQuota{}
+	if err := c.ShouldBind(&quota); err != nil {
+		middleware.AbortWithError(c, entity.NewValidationError("invalid_request_body", err.Error()))
+		return
+	}
+
+	override := entity.DeploymentQuotaOverride{
+		UserId: c.Param("userId"),
+		Quota:  quota,
+	}
+
+	if err := q.quotaService.UpsertQuotaOverride(c.Request.Context(), override); err != nil {
+		middleware.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, override)
+}
+
+func (q *quotaHandler) DeleteQuotaOverride(c *gin.Context) {
+	logger.LogInfo(c.Request.Context(), "deleting quota override")
+
+	if err := q.quotaService.DeleteQuotaOverride(c.Request.Context(), c.Param("userId")); err != nil {
+		middleware.AbortWithError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"net/http"
+
+	"actlabs-hub/internal/entity"
+	"actlabs-hub/internal/logger"
+	"actlabs-hub/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+type scheduleHandler struct {
+	scheduleService entity.ScheduleService
+}
+
+func NewScheduleHandlerMentorRequired(r *gin.RouterGroup, scheduleService entity.ScheduleService) {
+	handler := &scheduleHandler{
+		scheduleService: scheduleService,
+	}
+
+	r.GET("/schedules", handler.GetSchedules)
+	r.GET("/schedules/:scheduleId", handler.GetSchedule)
+	r.POST("/schedules", handler.CreateSchedule)
+	r.DELETE("/schedules/:scheduleId", handler.DeleteSchedule)
+}
+
+func (s *scheduleHandler) GetSchedules(c *gin.Context) {
+	logger.LogInfo(c.Request.Context(), "getting schedules")
+
+	schedules, err := s.scheduleService.GetSchedules(c.Request.Context())
+	if err != nil {
+		middleware.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, schedules)
+}
+
+func (s *scheduleHandler) GetSchedule(c *gin.Context) {
+	logger.LogInfo(c.Request.Context(), "getting schedule")
+
+	schedule, err := s.scheduleService.GetSchedule(c.Request.Context(), c.Param("scheduleId"))
+	if err != nil {
+		middleware.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+func (s *scheduleHandler) CreateSchedule(c *gin.Context) {
+	logger.LogInfo(c.Request.Context(), "creating schedule")
+
+	schedule := entity.Schedule{}
+	if err := c.ShouldBind(&schedule); err != nil {
+		middleware.AbortWithError(c, entity.NewValidationError("invalid_request_body", err.Error()))
+		return
+	}
+
+	userPrincipal := callingUserPrincipal(c)
+	if userPrincipal == "" {
+		middleware.AbortWithError(c, entity.NewUnauthenticatedError("unauthenticated", "unauthorized"))
+		return
+	}
+	schedule.CreatedBy = userPrincipal
+
+	schedule, err := s.scheduleService.CreateSchedule(c.Request.Context(), schedule)
+	if err != nil {
+		middleware.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, schedule)
+}
+
+func (s *scheduleHandler) DeleteSchedule(c *gin.Context) {
+	logger.LogInfo(c.Request.Context(), "deleting schedule")
+
+	if err := s.scheduleService.DeleteSchedule(c.Request.Context(), c.Param("scheduleId")); err != nil {
+		middleware.AbortWithError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
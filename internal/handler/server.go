@@ -1,10 +1,12 @@
 package handler
 
 import (
-	"actlabs-hub/internal/auth"
 	"actlabs-hub/internal/entity"
 	"actlabs-hub/internal/logger"
+	"actlabs-hub/internal/middleware"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -32,6 +34,8 @@ func NewAdminServerHandler(r *gin.RouterGroup, serverService entity.ServerServic
 	}
 
 	r.GET("/admin/servers", handler.AdminGetAllServers)
+	r.GET("/admin/servers/health", handler.GetServersHealth)
+	r.GET("/admin/servers/summary", handler.GetServersSummary)
 	r.DELETE("/admin/server/unregister/:userPrincipalName", handler.AdminUnregister)
 }
 
@@ -47,15 +51,15 @@ func NewServerHandlerArmToken(r *gin.RouterGroup, serverService entity.ServerSer
 func (h *serverHandler) GetServer(c *gin.Context) {
 	logger.LogInfo(c.Request.Context(), "getting server")
 
-	userPrincipal, err := auth.GetUserPrincipalFromToken(c.Request.Context(), c.GetHeader("Authorization"))
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authorized or invalid token"})
+	userPrincipal := callingUserPrincipal(c)
+	if userPrincipal == "" {
+		middleware.AbortWithError(c, entity.NewUnauthenticatedError("unauthenticated", "not authorized or invalid token"))
 		return
 	}
 
 	server, err := h.serverService.GetServer(c.Request.Context(), userPrincipal)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 
@@ -67,12 +71,12 @@ func (h *serverHandler) RegisterSubscription(c *gin.Context) {
 
 	server := entity.Server{}
 	if err := c.ShouldBindJSON(&server); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid object"})
+		middleware.AbortWithError(c, entity.NewValidationError("invalid_request_body", "Invalid object"))
 		return
 	}
 
 	if err := h.serverService.RegisterSubscription(c.Request.Context(), server); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 
@@ -82,13 +86,14 @@ func (h *serverHandler) RegisterSubscription(c *gin.Context) {
 func (h *serverHandler) Unregister(c *gin.Context) {
 	logger.LogInfo(c.Request.Context(), "unregistering server")
 
-	userPrincipalName, err := auth.GetUserPrincipalFromToken(c.Request.Context(), c.GetHeader("Authorization"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "not authorized or invalid token"})
+	userPrincipalName := callingUserPrincipal(c)
+	if userPrincipalName == "" {
+		middleware.AbortWithError(c, entity.NewUnauthenticatedError("unauthenticated", "not authorized or invalid token"))
+		return
 	}
 
 	if err := h.serverService.Unregister(c.Request.Context(), userPrincipalName); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 
@@ -101,7 +106,7 @@ func (h *serverHandler) AdminUnregister(c *gin.Context) {
 	userPrincipalName := c.Param("userPrincipalName")
 
 	if err := h.serverService.Unregister(c.Request.Context(), userPrincipalName); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 
@@ -113,13 +118,13 @@ func (h *serverHandler) ArmGetServer(c *gin.Context) {
 
 	userPrincipalName := c.Param("userPrincipalName")
 	if userPrincipalName == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "userPrincipalName is required"})
+		middleware.AbortWithError(c, entity.NewValidationError("invalid_user_principal_name", "userPrincipalName is required"))
 		return
 	}
 
 	server, err := h.serverService.GetServer(c.Request.Context(), userPrincipalName)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 
@@ -129,13 +134,67 @@ func (h *serverHandler) ArmGetServer(c *gin.Context) {
 func (h *serverHandler) AdminGetAllServers(c *gin.Context) {
 	logger.LogInfo(c.Request.Context(), "getting all servers for admin")
 
-	servers, err := h.serverService.GetAllServers(c.Request.Context())
+	query, paged, err := listQuery[entity.Server](c)
+	if err != nil {
+		middleware.AbortWithError(c, err)
+		return
+	}
+
+	page, err := h.serverService.ListServers(c.Request.Context(), query)
+	if err != nil {
+		middleware.AbortWithError(c, err)
+		return
+	}
+
+	respondWithList(c, query, paged, page)
+}
+
+func (h *serverHandler) GetServersHealth(c *gin.Context) {
+	logger.LogInfo(c.Request.Context(), "getting servers health for admin")
+
+	health, err := h.serverService.GetServersHealth(c.Request.Context())
+	if err != nil {
+		middleware.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, health)
+}
+
+// GetServersSummary accepts idleMinutes (default 60), stuckMinutes (default
+// 30) and top (default 10) query parameters.
+func (h *serverHandler) GetServersSummary(c *gin.Context) {
+	logger.LogInfo(c.Request.Context(), "getting servers summary for admin")
+
+	idleMinutes, err := strconv.Atoi(c.DefaultQuery("idleMinutes", "60"))
+	if err != nil || idleMinutes < 0 {
+		middleware.AbortWithError(c, entity.NewValidationError("invalid_query", "invalid idleMinutes"))
+		return
+	}
+
+	stuckMinutes, err := strconv.Atoi(c.DefaultQuery("stuckMinutes", "30"))
+	if err != nil || stuckMinutes < 0 {
+		middleware.AbortWithError(c, entity.NewValidationError("invalid_query", "invalid stuckMinutes"))
+		return
+	}
+
+	top, err := strconv.Atoi(c.DefaultQuery("top", "10"))
+	if err != nil || top < 0 {
+		middleware.AbortWithError(c, entity.NewValidationError("invalid_query", "invalid top"))
+		return
+	}
+
+	summary, err := h.serverService.GetServersSummary(c.Request.Context(), entity.ServerSummaryOptions{
+		IdleThreshold:  time.Duration(idleMinutes) * time.Minute,
+		StuckThreshold: time.Duration(stuckMinutes) * time.Minute,
+		TopUsers:       top,
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 
-	c.JSON(200, servers)
+	c.JSON(http.StatusOK, summary)
 }
 
 func (h *serverHandler) UpdateActivityStatus(c *gin.Context) {
@@ -143,13 +202,13 @@ func (h *serverHandler) UpdateActivityStatus(c *gin.Context) {
 
 	userPrincipalName := c.Param("userPrincipalName")
 
-	if !auth.VerifyUserPrincipalName(c.Request.Context(), userPrincipalName, c.GetHeader("Authorization")) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid request"})
+	if userPrincipalName != callingUserPrincipal(c) {
+		middleware.AbortWithError(c, entity.NewForbiddenError("server_forbidden", "invalid request"))
 		return
 	}
 
 	if err := h.serverService.UpdateActivityStatus(c.Request.Context(), userPrincipalName); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, err)
 		return
 	}
 
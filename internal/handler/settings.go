@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"net/http"
+
+	"actlabs-hub/internal/entity"
+	"actlabs-hub/internal/logger"
+	"actlabs-hub/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+type settingsHandler struct {
+	settingsService entity.SettingsService
+}
+
+func NewAdminSettingsHandler(r *gin.RouterGroup, settingsService entity.SettingsService) {
+	handler := &settingsHandler{
+		settingsService: settingsService,
+	}
+
+	r.GET("/admin/settings", handler.GetSettings)
+	r.PUT("/admin/settings", handler.UpdateSettings)
+}
+
+func (s *settingsHandler) GetSettings(c *gin.Context) {
+	logger.LogInfo(c.Request.Context(), "getting runtime settings")
+
+	settings, err := s.settingsService.GetSettings(c.Request.Context())
+	if err != nil {
+		middleware.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+func (s *settingsHandler) UpdateSettings(c *gin.Context) {
+	logger.LogInfo(c.Request.Context(), "updating runtime settings")
+
+	settings := entity.RuntimeSettings{}
+	if err := c.ShouldBind(&settings); err != nil {
+		middleware.AbortWithError(c, entity.NewValidationError("invalid_request_body", err.Error()))
+		return
+	}
+
+	settings, err := s.settingsService.UpdateSettings(c.Request.Context(), settings)
+	if err != nil {
+		middleware.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
@@ -0,0 +1,73 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/aztables"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/redis/go-redis/v9"
+)
+
+func RedisCheck(rdb *redis.Client) Check {
+	return Check{
+		Name: "redis",
+		Check: func(ctx context.Context) error {
+			return rdb.Ping(ctx).Err()
+		},
+	}
+}
+
+// TableCheck reads at most one entity from the table.
+func TableCheck(name string, client *aztables.Client) Check {
+	return Check{
+		Name: "table:" + name,
+		Check: func(ctx context.Context) error {
+			top := int32(1)
+			_, err := client.NewListEntitiesPager(&aztables.ListEntitiesOptions{Top: &top}).NextPage(ctx)
+			return err
+		},
+	}
+}
+
+// BlobContainerCheck reads the container's properties.
+func BlobContainerCheck(name string, client *container.Client) Check {
+	return Check{
+		Name: "blob:" + name,
+		Check: func(ctx context.Context) error {
+			_, err := client.GetProperties(ctx, nil)
+			return err
+		},
+	}
+}
+
+// TCPCheck checks that something is listening at the endpoint's host and
+// port, without calling it.
+func TCPCheck(name string, endpoint string) Check {
+	return Check{
+		Name: name,
+		Check: func(ctx context.Context) error {
+			u, err := url.Parse(endpoint)
+			if err != nil {
+				return fmt.Errorf("invalid endpoint: %w", err)
+			}
+
+			host := u.Host
+			if u.Port() == "" {
+				port := "80"
+				if u.Scheme == "https" {
+					port = "443"
+				}
+				host = net.JoinHostPort(u.Hostname(), port)
+			}
+
+			conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", host)
+			if err != nil {
+				return err
+			}
+			return conn.Close()
+		},
+	}
+}
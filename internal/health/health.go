@@ -0,0 +1,155 @@
+// Package health runs the checks behind the readiness and liveness probes.
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusUp   Status = "up"
+	StatusDown Status = "down"
+)
+
+// Check is one dependency or internal component to check.
+type Check struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+type CheckResult struct {
+	Status     Status `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// Report is the result of running every check. It's up only when all checks
+// are.
+type Report struct {
+	Status    Status                 `json:"status"`
+	CheckedAt string                 `json:"checkedAt"`
+	Checks    map[string]CheckResult `json:"checks"`
+}
+
+// Checker runs its checks concurrently, each with its own timeout, and caches
+// the report so probes from many callers don't hammer the dependencies.
+type Checker struct {
+	checks   []Check
+	timeout  time.Duration
+	cacheTTL time.Duration
+
+	mu        sync.Mutex
+	report    Report
+	expiresAt time.Time
+	draining  bool
+}
+
+// NewChecker returns a checker for checks. A zero cacheTTL runs the checks on
+// every call.
+func NewChecker(timeout time.Duration, cacheTTL time.Duration, checks ...Check) *Checker {
+	return &Checker{
+		checks:   checks,
+		timeout:  timeout,
+		cacheTTL: cacheTTL,
+	}
+}
+
+// Drain makes every later run report down without running the checks, so
+// load balancers stop routing to the hub before it stops accepting requests.
+func (c *Checker) Drain() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.draining = true
+}
+
+// Run returns the cached report, or runs the checks when it's expired.
+// Concurrent callers wait for the same run.
+func (c *Checker) Run(ctx context.Context) Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.draining {
+		return Report{
+			Status:    StatusDown,
+			CheckedAt: time.Now().UTC().Format(time.RFC3339),
+			Checks: map[string]CheckResult{
+				"shutdown": {Status: StatusDown, Error: "shutting down"},
+			},
+		}
+	}
+
+	if time.Now().Before(c.expiresAt) {
+		return c.report
+	}
+
+	c.report = c.run(ctx)
+	c.expiresAt = time.Now().Add(c.cacheTTL)
+
+	return c.report
+}
+
+func (c *Checker) run(ctx context.Context) Report {
+	// Callers going away shouldn't fail the cached report.
+	ctx = context.WithoutCancel(ctx)
+
+	results := make([]CheckResult, len(c.checks))
+
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.runCheck(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{
+		Status:    StatusUp,
+		CheckedAt: time.Now().UTC().Format(time.RFC3339),
+		Checks:    make(map[string]CheckResult, len(c.checks)),
+	}
+	for i, check := range c.checks {
+		report.Checks[check.Name] = results[i]
+		if results[i].Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+
+	return report
+}
+
+func (c *Checker) runCheck(ctx context.Context, check Check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+
+	// The check may ignore ctx, so the timeout is enforced here too.
+	done := make(chan error, 1)
+	go func() {
+		done <- check.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := CheckResult{
+		Status:     StatusUp,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+
+	return result
+}
@@ -0,0 +1,79 @@
+package health
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCheckerCachesAndTimesOut(t *testing.T) {
+	var calls atomic.Int32
+
+	checker := NewChecker(50*time.Millisecond, time.Minute,
+		Check{Name: "counted", Check: func(ctx context.Context) error {
+			calls.Add(1)
+			return nil
+		}},
+		Check{Name: "hung", Check: func(ctx context.Context) error {
+			// Ignores ctx, the checker must give up on it anyway.
+			time.Sleep(time.Second)
+			return nil
+		}},
+	)
+
+	report := checker.Run(context.Background())
+	if report.Status != StatusDown || report.Checks["hung"].Status != StatusDown || report.Checks["counted"].Status != StatusUp {
+		t.Errorf("expected only the hung check to fail, got %+v", report)
+	}
+
+	checker.Run(context.Background())
+	if calls.Load() != 1 {
+		t.Errorf("expected the cached report to be reused, checks ran %d times", calls.Load())
+	}
+}
+
+func TestCheckerDrain(t *testing.T) {
+	checker := NewChecker(time.Second, time.Minute, Check{Name: "up", Check: func(ctx context.Context) error { return nil }})
+
+	if report := checker.Run(context.Background()); report.Status != StatusUp {
+		t.Fatalf("expected up before draining, got %+v", report)
+	}
+
+	// The cached report must not hide the drain.
+	checker.Drain()
+	if report := checker.Run(context.Background()); report.Status != StatusDown || report.Checks["shutdown"].Status != StatusDown {
+		t.Errorf("expected down while draining, got %+v", report)
+	}
+}
+
+func TestHeartbeatCheck(t *testing.T) {
+	ExpectHeartbeat("TestLoop", time.Hour)
+	if err := HeartbeatCheck(ReadinessMissedBeats).Check(context.Background()); err != nil {
+		t.Fatalf("expected a fresh heartbeat to pass, got %v", err)
+	}
+
+	heartbeatsMu.Lock()
+	heartbeats["TestLoop"].last = time.Now().Add(-4 * time.Hour)
+	heartbeatsMu.Unlock()
+
+	if err := HeartbeatCheck(ReadinessMissedBeats).Check(context.Background()); err == nil {
+		t.Fatal("expected a missed heartbeat to fail")
+	}
+
+	// Liveness tolerates more missed beats.
+	if err := HeartbeatCheck(LivenessMissedBeats).Check(context.Background()); err != nil {
+		t.Fatalf("expected liveness to tolerate a slow loop, got %v", err)
+	}
+	heartbeatsMu.Lock()
+	heartbeats["TestLoop"].last = time.Now().Add(-11 * time.Hour)
+	heartbeatsMu.Unlock()
+	if err := HeartbeatCheck(LivenessMissedBeats).Check(context.Background()); err == nil {
+		t.Fatal("expected a stuck loop to fail liveness")
+	}
+
+	Beat("TestLoop")
+	if err := HeartbeatCheck(ReadinessMissedBeats).Check(context.Background()); err != nil {
+		t.Fatalf("expected a beat to recover the heartbeat, got %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type heartbeat struct {
+	interval time.Duration
+	last     time.Time
+}
+
+var (
+	heartbeatsMu sync.Mutex
+	heartbeats   = map[string]*heartbeat{}
+)
+
+// How many intervals a loop can go without beating before its heartbeat
+// counts as missed. Readiness takes a slow loop out of rotation; liveness only
+// restarts the hub for a loop that's stuck for good.
+const (
+	ReadinessMissedBeats = 3
+	LivenessMissedBeats  = 10
+)
+
+// ExpectHeartbeat registers a background loop that beats every interval. Call
+// it when starting the loop; it counts as the first beat.
+func ExpectHeartbeat(name string, interval time.Duration) {
+	heartbeatsMu.Lock()
+	defer heartbeatsMu.Unlock()
+
+	heartbeats[name] = &heartbeat{interval: interval, last: time.Now()}
+}
+
+// Beat records that the loop is still making progress. Beats of loops that
+// weren't registered are ignored.
+func Beat(name string) {
+	heartbeatsMu.Lock()
+	defer heartbeatsMu.Unlock()
+
+	if h, ok := heartbeats[name]; ok {
+		h.last = time.Now()
+	}
+}
+
+// HeartbeatCheck fails when any registered loop went more than missedBeats
+// intervals without beating, which means it's stuck or has stopped.
+func HeartbeatCheck(missedBeats int) Check {
+	return Check{
+		Name: "heartbeats",
+		Check: func(ctx context.Context) error {
+			heartbeatsMu.Lock()
+			defer heartbeatsMu.Unlock()
+
+			stale := []string{}
+			for name, h := range heartbeats {
+				if since := time.Since(h.last); since > time.Duration(missedBeats)*h.interval {
+					stale = append(stale, fmt.Sprintf("%s (last beat %s ago)", name, since.Round(time.Second)))
+				}
+			}
+
+			if len(stale) > 0 {
+				sort.Strings(stale)
+				return fmt.Errorf("missed heartbeats: %s", strings.Join(stale, ", "))
+			}
+			return nil
+		},
+	}
+}
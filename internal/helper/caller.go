@@ -0,0 +1,50 @@
+package helper
+
+import (
+	"runtime"
+	"strings"
+	"unicode"
+)
+
+const repositoryPackage = "actlabs-hub/internal/repository."
+
+// RepositoryMethod is the innermost exported repository method on the call
+// stack, like "deploymentRepository.GetAllDeployments", or "unknown".
+// Storage clients use it to tell which repository method sent a request.
+func RepositoryMethod() string {
+	pcs := make([]uintptr, 64)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+
+	for {
+		frame, more := frames.Next()
+		if method, ok := parseRepositoryMethod(frame.Function); ok {
+			return method
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+// parseRepositoryMethod turns a function name like
+// "actlabs-hub/internal/repository.(*labRepository).GetLab.func1" into
+// "labRepository.GetLab".
+func parseRepositoryMethod(function string) (string, bool) {
+	name, ok := strings.CutPrefix(function, repositoryPackage)
+	if !ok {
+		return "", false
+	}
+
+	receiver, method, ok := strings.Cut(name, ").")
+	if !ok {
+		return "", false
+	}
+	receiver = strings.TrimLeft(receiver, "(*")
+	method, _, _ = strings.Cut(method, ".")
+
+	if method == "" || !unicode.IsUpper(rune(method[0])) {
+		return "", false
+	}
+
+	return receiver + "." + method, true
+}
@@ -0,0 +1,24 @@
+package helper
+
+import "testing"
+
+func TestParseRepositoryMethod(t *testing.T) {
+	tests := []struct {
+		function string
+		want     string
+		ok       bool
+	}{
+		{"actlabs-hub/internal/repository.(*labRepository).GetLab", "labRepository.GetLab", true},
+		{"actlabs-hub/internal/repository.(*deploymentRepository).GetAllDeployments.func1", "deploymentRepository.GetAllDeployments", true},
+		{"actlabs-hub/internal/repository.(*labRepository).supportingDocumentsContainerClient", "", false},
+		{"actlabs-hub/internal/repository.redisKey", "", false},
+		{"actlabs-hub/internal/service.(*labService).GetLab", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseRepositoryMethod(tt.function)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseRepositoryMethod(%q) = %q, %v, want %q, %v", tt.function, got, ok, tt.want, tt.ok)
+		}
+	}
+}
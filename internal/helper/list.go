@@ -0,0 +1,134 @@
+package helper
+
+import (
+	"cmp"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+
+	"actlabs-hub/internal/entity"
+)
+
+// jsonFields maps the JSON names of T's fields to their index.
+func jsonFields[T any]() map[string]int {
+	t := reflect.TypeFor[T]()
+	fields := map[string]int{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = i
+	}
+	return fields
+}
+
+// CheckListQuery makes sure the query only sorts by and selects JSON fields
+// of T, and only sorts by strings, numbers and booleans.
+func CheckListQuery[T any](query entity.ListQuery) error {
+	t := reflect.TypeFor[T]()
+	fields := jsonFields[T]()
+
+	for _, name := range query.Fields {
+		if _, ok := fields[name]; !ok {
+			return entity.NewValidationError("invalid_list_query", "unknown field "+name)
+		}
+	}
+
+	for _, sort := range query.Sort {
+		i, ok := fields[sort.Field]
+		if !ok {
+			return entity.NewValidationError("invalid_list_query", "unknown sort field "+sort.Field)
+		}
+		switch t.Field(i).Type.Kind() {
+		case reflect.String, reflect.Bool,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+		default:
+			return entity.NewValidationError("invalid_list_query", "can't sort by "+sort.Field)
+		}
+	}
+
+	return nil
+}
+
+// SortItems orders items by the query's sort fields, which must have passed
+// CheckListQuery.
+func SortItems[T any](items []T, sort []entity.SortField) {
+	if len(sort) == 0 {
+		return
+	}
+
+	fields := jsonFields[T]()
+	slices.SortStableFunc(items, func(a, b T) int {
+		va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+		for _, s := range sort {
+			i := fields[s.Field]
+			c := compareValues(va.Field(i), vb.Field(i))
+			if s.Descending {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+}
+
+func compareValues(a, b reflect.Value) int {
+	switch a.Kind() {
+	case reflect.String:
+		return cmp.Compare(a.String(), b.String())
+	case reflect.Bool:
+		switch {
+		case a.Bool() == b.Bool():
+			return 0
+		case a.Bool():
+			return 1
+		default:
+			return -1
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmp.Compare(a.Int(), b.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return cmp.Compare(a.Uint(), b.Uint())
+	case reflect.Float32, reflect.Float64:
+		return cmp.Compare(a.Float(), b.Float())
+	}
+	return 0
+}
+
+// SelectFields returns items with only the given JSON fields.
+func SelectFields[T any](items []T, fields []string) ([]map[string]json.RawMessage, error) {
+	selected := make([]map[string]json.RawMessage, 0, len(items))
+	for _, item := range items {
+		b, err := json.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+
+		all := map[string]json.RawMessage{}
+		if err := json.Unmarshal(b, &all); err != nil {
+			return nil, err
+		}
+
+		some := map[string]json.RawMessage{}
+		for _, field := range fields {
+			if value, ok := all[field]; ok {
+				some[field] = value
+			}
+		}
+		selected = append(selected, some)
+	}
+	return selected, nil
+}
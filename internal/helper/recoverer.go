@@ -6,10 +6,36 @@ import (
 	"errors"
 )
 
+// Recoverer runs f, running it again each time it panics, up to maxRetries
+// times. Restarts happen in the calling goroutine so a TaskGroup running the
+// caller keeps tracking f. Once the retries are used up the panic is raised
+// again.
 func Recoverer(ctx context.Context, maxRetries int, id string, f func()) {
+	for remaining := maxRetries; ; remaining-- {
+		err := runRecovered(ctx, id, f)
+		if err == nil {
+			return
+		}
+
+		logger.LogError(ctx, "recovering goroutine panic",
+			"id", id,
+			"err", err,
+			"retry", remaining,
+			"remaining retries", remaining-1,
+		)
+		if remaining == 0 {
+			logger.LogError(ctx, "max retries exceeded, not retrying",
+				"id", id,
+			)
+			panic("max retries exceeded, not retrying")
+		}
+	}
+}
+
+// runRecovered calls f and returns what it panicked with as an error.
+func runRecovered(ctx context.Context, id string, f func()) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
-			var err error
 			switch x := r.(type) {
 			case string:
 				err = errors.New(x)
@@ -18,24 +44,11 @@ func Recoverer(ctx context.Context, maxRetries int, id string, f func()) {
 			default:
 				err = errors.New("unknown panic")
 			}
-			logger.LogError(ctx, "recovering goroutine panic",
-				"id", id,
-				"err", err,
-				"retry", maxRetries,
-				"remaining retries", maxRetries-1,
-			)
-			if maxRetries == 0 {
-				logger.LogError(ctx, "max retries exceeded, not retrying",
-					"id", id,
-				)
-				panic("max retries exceeded, not retrying")
-			} else {
-				go Recoverer(ctx, maxRetries-1, id, f)
-			}
 		}
 	}()
 	logger.LogInfo(ctx, "starting goroutine",
 		"id", id,
 	)
 	f() // call the function
+	return nil
 }
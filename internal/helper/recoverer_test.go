@@ -0,0 +1,35 @@
+package helper
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRecovererRestartsInsideTaskGroup(t *testing.T) {
+	tasks := NewTaskGroup()
+
+	runs := 0
+	release := make(chan struct{})
+	tasks.Go("loop", func() {
+		Recoverer(context.Background(), 3, "loop", func() {
+			runs++
+			if runs < 3 {
+				panic("boom")
+			}
+			<-release
+		})
+	})
+
+	// The restarted loop is still the group's task.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if abandoned := tasks.Wait(ctx); len(abandoned) != 1 || abandoned[0] != "loop" {
+		t.Fatalf("expected the restarted loop to be tracked, got %v", abandoned)
+	}
+
+	close(release)
+	if abandoned := tasks.Wait(context.Background()); len(abandoned) != 0 || runs != 3 {
+		t.Errorf("expected 3 runs and nothing abandoned, got %d runs and %v", runs, abandoned)
+	}
+}
@@ -0,0 +1,78 @@
+package helper
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// TaskGroup tracks background tasks so shutdown can wait for them instead of
+// killing them halfway.
+type TaskGroup struct {
+	mu      sync.Mutex
+	wg      sync.WaitGroup
+	running map[string]int
+}
+
+func NewTaskGroup() *TaskGroup {
+	return &TaskGroup{
+		running: map[string]int{},
+	}
+}
+
+// Go runs f in a goroutine tracked under name. A nil TaskGroup runs f
+// untracked.
+func (t *TaskGroup) Go(name string, f func()) {
+	if t == nil {
+		go f()
+		return
+	}
+
+	t.mu.Lock()
+	t.running[name]++
+	t.mu.Unlock()
+	t.wg.Add(1)
+
+	go func() {
+		defer func() {
+			t.mu.Lock()
+			t.running[name]--
+			if t.running[name] == 0 {
+				delete(t.running, name)
+			}
+			t.mu.Unlock()
+			t.wg.Done()
+		}()
+
+		f()
+	}()
+}
+
+// Wait waits for every task to finish or ctx to be done, whichever is first.
+// It returns the names of the tasks still running, which are abandoned.
+func (t *TaskGroup) Wait(ctx context.Context) []string {
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	abandoned := []string{}
+	for name, count := range t.running {
+		for range count {
+			abandoned = append(abandoned, name)
+		}
+	}
+	sort.Strings(abandoned)
+
+	return abandoned
+}
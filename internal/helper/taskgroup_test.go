@@ -0,0 +1,25 @@
+package helper
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTaskGroupWaitReportsAbandonedTasks(t *testing.T) {
+	tasks := NewTaskGroup()
+
+	release := make(chan struct{})
+	defer close(release)
+
+	tasks.Go("quick", func() {})
+	tasks.Go("stuck", func() { <-release })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	abandoned := tasks.Wait(ctx)
+	if len(abandoned) != 1 || abandoned[0] != "stuck" {
+		t.Errorf("expected only the stuck task to be abandoned, got %v", abandoned)
+	}
+}
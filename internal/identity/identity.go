@@ -0,0 +1,92 @@
+// Package identity resolves user IDs against the domains and tenants the hub
+// is configured to serve.
+package identity
+
+import (
+	"fmt"
+	"strings"
+
+	"actlabs-hub/internal/entity"
+)
+
+// Directory holds the configured tenants. The first entry's domain is the
+// default used to qualify bare aliases.
+type Directory struct {
+	tenants []entity.Tenant
+}
+
+func NewDirectory(tenants []entity.Tenant) *Directory {
+	return &Directory{
+		tenants: tenants,
+	}
+}
+
+// DefaultDomain is the domain appended to user IDs given without one.
+func (d *Directory) DefaultDomain() string {
+	if d == nil || len(d.tenants) == 0 {
+		return ""
+	}
+	return d.tenants[0].Domain
+}
+
+// IsAllowedDomain reports whether users of domain may use the hub.
+func (d *Directory) IsAllowedDomain(domain string) bool {
+	if d == nil {
+		return false
+	}
+	for _, tenant := range d.tenants {
+		if strings.EqualFold(tenant.Domain, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// QualifyUserId appends the default domain to a bare alias and checks that a
+// full user ID belongs to an allowed domain.
+func (d *Directory) QualifyUserId(userId string) (string, error) {
+	alias, domain, found := strings.Cut(userId, "@")
+	if !found {
+		if d.DefaultDomain() == "" {
+			return "", fmt.Errorf("no default domain configured for user id %s", userId)
+		}
+		return alias + "@" + d.DefaultDomain(), nil
+	}
+
+	if !d.IsAllowedDomain(domain) {
+		return "", entity.NewValidationError("domain_not_allowed", fmt.Sprintf("invalid email domain for user id %s", userId))
+	}
+
+	return alias + "@" + strings.ToLower(domain), nil
+}
+
+// ResolveUser returns the user principal name and tenant for a user signing
+// in from tenantId. A bare alias gets the first domain configured for the
+// tenant; a full user ID must use one of the tenant's domains.
+func (d *Directory) ResolveUser(userId string, tenantId string) (string, entity.Tenant, error) {
+	var candidates []entity.Tenant
+	if d != nil {
+		for _, tenant := range d.tenants {
+			if tenant.TenantId == tenantId {
+				candidates = append(candidates, tenant)
+			}
+		}
+	}
+
+	if len(candidates) == 0 {
+		return "", entity.Tenant{}, entity.NewForbiddenError("tenant_not_allowed", fmt.Sprintf("tenant %s is not allowed", tenantId))
+	}
+
+	alias, domain, found := strings.Cut(userId, "@")
+	if !found {
+		return alias + "@" + candidates[0].Domain, candidates[0], nil
+	}
+
+	for _, tenant := range candidates {
+		if strings.EqualFold(tenant.Domain, domain) {
+			return alias + "@" + tenant.Domain, tenant, nil
+		}
+	}
+
+	return "", entity.Tenant{}, entity.NewForbiddenError("domain_not_allowed", fmt.Sprintf("domain %s is not allowed for tenant %s", domain, tenantId))
+}
@@ -10,6 +10,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Context keys to avoid collisions
@@ -84,54 +86,38 @@ func getProjectRelativePath(fullPath string) string {
 	return filepath.Base(fullPath)
 }
 
+// level is shared by every logger SetupLogger creates, so SetLevel applies
+// to them without setting them up again.
+var level = new(slog.LevelVar)
+
 // SetupLogger initializes the global logger with custom formatting
 //
 // ACTLABS_HUB_LOG_LEVEL environment variable supports both formats:
 // String: DEBUG, INFO, WARN, ERROR (recommended)
 // Number: -4 (debug), 0 (info), 4 (warn), 8 (error)
 func SetupLogger(ctx context.Context) {
+	SetupLoggerTo(ctx, os.Stdout)
+}
+
+// SetupLoggerTo is SetupLogger writing to w, so command line tools can keep
+// stdout for their output.
+func SetupLoggerTo(ctx context.Context, w io.Writer) {
 	logLevel := os.Getenv("ACTLABS_HUB_LOG_LEVEL")
 	if logLevel == "" {
 		slog.Info("ACTLABS_HUB_LOG_LEVEL not set defaulting to INFO")
 		logLevel = "INFO" // Default to INFO
 	}
 
-	var level slog.Level
-
-	// First try string format (case-insensitive)
-	switch strings.ToUpper(logLevel) {
-	case "DEBUG":
-		level = slog.LevelDebug
-	case "INFO":
-		level = slog.LevelInfo
-	case "WARN", "WARNING":
-		level = slog.LevelWarn
-	case "ERROR":
-		level = slog.LevelError
-	default:
-		// Fallback to numeric format for backward compatibility
-		logLevelInt, err := strconv.Atoi(logLevel)
-		if err != nil {
-			slog.Error("Invalid ACTLABS_HUB_LOG_LEVEL value", "value", logLevel, "error", err.Error())
-			level = slog.LevelInfo // Default to INFO on error
-		} else {
-			// Convert numeric to slog.Level
-			switch {
-			case logLevelInt <= -4: // Debug and below
-				level = slog.LevelDebug
-			case logLevelInt <= 0: // Info level
-				level = slog.LevelInfo
-			case logLevelInt <= 4: // Warn level
-				level = slog.LevelWarn
-			default: // Error and above
-				level = slog.LevelError
-			}
-		}
+	resolved, err := ParseLevel(logLevel)
+	if err != nil {
+		slog.Error("Invalid ACTLABS_HUB_LOG_LEVEL value", "value", logLevel, "error", err.Error())
+		resolved = slog.LevelInfo // Default to INFO on error
 	}
+	level.Set(resolved)
 
 	slog.Info("Setting up logger",
 		"log_level_env", logLevel,
-		"resolved_level", level.String())
+		"resolved_level", resolved.String())
 
 	opts := &slog.HandlerOptions{
 		AddSource: false, // We handle source ourselves
@@ -139,10 +125,54 @@ func SetupLogger(ctx context.Context) {
 	}
 
 	// Use our custom handler for simplified source format
-	customHandler := NewCustomHandler(os.Stdout, opts)
+	customHandler := NewCustomHandler(w, opts)
 	slog.SetDefault(slog.New(customHandler))
 }
 
+// ParseLevel reads a level in either of the formats ACTLABS_HUB_LOG_LEVEL
+// supports.
+func ParseLevel(logLevel string) (slog.Level, error) {
+	// First try string format (case-insensitive)
+	switch strings.ToUpper(logLevel) {
+	case "DEBUG":
+		return slog.LevelDebug, nil
+	case "INFO":
+		return slog.LevelInfo, nil
+	case "WARN", "WARNING":
+		return slog.LevelWarn, nil
+	case "ERROR":
+		return slog.LevelError, nil
+	}
+
+	// Fallback to numeric format for backward compatibility
+	logLevelInt, err := strconv.Atoi(logLevel)
+	if err != nil {
+		return slog.LevelInfo, err
+	}
+
+	// Convert numeric to slog.Level
+	switch {
+	case logLevelInt <= -4: // Debug and below
+		return slog.LevelDebug, nil
+	case logLevelInt <= 0: // Info level
+		return slog.LevelInfo, nil
+	case logLevelInt <= 4: // Warn level
+		return slog.LevelWarn, nil
+	default: // Error and above
+		return slog.LevelError, nil
+	}
+}
+
+// SetLevel changes the level of the running logger.
+func SetLevel(logLevel string) error {
+	resolved, err := ParseLevel(logLevel)
+	if err != nil {
+		return err
+	}
+	level.Set(resolved)
+	return nil
+}
+
 // GetUserID extracts user ID from context
 func GetUserID(ctx context.Context) string {
 	if userID, ok := ctx.Value(UserIDKey).(string); ok {
@@ -249,9 +279,10 @@ func LogWarning(ctx context.Context, msg string, args ...any) {
 	_ = logger.Handler().Handle(ctx, r)
 }
 
-// CreateBackgroundContextWithValues creates a background context preserving trace and user IDs
+// CreateBackgroundContextWithValues creates a background context preserving
+// the span, trace and user IDs
 func CreateBackgroundContextWithValues(sourceCtx context.Context) context.Context {
-	bgCtx := context.Background()
+	bgCtx := trace.ContextWithSpanContext(context.Background(), trace.SpanContextFromContext(sourceCtx))
 
 	if traceID := GetTraceID(sourceCtx); traceID != "" {
 		bgCtx = context.WithValue(bgCtx, TraceIDKey, traceID)
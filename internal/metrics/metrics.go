@@ -0,0 +1,115 @@
+// Package metrics holds the hub's Prometheus metrics and the hooks that
+// record them. Everything is registered on Registry, which /metrics serves.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "actlabs_hub"
+
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+var (
+	httpRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Duration of HTTP requests by method, route template and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	rateLimitedRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_requests_total",
+		Help:      "Requests rejected by a rate limiter.",
+	}, []string{"limiter"})
+
+	autoDestroyDeploymentsScanned = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "auto_destroy_deployments_scanned",
+		Help:      "Deployments scanned by the last auto destroy poll.",
+	})
+
+	autoDestroyRequestsSent = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "auto_destroy_requests_sent",
+		Help:      "Destroy requests sent by the last auto destroy poll.",
+	})
+
+	autoDestroyFailures = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "auto_destroy_failures",
+		Help:      "Destroy requests that failed in the last auto destroy poll.",
+	})
+
+	autoDestroyLastPoll = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "auto_destroy_last_poll_timestamp_seconds",
+		Help:      "Unix time the last auto destroy poll finished.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler serves the metrics in Registry.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// GinMiddleware records the duration of every request. Requests are labeled
+// with the route template, not the path, so IDs in paths don't blow up the
+// number of series.
+func GinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		httpRequestDuration.WithLabelValues(
+			c.Request.Method,
+			route,
+			strconv.Itoa(c.Writer.Status()),
+		).Observe(time.Since(start).Seconds())
+	}
+}
+
+// CountRateLimited wraps a rate limiting middleware and counts the requests it
+// rejects. A rejection is a request the limiter aborted with 429; other 429s,
+// like deployment quotas, don't abort the chain and aren't counted.
+func CountRateLimited(limiter string, rateLimit gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rateLimit(c)
+
+		if c.IsAborted() && c.Writer.Status() == http.StatusTooManyRequests {
+			rateLimitedRequests.WithLabelValues(limiter).Inc()
+		}
+	}
+}
+
+// RecordAutoDestroyPoll records the outcome of one auto destroy poll.
+func RecordAutoDestroyPoll(scanned int, sent int, failed int) {
+	autoDestroyDeploymentsScanned.Set(float64(scanned))
+	autoDestroyRequestsSent.Set(float64(sent))
+	autoDestroyFailures.Set(float64(failed))
+	autoDestroyLastPoll.SetToCurrentTime()
+}
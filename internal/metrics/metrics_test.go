@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCacheKeyFamily(t *testing.T) {
+	tests := map[string]string{
+		"deployment-user@example.com-default-sub":                  "deployment",
+		"deployment-user@example.com-deployments":                  "user_deployments",
+		"deployment-expiry-warning-user-sub-default-1700000000-30": "deployment_expiry_warning",
+		"deployment-lifespan-user-2026-W01":                        "deployment_lifespan",
+		"labWithVersions-readinesslab-lab.json":                    "lab_versions",
+		"lab-readinesslab-lab.json":                                "lab",
+		"blobs-publiclab":                                          "lab_blobs",
+		"something-else":                                           "other",
+	}
+
+	for key, want := range tests {
+		if got := cacheKeyFamily(key); got != want {
+			t.Errorf("cacheKeyFamily(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestCountRateLimited(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(CountRateLimited("test", func(c *gin.Context) {
+		if c.GetHeader("x-limited") != "" {
+			c.AbortWithStatus(http.StatusTooManyRequests)
+		}
+	}))
+	router.GET("/limited", func(c *gin.Context) {
+		// Quotas answer 429 without aborting and aren't rate limiting.
+		c.Status(http.StatusTooManyRequests)
+	})
+
+	before := testutil.ToFloat64(rateLimitedRequests.WithLabelValues("test"))
+
+	for _, limited := range []bool{true, false} {
+		req := httptest.NewRequest(http.MethodGet, "/limited", nil)
+		if limited {
+			req.Header.Set("x-limited", "true")
+		}
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if got := testutil.ToFloat64(rateLimitedRequests.WithLabelValues("test")) - before; got != 1 {
+		t.Errorf("expected 1 rate limited request, got %v", got)
+	}
+}
+
+func TestHandlerServesRouteTemplates(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(GinMiddleware())
+	router.GET("/lab/:labId", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/metrics", gin.WrapH(Handler()))
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/lab/1234", nil))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body := w.Body.String()
+	if !strings.Contains(body, `route="/lab/:labId"`) {
+		t.Errorf("expected requests labeled by route template, got:\n%s", body)
+	}
+}
@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+)
+
+var cacheRequests = factory.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "cache_requests_total",
+	Help:      "Redis cache reads by key family and result (hit or miss).",
+}, []string{"family", "result"})
+
+// Key families by key prefix. Longer prefixes come first so they win over the
+// shorter ones they start with.
+var cacheKeyPrefixes = []struct {
+	prefix string
+	family string
+}{
+	{"deployment-expiry-warning-", "deployment_expiry_warning"},
+	{"deployment-lifespan-", "deployment_lifespan"},
+	{"deployment-", "deployment"},
+	{"labWithVersions-", "lab_versions"},
+	{"lab-", "lab"},
+	{"blobs-", "lab_blobs"},
+	{"server-verification-results", "server_verification"},
+	{"runtime-settings", "runtime_settings"},
+	{"api_key_ratelimit", "ratelimit"},
+	{"ratelimit", "ratelimit"},
+}
+
+// RedisHook counts cache hits and misses of GET, HGET and HGETALL.
+type RedisHook struct{}
+
+func (RedisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (RedisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		err := next(ctx, cmd)
+		recordCacheRead(cmd, err)
+		return err
+	}
+}
+
+func (RedisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		err := next(ctx, cmds)
+		for _, cmd := range cmds {
+			recordCacheRead(cmd, cmd.Err())
+		}
+		return err
+	}
+}
+
+func recordCacheRead(cmd redis.Cmder, err error) {
+	args := cmd.Args()
+	if len(args) < 2 {
+		return
+	}
+
+	var hit bool
+	switch cmd.Name() {
+	case "get", "hget":
+		if err != nil && err != redis.Nil {
+			return
+		}
+		hit = err == nil
+	case "hgetall":
+		if err != nil {
+			return
+		}
+		values, ok := cmd.(*redis.MapStringStringCmd)
+		hit = ok && len(values.Val()) > 0
+	default:
+		return
+	}
+
+	key, _ := args[1].(string)
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+
+	cacheRequests.WithLabelValues(cacheKeyFamily(key), result).Inc()
+}
+
+func cacheKeyFamily(key string) string {
+	// A user's deployments are keyed "<user>-deployments", and user IDs can
+	// start with anything.
+	if strings.HasSuffix(key, "-deployments") {
+		return "user_deployments"
+	}
+
+	for _, p := range cacheKeyPrefixes {
+		if strings.HasPrefix(key, p.prefix) {
+			return p.family
+		}
+	}
+
+	return "other"
+}
@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"actlabs-hub/internal/helper"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	storageRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "storage_request_duration_seconds",
+		Help:      "Duration of Azure Table and Blob requests by repository method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"storage", "method"})
+
+	storageErrors = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "storage_errors_total",
+		Help:      "Failed Azure Table and Blob requests by repository method and status code.",
+	}, []string{"storage", "method", "code"})
+)
+
+// StoragePolicy returns an Azure SDK policy that records every request the
+// client sends. storage is "table" or "blob".
+//
+// Requests are labeled with the exported repository method that sent them,
+// found on the call stack, so repositories don't have to label each call.
+// Requests sent from other goroutines, like parallel blob uploads, are
+// labeled "unknown".
+func StoragePolicy(storage string) policy.Policy {
+	return storagePolicy{storage: storage}
+}
+
+type storagePolicy struct {
+	storage string
+}
+
+func (p storagePolicy) Do(req *policy.Request) (*http.Response, error) {
+	method := helper.RepositoryMethod()
+	start := time.Now()
+
+	resp, err := req.Next()
+
+	storageRequestDuration.WithLabelValues(p.storage, method).Observe(time.Since(start).Seconds())
+
+	switch {
+	case err != nil:
+		storageErrors.WithLabelValues(p.storage, method, "transport").Inc()
+	case resp.StatusCode >= http.StatusBadRequest:
+		storageErrors.WithLabelValues(p.storage, method, strconv.Itoa(resp.StatusCode)).Inc()
+	}
+
+	return resp, err
+}
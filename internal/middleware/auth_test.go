@@ -0,0 +1,205 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"actlabs-hub/internal/auth"
+	"actlabs-hub/internal/config"
+	"actlabs-hub/internal/fakemise"
+	"actlabs-hub/internal/logger"
+	"actlabs-hub/internal/mise"
+	"actlabs-hub/internal/miseadapter"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testAudience = "api://actlabs-hub-test"
+	testIssuer   = "https://login.example.com/test-tenant/v2.0"
+)
+
+// setupMISERouter wires middleware.Auth in MISE mode against an in-process
+// fake MISE container. The handler echoes the authenticated principal.
+func setupMISERouter(t *testing.T) (*gin.Engine, *fakemise.Server) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	fake, err := fakemise.NewWithGeneratedKey(fakemise.Options{
+		Audience: testAudience,
+		Issuer:   testIssuer,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	miseContainer := httptest.NewServer(fake)
+	t.Cleanup(miseContainer.Close)
+
+	miseServer := mise.Server{
+		ContainerClient: miseadapter.NewMISEAdapter(context.Background(), miseContainer.Client(), miseContainer.URL+"/ValidateRequest"),
+	}
+
+	authenticator, err := auth.NewAuthenticatorChain(&config.Config{
+		AuthAuthenticators: []string{auth.AuthMethodMISE},
+	}, miseServer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	router.Use(ErrorHandler())
+	router.Use(Auth(authenticator))
+	router.GET("/whoami", func(c *gin.Context) {
+		principal, _ := auth.GetPrincipal(c.Request.Context())
+		c.JSON(http.StatusOK, gin.H{
+			"userId":     logger.GetUserID(c.Request.Context()),
+			"tenantId":   principal.TenantId,
+			"authMethod": principal.AuthMethod,
+		})
+	})
+
+	return router, fake
+}
+
+func TestAuthMISE(t *testing.T) {
+	router, fake := setupMISERouter(t)
+
+	validToken, err := fake.SignToken(jwt.MapClaims{
+		"preferred_username": "alice@contoso.com",
+		"tid":                "test-tenant",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expiredToken, err := fake.SignToken(jwt.MapClaims{
+		"preferred_username": "alice@contoso.com",
+		"exp":                time.Now().Add(-time.Hour).Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wrongAudienceToken, err := fake.SignToken(jwt.MapClaims{
+		"preferred_username": "alice@contoso.com",
+		"aud":                "api://someone-else",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	noUsernameToken, err := fake.SignToken(jwt.MapClaims{"tid": "test-tenant"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		authHeader string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "valid token",
+			authHeader: "Bearer " + validToken,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"authMethod":"mise","tenantId":"test-tenant","userId":"alice@contoso.com"}`,
+		},
+		{
+			name:       "no authorization header",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "not a bearer token",
+			authHeader: "Basic YWxpY2U6c2VjcmV0",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "tampered token",
+			authHeader: "Bearer " + validToken[:len(validToken)-4] + "abcd",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "expired token",
+			authHeader: "Bearer " + expiredToken,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "wrong audience",
+			authHeader: "Bearer " + wrongAudienceToken,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "missing preferred_username",
+			authHeader: "Bearer " + noUsernameToken,
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+			if tt.authHeader != "" {
+				req.Header.Set("Authorization", tt.authHeader)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("expected body %s, got %s", tt.wantBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthMISEConfiguredResponses(t *testing.T) {
+	router, fake := setupMISERouter(t)
+
+	token, err := fake.SignToken(jwt.MapClaims{"preferred_username": "alice@contoso.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("claims override", func(t *testing.T) {
+		fake.SetSubjectClaims(map[string][]string{
+			"preferred_username": {"bob@fabrikam.com"},
+			"tid":                {"fabrikam-tenant"},
+		})
+		defer fake.SetSubjectClaims(nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		want := `{"authMethod":"mise","tenantId":"fabrikam-tenant","userId":"bob@fabrikam.com"}`
+		if w.Code != http.StatusOK || w.Body.String() != want {
+			t.Fatalf("expected 200 %s, got %d %s", want, w.Code, w.Body.String())
+		}
+	})
+
+	for _, statusCode := range []int{http.StatusForbidden, http.StatusInternalServerError} {
+		t.Run(http.StatusText(statusCode), func(t *testing.T) {
+			fake.SetStatusCode(statusCode)
+			defer fake.SetStatusCode(0)
+
+			req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusUnauthorized {
+				t.Fatalf("expected 401, got %d: %s", w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -1,7 +1,6 @@
 package middleware
 
 import (
-	"actlabs-hub/internal/auth"
 	"actlabs-hub/internal/entity"
 	"actlabs-hub/internal/helper"
 	"bytes"
@@ -28,22 +27,16 @@ func ChallengeMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Get the auth token from the request header
-		authToken := c.GetHeader("Authorization")
-
-		// Remove Bearer from the authToken
-		authToken = strings.Split(authToken, "Bearer ")[1]
-
-		callingUserPrincipal, err := auth.GetUserPrincipalFromToken(c.Request.Context(), authToken)
+		callingUserPrincipal, err := getCallingUserPrincipal(c)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			AbortWithError(c, err)
 			return
 		}
 
 		//get challenge from the payload
 		challenges := []entity.Challenge{}
-		if err := c.Bind(&challenges); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		if err := c.ShouldBind(&challenges); err != nil {
+			AbortWithError(c, entity.NewValidationError("invalid_request_body", err.Error()))
 			return
 		}
 
@@ -61,7 +54,7 @@ func ChallengeMiddleware() gin.HandlerFunc {
 		// update request payload
 		marshaledChallenges, err := json.Marshal(updatedChallenges)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			AbortWithError(c, err)
 			return
 		}
 
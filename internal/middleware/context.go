@@ -2,11 +2,19 @@ package middleware
 
 import (
 	"context"
+	"net/http"
 
+	"actlabs-hub/internal/auth"
+	"actlabs-hub/internal/entity"
 	"actlabs-hub/internal/logger"
+	"actlabs-hub/internal/tracing"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // GetContextFromGin extracts context from Gin context
@@ -20,27 +28,56 @@ func SetUserIDInGin(c *gin.Context, userID string) {
 	c.Request = c.Request.WithContext(ctx)
 }
 
-// ContextMiddleware adds trace ID and other context data to requests
+// SetPrincipalInGin stores the authenticated principal, and its user ID, in the
+// request context
+func SetPrincipalInGin(c *gin.Context, principal entity.Principal) {
+	ctx := auth.WithPrincipal(c.Request.Context(), principal)
+	c.Request = c.Request.WithContext(ctx)
+	SetUserIDInGin(c, principal.UserId)
+}
+
+// ContextMiddleware starts the request's span and adds its trace ID and other
+// context data to the request. A W3C traceparent header continues the
+// caller's trace, otherwise a new trace starts.
 func ContextMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Check for existing X-Trace-ID header, otherwise generate new one
-		traceID := c.GetHeader("X-Trace-ID")
-		if _, err := uuid.Parse(traceID); err != nil {
-			traceID = uuid.New().String()
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		// Routes are matched before middleware runs, so the span is named
+		// after the route template rather than the path.
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
 		}
 
-		// Get or create context
-		ctx := c.Request.Context()
+		ctx, span := tracing.Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Request.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(c.Request.URL.Path),
+				semconv.ClientAddress(c.ClientIP()),
+			),
+		)
+		defer span.End()
 
-		// Add trace ID to context
-		ctx = context.WithValue(ctx, logger.TraceIDKey, traceID)
+		// Logs carry the span's trace ID, so they line up with the trace.
+		traceID := tracing.TraceID(ctx)
+		ctx = logger.WithTraceID(ctx, traceID)
 
 		// Update request with new context
 		c.Request = c.Request.WithContext(ctx)
 
-		// Set trace ID header for downstream services
+		// Hand the trace back to the caller
 		c.Header("X-Trace-ID", traceID)
+		otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(c.Writer.Header()))
 
 		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
 	}
 }
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"actlabs-hub/internal/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestContextMiddlewareContinuesTraceparent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var loggedTraceID string
+	router := gin.New()
+	router.Use(ContextMiddleware())
+	router.GET("/lab/protected/:typeOfLab", func(c *gin.Context) {
+		loggedTraceID = logger.GetTraceID(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/lab/protected/readinesslab", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if loggedTraceID != traceID || w.Header().Get("X-Trace-ID") != traceID {
+		t.Errorf("expected trace %s to continue, got %q in logs and %q in the response", traceID, loggedTraceID, w.Header().Get("X-Trace-ID"))
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	if spans[0].Name() != "GET /lab/protected/:typeOfLab" {
+		t.Errorf("expected span named after the route, got %q", spans[0].Name())
+	}
+	if spans[0].Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("expected span to be a child of the caller's span, got parent %s", spans[0].Parent().SpanID())
+	}
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"actlabs-hub/internal/entity"
+	"actlabs-hub/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProblemTypePrefix prefixes the error code in a problem's type.
+const ProblemTypePrefix = "urn:actlabs-hub:problem:"
+
+// ProblemVersion is the version of the problem contract, sent in every
+// problem. It's bumped when a field or code is removed or changes meaning,
+// adding them doesn't bump it.
+const ProblemVersion = 1
+
+// Problem is an RFC 7807 problem details response.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code"`
+	TraceId  string `json:"traceId,omitempty"`
+	Version  int    `json:"version"`
+	// Same as Detail, for clients that still read {"error": "..."}. It goes
+	// away in version 2.
+	Error string                     `json:"error"`
+	Quota *entity.QuotaExceededError `json:"quota,omitempty"`
+}
+
+var errorKindStatus = map[entity.ErrorKind]int{
+	entity.ErrorKindNotFound:             http.StatusNotFound,
+	entity.ErrorKindUnauthenticated:      http.StatusUnauthorized,
+	entity.ErrorKindForbidden:            http.StatusForbidden,
+	entity.ErrorKindConflict:             http.StatusConflict,
+	entity.ErrorKindValidation:           http.StatusBadRequest,
+	entity.ErrorKindTooLarge:             http.StatusRequestEntityTooLarge,
+	entity.ErrorKindUnsupportedMediaType: http.StatusUnsupportedMediaType,
+	entity.ErrorKindUpstream:             http.StatusServiceUnavailable,
+}
+
+// ErrorHandler writes the last error a handler added with c.Error as
+// application/problem+json, unless the handler already wrote a response.
+// Errors that aren't an *entity.Error are a 500.
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		err := c.Errors.Last().Err
+		problem := NewProblem(err)
+		problem.Instance = c.Request.URL.Path
+		problem.TraceId = logger.GetTraceID(c.Request.Context())
+
+		if problem.Status >= http.StatusInternalServerError {
+			logger.LogError(c.Request.Context(), "request failed",
+				"code", problem.Code,
+				"error", err,
+				"cause", errors.Unwrap(err),
+			)
+		}
+
+		c.Header("Content-Type", "application/problem+json")
+		c.JSON(problem.Status, problem)
+	}
+}
+
+// NewProblem describes err as a problem, without the request specific
+// instance and trace ID.
+func NewProblem(err error) Problem {
+	problem := Problem{
+		Status:  http.StatusInternalServerError,
+		Code:    "internal_error",
+		Detail:  err.Error(),
+		Version: ProblemVersion,
+		Error:   err.Error(),
+	}
+
+	var domainErr *entity.Error
+	var quotaErr *entity.QuotaExceededError
+	switch {
+	case errors.As(err, &quotaErr):
+		problem.Status = http.StatusTooManyRequests
+		problem.Code = "quota_exceeded"
+		problem.Quota = quotaErr
+	case errors.As(err, &domainErr):
+		if status, ok := errorKindStatus[domainErr.Kind]; ok {
+			problem.Status = status
+		}
+		problem.Code = domainErr.Code
+	}
+
+	problem.Type = ProblemTypePrefix + problem.Code
+	problem.Title = http.StatusText(problem.Status)
+	return problem
+}
+
+// AbortWithError stops the chain and leaves err for ErrorHandler to write.
+func AbortWithError(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"actlabs-hub/internal/entity"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestErrorHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(ContextMiddleware())
+	router.Use(ErrorHandler())
+	router.GET("/typed", func(c *gin.Context) {
+		AbortWithError(c, fmt.Errorf("%w: lab-1", entity.NewNotFoundError("lab_not_found", "lab not found")))
+	})
+	router.GET("/storage", func(c *gin.Context) {
+		AbortWithError(c, entity.NewStorageError("not able to get labs", errors.New("dial tcp: connection refused")))
+	})
+	router.GET("/untyped", func(c *gin.Context) {
+		AbortWithError(c, errors.New("something broke"))
+	})
+	router.GET("/quota", func(c *gin.Context) {
+		AbortWithError(c, &entity.QuotaExceededError{Limit: entity.QuotaLimitWorkspaces, Max: 2, Current: 2, Requested: 1})
+	})
+	router.GET("/written", func(c *gin.Context) {
+		_ = c.Error(errors.New("already handled"))
+		c.String(http.StatusTeapot, "teapot")
+	})
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantCode   string
+		wantDetail string
+	}{
+		{"/typed", http.StatusNotFound, "lab_not_found", "lab not found: lab-1"},
+		// The cause is logged, not sent.
+		{"/storage", http.StatusServiceUnavailable, "storage_unavailable", "not able to get labs"},
+		{"/untyped", http.StatusInternalServerError, "internal_error", "something broke"},
+		{"/quota", http.StatusTooManyRequests, "quota_exceeded", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if contentType := w.Header().Get("Content-Type"); contentType != "application/problem+json" {
+				t.Errorf("expected problem+json, got %s", contentType)
+			}
+
+			problem := Problem{}
+			if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+				t.Fatal(err)
+			}
+			if problem.Version != ProblemVersion {
+				t.Errorf("expected version %d, got %d", ProblemVersion, problem.Version)
+			}
+			if problem.Code != tt.wantCode || problem.Type != ProblemTypePrefix+tt.wantCode {
+				t.Errorf("expected code %s, got %+v", tt.wantCode, problem)
+			}
+			if tt.wantDetail != "" && (problem.Detail != tt.wantDetail || problem.Error != tt.wantDetail) {
+				t.Errorf("expected detail %q, got %+v", tt.wantDetail, problem)
+			}
+			if problem.Status != tt.wantStatus || problem.Instance != tt.path {
+				t.Errorf("expected status %d and instance %s, got %+v", tt.wantStatus, tt.path, problem)
+			}
+			if problem.TraceId == "" || problem.TraceId != w.Header().Get("X-Trace-ID") {
+				t.Errorf("expected trace id %s, got %s", w.Header().Get("X-Trace-ID"), problem.TraceId)
+			}
+			if tt.wantCode == "quota_exceeded" && (problem.Quota == nil || problem.Quota.Limit != entity.QuotaLimitWorkspaces) {
+				t.Errorf("expected the quota that was hit, got %+v", problem.Quota)
+			}
+		})
+	}
+
+	t.Run("response already written", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/written", nil))
+
+		if w.Code != http.StatusTeapot || w.Body.String() != "teapot" {
+			t.Errorf("expected the handler's response, got %d %s", w.Code, w.Body.String())
+		}
+	})
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InFlight counts the requests being handled, so shutdown can report how many
+// it abandoned.
+type InFlight struct {
+	count atomic.Int64
+}
+
+func (i *InFlight) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		i.count.Add(1)
+		defer i.count.Add(-1)
+
+		c.Next()
+	}
+}
+
+func (i *InFlight) Count() int64 {
+	return i.count.Load()
+}
@@ -1,7 +1,6 @@
 package middleware
 
 import (
-	"actlabs-hub/internal/auth"
 	"actlabs-hub/internal/entity"
 	"actlabs-hub/internal/helper"
 	"bytes"
@@ -28,22 +27,16 @@ func UpdateCredits() gin.HandlerFunc {
 			return
 		}
 
-		// Get the auth token from the request header
-		authToken := c.GetHeader("Authorization")
-
-		// Remove Bearer from the authToken
-		authToken = strings.Split(authToken, "Bearer ")[1]
-
-		callingUserPrincipal, err := auth.GetUserPrincipalFromToken(c.Request.Context(), authToken)
+		callingUserPrincipal, err := getCallingUserPrincipal(c)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			AbortWithError(c, err)
 			return
 		}
 
 		// get lab from the payload
 		lab := entity.LabType{}
-		if err := c.Bind(&lab); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		if err := c.ShouldBind(&lab); err != nil {
+			AbortWithError(c, entity.NewValidationError("invalid_request_body", err.Error()))
 			return
 		}
 
@@ -59,7 +52,7 @@ func UpdateCredits() gin.HandlerFunc {
 		// update request payload
 		marshaledLab, err := json.Marshal(lab)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			AbortWithError(c, err)
 			return
 		}
 
@@ -34,6 +34,8 @@ func DefaultLoggingConfig() LoggingConfig {
 			"/status",
 			"/health",
 			"/healthz",
+			"/readyz",
+			"/livez",
 			"/ping",
 			"/metrics",
 			"/server",
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Reloadable runs the handler last given to Set, so middleware built from the
+// runtime settings can be rebuilt when they change. Requests pass straight
+// through until a handler is set.
+type Reloadable struct {
+	handler atomic.Pointer[gin.HandlerFunc]
+}
+
+func (r *Reloadable) Set(handler gin.HandlerFunc) {
+	r.handler.Store(&handler)
+}
+
+func (r *Reloadable) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		handler := r.handler.Load()
+		if handler == nil {
+			c.Next()
+			return
+		}
+
+		(*handler)(c)
+	}
+}
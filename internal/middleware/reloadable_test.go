@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestReloadableRunsLatestHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	reloadable := &Reloadable{}
+	router := gin.New()
+	router.Use(reloadable.Middleware())
+	router.GET("/", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	status := func() int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		return w.Code
+	}
+
+	if got := status(); got != http.StatusOK {
+		t.Errorf("expected requests to pass through before a handler is set, got %d", got)
+	}
+
+	reloadable.Set(func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusTooManyRequests)
+	})
+	if got := status(); got != http.StatusTooManyRequests {
+		t.Errorf("expected the set handler to run, got %d", got)
+	}
+
+	reloadable.Set(func(c *gin.Context) {
+		c.Next()
+	})
+	if got := status(); got != http.StatusOK {
+		t.Errorf("expected the replaced handler to run, got %d", got)
+	}
+}
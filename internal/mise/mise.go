@@ -9,6 +9,7 @@ import (
 
 	"actlabs-hub/internal/logger"
 	"actlabs-hub/internal/miseadapter"
+	"actlabs-hub/internal/tracing"
 )
 
 type Server struct {
@@ -26,7 +27,10 @@ func (e *ErrTokenValidation) Error() string {
 	return fmt.Sprintf("StatusCode: %d, ErrorDescription: %v, WWWAuthenticate: %v", e.StatusCode, e.ErrorDescription, e.WWWAuthenticate)
 }
 
-func (s Server) DelegateAuthToContainer(ctx context.Context, authHeader, uri, method, ipAddr string) (miseadapter.Result, error) {
+func (s Server) DelegateAuthToContainer(ctx context.Context, authHeader, uri, method, ipAddr string) (result miseadapter.Result, err error) {
+	ctx, span := tracing.Start(ctx, "mise.ValidateRequest")
+	defer func() { tracing.End(span, err) }()
+
 	if s.VerboseLogging {
 		logger.LogDebug(ctx, "delegating auth to mise container",
 			"url", uri,
@@ -41,7 +45,7 @@ func (s Server) DelegateAuthToContainer(ctx context.Context, authHeader, uri, me
 	// This ensures the external call can complete even if the request is cancelled
 	bgCtx := context.WithoutCancel(ctx)
 
-	result, err := s.ContainerClient.ValidateRequest(bgCtx, miseadapter.Input{
+	result, err = s.ContainerClient.ValidateRequest(bgCtx, miseadapter.Input{
 		OriginalUri:    uri,
 		OriginalMethod: method,
 
@@ -51,7 +55,7 @@ func (s Server) DelegateAuthToContainer(ctx context.Context, authHeader, uri, me
 		// Replace SubjectClaimsToReturn with ReturnAllSubjectClaims
 		// to return all claims in the subject token instead of just an allow list.
 		//ReturnAllSubjectClaims: true,
-		SubjectClaimsToReturn: []string{"preferred_username"},
+		SubjectClaimsToReturn: []string{"preferred_username", "tid", "oid"},
 	})
 
 	end := time.Now()
@@ -0,0 +1,94 @@
+// Package notifier holds the ways the hub can reach users directly, selected
+// with ACTLABS_HUB_NOTIFIER.
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"actlabs-hub/internal/config"
+	"actlabs-hub/internal/entity"
+	"actlabs-hub/internal/logger"
+)
+
+const (
+	NotifierLog     = "log"
+	NotifierWebhook = "webhook"
+)
+
+func NewNotifier(appConfig *config.Config) (entity.Notifier, error) {
+	switch appConfig.ActlabsHubNotifier {
+	case "", NotifierLog:
+		return &LogNotifier{}, nil
+	case NotifierWebhook:
+		if appConfig.ActlabsHubNotifierWebhookURL == "" {
+			return nil, fmt.Errorf("ACTLABS_HUB_NOTIFIER_WEBHOOK_URL is required for the webhook notifier")
+		}
+		return &WebhookNotifier{
+			URL:    appConfig.ActlabsHubNotifierWebhookURL,
+			Client: &http.Client{Timeout: 10 * time.Second},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown notifier %q", appConfig.ActlabsHubNotifier)
+	}
+}
+
+// LogNotifier only logs notifications. It's the default until a delivery
+// channel is configured.
+type LogNotifier struct{}
+
+func (l *LogNotifier) Notify(ctx context.Context, notification entity.Notification) error {
+	logger.LogInfo(ctx, "notification",
+		"requested_user_id", notification.UserId,
+		"reason", notification.Reason,
+		"subject", notification.Subject,
+		"message", notification.Message,
+	)
+	return nil
+}
+
+// WebhookNotifier posts notifications as JSON to URL, for example a Logic App
+// or Power Automate flow that sends the email or Teams message.
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+func (w *WebhookNotifier) Notify(ctx context.Context, notification entity.Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		logger.LogError(ctx, "failed to send notification to webhook",
+			"requested_user_id", notification.UserId,
+			"reason", notification.Reason,
+			"error", err,
+		)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		logger.LogError(ctx, "notification webhook returned an error",
+			"requested_user_id", notification.UserId,
+			"reason", notification.Reason,
+			"status_code", resp.StatusCode,
+		)
+		return fmt.Errorf("notification webhook returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,328 @@
+// Package openapi describes the hub's routes as an OpenAPI 3 document and
+// validates request bodies against it.
+//
+// The description of each route lives in routes.go, keyed by method and path.
+// Which routes exist and how they are authenticated comes from the router
+// itself: main wraps each group of handler constructors in RouteTable.Register
+// with the access the group's middleware enforces.
+package openapi
+
+import (
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Description  string `json:"description,omitempty"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Name         string `json:"name,omitempty"`
+	In           string `json:"in,omitempty"`
+}
+
+// PathItem is the operations on a path, keyed by lower case method.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationId string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security"`
+	// RequiredRole is the hub role the caller's profile must have.
+	RequiredRole string `json:"x-required-role,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// Access is how a group of routes is authenticated and authorized.
+type Access struct {
+	// Security names the security schemes any one of which is accepted.
+	Security []string
+	// Role is the role required on top of authentication, if any.
+	Role string
+}
+
+var (
+	// Public routes need no credentials.
+	Public = Access{}
+	// User routes need a bearer token for the calling user.
+	User = Access{Security: []string{"bearer"}}
+	// APIKey routes are called by actlabs-server on behalf of a user.
+	APIKey = Access{Security: []string{"apiKey"}}
+)
+
+// Role is User access that also requires role.
+func Role(role string) Access {
+	return Access{Security: User.Security, Role: role}
+}
+
+// RouteTable records the access of the routes registered on an engine.
+type RouteTable struct {
+	engine *gin.Engine
+	access map[string]Access
+}
+
+func NewRouteTable(engine *gin.Engine) *RouteTable {
+	return &RouteTable{
+		engine: engine,
+		access: map[string]Access{},
+	}
+}
+
+// Register calls register and records access for the routes it added.
+func (t *RouteTable) Register(access Access, register func()) {
+	before := map[string]bool{}
+	for _, route := range t.engine.Routes() {
+		before[routeKey(route.Method, route.Path)] = true
+	}
+
+	register()
+
+	for _, route := range t.engine.Routes() {
+		if key := routeKey(route.Method, route.Path); !before[key] {
+			t.access[key] = access
+		}
+	}
+}
+
+// Undocumented lists the engine's routes missing from the spec, either
+// because routes.go doesn't describe them or they weren't registered through
+// Register.
+func (t *RouteTable) Undocumented() []string {
+	undocumented := []string{}
+	for _, route := range t.engine.Routes() {
+		key := routeKey(route.Method, route.Path)
+		_, described := routes[key]
+		_, registered := t.access[key]
+		if !described || !registered {
+			undocumented = append(undocumented, key)
+		}
+	}
+	sort.Strings(undocumented)
+	return undocumented
+}
+
+// Document describes every documented route on the engine.
+func (t *RouteTable) Document() Document {
+	document := Document{
+		OpenAPI: "3.0.3",
+		Info: Info{
+			Title:       "ACT Labs Hub",
+			Description: "Labs, assignments, challenges, deployments and servers for ACT Labs. Errors are application/problem+json, with the version of their contract in version.",
+			Version:     "1.0.0",
+		},
+		Paths: map[string]PathItem{},
+		Components: Components{
+			Schemas: spec.components,
+			SecuritySchemes: map[string]*SecurityScheme{
+				"bearer": {
+					Type:         "http",
+					Scheme:       "bearer",
+					BearerFormat: "JWT",
+					Description:  "Entra ID token for the calling user, validated by MISE or against the tenant's JWKS. Local development can use the static dev token.",
+				},
+				"apiKey": {
+					Type:        "apiKey",
+					In:          "header",
+					Name:        "x-api-key",
+					Description: "Shared key used by actlabs-server. The user the call is made for goes in the x-user-id header.",
+				},
+			},
+		},
+	}
+
+	for _, r := range t.engine.Routes() {
+		key := routeKey(r.Method, r.Path)
+		route, described := routes[key]
+		access, registered := t.access[key]
+		if !described || !registered {
+			continue
+		}
+
+		path, parameters := openAPIPath(r.Path)
+		if document.Paths[path] == nil {
+			document.Paths[path] = PathItem{}
+		}
+		document.Paths[path][strings.ToLower(r.Method)] = operation(r.Method, r.Path, route, access, parameters)
+	}
+
+	return document
+}
+
+func operation(method string, ginPath string, route route, access Access, parameters []Parameter) *Operation {
+	op := &Operation{
+		OperationId:  operationId(method, ginPath),
+		Summary:      route.summary,
+		Tags:         []string{tag(ginPath)},
+		Parameters:   slices.Concat(parameters, route.query),
+		Responses:    map[string]*Response{},
+		Security:     []map[string][]string{},
+		RequiredRole: access.Role,
+	}
+	if route.list != nil {
+		op.Parameters = append(op.Parameters, listQuery...)
+	}
+
+	for _, scheme := range access.Security {
+		op.Security = append(op.Security, map[string][]string{scheme: {}})
+		if scheme == "apiKey" {
+			op.Parameters = append(op.Parameters, Parameter{
+				Name:        "x-user-id",
+				In:          "header",
+				Description: "User principal name the call is made for.",
+				Required:    true,
+				Schema:      &Schema{Type: "string"},
+			})
+		}
+	}
+
+	key := routeKey(method, ginPath)
+	if schema, ok := spec.requestSchemas[key]; ok {
+		op.RequestBody = &RequestBody{Required: true, Content: map[string]*MediaType{"application/json": {Schema: schema}}}
+	} else if schema, ok := spec.multipartSchemas[key]; ok {
+		op.RequestBody = &RequestBody{Required: true, Content: map[string]*MediaType{"multipart/form-data": {Schema: schema}}}
+	}
+
+	status := route.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := &Response{Description: http.StatusText(status)}
+	switch {
+	case strings.HasPrefix(route.contentType, "text/"):
+		success.Content = map[string]*MediaType{route.contentType: {Schema: &Schema{Type: "string"}}}
+	case route.contentType != "":
+		success.Content = map[string]*MediaType{route.contentType: {Schema: &Schema{Type: "string", Format: "binary"}}}
+	case spec.responseSchemas[key] != nil:
+		success.Content = map[string]*MediaType{"application/json": {Schema: spec.responseSchemas[key]}}
+	}
+	op.Responses[statusCode(status)] = success
+
+	problem := map[string]*MediaType{"application/problem+json": {Schema: spec.problem}}
+	if len(access.Security) > 0 {
+		op.Responses[statusCode(http.StatusUnauthorized)] = &Response{Description: http.StatusText(http.StatusUnauthorized), Content: problem}
+	}
+	if access.Role != "" {
+		op.Responses[statusCode(http.StatusForbidden)] = &Response{Description: http.StatusText(http.StatusForbidden), Content: problem}
+	}
+	op.Responses["default"] = &Response{Description: "Error", Content: problem}
+
+	return op
+}
+
+func routeKey(method string, path string) string {
+	return method + " " + path
+}
+
+func statusCode(status int) string {
+	return strconv.Itoa(status)
+}
+
+// openAPIPath turns gin's :param and *param into {param}.
+func openAPIPath(ginPath string) (string, []Parameter) {
+	parameters := []Parameter{}
+	segments := strings.Split(ginPath, "/")
+	for i, segment := range segments {
+		if name, ok := pathParam(segment); ok {
+			segments[i] = "{" + name + "}"
+			parameters = append(parameters, Parameter{
+				Name:     name,
+				In:       "path",
+				Required: true,
+				Schema:   &Schema{Type: "string"},
+			})
+		}
+	}
+	return strings.Join(segments, "/"), parameters
+}
+
+func pathParam(segment string) (string, bool) {
+	if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+		return segment[1:], true
+	}
+	return "", false
+}
+
+// operationId is the method and the path, e.g. GET /assignment/lab/:labId
+// is getAssignmentLabByLabId.
+func operationId(method string, ginPath string) string {
+	id := strings.ToLower(method)
+	params := []string{}
+	for _, segment := range strings.Split(ginPath, "/") {
+		if name, ok := pathParam(segment); ok {
+			params = append(params, upperFirst(name))
+			continue
+		}
+		id += upperFirst(segment)
+	}
+	if len(params) > 0 {
+		id += "By" + strings.Join(params, "And")
+	}
+	return id
+}
+
+func upperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	r := []rune(s)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+// tag groups operations by the first path segment, skipping admin and arm.
+func tag(ginPath string) string {
+	segments := strings.Split(strings.Trim(ginPath, "/"), "/")
+	if len(segments) > 1 && (segments[0] == "admin" || segments[0] == "arm") {
+		return segments[1]
+	}
+	return segments[0]
+}
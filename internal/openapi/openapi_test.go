@@ -0,0 +1,149 @@
+package openapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"actlabs-hub/internal/entity"
+	"actlabs-hub/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+type testEmbedded struct {
+	Embedded string `json:"embedded"`
+}
+
+type testItem struct {
+	testEmbedded
+	Name     string            `json:"name"`
+	Count    int32             `json:"count"`
+	Ratio    float64           `json:"ratio"`
+	Enabled  bool              `json:"enabled"`
+	Tags     []string          `json:"tags"`
+	Labels   map[string]string `json:"labels"`
+	Parent   *testItem         `json:"parent"`
+	Created  time.Time         `json:"created"`
+	Quoted   int               `json:"quoted,string"`
+	Ignored  string            `json:"-"`
+	internal string
+}
+
+func TestSchemasFollowEncodingJSON(t *testing.T) {
+	s := newSchemas()
+
+	if ref := s.of(testItem{}).Ref; ref != componentPrefix+"testItem" {
+		t.Fatalf("expected a reference to testItem, got %q", ref)
+	}
+
+	item := s.components["testItem"]
+	want := map[string]string{
+		"embedded": "string",
+		"name":     "string",
+		"count":    "integer",
+		"ratio":    "number",
+		"enabled":  "boolean",
+		"tags":     "array",
+		"labels":   "object",
+		"created":  "string",
+		"quoted":   "string",
+	}
+	for name, typ := range want {
+		if property := item.Properties[name]; property == nil || property.Type != typ {
+			t.Errorf("expected %s to be %s, got %+v", name, typ, property)
+		}
+	}
+	if len(item.Properties) != len(want)+1 {
+		t.Errorf("expected %d properties, got %v", len(want)+1, item.Properties)
+	}
+
+	parent := item.Properties["parent"]
+	if !parent.Nullable || len(parent.AllOf) != 1 || parent.AllOf[0].Ref != componentPrefix+"testItem" {
+		t.Errorf("expected parent to be a nullable reference, got %+v", parent)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	s := newSchemas()
+	schema := s.of(testItem{})
+
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{"valid", `{"name":"lab","count":2,"tags":["a"],"labels":{"k":"v"},"parent":{"name":"p"},"quoted":"3"}`, ""},
+		{"null", `{"parent":null,"enabled":null}`, ""},
+		{"unknown fields", `{"other":1}`, ""},
+		{"empty", ``, "request body is required"},
+		{"not json", `{`, "not valid JSON"},
+		{"not an object", `[]`, "body must be an object, got array"},
+		{"wrong type", `{"name":1}`, "body.name must be a string, got integer"},
+		{"fraction for integer", `{"count":1.5}`, "body.count must be an integer, got number"},
+		{"nested", `{"parent":{"tags":[1]}}`, "body.parent.tags[0] must be a string"},
+		{"map value", `{"labels":{"k":true}}`, "body.labels.k must be a string, got boolean"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.validate(schema, []byte(tt.body))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.ErrorHandler(), ValidateRequest())
+
+	bound := entity.ExtendDeploymentRequest{}
+	router.POST("/deployments/:subscriptionId/:workspace/extend", func(c *gin.Context) {
+		if err := c.ShouldBind(&bound); err != nil {
+			middleware.AbortWithError(c, err)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/deployments/sub/ws/extend", strings.NewReader(`{"durationSeconds":"long"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "invalid_request_body") {
+		t.Fatalf("expected a 400 problem, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/deployments/sub/ws/extend", strings.NewReader(`{"durationSeconds":3600}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d %s", w.Code, w.Body.String())
+	}
+	if bound.DurationSeconds != 3600 {
+		t.Errorf("expected the handler to read the body, got %+v", bound)
+	}
+}
+
+func TestOperationId(t *testing.T) {
+	if id := operationId(http.MethodGet, "/assignment/lab/:labId"); id != "getAssignmentLabByLabId" {
+		t.Errorf("got %s", id)
+	}
+	if id := operationId(http.MethodDelete, "/deployments/:subscriptionId/:workspace"); id != "deleteDeploymentsBySubscriptionIdAndWorkspace" {
+		t.Errorf("got %s", id)
+	}
+}
@@ -0,0 +1,223 @@
+package openapi
+
+import (
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"actlabs-hub/internal/entity"
+	"actlabs-hub/internal/health"
+	"actlabs-hub/internal/middleware"
+)
+
+// route describes one route. request is a value of the JSON body's type,
+// multipart a struct describing the form fields, response a value of the
+// JSON response's type. list is set instead of response for list endpoints,
+// to the entity.Page they return. contentType is set for responses that
+// aren't JSON.
+type route struct {
+	summary     string
+	request     any
+	multipart   any
+	response    any
+	list        any
+	status      int
+	contentType string
+	query       []Parameter
+}
+
+// StatusResponse is returned by server operations that have nothing else to say.
+type StatusResponse struct {
+	Status string `json:"status"`
+}
+
+type ServerIdResponse struct {
+	Id string `json:"id" description:"Subscription ID of the user's server."`
+}
+
+type SupportingDocumentIdResponse struct {
+	SupportingDocumentId string `json:"supportingDocumentId"`
+}
+
+type SupportingDocumentForm struct {
+	SupportingDocument string `json:"supportingDocument" format:"binary"`
+}
+
+type LabWithSupportingDocumentForm struct {
+	Lab                string `json:"lab" description:"The lab as JSON."`
+	SupportingDocument string `json:"supportingDocument" format:"binary" description:"Optional."`
+}
+
+var (
+	labs        = []entity.LabType{}
+	assignments = []entity.Assignment{}
+	challenges  = []entity.Challenge{}
+	ids         = []string{}
+	labPage     = entity.Page[entity.LabType]{}
+)
+
+// routes describes every route the hub serves, keyed by method and gin path.
+var routes = map[string]route{
+	// health
+	"GET /healthz": {summary: "Report that the process is up"},
+	"GET /readyz":  {summary: "Check the hub's dependencies", response: health.Report{}},
+	"GET /livez":   {summary: "Check the hub is serving requests and no background loop is stuck", response: health.Report{}},
+
+	"GET /openapi.json": {summary: "This document", response: map[string]any{}},
+
+	// assignments
+	"GET /assignment/labs":                   {summary: "List labs that can be assigned, redacted", response: labs},
+	"GET /assignment/labs/my":                {summary: "List labs assigned to the calling user, redacted", response: labs},
+	"GET /assignment/my":                     {summary: "List the calling user's assignments", response: assignments},
+	"POST /assignment/my":                    {summary: "Assign labs to the calling user", request: entity.BulkAssignment{}, status: http.StatusCreated},
+	"DELETE /assignment/my":                  {summary: "Delete the calling user's assignments", request: ids, status: http.StatusNoContent},
+	"PUT /assignment/:userId/:labId/:status": {summary: "Update the status of a user's assignment"},
+	"GET /assignment":                        {summary: "List all assignments", list: entity.Page[entity.Assignment]{}},
+	"GET /assignment/lab/:labId":             {summary: "List the assignments for a lab", response: assignments},
+	"GET /assignment/user/:userId":           {summary: "List a user's assignments", response: assignments},
+	"POST /assignment":                       {summary: "Assign labs to users", request: entity.BulkAssignment{}, status: http.StatusCreated},
+	"DELETE /assignment":                     {summary: "Delete assignments", request: ids, status: http.StatusNoContent},
+
+	// challenges
+	"GET /challenge/labs":                   {summary: "List labs that can be challenges, redacted", response: labs},
+	"GET /challenge/labs/my":                {summary: "List labs the calling user is challenged with, redacted", response: labs},
+	"GET /challenge":                        {summary: "List all challenges", list: entity.Page[entity.Challenge]{}},
+	"GET /challenge/my":                     {summary: "List the calling user's challenges", response: challenges},
+	"GET /challenge/lab/:labId":             {summary: "List the challenges for a lab", response: challenges},
+	"POST /challenge":                       {summary: "Create or update challenges", request: challenges},
+	"DELETE /challenge/:challengeId":        {summary: "Delete a challenge", response: ""},
+	"PUT /challenge/:userId/:labId/:status": {summary: "Update the status of a user's challenge"},
+
+	// profiles
+	"GET /profiles/:userPrincipal":          {summary: "Get a user's profile", response: entity.Profile{}},
+	"POST /profiles":                        {summary: "Create or update the calling user's profile", request: entity.Profile{}},
+	"GET /profilesRedacted":                 {summary: "List profiles without their roles", response: []entity.Profile{}},
+	"GET /profiles":                         {summary: "List all profiles", list: entity.Page[entity.Profile]{}},
+	"POST /profiles/:userPrincipal/:role":   {summary: "Add a role to a user"},
+	"DELETE /profiles/:userPrincipal/:role": {summary: "Remove a role from a user"},
+
+	// labs
+	"GET /lab/private/:typeOfLab":                                          {summary: "List the calling user's private labs", list: labPage},
+	"POST /lab/private":                                                    {summary: "Create or update a private lab", request: entity.LabType{}, response: entity.LabType{}},
+	"DELETE /lab/private/:typeOfLab/:labId":                                {summary: "Delete a private lab", status: http.StatusNoContent},
+	"GET /lab/private/versions/:typeOfLab/:labId":                          {summary: "List the versions of a private lab", response: labs},
+	"GET /lab/private/:typeOfLab/:labId/cost":                              {summary: "Estimate the cost of a private lab", response: entity.CostEstimate{}},
+	"GET /lab/public/:typeOfLab":                                           {summary: "List public labs", list: labPage},
+	"POST /lab/public":                                                     {summary: "Create or update a public lab", request: entity.LabType{}, response: entity.LabType{}},
+	"DELETE /lab/public/:typeOfLab/:labId":                                 {summary: "Delete a public lab", status: http.StatusNoContent},
+	"GET /lab/public/versions/:typeOfLab/:labId":                           {summary: "List the versions of a public lab", response: labs},
+	"GET /lab/public/:typeOfLab/:labId/cost":                               {summary: "Estimate the cost of a public lab", response: entity.CostEstimate{}},
+	"GET /lab/protected/:typeOfLab/:labId":                                 {summary: "Get a protected or private lab for a user", response: entity.LabType{}},
+	"POST /lab/protected":                                                  {summary: "Create or update a protected lab", request: entity.LabType{}, response: entity.LabType{}},
+	"POST /lab/protected/withSupportingDocument":                           {summary: "Create or update a protected lab and its supporting document", multipart: LabWithSupportingDocumentForm{}, response: entity.LabType{}},
+	"GET /lab/protected/:typeOfLab":                                        {summary: "List protected labs", list: labPage},
+	"GET /lab/protected/versions/:typeOfLab/:labId":                        {summary: "List the versions of a protected lab", response: labs},
+	"DELETE /lab/protected/:typeOfLab/:labId":                              {summary: "Delete a protected lab", status: http.StatusNoContent},
+	"GET /lab/protected/:typeOfLab/:labId/cost":                            {summary: "Estimate the cost of a protected lab", response: entity.CostEstimate{}},
+	"POST /lab/protected/supportingDocument":                               {summary: "Upload a supporting document", multipart: SupportingDocumentForm{}, response: SupportingDocumentIdResponse{}},
+	"DELETE /lab/protected/supportingDocument/:supportingDocumentId":       {summary: "Delete a supporting document", status: http.StatusNoContent},
+	"GET /lab/protected/supportingDocument/:supportingDocumentId":          {summary: "Download a supporting document", contentType: "application/octet-stream"},
+	"GET /lab/protected/supportingDocument/:supportingDocumentId/metadata": {summary: "Get a supporting document's metadata", response: entity.SupportingDocument{}},
+
+	// deployments
+	"GET /deployments": {summary: "List the calling user's deployments", response: []entity.Deployment{}},
+	"PUT /deployments": {summary: "Create or update a deployment", request: entity.Deployment{}},
+	"DELETE /deployments/:subscriptionId/:workspace":                    {summary: "Delete a deployment", status: http.StatusNoContent},
+	"GET /deployments/:subscriptionId/:workspace/history":               {summary: "Page through a deployment's operations", response: entity.DeploymentHistory{}, query: historyQuery},
+	"POST /deployments/:subscriptionId/:workspace/extend":               {summary: "Extend a deployment's lifespan", request: entity.ExtendDeploymentRequest{}, response: entity.Deployment{}},
+	"GET /admin/deployments/:userId/:subscriptionId/:workspace/history": {summary: "Page through a user's deployment operations", response: entity.DeploymentHistory{}, query: historyQuery},
+	"GET /admin/deployments/stuck":                                      {summary: "List deployments stuck in a transitional state", response: []entity.StuckDeployment{}},
+
+	// quotas
+	"GET /deployments/quota":       {summary: "Get the calling user's deployment quota and usage", response: entity.DeploymentQuotaUsage{}},
+	"GET /admin/quotas":            {summary: "List quota overrides", response: []entity.DeploymentQuotaOverride{}},
+	"GET /admin/quotas/:userId":    {summary: "Get a user's deployment quota and usage", response: entity.DeploymentQuotaUsage{}},
+	"PUT /admin/quotas/:userId":    {summary: "Override a user's deployment quota", request: entity.DeploymentQuota{}, response: entity.DeploymentQuotaOverride{}},
+	"DELETE /admin/quotas/:userId": {summary: "Remove a user's quota override", status: http.StatusNoContent},
+
+	// runtime settings
+	"GET /admin/settings": {summary: "Get the runtime settings", response: entity.RuntimeSettings{}},
+	"PUT /admin/settings": {summary: "Change the runtime settings on every instance", request: entity.RuntimeSettings{}, response: entity.RuntimeSettings{}},
+
+	// schedules
+	"GET /schedules":                {summary: "List schedules", response: []entity.Schedule{}},
+	"GET /schedules/:scheduleId":    {summary: "Get a schedule", response: entity.Schedule{}},
+	"POST /schedules":               {summary: "Create a schedule", request: entity.Schedule{}, response: entity.Schedule{}, status: http.StatusCreated},
+	"DELETE /schedules/:scheduleId": {summary: "Delete a schedule", status: http.StatusNoContent},
+
+	// servers
+	"GET /server":                                        {summary: "Get the calling user's server", response: entity.Server{}},
+	"PUT /server/register":                               {summary: "Register the calling user's server", request: entity.Server{}, response: StatusResponse{}},
+	"PUT /server/unregister":                             {summary: "Unregister the calling user's server", response: StatusResponse{}},
+	"PUT /server/activity/:userPrincipalName":            {summary: "Record activity on the calling user's server", response: StatusResponse{}},
+	"GET /admin/servers":                                 {summary: "List all servers", list: entity.Page[entity.Server]{}},
+	"GET /admin/servers/health":                          {summary: "Check the health of every server", response: []entity.ServerHealth{}},
+	"GET /admin/servers/summary":                         {summary: "Summarize servers and deployments", response: entity.ServerSummary{}, query: summaryQuery},
+	"DELETE /admin/server/unregister/:userPrincipalName": {summary: "Unregister a user's server", response: StatusResponse{}},
+	"PUT /arm/server/register":                           {summary: "Register a user's server on their behalf", request: entity.Server{}, response: StatusResponse{}},
+	"GET /arm/server/:userPrincipalName":                 {summary: "Get the subscription of a user's server", response: ServerIdResponse{}},
+}
+
+var historyQuery = []Parameter{
+	{Name: "limit", In: "query", Description: "Operations per page, 100 by default.", Schema: &Schema{Type: "integer", Format: "int32"}},
+	{Name: "nextToken", In: "query", Description: "Token from the previous page.", Schema: &Schema{Type: "string"}},
+}
+
+// listQuery are the parameters of list endpoints. Without any of them the
+// endpoint returns every item as an array.
+var listQuery = []Parameter{
+	{Name: "limit", In: "query", Description: "Items per page, 1 to 1000, 100 by default.", Schema: &Schema{Type: "integer", Format: "int32"}},
+	{Name: "nextToken", In: "query", Description: "Token from the previous page.", Schema: &Schema{Type: "string"}},
+	{Name: "sort", In: "query", Description: "Comma separated fields to order by, - in front for descending. Sorted lists are returned in a single page, so it can't be combined with limit or nextToken.", Schema: &Schema{Type: "string"}},
+	{Name: "fields", In: "query", Description: "Comma separated fields to return.", Schema: &Schema{Type: "string"}},
+}
+
+var summaryQuery = []Parameter{
+	{Name: "idleMinutes", In: "query", Description: "Minutes without activity before a server is idle, 60 by default.", Schema: &Schema{Type: "integer", Format: "int32"}},
+	{Name: "stuckMinutes", In: "query", Description: "Minutes in a transitional state before a deployment is stuck, 30 by default.", Schema: &Schema{Type: "integer", Format: "int32"}},
+	{Name: "top", In: "query", Description: "Users to list by deployment count, 10 by default.", Schema: &Schema{Type: "integer", Format: "int32"}},
+}
+
+// catalog holds the schemas of every route, generated once so the document
+// and the validator share them.
+type catalog struct {
+	*schemas
+	requestSchemas   map[string]*Schema
+	multipartSchemas map[string]*Schema
+	responseSchemas  map[string]*Schema
+	problem          *Schema
+}
+
+var spec = newCatalog(routes)
+
+func newCatalog(routes map[string]route) *catalog {
+	c := &catalog{
+		schemas:          newSchemas(),
+		requestSchemas:   map[string]*Schema{},
+		multipartSchemas: map[string]*Schema{},
+		responseSchemas:  map[string]*Schema{},
+	}
+	c.problem = c.of(middleware.Problem{})
+
+	// Sorted so components with clashing names are named the same way every
+	// time.
+	for _, key := range slices.Sorted(maps.Keys(routes)) {
+		route := routes[key]
+		if route.request != nil {
+			c.requestSchemas[key] = c.of(route.request)
+		}
+		if route.multipart != nil {
+			c.multipartSchemas[key] = c.of(route.multipart)
+		}
+		if route.response != nil {
+			c.responseSchemas[key] = c.of(route.response)
+		}
+		if route.list != nil {
+			page := c.of(route.list)
+			items := c.components[strings.TrimPrefix(page.Ref, componentPrefix)].Properties["items"]
+			c.responseSchemas[key] = &Schema{OneOf: []*Schema{items, page}}
+		}
+	}
+	return c
+}
@@ -0,0 +1,180 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Schema is the subset of the OpenAPI 3.0 schema object the hub uses.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+const componentPrefix = "#/components/schemas/"
+
+var (
+	timeType       = reflect.TypeFor[time.Time]()
+	rawMessageType = reflect.TypeFor[json.RawMessage]()
+)
+
+// schemas generates schemas from Go types the way encoding/json marshals
+// them. Named structs become components and are referenced.
+type schemas struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+func newSchemas() *schemas {
+	return &schemas{
+		components: map[string]*Schema{},
+		names:      map[reflect.Type]string{},
+	}
+}
+
+// of is the schema of v's type, nil when v is nil.
+func (s *schemas) of(v any) *Schema {
+	if v == nil {
+		return nil
+	}
+	return s.schema(reflect.TypeOf(v))
+}
+
+func (s *schemas) schema(t reflect.Type) *Schema {
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		elem := s.schema(t.Elem())
+		if elem.Ref != "" {
+			return &Schema{Nullable: true, AllOf: []*Schema{elem}}
+		}
+		elem.Nullable = true
+		return elem
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		return s.component(t)
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.schema(t.Elem()), Nullable: true}
+	case reflect.Array:
+		return &Schema{Type: "array", Items: s.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.schema(t.Elem()), Nullable: true}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	default:
+		// Interfaces and anything else can hold any value.
+		return &Schema{}
+	}
+}
+
+func (s *schemas) component(t reflect.Type) *Schema {
+	name, ok := s.names[t]
+	if !ok {
+		name = typeName(t)
+		// Same name from another package.
+		for other, otherName := range s.names {
+			if otherName == name && other != t {
+				name = strings.ReplaceAll(t.PkgPath(), "/", ".") + "." + name
+				break
+			}
+		}
+		s.names[t] = name
+		// Registered before the fields so recursive types end.
+		s.components[name] = &Schema{}
+		*s.components[name] = *s.object(t)
+	}
+	return &Schema{Ref: componentPrefix + name}
+}
+
+// typeName is t's name, with the type arguments of generic types in front,
+// e.g. AssignmentPage for Page[Assignment].
+func typeName(t reflect.Type) string {
+	name, args, generic := strings.Cut(t.Name(), "[")
+	if !generic {
+		return name
+	}
+	prefix := ""
+	for _, arg := range strings.Split(strings.TrimSuffix(args, "]"), ",") {
+		prefix += arg[strings.LastIndex(arg, ".")+1:]
+	}
+	return prefix + name
+}
+
+func (s *schemas) object(t reflect.Type) *Schema {
+	object := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	s.addFields(object, t)
+	return object
+}
+
+func (s *schemas) addFields(object *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		// Untagged embedded structs are flattened, as encoding/json does.
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				s.addFields(object, embedded)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := s.schema(field.Type)
+		if slices.Contains(strings.Split(options, ","), "string") {
+			property = &Schema{Type: "string"}
+		}
+		if format := field.Tag.Get("format"); format != "" {
+			property.Format = format
+		}
+		if description := field.Tag.Get("description"); description != "" {
+			property.Description = description
+		}
+		object.Properties[name] = property
+	}
+}
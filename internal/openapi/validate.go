@@ -0,0 +1,157 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+
+	"actlabs-hub/internal/entity"
+	"actlabs-hub/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxValidationErrors is how many problems are reported for one body.
+const maxValidationErrors = 10
+
+// ValidateRequest rejects JSON bodies that don't match the route's request
+// schema with a 400. Only types are checked: unknown, missing and null fields
+// are left to the handler, as encoding/json does. Routes without a JSON body
+// are let through.
+func ValidateRequest() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		schema, ok := spec.requestSchemas[routeKey(c.Request.Method, c.FullPath())]
+		if !ok {
+			c.Next()
+			return
+		}
+
+		if mediaType, _, _ := mime.ParseMediaType(c.ContentType()); mediaType != "" && mediaType != "application/json" {
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			middleware.AbortWithError(c, entity.NewValidationError("invalid_request_body", "not able to read request body"))
+			return
+		}
+		// The handler binds the body again.
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		if err := spec.validate(schema, body); err != nil {
+			middleware.AbortWithError(c, entity.NewValidationError("invalid_request_body", err.Error()))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// validate checks body is JSON matching schema.
+func (s *schemas) validate(schema *Schema, body []byte) error {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return errors.New("request body is required")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("request body is not valid JSON: %w", err)
+	}
+
+	problems := []string{}
+	s.check(schema, value, "body", &problems)
+	if len(problems) == 0 {
+		return nil
+	}
+	if len(problems) > maxValidationErrors {
+		problems = append(problems[:maxValidationErrors], fmt.Sprintf("and %d more", len(problems)-maxValidationErrors))
+	}
+	return errors.New(strings.Join(problems, "; "))
+}
+
+func (s *schemas) check(schema *Schema, value any, path string, problems *[]string) {
+	if schema.Ref != "" {
+		s.check(s.components[strings.TrimPrefix(schema.Ref, componentPrefix)], value, path, problems)
+		return
+	}
+
+	// encoding/json leaves the field alone on null, so the handler sees the
+	// same thing as when it's missing.
+	if value == nil {
+		return
+	}
+
+	for _, part := range schema.AllOf {
+		s.check(part, value, path, problems)
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			*problems = append(*problems, fmt.Sprintf("%s must be an object, got %s", path, jsonType(value)))
+			return
+		}
+		for name, property := range object {
+			if propertySchema, ok := schema.Properties[name]; ok {
+				s.check(propertySchema, property, path+"."+name, problems)
+			} else if schema.AdditionalProperties != nil {
+				s.check(schema.AdditionalProperties, property, path+"."+name, problems)
+			}
+		}
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			*problems = append(*problems, fmt.Sprintf("%s must be an array, got %s", path, jsonType(value)))
+			return
+		}
+		for i, item := range array {
+			s.check(schema.Items, item, fmt.Sprintf("%s[%d]", path, i), problems)
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			*problems = append(*problems, fmt.Sprintf("%s must be a string, got %s", path, jsonType(value)))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			*problems = append(*problems, fmt.Sprintf("%s must be a boolean, got %s", path, jsonType(value)))
+		}
+	case "integer":
+		number, ok := value.(json.Number)
+		if _, err := number.Int64(); !ok || err != nil {
+			*problems = append(*problems, fmt.Sprintf("%s must be an integer, got %s", path, jsonType(value)))
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			*problems = append(*problems, fmt.Sprintf("%s must be a number, got %s", path, jsonType(value)))
+		}
+	}
+}
+
+func jsonType(value any) string {
+	switch value := value.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if _, err := value.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	default:
+		return "null"
+	}
+}
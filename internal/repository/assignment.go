@@ -32,38 +32,39 @@ func NewAssignmentRepository(
 }
 
 func (a *assignmentRepository) GetAllAssignments(ctx context.Context) ([]entity.Assignment, error) {
-	assignment := entity.Assignment{}
-	assignments := []entity.Assignment{}
+	page, err := a.ListAssignments(ctx, entity.ListQuery{})
+	return page.Items, err
+}
 
-	pager := a.auth.ActlabsReadinessTableClient.NewListEntitiesPager(nil)
-	for pager.More() {
-		response, err := pager.NextPage(ctx)
-		if err != nil {
-			logger.LogError(ctx, "Table storage query failed for all assignments",
-				"operation", "get_all_assignments",
+func (a *assignmentRepository) ListAssignments(ctx context.Context, query entity.ListQuery) (entity.Page[entity.Assignment], error) {
+	page := entity.Page[entity.Assignment]{Items: []entity.Assignment{}}
+
+	nextToken, err := listEntities(ctx, a.auth.ActlabsReadinessTableClient, "", query, nil, func(element []byte) error {
+		assignment := entity.Assignment{}
+		if err := json.Unmarshal(element, &assignment); err != nil {
+			logger.LogError(ctx, "JSON unmarshal failed for assignment entity",
+				"operation", "list_assignments",
 				"table", "actlabs_readiness",
-				"error_type", "database",
+				"error_type", "serialization",
 				"error", err.Error(),
 			)
-			return assignments, err
-		}
-
-		for _, element := range response.Entities {
-			//var myEntity aztables.EDMEntity
-			if err := json.Unmarshal(element, &assignment); err != nil {
-				logger.LogError(ctx, "JSON unmarshal failed for assignment entity",
-					"operation", "get_all_assignments",
-					"table", "actlabs_readiness",
-					"error_type", "serialization",
-					"error", err.Error(),
-				)
-				return assignments, err
-			}
-			assignments = append(assignments, assignment)
+			return err
 		}
+		page.Items = append(page.Items, assignment)
+		return nil
+	})
+	if err != nil {
+		logger.LogError(ctx, "Table storage query failed for assignments",
+			"operation", "list_assignments",
+			"table", "actlabs_readiness",
+			"error_type", "database",
+			"error", err.Error(),
+		)
+		return page, err
 	}
 
-	return assignments, nil
+	page.NextToken = nextToken
+	return page, nil
 }
 
 func (a *assignmentRepository) GetAssignmentsByLabId(ctx context.Context, labId string) ([]entity.Assignment, error) {
@@ -69,64 +69,75 @@ func (r *AuthRepository) GetProfile(ctx context.Context, userPrincipal string) (
 	return profile, nil
 }
 
+// profileColumns maps Profile's JSON fields to ProfileRecord's properties.
+var profileColumns = map[string]string{
+	"objectId":      "ObjectId",
+	"userPrincipal": "UserPrincipal",
+	"displayName":   "DisplayName",
+	"profilePhoto":  "ProfilePhoto",
+	"roles":         "Roles",
+}
+
 func (r *AuthRepository) GetAllProfiles(ctx context.Context) ([]entity.Profile, error) {
-	profile := entity.Profile{}
-	profiles := []entity.Profile{}
+	page, err := r.ListProfiles(ctx, entity.ListQuery{})
+	return page.Items, err
+}
 
-	pager := r.auth.ActlabsProfilesTableClient.NewListEntitiesPager(nil)
-	for pager.More() {
-		response, err := pager.NextPage(ctx)
-		if err != nil {
-			logger.LogError(ctx, "failed to get entities from table storage",
+func (r *AuthRepository) ListProfiles(ctx context.Context, query entity.ListQuery) (entity.Page[entity.Profile], error) {
+	page := entity.Page[entity.Profile]{Items: []entity.Profile{}}
+
+	nextToken, err := listEntities(ctx, r.auth.ActlabsProfilesTableClient, "", query, profileColumns, func(e []byte) error {
+		var myEntity aztables.EDMEntity
+		if err := json.Unmarshal(e, &myEntity); err != nil {
+			logger.LogError(ctx, "failed to unmarshal profile record",
 				"error", err,
 			)
-			return profiles, err
+			return err
 		}
 
-		for _, entity := range response.Entities {
-			var myEntity aztables.EDMEntity
-			if err := json.Unmarshal(entity, &myEntity); err != nil {
-				logger.LogError(ctx, "failed to unmarshal profile record",
-					"error", err,
-				)
-				return profiles, err
-			}
-
-			if value, ok := myEntity.Properties["ObjectId"]; ok {
-				profile.ObjectId = value.(string)
-			} else {
-				profile.ObjectId = ""
-			}
-
-			if value, ok := myEntity.Properties["DisplayName"]; ok {
-				profile.DisplayName = value.(string)
-			} else {
-				profile.DisplayName = ""
-			}
+		profile := entity.Profile{}
+		if value, ok := myEntity.Properties["ObjectId"]; ok {
+			profile.ObjectId = value.(string)
+		} else {
+			profile.ObjectId = ""
+		}
 
-			if value, ok := myEntity.Properties["ProfilePhoto"]; ok {
-				profile.ProfilePhoto = value.(string)
-			} else {
-				profile.ProfilePhoto = ""
-			}
+		if value, ok := myEntity.Properties["DisplayName"]; ok {
+			profile.DisplayName = value.(string)
+		} else {
+			profile.DisplayName = ""
+		}
 
-			if value, ok := myEntity.Properties["UserPrincipal"]; ok {
-				profile.UserPrincipal = value.(string)
-			} else {
-				profile.UserPrincipal = ""
-			}
+		if value, ok := myEntity.Properties["ProfilePhoto"]; ok {
+			profile.ProfilePhoto = value.(string)
+		} else {
+			profile.ProfilePhoto = ""
+		}
 
-			if value, ok := myEntity.Properties["Roles"]; ok {
-				profile.Roles = helper.StringToSlice(value.(string))
-			} else {
-				profile.Roles = []string{}
-			}
+		if value, ok := myEntity.Properties["UserPrincipal"]; ok {
+			profile.UserPrincipal = value.(string)
+		} else {
+			profile.UserPrincipal = ""
+		}
 
-			profiles = append(profiles, profile)
+		if value, ok := myEntity.Properties["Roles"]; ok {
+			profile.Roles = helper.StringToSlice(value.(string))
+		} else {
+			profile.Roles = []string{}
 		}
+
+		page.Items = append(page.Items, profile)
+		return nil
+	})
+	if err != nil {
+		logger.LogError(ctx, "failed to get entities from table storage",
+			"error", err,
+		)
+		return page, err
 	}
 
-	return profiles, nil
+	page.NextToken = nextToken
+	return page, nil
 }
 
 // Use this function to complete delete the record for UserPrincipal.
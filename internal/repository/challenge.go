@@ -34,31 +34,33 @@ func NewChallengeRepository(
 }
 
 func (c *challengeRepository) GetAllChallenges(ctx context.Context) ([]entity.Challenge, error) {
-	challenge := entity.Challenge{}
-	challenges := []entity.Challenge{}
+	page, err := c.ListChallenges(ctx, entity.ListQuery{})
+	return page.Items, err
+}
 
-	pager := c.auth.ActlabsChallengesTableClient.NewListEntitiesPager(nil)
-	for pager.More() {
-		page, err := pager.NextPage(ctx)
-		if err != nil {
-			logger.LogError(ctx, "failed to get entities from table storage",
+func (c *challengeRepository) ListChallenges(ctx context.Context, query entity.ListQuery) (entity.Page[entity.Challenge], error) {
+	page := entity.Page[entity.Challenge]{Items: []entity.Challenge{}}
+
+	nextToken, err := listEntities(ctx, c.auth.ActlabsChallengesTableClient, "", query, nil, func(e []byte) error {
+		challenge := entity.Challenge{}
+		if err := json.Unmarshal(e, &challenge); err != nil {
+			logger.LogError(ctx, "failed to unmarshal entity",
 				"error", err,
 			)
-			return challenges, err
-		}
-
-		for _, entity := range page.Entities {
-			if err := json.Unmarshal(entity, &challenge); err != nil {
-				logger.LogError(ctx, "failed to unmarshal entity",
-					"error", err,
-				)
-				continue
-			}
-			challenges = append(challenges, challenge)
+			return nil
 		}
+		page.Items = append(page.Items, challenge)
+		return nil
+	})
+	if err != nil {
+		logger.LogError(ctx, "failed to get entities from table storage",
+			"error", err,
+		)
+		return page, err
 	}
 
-	return challenges, nil
+	page.NextToken = nextToken
+	return page, nil
 }
 
 func (c *challengeRepository) GetChallengeByUserIdAndLabId(ctx context.Context, userId string, labId string) (entity.Challenge, error) {
@@ -1,12 +1,15 @@
 package repository
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
-	"net/http"
+	"fmt"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"actlabs-hub/internal/actlabsserver"
 	"actlabs-hub/internal/auth"
 	"actlabs-hub/internal/config"
 	"actlabs-hub/internal/entity"
@@ -15,21 +18,21 @@ import (
 
 	"github.com/Azure/azure-sdk-for-go/sdk/data/aztables"
 	"github.com/redis/go-redis/v9"
-
-	"github.com/google/uuid"
 )
 
 type deploymentRepository struct {
-	auth   *auth.Auth
-	rdb    *redis.Client
-	config *config.Config
+	auth          *auth.Auth
+	rdb           *redis.Client
+	config        *config.Config
+	actlabsServer *actlabsserver.Client
 }
 
-func NewDeploymentRepository(auth *auth.Auth, rdb *redis.Client, config *config.Config) (entity.DeploymentRepository, error) {
+func NewDeploymentRepository(auth *auth.Auth, rdb *redis.Client, config *config.Config, actlabsServer *actlabsserver.Client) (entity.DeploymentRepository, error) {
 	return &deploymentRepository{
-		auth:   auth,
-		rdb:    rdb,
-		config: config,
+		auth:          auth,
+		rdb:           rdb,
+		config:        config,
+		actlabsServer: actlabsServer,
 	}, nil
 }
 
@@ -145,11 +148,11 @@ func (d *deploymentRepository) GetUserDeployments(ctx context.Context, userPrinc
 	return deployments, nil
 }
 
-func (d *deploymentRepository) GetDeployment(ctx context.Context, userId string, subscriptionId string, workspace string) (entity.Deployment, error) {
+func (d *deploymentRepository) GetDeployment(ctx context.Context, key entity.DeploymentKey) (entity.Deployment, error) {
 	deployment := entity.Deployment{}
 
 	// check if deployment already exist in redis
-	deploymentString, err := d.rdb.Get(ctx, userId+"-"+subscriptionId+"-"+workspace).Result()
+	deploymentString, err := d.rdb.Get(ctx, key.CacheKey()).Result()
 	if err != nil {
 		// Redis miss is expected, continue to table storage silently
 	}
@@ -160,12 +163,12 @@ func (d *deploymentRepository) GetDeployment(ctx context.Context, userId string,
 		// If unmarshal fails, continue to table storage silently
 	}
 
-	response, err := d.auth.ActlabsDeploymentsTableClient.GetEntity(ctx, userId, userId+"-"+subscriptionId+"-"+workspace, nil)
+	response, err := d.auth.ActlabsDeploymentsTableClient.GetEntity(ctx, key.PartitionKey(), key.RowKey(), nil)
 	if err != nil {
 		logger.LogError(ctx, "failed to get deployment from table storage",
-			"user_id", userId,
-			"subscription_id", subscriptionId,
-			"workspace", workspace,
+			"user_id", key.UserId,
+			"subscription_id", key.SubscriptionId,
+			"workspace", key.Workspace,
 			"error", err,
 		)
 		return entity.Deployment{}, err
@@ -175,9 +178,9 @@ func (d *deploymentRepository) GetDeployment(ctx context.Context, userId string,
 	err = json.Unmarshal(response.Value, &myEntity)
 	if err != nil {
 		logger.LogError(ctx, "failed to unmarshal deployment entity from table storage",
-			"user_id", userId,
-			"subscription_id", subscriptionId,
-			"workspace", workspace,
+			"user_id", key.UserId,
+			"subscription_id", key.SubscriptionId,
+			"workspace", key.Workspace,
 			"error", err,
 		)
 		return entity.Deployment{}, err
@@ -186,9 +189,9 @@ func (d *deploymentRepository) GetDeployment(ctx context.Context, userId string,
 	deploymentString = myEntity.Properties["Deployment"].(string)
 	if err := json.Unmarshal([]byte(deploymentString), &deployment); err != nil {
 		logger.LogError(ctx, "failed to unmarshal deployment data from table storage",
-			"user_id", userId,
-			"subscription_id", subscriptionId,
-			"workspace", workspace,
+			"user_id", key.UserId,
+			"subscription_id", key.SubscriptionId,
+			"workspace", key.Workspace,
 			"error", err,
 		)
 		return entity.Deployment{}, err
@@ -201,7 +204,7 @@ func (d *deploymentRepository) GetDeployment(ctx context.Context, userId string,
 		return deployment, nil
 	}
 
-	err = d.rdb.Set(ctx, userId+"-"+subscriptionId+"-"+workspace, marshalledDeployment, 0).Err()
+	err = d.rdb.Set(ctx, key.CacheKey(), marshalledDeployment, 0).Err()
 	if err != nil {
 		// Redis error is not critical, continue without caching
 	}
@@ -210,6 +213,9 @@ func (d *deploymentRepository) GetDeployment(ctx context.Context, userId string,
 }
 
 func (d *deploymentRepository) UpsertDeployment(ctx context.Context, deployment entity.Deployment) error {
+	key := entity.NewDeploymentKey(deployment)
+	deployment.DeploymentId = key.RowKey()
+
 	marshalledDeployment, err := json.Marshal(deployment)
 	if err != nil {
 		logger.LogError(ctx, "failed to marshal deployment for storage",
@@ -223,8 +229,8 @@ func (d *deploymentRepository) UpsertDeployment(ctx context.Context, deployment
 
 	deploymentEntry := entity.DeploymentEntry{
 		Entity: aztables.Entity{
-			PartitionKey: deployment.DeploymentUserId,
-			RowKey:       deployment.DeploymentId,
+			PartitionKey: key.PartitionKey(),
+			RowKey:       key.RowKey(),
 		},
 		Deployment: string(marshalledDeployment),
 	}
@@ -252,23 +258,40 @@ func (d *deploymentRepository) UpsertDeployment(ctx context.Context, deployment
 	}
 
 	// save deployment to redis
-	if err := d.rdb.Set(ctx, deployment.DeploymentUserId+"-"+deployment.DeploymentSubscriptionId+"-"+deployment.DeploymentWorkspace, marshalledDeployment, 0).Err(); err != nil {
+	if err := d.rdb.Set(ctx, key.CacheKey(), marshalledDeployment, 0).Err(); err != nil {
 		// Redis error is not critical, continue without caching
 
 		// if not able to add deployment, delete existing deployment from redis if any
-		if err := d.rdb.Del(ctx, deployment.DeploymentUserId+"-"+deployment.DeploymentSubscriptionId+"-"+deployment.DeploymentWorkspace).Err(); err != nil {
+		if err := d.rdb.Del(ctx, key.CacheKey()).Err(); err != nil {
 			// Redis deletion error is not critical
 		}
 	}
 
-	// delete deployments for user from redis
-	if err := d.rdb.Del(ctx, deployment.DeploymentUserId+"-deployments").Err(); err != nil {
-		// Redis deletion error is not critical
-	}
+	// delete deployments for user and any legacy keys from redis
+	d.invalidateDeploymentCache(ctx, key, false)
 
 	return nil
 }
 
+// invalidateDeploymentCache deletes the user's deployments and the legacy keys
+// of the deployment from redis, and the deployment itself when includeSelf.
+func (d *deploymentRepository) invalidateDeploymentCache(ctx context.Context, key entity.DeploymentKey, includeSelf bool) {
+	keys := append([]string{key.UserCacheKey()}, key.LegacyCacheKeys()...)
+	if includeSelf {
+		keys = append(keys, key.CacheKey())
+	}
+
+	if err := d.rdb.Del(ctx, keys...).Err(); err != nil {
+		// Redis deletion error is not critical, but cached entries may be stale
+		logger.LogWarning(ctx, "failed to delete deployment keys from redis",
+			"user_id", key.UserId,
+			"subscription_id", key.SubscriptionId,
+			"workspace", key.Workspace,
+			"error", err,
+		)
+	}
+}
+
 func (d *deploymentRepository) DeploymentOperationEntry(ctx context.Context, deployment entity.Deployment) error {
 	marshalledDeploymentLab, err := json.Marshal(deployment.DeploymentLab)
 	if err != nil {
@@ -283,7 +306,7 @@ func (d *deploymentRepository) DeploymentOperationEntry(ctx context.Context, dep
 
 	operationEntry := entity.OperationEntry{
 		PartitionKey:                 deployment.DeploymentUserId,
-		RowKey:                       helper.Generate(64),
+		RowKey:                       operationRowKey(time.Now()),
 		DeploymentUserId:             deployment.DeploymentUserId,
 		DeploymentSubscriptionId:     deployment.DeploymentSubscriptionId,
 		DeploymentWorkspace:          deployment.DeploymentWorkspace,
@@ -319,52 +342,248 @@ func (d *deploymentRepository) DeploymentOperationEntry(ctx context.Context, dep
 	return nil
 }
 
-func (d *deploymentRepository) DeleteDeployment(ctx context.Context, userId string, workspace string, subscriptionId string) error {
-	_, err := d.auth.ActlabsDeploymentsTableClient.DeleteEntity(ctx, userId, userId+"-"+workspace+"-"+subscriptionId, nil)
+// operationRowKey orders operation entries by time within a user's partition
+// so they can be paged chronologically. The random suffix keeps keys unique
+// when two updates land in the same nanosecond.
+func operationRowKey(t time.Time) string {
+	return fmt.Sprintf("%019d-%s", t.UnixNano(), helper.Generate(8))
+}
+
+// operationTime returns when an operation entry was written. Entries from
+// before time-ordered row keys fall back to the table timestamp; they sort
+// among the newer entries by their random key, so only the order within a
+// page is guaranteed for them.
+func operationTime(rowKey string, timestamp time.Time) time.Time {
+	nanos, _, found := strings.Cut(rowKey, "-")
+	if found {
+		if n, err := strconv.ParseInt(nanos, 10, 64); err == nil {
+			return time.Unix(0, n).UTC()
+		}
+	}
+	return timestamp.UTC()
+}
+
+func (d *deploymentRepository) GetDeploymentOperations(ctx context.Context, userId string, subscriptionId string, workspace string, limit int32, nextToken string) ([]entity.DeploymentOperation, string, error) {
+	operations := []entity.DeploymentOperation{}
+
+	filter := "PartitionKey eq '" + escapeODataString(userId) +
+		"' and DeploymentSubscriptionId eq '" + escapeODataString(subscriptionId) +
+		"' and DeploymentWorkspace eq '" + escapeODataString(workspace) + "'"
+
+	options := &aztables.ListEntitiesOptions{
+		Filter: &filter,
+		Top:    &limit,
+	}
+	if nextToken != "" {
+		options.NextPartitionKey = &userId
+		options.NextRowKey = &nextToken
+	}
+
+	pager := d.auth.ActlabSDeploymentOperationsTableClient.NewListEntitiesPager(options)
+	if !pager.More() {
+		return operations, "", nil
+	}
+
+	response, err := pager.NextPage(ctx)
 	if err != nil {
-		logger.LogError(ctx, "failed to delete deployment from table storage",
+		logger.LogError(ctx, "failed to get deployment operations from table storage",
 			"user_id", userId,
 			"subscription_id", subscriptionId,
 			"workspace", workspace,
 			"error", err,
 		)
-		return err
+		return nil, "", err
 	}
 
-	// delete deployment from redis
-	if err := d.rdb.Del(ctx, userId+"-"+subscriptionId+"-"+workspace).Err(); err != nil {
-		// Redis deletion error is not critical
+	for _, e := range response.Entities {
+		var operationEntry entity.OperationEntry
+		if err := json.Unmarshal(e, &operationEntry); err != nil {
+			logger.LogError(ctx, "failed to unmarshal deployment operation entry",
+				"user_id", userId,
+				"error", err,
+			)
+			return nil, "", err
+		}
+
+		var metadata struct {
+			Timestamp time.Time `json:"Timestamp"`
+		}
+		if err := json.Unmarshal(e, &metadata); err != nil {
+			logger.LogWarning(ctx, "failed to get timestamp of deployment operation entry",
+				"user_id", userId,
+				"error", err,
+			)
+		}
+
+		var lab entity.LabType
+		if operationEntry.DeploymentLab != "" {
+			if err := json.Unmarshal([]byte(operationEntry.DeploymentLab), &lab); err != nil {
+				logger.LogWarning(ctx, "failed to unmarshal lab of deployment operation entry",
+					"user_id", userId,
+					"error", err,
+				)
+			}
+		}
+
+		operations = append(operations, entity.DeploymentOperation{
+			Status:             operationEntry.DeploymentStatus,
+			Timestamp:          operationTime(operationEntry.RowKey, metadata.Timestamp).Format(time.RFC3339Nano),
+			AutoDelete:         operationEntry.DeploymentAutoDelete,
+			Lifespan:           operationEntry.DeploymentLifespan,
+			AutoDeleteUnixTime: operationEntry.DeploymentAutoDeleteUnixTime,
+			Lab:                lab,
+		})
+	}
+
+	sort.SliceStable(operations, func(i, j int) bool {
+		ti, _ := time.Parse(time.RFC3339Nano, operations[i].Timestamp)
+		tj, _ := time.Parse(time.RFC3339Nano, operations[j].Timestamp)
+		return ti.Before(tj)
+	})
+
+	next := ""
+	if response.NextRowKey != nil {
+		next = *response.NextRowKey
 	}
 
-	// delete deployments for user from redis
-	if err := d.rdb.Del(ctx, userId+"-deployments").Err(); err != nil {
-		// Redis deletion error is not critical
+	return operations, next, nil
+}
+
+// escapeODataString escapes single quotes for use inside an OData string literal.
+func escapeODataString(value string) string {
+	return strings.ReplaceAll(value, "'", "''")
+}
+
+func (d *deploymentRepository) DeleteDeployment(ctx context.Context, key entity.DeploymentKey) error {
+	_, err := d.auth.ActlabsDeploymentsTableClient.DeleteEntity(ctx, key.PartitionKey(), key.RowKey(), nil)
+	if err != nil {
+		logger.LogError(ctx, "failed to delete deployment from table storage",
+			"user_id", key.UserId,
+			"subscription_id", key.SubscriptionId,
+			"workspace", key.Workspace,
+			"error", err,
+		)
+		return err
 	}
 
+	d.invalidateDeploymentCache(ctx, key, true)
+
 	return nil
 }
 
-func (d *deploymentRepository) AutoDestroyDeployment(ctx context.Context, userPrincipalName string, deployment entity.Deployment) error {
+func (d *deploymentRepository) MigrateDeploymentKeys(ctx context.Context, dryRun bool) (int, error) {
+	migrated := 0
+
+	pager := d.auth.ActlabsDeploymentsTableClient.NewListEntitiesPager(nil)
+	for pager.More() {
+		response, err := pager.NextPage(ctx)
+		if err != nil {
+			logger.LogError(ctx, "failed to get deployments from table storage for key migration",
+				"error", err,
+			)
+			return migrated, err
+		}
+
+		for _, e := range response.Entities {
+			var myEntity aztables.EDMEntity
+			if err := json.Unmarshal(e, &myEntity); err != nil {
+				logger.LogError(ctx, "failed to unmarshal deployment entity for key migration",
+					"error", err,
+				)
+				return migrated, err
+			}
+
+			deploymentString, _ := myEntity.Properties["Deployment"].(string)
+
+			var deployment entity.Deployment
+			if err := json.Unmarshal([]byte(deploymentString), &deployment); err != nil {
+				logger.LogError(ctx, "failed to unmarshal deployment for key migration, skipping",
+					"partition_key", myEntity.PartitionKey,
+					"row_key", myEntity.RowKey,
+					"error", err,
+				)
+				continue
+			}
+
+			key := entity.NewDeploymentKey(deployment)
+			if myEntity.PartitionKey == key.PartitionKey() &&
+				myEntity.RowKey == key.RowKey() &&
+				deployment.DeploymentId == key.RowKey() {
+				continue
+			}
+
+			logger.LogInfo(ctx, "migrating deployment to canonical key",
+				"partition_key", myEntity.PartitionKey,
+				"row_key", myEntity.RowKey,
+				"new_row_key", key.RowKey(),
+				"dry_run", dryRun,
+			)
+			migrated++
+
+			if dryRun {
+				continue
+			}
 
-	// http://actlabsserver.com/api/terraform/destroy/operationId
-	endpoint := strings.TrimSuffix(d.config.ActlabsServerEndpointInternal, "/")
-	autoDestroyServiceEndpoint := endpoint + "/api/terraform/destroy/" + uuid.New().String()
+			if err := d.UpsertDeployment(ctx, deployment); err != nil {
+				return migrated, err
+			}
+
+			// The old row is left alone when it was already at the canonical key
+			// and only the DeploymentId inside it was wrong.
+			if myEntity.PartitionKey != key.PartitionKey() || myEntity.RowKey != key.RowKey() {
+				if _, err := d.auth.ActlabsDeploymentsTableClient.DeleteEntity(ctx, myEntity.PartitionKey, myEntity.RowKey, nil); err != nil {
+					logger.LogError(ctx, "failed to delete deployment under old key",
+						"partition_key", myEntity.PartitionKey,
+						"row_key", myEntity.RowKey,
+						"error", err,
+					)
+					return migrated, err
+				}
+			}
+
+			d.invalidateDeploymentCache(ctx, key, false)
+			if myEntity.PartitionKey != key.PartitionKey() {
+				if err := d.rdb.Del(ctx, myEntity.PartitionKey+"-deployments").Err(); err != nil {
+					// Redis deletion error is not critical
+				}
+			}
+		}
+	}
 
-	// Marshal deployment to JSON for request body
-	deploymentJSON, err := json.Marshal(deployment)
+	return migrated, nil
+}
+
+func (d *deploymentRepository) MarkExpiryWarningSent(ctx context.Context, deployment entity.Deployment, windowMinutes int) (bool, error) {
+	// Keyed on the auto delete time too, so extending the deployment re-arms the warnings.
+	key := fmt.Sprintf("deployment-expiry-warning-%s-%s-%s-%d-%d",
+		deployment.DeploymentUserId,
+		deployment.DeploymentSubscriptionId,
+		deployment.DeploymentWorkspace,
+		deployment.DeploymentAutoDeleteUnixTime,
+		windowMinutes,
+	)
+
+	// Keep the marker until well after the deployment would've been deleted.
+	expiration := time.Until(time.Unix(deployment.DeploymentAutoDeleteUnixTime, 0)) + 24*time.Hour
+
+	ok, err := d.rdb.SetNX(ctx, key, time.Now().Format(time.RFC3339), expiration).Result()
 	if err != nil {
-		logger.LogError(ctx, "failed to marshal deployment for auto-destroy request",
-			"user_id", userPrincipalName,
-			"workspace", deployment.DeploymentWorkspace,
+		logger.LogError(ctx, "failed to record deployment expiry warning in redis",
+			"user_id", deployment.DeploymentUserId,
 			"subscription_id", deployment.DeploymentSubscriptionId,
+			"workspace", deployment.DeploymentWorkspace,
 			"error", err,
 		)
-		return err
+		return false, err
 	}
 
-	req, err := http.NewRequest("POST", autoDestroyServiceEndpoint, bytes.NewBuffer(deploymentJSON))
+	return ok, nil
+}
+
+func (d *deploymentRepository) AutoDestroyDeployment(ctx context.Context, userPrincipalName string, deployment entity.Deployment) error {
+	response, err := d.actlabsServer.Destroy(ctx, userPrincipalName, deployment)
 	if err != nil {
-		logger.LogError(ctx, "failed to create HTTP request for auto-destroy",
+		logger.LogError(ctx, "failed to request auto destroy of deployment",
 			"user_id", userPrincipalName,
 			"workspace", deployment.DeploymentWorkspace,
 			"subscription_id", deployment.DeploymentSubscriptionId,
@@ -373,28 +592,34 @@ func (d *deploymentRepository) AutoDestroyDeployment(ctx context.Context, userPr
 		return err
 	}
 
-	req.Header.Set("x-api-key", d.config.ActlabsServerApiKey)
-	req.Header.Set("x-user-id", userPrincipalName)
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Content-Type", "application/json")
+	logger.LogInfo(ctx, "auto destroy of deployment requested",
+		"user_id", userPrincipalName,
+		"workspace", deployment.DeploymentWorkspace,
+		"subscription_id", deployment.DeploymentSubscriptionId,
+		"operation_id", response.OperationId,
+	)
+
+	return nil
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+func (d *deploymentRepository) DeployDeployment(ctx context.Context, userPrincipalName string, deployment entity.Deployment) error {
+	response, err := d.actlabsServer.Apply(ctx, userPrincipalName, deployment)
 	if err != nil {
-		logger.LogError(ctx, "failed to make http request for auto destroy deployment",
+		logger.LogError(ctx, "failed to request deploy of deployment",
+			"user_id", userPrincipalName,
+			"workspace", deployment.DeploymentWorkspace,
+			"subscription_id", deployment.DeploymentSubscriptionId,
 			"error", err,
 		)
 		return err
 	}
 
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusAccepted {
-		logger.LogError(ctx, "http request for auto destroy deployment failed",
-			"status_code", resp.StatusCode,
-		)
-		return err
-	}
+	logger.LogInfo(ctx, "deploy of deployment requested",
+		"user_id", userPrincipalName,
+		"workspace", deployment.DeploymentWorkspace,
+		"subscription_id", deployment.DeploymentSubscriptionId,
+		"operation_id", response.OperationId,
+	)
 
 	return nil
 }
@@ -65,6 +65,10 @@ func (er *eventRepository) GetEvents(ctx context.Context) ([]entity.Event, error
 				return events, err
 			}
 
+			// The keys aren't among the properties.
+			event.PartitionKey = tableEntity.PartitionKey
+			event.RowKey = tableEntity.RowKey
+
 			events = append(events, event)
 		}
 
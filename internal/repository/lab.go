@@ -6,14 +6,22 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"mime/multipart"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
 
 	"actlabs-hub/internal/auth"
 	"actlabs-hub/internal/config"
 	"actlabs-hub/internal/entity"
 	"actlabs-hub/internal/logger"
+	"actlabs-hub/internal/metrics"
+	"actlabs-hub/internal/tracing"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
@@ -40,6 +48,27 @@ func NewLabRepository(
 
 const ReproProjectPrefix = "repro-project-"
 
+const supportingDocumentsContainer = ReproProjectPrefix + "supporting-documents"
+
+// LabContainers are the blob containers labs and their supporting documents
+// are kept in.
+func LabContainers() []string {
+	containers := []string{supportingDocumentsContainer}
+	for _, typeOfLab := range slices.Concat(entity.PrivateLab, entity.PublicLab, entity.ProtectedLabs) {
+		containers = append(containers, ReproProjectPrefix+typeOfLab)
+	}
+	return containers
+}
+
+// Blob metadata keys of supporting documents. Values must be ASCII, so the
+// file name is URL encoded.
+const (
+	supportingDocumentFileNameKey   = "filename"
+	supportingDocumentUploadedByKey = "uploadedby"
+	supportingDocumentUploadedAtKey = "uploadedat"
+	supportingDocumentLabIdsKey     = "labids"
+)
+
 func (l *labRepository) ListBlobs(
 	ctx context.Context,
 	labType string,
@@ -66,7 +95,7 @@ func (l *labRepository) ListBlobs(
 		serviceURL = "https://localhost:10000/devstoreaccount1/"
 	}
 
-	client, err := azblob.NewClient(serviceURL, l.auth.Cred, nil)
+	client, err := azblob.NewClient(serviceURL, l.auth.Cred, blobClientOptions())
 	if err != nil {
 		return nil, err
 	}
@@ -141,7 +170,7 @@ func (l *labRepository) GetLabWithVersions(ctx context.Context, typeOfLab string
 		serviceURL = "https://localhost:10000/devstoreaccount1/"
 	}
 
-	client, err := azblob.NewClient(serviceURL, l.auth.Cred, nil)
+	client, err := azblob.NewClient(serviceURL, l.auth.Cred, blobClientOptions())
 	if err != nil {
 		return labs, err
 	}
@@ -153,7 +182,7 @@ func (l *labRepository) GetLabWithVersions(ctx context.Context, typeOfLab string
 		containerURL = "https://localhost:10000/devstoreaccount1/" + ReproProjectPrefix + typeOfLab
 	}
 
-	containerClient, err := container.NewClient(containerURL, l.auth.Cred, nil)
+	containerClient, err := container.NewClient(containerURL, l.auth.Cred, containerClientOptions())
 	if err != nil {
 		return labs, err
 	}
@@ -257,7 +286,7 @@ func (l *labRepository) GetLab(
 		containerURL = "https://localhost:10000/devstoreaccount1/" + ReproProjectPrefix + typeOfLab
 	}
 
-	containerClient, err := container.NewClient(containerURL, l.auth.Cred, nil)
+	containerClient, err := container.NewClient(containerURL, l.auth.Cred, containerClientOptions())
 	if err != nil {
 		return lab, err
 	}
@@ -294,7 +323,7 @@ func (l *labRepository) UpsertLab(ctx context.Context, labId string, lab string,
 		serviceURL = "https://localhost:10000/devstoreaccount1/"
 	}
 
-	client, err := azblob.NewClient(serviceURL, l.auth.Cred, nil)
+	client, err := azblob.NewClient(serviceURL, l.auth.Cred, blobClientOptions())
 	if err != nil {
 		return err
 	}
@@ -325,7 +354,7 @@ func (l *labRepository) DeleteLab(ctx context.Context, typeOfLab string, labId s
 		serviceURL = "https://localhost:10000/devstoreaccount1/"
 	}
 
-	client, err := azblob.NewClient(serviceURL, l.auth.Cred, nil)
+	client, err := azblob.NewClient(serviceURL, l.auth.Cred, blobClientOptions())
 	if err != nil {
 		return err
 	}
@@ -343,7 +372,7 @@ func (l *labRepository) DeleteLab(ctx context.Context, typeOfLab string, labId s
 	return nil
 }
 
-func (l *labRepository) UpsertSupportingDocument(ctx context.Context, supportingDocument multipart.File) (string, error) {
+func (l *labRepository) UpsertSupportingDocument(ctx context.Context, supportingDocument io.Reader, metadata entity.SupportingDocument) (string, error) {
 	serviceURL := fmt.Sprintf("https://%s.blob.core.windows.net/", l.appConfig.ActlabsHubStorageAccount)
 
 	// Use this for local emulator
@@ -351,15 +380,20 @@ func (l *labRepository) UpsertSupportingDocument(ctx context.Context, supporting
 		serviceURL = "https://localhost:10000/devstoreaccount1/"
 	}
 
-	client, err := azblob.NewClient(serviceURL, l.auth.Cred, nil)
+	client, err := azblob.NewClient(serviceURL, l.auth.Cred, blobClientOptions())
 	if err != nil {
 		return "", err
 	}
 
-	containerName := "repro-project-supporting-documents"
+	containerName := supportingDocumentsContainer
 	blobName := uuid.New().String()
 
-	_, err = client.UploadStream(ctx, containerName, blobName, supportingDocument, nil)
+	_, err = client.UploadStream(ctx, containerName, blobName, supportingDocument, &azblob.UploadStreamOptions{
+		HTTPHeaders: &blob.HTTPHeaders{
+			BlobContentType: &metadata.ContentType,
+		},
+		Metadata: supportingDocumentBlobMetadata(metadata),
+	})
 	if err != nil {
 		return "", err
 	}
@@ -367,6 +401,82 @@ func (l *labRepository) UpsertSupportingDocument(ctx context.Context, supporting
 	return blobName, nil
 }
 
+func (l *labRepository) GetSupportingDocumentMetadata(ctx context.Context, supportingDocumentId string) (entity.SupportingDocument, error) {
+	containerClient, err := l.supportingDocumentsContainerClient()
+	if err != nil {
+		return entity.SupportingDocument{}, err
+	}
+
+	properties, err := containerClient.NewBlobClient(supportingDocumentId).GetProperties(ctx, nil)
+	if err != nil {
+		return entity.SupportingDocument{}, err
+	}
+
+	return supportingDocumentFromBlob(supportingDocumentId, properties.Metadata, properties.ContentType, properties.ContentLength, properties.LastModified), nil
+}
+
+func (l *labRepository) SetSupportingDocumentLabIds(ctx context.Context, supportingDocumentId string, labIds []string) error {
+	containerClient, err := l.supportingDocumentsContainerClient()
+	if err != nil {
+		return err
+	}
+
+	blobClient := containerClient.NewBlobClient(supportingDocumentId)
+
+	properties, err := blobClient.GetProperties(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	// Setting metadata replaces all of it, so keep what's there.
+	metadata := map[string]*string{}
+	for key, value := range properties.Metadata {
+		metadata[strings.ToLower(key)] = value
+	}
+	joined := strings.Join(labIds, ",")
+	metadata[supportingDocumentLabIdsKey] = &joined
+
+	_, err = blobClient.SetMetadata(ctx, metadata, nil)
+	return err
+}
+
+func (l *labRepository) ListSupportingDocuments(ctx context.Context) ([]entity.SupportingDocument, error) {
+	containerClient, err := l.supportingDocumentsContainerClient()
+	if err != nil {
+		return nil, err
+	}
+
+	supportingDocuments := []entity.SupportingDocument{}
+
+	pager := containerClient.NewListBlobsFlatPager(&container.ListBlobsFlatOptions{
+		Include: container.ListBlobsInclude{
+			Metadata: true,
+		},
+	})
+	for pager.More() {
+		resp, err := pager.NextPage(ctx)
+		if err != nil {
+			logger.LogError(ctx, "failed to list supporting documents", "error", err.Error())
+			return nil, err
+		}
+		for _, item := range resp.Segment.BlobItems {
+			supportingDocuments = append(supportingDocuments, supportingDocumentFromBlob(*item.Name, item.Metadata, item.Properties.ContentType, item.Properties.ContentLength, item.Properties.LastModified))
+		}
+	}
+
+	return supportingDocuments, nil
+}
+
+func (l *labRepository) supportingDocumentsContainerClient() (*container.Client, error) {
+	containerUrl := fmt.Sprintf("https://%s.blob.core.windows.net/%s", l.appConfig.ActlabsHubStorageAccount, supportingDocumentsContainer)
+
+	if l.appConfig.ActlabsHubStorageAccount == "devstoreaccount1" {
+		containerUrl = "https://localhost:10000/devstoreaccount1/" + supportingDocumentsContainer
+	}
+
+	return container.NewClient(containerUrl, l.auth.Cred, containerClientOptions())
+}
+
 func (l *labRepository) GetSupportingDocument(ctx context.Context, supportingDocumentId string) (io.ReadCloser, error) {
 	containerUrl := fmt.Sprintf("https://%s.blob.core.windows.net/%s", l.appConfig.ActlabsHubStorageAccount, "repro-project-supporting-documents")
 
@@ -374,7 +484,7 @@ func (l *labRepository) GetSupportingDocument(ctx context.Context, supportingDoc
 		containerUrl = "https://localhost:10000/devstoreaccount1/repro-project-supporting-documents"
 	}
 
-	containerClient, err := container.NewClient(containerUrl, l.auth.Cred, nil)
+	containerClient, err := container.NewClient(containerUrl, l.auth.Cred, containerClientOptions())
 	if err != nil {
 		return nil, err
 	}
@@ -396,7 +506,7 @@ func (l *labRepository) DeleteSupportingDocument(ctx context.Context, supporting
 		serviceURL = "https://localhost:10000/devstoreaccount1/"
 	}
 
-	client, err := azblob.NewClient(serviceURL, l.auth.Cred, nil)
+	client, err := azblob.NewClient(serviceURL, l.auth.Cred, blobClientOptions())
 	if err != nil {
 		return err
 	}
@@ -416,7 +526,7 @@ func (l *labRepository) DoesSupportingDocumentExist(ctx context.Context, support
 		containerUrl = "https://localhost:10000/devstoreaccount1/repro-project-supporting-documents"
 	}
 
-	containerClient, err := container.NewClient(containerUrl, l.auth.Cred, nil)
+	containerClient, err := container.NewClient(containerUrl, l.auth.Cred, containerClientOptions())
 	if err != nil {
 		return false
 	}
@@ -427,6 +537,54 @@ func (l *labRepository) DoesSupportingDocumentExist(ctx context.Context, support
 	return err == nil
 }
 
+func supportingDocumentBlobMetadata(metadata entity.SupportingDocument) map[string]*string {
+	fileName := url.QueryEscape(metadata.FileName)
+	labIds := strings.Join(metadata.LabIds, ",")
+
+	return map[string]*string{
+		supportingDocumentFileNameKey:   &fileName,
+		supportingDocumentUploadedByKey: &metadata.UploadedBy,
+		supportingDocumentUploadedAtKey: &metadata.UploadedAt,
+		supportingDocumentLabIdsKey:     &labIds,
+	}
+}
+
+func supportingDocumentFromBlob(id string, metadata map[string]*string, contentType *string, size *int64, lastModified *time.Time) entity.SupportingDocument {
+	supportingDocument := entity.SupportingDocument{
+		Id:     id,
+		LabIds: []string{},
+	}
+
+	// Metadata keys come back in whatever case the service or HTTP headers used.
+	value := func(key string) string {
+		for k, v := range metadata {
+			if strings.EqualFold(k, key) && v != nil {
+				return *v
+			}
+		}
+		return ""
+	}
+
+	supportingDocument.FileName, _ = url.QueryUnescape(value(supportingDocumentFileNameKey))
+	supportingDocument.UploadedBy = value(supportingDocumentUploadedByKey)
+	supportingDocument.UploadedAt = value(supportingDocumentUploadedAtKey)
+	if labIds := value(supportingDocumentLabIdsKey); labIds != "" {
+		supportingDocument.LabIds = strings.Split(labIds, ",")
+	}
+
+	if contentType != nil {
+		supportingDocument.ContentType = *contentType
+	}
+	if size != nil {
+		supportingDocument.Size = *size
+	}
+	if supportingDocument.UploadedAt == "" && lastModified != nil {
+		supportingDocument.UploadedAt = lastModified.UTC().Format(time.RFC3339)
+	}
+
+	return supportingDocument
+}
+
 func appendDotJson(labId string) string {
 	if labId[len(labId)-5:] == ".json" {
 		return labId
@@ -434,6 +592,24 @@ func appendDotJson(labId string) string {
 	return labId + ".json"
 }
 
+// blobClientOptions and containerClientOptions record metrics and spans for
+// every request the clients send.
+func blobClientOptions() *azblob.ClientOptions {
+	return &azblob.ClientOptions{
+		ClientOptions: azcore.ClientOptions{
+			PerCallPolicies: []policy.Policy{metrics.StoragePolicy("blob"), tracing.StoragePolicy("blob")},
+		},
+	}
+}
+
+func containerClientOptions() *container.ClientOptions {
+	return &container.ClientOptions{
+		ClientOptions: azcore.ClientOptions{
+			PerCallPolicies: []policy.Policy{metrics.StoragePolicy("blob"), tracing.StoragePolicy("blob")},
+		},
+	}
+}
+
 func redisKey(funcIdentifier string, typeOfLab string, labId string) string {
 	return funcIdentifier + "-" + typeOfLab + "-" + labId
 }
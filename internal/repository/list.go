@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"actlabs-hub/internal/entity"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/aztables"
+)
+
+// continuation is the next partition and row key of a table listing. It's
+// handed to clients as an opaque nextToken.
+type continuation struct {
+	PartitionKey string `json:"pk"`
+	RowKey       string `json:"rk,omitempty"`
+}
+
+func encodeNextToken(partitionKey *string, rowKey *string) string {
+	if partitionKey == nil {
+		return ""
+	}
+	next := continuation{PartitionKey: *partitionKey}
+	if rowKey != nil {
+		next.RowKey = *rowKey
+	}
+	b, _ := json.Marshal(next)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeNextToken(token string) (continuation, error) {
+	next := continuation{}
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return next, fmt.Errorf("%w: %w", entity.ErrInvalidNextToken, err)
+	}
+	if err := json.Unmarshal(b, &next); err != nil || next.PartitionKey == "" {
+		return next, entity.ErrInvalidNextToken
+	}
+	return next, nil
+}
+
+// listEntities reads the page of client's entities matching filter that
+// query asks for and passes each to add. It returns the token of the next
+// page. columns maps JSON fields to table properties where their names
+// differ, so the query's fields can be pushed down as Select.
+func listEntities(
+	ctx context.Context,
+	client *aztables.Client,
+	filter string,
+	query entity.ListQuery,
+	columns map[string]string,
+	add func(e []byte) error,
+) (string, error) {
+	options := &aztables.ListEntitiesOptions{}
+	if filter != "" {
+		options.Filter = &filter
+	}
+	if query.Limit > 0 {
+		options.Top = &query.Limit
+	}
+	if selected := selectColumns(query, columns); selected != "" {
+		options.Select = &selected
+	}
+	if query.NextToken != "" {
+		next, err := decodeNextToken(query.NextToken)
+		if err != nil {
+			return "", err
+		}
+		options.NextPartitionKey = &next.PartitionKey
+		if next.RowKey != "" {
+			options.NextRowKey = &next.RowKey
+		}
+	}
+
+	pager := client.NewListEntitiesPager(options)
+	for pager.More() {
+		response, err := pager.NextPage(ctx)
+		if err != nil {
+			return "", err
+		}
+
+		for _, e := range response.Entities {
+			if err := add(e); err != nil {
+				return "", err
+			}
+		}
+
+		// With a limit only one page is read.
+		if query.Limit > 0 {
+			return encodeNextToken(response.NextPartitionKey, response.NextRowKey), nil
+		}
+	}
+
+	return "", nil
+}
+
+// selectColumns is the table properties needed for the query's fields and
+// sort, or empty for all of them.
+func selectColumns(query entity.ListQuery, columns map[string]string) string {
+	if len(query.Fields) == 0 {
+		return ""
+	}
+
+	selected := []string{}
+	add := func(field string) {
+		column, ok := columns[field]
+		if !ok {
+			column = field
+		}
+		if !slices.Contains(selected, column) {
+			selected = append(selected, column)
+		}
+	}
+	for _, field := range query.Fields {
+		add(field)
+	}
+	for _, sort := range query.Sort {
+		add(sort.Field)
+	}
+
+	return strings.Join(selected, ",")
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"os"
+	"sync"
+	"time"
+
+	"actlabs-hub/internal/entity"
+	"actlabs-hub/internal/logger"
+)
+
+// defaultPriceSheet is a rough pay-as-you-go, Linux, East US price sheet used
+// when no price sheet file is configured.
+var defaultPriceSheet = entity.PriceSheet{
+	Currency: "USD",
+	VmHourly: map[string]float64{
+		"Standard_B2s":     0.0416,
+		"Standard_B2ms":    0.0832,
+		"Standard_B4ms":    0.166,
+		"Standard_DS2_v2":  0.146,
+		"Standard_D2s_v3":  0.096,
+		"Standard_D4s_v3":  0.192,
+		"Standard_D8s_v3":  0.384,
+		"Standard_D2s_v5":  0.096,
+		"Standard_D4s_v5":  0.192,
+		"Standard_D8s_v5":  0.384,
+		"Standard_D2as_v5": 0.086,
+		"Standard_D4as_v5": 0.172,
+		"Standard_E4s_v3":  0.252,
+	},
+	DefaultVmHourly:         0.192,
+	DefaultNodeVmSize:       "Standard_D2s_v3",
+	DefaultJumpserverVmSize: "Standard_B2s",
+	DefaultAroWorkerVmSize:  "Standard_D4s_v3",
+	KubernetesClusterHourly: 0,
+	AroClusterHourly:        1.5,
+	AppGatewayHourly:        0.246,
+	ContainerRegistryHourly: 0.0069,
+	FirewallHourly: map[string]float64{
+		"Basic":    0.395,
+		"Standard": 1.25,
+		"Premium":  1.75,
+	},
+	DefaultFirewallHourly: 1.25,
+}
+
+// filePriceSheetRepository reads the price sheet from a JSON file, reloading
+// it when the file changes. Without a file the built-in defaults are used.
+type filePriceSheetRepository struct {
+	path string
+
+	mu         sync.Mutex
+	modTime    time.Time
+	priceSheet entity.PriceSheet
+}
+
+func NewPriceSheetRepository(path string) (entity.PriceSheetRepository, error) {
+	repository := &filePriceSheetRepository{
+		path:       path,
+		priceSheet: defaultPriceSheet,
+	}
+
+	// Fail at startup rather than on the first estimate.
+	if _, err := repository.GetPriceSheet(context.Background()); err != nil {
+		return nil, err
+	}
+
+	return repository, nil
+}
+
+func (f *filePriceSheetRepository) GetPriceSheet(ctx context.Context) (entity.PriceSheet, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.path == "" {
+		return f.priceSheet, nil
+	}
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		logger.LogError(ctx, "failed to stat price sheet file",
+			"path", f.path,
+			"error", err,
+		)
+		return entity.PriceSheet{}, err
+	}
+
+	if info.ModTime().Equal(f.modTime) {
+		return f.priceSheet, nil
+	}
+
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		logger.LogError(ctx, "failed to read price sheet file",
+			"path", f.path,
+			"error", err,
+		)
+		return entity.PriceSheet{}, err
+	}
+
+	// Anything the file leaves out keeps its default. Maps are copied so
+	// entries from the file merge into the defaults without changing them.
+	priceSheet := defaultPriceSheet
+	priceSheet.VmHourly = maps.Clone(defaultPriceSheet.VmHourly)
+	priceSheet.FirewallHourly = maps.Clone(defaultPriceSheet.FirewallHourly)
+	if err := json.Unmarshal(data, &priceSheet); err != nil {
+		logger.LogError(ctx, "failed to unmarshal price sheet file",
+			"path", f.path,
+			"error", err,
+		)
+		return entity.PriceSheet{}, err
+	}
+
+	logger.LogInfo(ctx, "loaded price sheet",
+		"path", f.path,
+		"currency", priceSheet.Currency,
+	)
+
+	f.priceSheet = priceSheet
+	f.modTime = info.ModTime()
+
+	return f.priceSheet, nil
+}
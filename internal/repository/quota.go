@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"actlabs-hub/internal/auth"
+	"actlabs-hub/internal/entity"
+	"actlabs-hub/internal/logger"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/aztables"
+	"github.com/redis/go-redis/v9"
+)
+
+const quotaOverridePartitionKey = "quota"
+
+type quotaRepository struct {
+	auth *auth.Auth
+	rdb  *redis.Client
+}
+
+func NewQuotaRepository(auth *auth.Auth, rdb *redis.Client) (entity.QuotaRepository, error) {
+	return &quotaRepository{
+		auth: auth,
+		rdb:  rdb,
+	}, nil
+}
+
+func (q *quotaRepository) GetQuotaOverride(ctx context.Context, userId string) (entity.DeploymentQuota, bool, error) {
+	response, err := q.auth.ActlabsQuotasTableClient.GetEntity(ctx, quotaOverridePartitionKey, userId, nil)
+	if err != nil {
+		var responseErr *azcore.ResponseError
+		if errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusNotFound {
+			return entity.DeploymentQuota{}, false, nil
+		}
+		logger.LogError(ctx, "failed to get quota override from table storage",
+			"requested_user_id", userId,
+			"error", err,
+		)
+		return entity.DeploymentQuota{}, false, err
+	}
+
+	override, err := unmarshalQuotaOverride(response.Value)
+	if err != nil {
+		logger.LogError(ctx, "failed to unmarshal quota override",
+			"requested_user_id", userId,
+			"error", err,
+		)
+		return entity.DeploymentQuota{}, false, err
+	}
+
+	return override.Quota, true, nil
+}
+
+func (q *quotaRepository) GetQuotaOverrides(ctx context.Context) ([]entity.DeploymentQuotaOverride, error) {
+	overrides := []entity.DeploymentQuotaOverride{}
+
+	pager := q.auth.ActlabsQuotasTableClient.NewListEntitiesPager(nil)
+	for pager.More() {
+		response, err := pager.NextPage(ctx)
+		if err != nil {
+			logger.LogError(ctx, "failed to get quota overrides from table storage",
+				"error", err,
+			)
+			return overrides, err
+		}
+
+		for _, e := range response.Entities {
+			override, err := unmarshalQuotaOverride(e)
+			if err != nil {
+				logger.LogError(ctx, "failed to unmarshal quota override",
+					"error", err,
+				)
+				return overrides, err
+			}
+			overrides = append(overrides, override)
+		}
+	}
+
+	return overrides, nil
+}
+
+func (q *quotaRepository) UpsertQuotaOverride(ctx context.Context, override entity.DeploymentQuotaOverride) error {
+	marshalledQuota, err := json.Marshal(override.Quota)
+	if err != nil {
+		return err
+	}
+
+	marshalled, err := json.Marshal(entity.DeploymentQuotaOverrideEntry{
+		Entity: aztables.Entity{
+			PartitionKey: quotaOverridePartitionKey,
+			RowKey:       override.UserId,
+		},
+		Quota: string(marshalledQuota),
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := q.auth.ActlabsQuotasTableClient.UpsertEntity(ctx, marshalled, nil); err != nil {
+		logger.LogError(ctx, "failed to upsert quota override in table storage",
+			"requested_user_id", override.UserId,
+			"error", err,
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (q *quotaRepository) DeleteQuotaOverride(ctx context.Context, userId string) error {
+	if _, err := q.auth.ActlabsQuotasTableClient.DeleteEntity(ctx, quotaOverridePartitionKey, userId, nil); err != nil {
+		logger.LogError(ctx, "failed to delete quota override from table storage",
+			"requested_user_id", userId,
+			"error", err,
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (q *quotaRepository) GetWeeklyLifespan(ctx context.Context, userId string, week string) (int64, error) {
+	lifespan, err := q.rdb.Get(ctx, weeklyLifespanKey(userId, week)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		logger.LogError(ctx, "failed to get weekly lifespan from redis",
+			"requested_user_id", userId,
+			"error", err,
+		)
+		return 0, err
+	}
+
+	return lifespan, nil
+}
+
+func (q *quotaRepository) AddWeeklyLifespan(ctx context.Context, userId string, week string, lifespanSeconds int64) error {
+	key := weeklyLifespanKey(userId, week)
+
+	pipe := q.rdb.TxPipeline()
+	pipe.IncrBy(ctx, key, lifespanSeconds)
+	// Only needed until the week is over.
+	pipe.Expire(ctx, key, 8*24*time.Hour)
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger.LogError(ctx, "failed to add weekly lifespan in redis",
+			"requested_user_id", userId,
+			"error", err,
+		)
+		return err
+	}
+
+	return nil
+}
+
+func weeklyLifespanKey(userId string, week string) string {
+	return "deployment-lifespan-" + userId + "-" + week
+}
+
+func unmarshalQuotaOverride(data []byte) (entity.DeploymentQuotaOverride, error) {
+	var entry entity.DeploymentQuotaOverrideEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return entity.DeploymentQuotaOverride{}, err
+	}
+
+	override := entity.DeploymentQuotaOverride{UserId: entry.RowKey}
+	if err := json.Unmarshal([]byte(entry.Quota), &override.Quota); err != nil {
+		return entity.DeploymentQuotaOverride{}, err
+	}
+
+	return override, nil
+}
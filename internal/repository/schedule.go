@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"actlabs-hub/internal/auth"
+	"actlabs-hub/internal/entity"
+	"actlabs-hub/internal/logger"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/aztables"
+)
+
+const schedulePartitionKey = "schedule"
+
+type scheduleRepository struct {
+	auth *auth.Auth
+}
+
+func NewScheduleRepository(auth *auth.Auth) (entity.ScheduleRepository, error) {
+	return &scheduleRepository{
+		auth: auth,
+	}, nil
+}
+
+func (s *scheduleRepository) GetSchedules(ctx context.Context) ([]entity.Schedule, error) {
+	schedules := []entity.Schedule{}
+
+	pager := s.auth.ActlabsSchedulesTableClient.NewListEntitiesPager(nil)
+	for pager.More() {
+		response, err := pager.NextPage(ctx)
+		if err != nil {
+			logger.LogError(ctx, "failed to get schedules from table storage",
+				"error", err,
+			)
+			return schedules, err
+		}
+
+		for _, e := range response.Entities {
+			schedule, err := unmarshalSchedule(e)
+			if err != nil {
+				logger.LogError(ctx, "failed to unmarshal schedule",
+					"error", err,
+				)
+				return schedules, err
+			}
+			schedules = append(schedules, schedule)
+		}
+	}
+
+	return schedules, nil
+}
+
+func (s *scheduleRepository) GetSchedule(ctx context.Context, scheduleId string) (entity.Schedule, error) {
+	response, err := s.auth.ActlabsSchedulesTableClient.GetEntity(ctx, schedulePartitionKey, scheduleId, nil)
+	if err != nil {
+		var responseErr *azcore.ResponseError
+		if errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusNotFound {
+			return entity.Schedule{}, entity.ErrScheduleNotFound
+		}
+		logger.LogError(ctx, "failed to get schedule from table storage",
+			"schedule_id", scheduleId,
+			"error", err,
+		)
+		return entity.Schedule{}, err
+	}
+
+	schedule, err := unmarshalSchedule(response.Value)
+	if err != nil {
+		logger.LogError(ctx, "failed to unmarshal schedule",
+			"schedule_id", scheduleId,
+			"error", err,
+		)
+		return entity.Schedule{}, err
+	}
+
+	return schedule, nil
+}
+
+func (s *scheduleRepository) UpsertSchedule(ctx context.Context, schedule entity.Schedule) error {
+	marshalledSchedule, err := json.Marshal(schedule)
+	if err != nil {
+		return err
+	}
+
+	marshalled, err := json.Marshal(entity.ScheduleEntry{
+		Entity: aztables.Entity{
+			PartitionKey: schedulePartitionKey,
+			RowKey:       schedule.Id,
+		},
+		Schedule: string(marshalledSchedule),
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.auth.ActlabsSchedulesTableClient.UpsertEntity(ctx, marshalled, nil); err != nil {
+		logger.LogError(ctx, "failed to upsert schedule in table storage",
+			"schedule_id", schedule.Id,
+			"error", err,
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (s *scheduleRepository) DeleteSchedule(ctx context.Context, scheduleId string) error {
+	if _, err := s.auth.ActlabsSchedulesTableClient.DeleteEntity(ctx, schedulePartitionKey, scheduleId, nil); err != nil {
+		logger.LogError(ctx, "failed to delete schedule from table storage",
+			"schedule_id", scheduleId,
+			"error", err,
+		)
+		return err
+	}
+
+	return nil
+}
+
+func unmarshalSchedule(data []byte) (entity.Schedule, error) {
+	var entry entity.ScheduleEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return entity.Schedule{}, err
+	}
+
+	var schedule entity.Schedule
+	if err := json.Unmarshal([]byte(entry.Schedule), &schedule); err != nil {
+		return entity.Schedule{}, err
+	}
+
+	return schedule, nil
+}
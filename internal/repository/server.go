@@ -8,6 +8,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/data/aztables"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/authorization/armauthorization/v3"
@@ -94,6 +95,7 @@ func (s *serverRepository) IsUserAuthorized(ctx context.Context, server entity.S
 func (s *serverRepository) UpsertServerInDatabase(ctx context.Context, server entity.Server) error {
 	server.PartitionKey = "actlabs"
 	server.RowKey = server.UserPrincipalName
+	server.LastModifiedTime = ""
 
 	val, err := json.Marshal(server)
 	if err != nil {
@@ -138,46 +140,55 @@ func (s *serverRepository) GetServerFromDatabase(ctx context.Context, partitionK
 
 }
 
+// serverColumns maps Server's JSON fields to table properties where they
+// differ.
+var serverColumns = map[string]string{
+	"lastModifiedTime": "Timestamp",
+}
+
 func (s *serverRepository) GetAllServersFromDatabase(ctx context.Context) ([]entity.Server, error) {
-	servers := []entity.Server{}
-	//server := entity.Server{}
-	pager := s.auth.ActlabsServersTableClient.NewListEntitiesPager(nil)
-	for pager.More() {
-		response, err := pager.NextPage(ctx)
+	page, err := s.ListServersFromDatabase(ctx, entity.ListQuery{})
+	return page.Items, err
+}
+
+func (s *serverRepository) ListServersFromDatabase(ctx context.Context, query entity.ListQuery) (entity.Page[entity.Server], error) {
+	page := entity.Page[entity.Server]{Items: []entity.Server{}}
+
+	nextToken, err := listEntities(ctx, s.auth.ActlabsServersTableClient, "", query, serverColumns, func(e []byte) error {
+		var myEntity aztables.EDMEntity
+		var server entity.Server
+		if err := json.Unmarshal(e, &myEntity); err != nil {
+			logger.LogError(ctx, "failed to unmarshal server entity from database",
+				"error", err,
+			)
+			return err
+		}
+		propertiesBytes, err := json.Marshal(myEntity.Properties)
 		if err != nil {
-			logger.LogError(ctx, "failed to get servers from database",
+			logger.LogError(ctx, "failed to marshal server properties from database",
 				"error", err,
 			)
-			return servers, err
+			return err
 		}
-
-		for _, e := range response.Entities {
-			var myEntity aztables.EDMEntity
-			var server entity.Server
-			if err := json.Unmarshal(e, &myEntity); err != nil {
-				logger.LogError(ctx, "failed to unmarshal server entity from database",
-					"error", err,
-				)
-				return servers, err
-			}
-			propertiesBytes, err := json.Marshal(myEntity.Properties)
-			if err != nil {
-				logger.LogError(ctx, "failed to marshal server properties from database",
-					"error", err,
-				)
-				return servers, err
-			}
-			if err := json.Unmarshal(propertiesBytes, &server); err != nil {
-				logger.LogError(ctx, "failed to unmarshal server properties from database",
-					"error", err,
-				)
-				return servers, err
-			}
-			servers = append(servers, server)
+		if err := json.Unmarshal(propertiesBytes, &server); err != nil {
+			logger.LogError(ctx, "failed to unmarshal server properties from database",
+				"error", err,
+			)
+			return err
 		}
+		server.LastModifiedTime = time.Time(myEntity.Timestamp).Format(time.RFC3339)
+		page.Items = append(page.Items, server)
+		return nil
+	})
+	if err != nil {
+		logger.LogError(ctx, "failed to get servers from database",
+			"error", err,
+		)
+		return page, err
 	}
 
-	return servers, nil
+	page.NextToken = nextToken
+	return page, nil
 }
 
 func (s *serverRepository) DeleteServerFromDatabase(ctx context.Context, server entity.Server) error {
@@ -191,3 +202,64 @@ func (s *serverRepository) DeleteServerFromDatabase(ctx context.Context, server
 
 	return nil
 }
+
+const serverVerificationResultsKey = "server-verification-results"
+
+func (s *serverRepository) SaveServerVerificationResult(ctx context.Context, result entity.ServerVerificationResult) error {
+	val, err := json.Marshal(result)
+	if err != nil {
+		logger.LogError(ctx, "failed to marshal server verification result",
+			"subscription_id", result.SubscriptionId,
+			"error", err,
+		)
+		return err
+	}
+
+	if err := s.rdb.HSet(ctx, serverVerificationResultsKey, result.UserPrincipalName, val).Err(); err != nil {
+		logger.LogError(ctx, "failed to save server verification result in redis",
+			"subscription_id", result.SubscriptionId,
+			"error", err,
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (s *serverRepository) GetServerVerificationResults(ctx context.Context) (map[string]entity.ServerVerificationResult, error) {
+	results := map[string]entity.ServerVerificationResult{}
+
+	values, err := s.rdb.HGetAll(ctx, serverVerificationResultsKey).Result()
+	if err != nil {
+		logger.LogError(ctx, "failed to get server verification results from redis",
+			"error", err,
+		)
+		return results, err
+	}
+
+	for userPrincipalName, value := range values {
+		var result entity.ServerVerificationResult
+		if err := json.Unmarshal([]byte(value), &result); err != nil {
+			logger.LogError(ctx, "failed to unmarshal server verification result",
+				"requested_user_id", userPrincipalName,
+				"error", err,
+			)
+			continue
+		}
+		results[userPrincipalName] = result
+	}
+
+	return results, nil
+}
+
+// localAuthorizationChecker authorizes every server. It stands in for the
+// role assignment check when running locally without access to ARM.
+type localAuthorizationChecker struct{}
+
+func NewLocalAuthorizationChecker() entity.ServerAuthorizationChecker {
+	return &localAuthorizationChecker{}
+}
+
+func (l *localAuthorizationChecker) IsUserAuthorized(ctx context.Context, server entity.Server) (bool, error) {
+	return true, nil
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+
+	"actlabs-hub/internal/actlabsserver"
+	"actlabs-hub/internal/config"
+	"actlabs-hub/internal/entity"
+)
+
+type serverStatusClient struct {
+	config        *config.Config
+	actlabsServer *actlabsserver.Client
+}
+
+func NewServerStatusClient(config *config.Config, actlabsServer *actlabsserver.Client) entity.ServerStatusClient {
+	return &serverStatusClient{
+		config:        config,
+		actlabsServer: actlabsServer,
+	}
+}
+
+func (s *serverStatusClient) IsServerUp(ctx context.Context, userPrincipalName string) (bool, error) {
+	if _, err := s.actlabsServer.Status(ctx, userPrincipalName, s.config.ActlabsServerReadinessProbePath); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (s *serverStatusClient) IsOperationRunning(ctx context.Context, userPrincipalName string, deployment entity.Deployment) (bool, error) {
+	status, err := s.actlabsServer.OperationStatus(ctx, userPrincipalName, deployment)
+	if err != nil {
+		return false, err
+	}
+
+	return status.Running, nil
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"actlabs-hub/internal/entity"
+	"actlabs-hub/internal/logger"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	runtimeSettingsKey     = "runtime-settings"
+	runtimeSettingsChannel = "runtime-settings-changed"
+)
+
+type settingsRepository struct {
+	rdb *redis.Client
+}
+
+func NewSettingsRepository(rdb *redis.Client) (entity.SettingsRepository, error) {
+	return &settingsRepository{
+		rdb: rdb,
+	}, nil
+}
+
+func (s *settingsRepository) GetSettings(ctx context.Context) (entity.RuntimeSettings, bool, error) {
+	settings := entity.RuntimeSettings{}
+
+	val, err := s.rdb.Get(ctx, runtimeSettingsKey).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return settings, false, nil
+	}
+	if err != nil {
+		logger.LogError(ctx, "failed to get runtime settings from redis",
+			"error", err,
+		)
+		return settings, false, err
+	}
+
+	if err := json.Unmarshal(val, &settings); err != nil {
+		logger.LogError(ctx, "failed to unmarshal runtime settings",
+			"error", err,
+		)
+		return settings, false, err
+	}
+
+	return settings, true, nil
+}
+
+func (s *settingsRepository) UpsertSettings(ctx context.Context, settings entity.RuntimeSettings) error {
+	val, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+
+	pipe := s.rdb.TxPipeline()
+	pipe.Set(ctx, runtimeSettingsKey, val, 0)
+	pipe.Publish(ctx, runtimeSettingsChannel, val)
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger.LogError(ctx, "failed to save runtime settings in redis",
+			"error", err,
+		)
+		return err
+	}
+
+	return nil
+}
+
+func (s *settingsRepository) WatchSettings(ctx context.Context, subscribed func(), changed func(settings entity.RuntimeSettings)) error {
+	sub := s.rdb.Subscribe(ctx, runtimeSettingsChannel)
+	defer sub.Close()
+
+	// Wait for the subscription so changes saved from now on aren't missed.
+	if _, err := sub.Receive(ctx); err != nil {
+		logger.LogError(ctx, "failed to subscribe to runtime settings changes",
+			"error", err,
+		)
+		return err
+	}
+
+	// Messages arriving while subscribed runs are buffered by the channel.
+	messages := sub.Channel()
+	subscribed()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case message, ok := <-messages:
+			if !ok {
+				return nil
+			}
+
+			settings := entity.RuntimeSettings{}
+			if err := json.Unmarshal([]byte(message.Payload), &settings); err != nil {
+				logger.LogError(ctx, "failed to unmarshal runtime settings change",
+					"error", err,
+				)
+				continue
+			}
+			changed(settings)
+		}
+	}
+}
@@ -2,23 +2,29 @@ package service
 
 import (
 	"context"
-	"errors"
+	"fmt"
+	"slices"
 	"strings"
 
 	"actlabs-hub/internal/entity"
 	"actlabs-hub/internal/helper"
+	"actlabs-hub/internal/identity"
 	"actlabs-hub/internal/logger"
 )
 
 type assignmentService struct {
 	assignmentRepository entity.AssignmentRepository
 	labService           entity.LabService
+	eventService         entity.EventService
+	directory            *identity.Directory
 }
 
-func NewAssignmentService(assignmentRepository entity.AssignmentRepository, labService entity.LabService) entity.AssignmentService {
+func NewAssignmentService(assignmentRepository entity.AssignmentRepository, labService entity.LabService, eventService entity.EventService, directory *identity.Directory) entity.AssignmentService {
 	return &assignmentService{
 		assignmentRepository: assignmentRepository,
 		labService:           labService,
+		eventService:         eventService,
+		directory:            directory,
 	}
 }
 
@@ -33,7 +39,7 @@ func (a *assignmentService) GetAllAssignments(ctx context.Context) ([]entity.Ass
 			"operation", "get_all_assignments",
 			"error", err,
 		)
-		return assignments, errors.New("not able to get assignments")
+		return assignments, entity.NewStorageError("not able to get assignments", err)
 	}
 
 	logger.LogInfo(ctx, "Successfully retrieved all assignments",
@@ -43,6 +49,19 @@ func (a *assignmentService) GetAllAssignments(ctx context.Context) ([]entity.Ass
 	return assignments, nil
 }
 
+func (a *assignmentService) ListAssignments(ctx context.Context, query entity.ListQuery) (entity.Page[entity.Assignment], error) {
+	page, err := a.assignmentRepository.ListAssignments(ctx, query)
+	if err != nil {
+		logger.LogError(ctx, "Failed to list assignments from repository",
+			"operation", "list_assignments",
+			"error", err,
+		)
+		return page, listError(err, "not able to get assignments")
+	}
+
+	return page, nil
+}
+
 func (a *assignmentService) GetAssignmentsByLabId(ctx context.Context, labId string) ([]entity.Assignment, error) {
 	logger.LogInfo(ctx, "Starting get assignments by lab ID operation",
 		"operation", "get_assignments_by_lab_id",
@@ -56,7 +75,7 @@ func (a *assignmentService) GetAssignmentsByLabId(ctx context.Context, labId str
 			"lab_id", labId,
 			"error", err,
 		)
-		return assignments, errors.New("not able to get assignments for lab")
+		return assignments, entity.NewStorageError("not able to get assignments for lab", err)
 	}
 
 	logger.LogInfo(ctx, "Successfully retrieved assignments by lab ID",
@@ -80,7 +99,7 @@ func (a *assignmentService) GetAssignmentsByUserId(ctx context.Context, userId s
 			"user_id", userId,
 			"error", err,
 		)
-		return assignments, errors.New("not able to get assignments for user")
+		return assignments, entity.NewStorageError("not able to get assignments for user", err)
 	}
 
 	// remove deleted assignments
@@ -192,9 +211,16 @@ func (a *assignmentService) CreateAssignments(ctx context.Context, userIds []str
 
 	for _, userId := range userIds {
 
-		if !strings.Contains(userId, "@microsoft.com") {
-			userId = userId + "@microsoft.com"
+		qualifiedUserId, err := a.directory.QualifyUserId(userId)
+		if err != nil {
+			logger.LogError(ctx, "Invalid user ID",
+				"operation", "create_assignments",
+				"user_id", userId,
+				"error", err,
+			)
+			continue
 		}
+		userId = qualifiedUserId
 
 		valid, err := a.assignmentRepository.ValidateUser(ctx, userId)
 		if err != nil {
@@ -286,7 +312,7 @@ func (a *assignmentService) UpdateAssignment(ctx context.Context, userId string,
 			"invalid_status", status,
 			"valid_statuses", "InProgress,Completed,Deleted",
 		)
-		return errors.New("invalid status")
+		return entity.NewValidationError("invalid_status", "invalid status")
 	}
 
 	assignment.Status = status
@@ -384,7 +410,7 @@ func getAssignmentByUserIdAndLabId(ctx context.Context, userId string, labId str
 			"user_id", userId,
 			"lab_id", labId,
 		)
-		return entity.Assignment{}, errors.New("not able to find assignment")
+		return entity.Assignment{}, entity.NewNotFoundError("assignment_not_found", "not able to find assignment")
 	}
 
 	return assignment, nil
@@ -399,3 +425,59 @@ func RemoveDeletedAssignments(assignments []entity.Assignment) []entity.Assignme
 	}
 	return updatedAssignments
 }
+
+// assignmentStatuses are the statuses an imported assignment can have.
+var assignmentStatuses = []entity.AssignmentStatus{
+	entity.AssignmentStatusCreated,
+	entity.AssignmentStatusInProgress,
+	entity.AssignmentStatusCompleted,
+	entity.AssignmentStatusCancelled,
+	entity.AssignmentStatusDeleted,
+}
+
+func (a *assignmentService) ImportAssignments(ctx context.Context, assignments []entity.Assignment) (int, error) {
+	problems := []string{}
+	for i, assignment := range assignments {
+		if assignment.UserId == "" || assignment.LabId == "" {
+			problems = append(problems, fmt.Sprintf("assignment %d needs a userId and a labId", i))
+			continue
+		}
+		if assignment.AssignmentId != assignment.UserId+"+"+assignment.LabId {
+			problems = append(problems, fmt.Sprintf("assignment %d has id %q instead of %s+%s", i, assignment.AssignmentId, assignment.UserId, assignment.LabId))
+		}
+		if qualified, err := a.directory.QualifyUserId(assignment.UserId); err != nil || qualified != assignment.UserId {
+			problems = append(problems, fmt.Sprintf("assignment %s needs the full user id of an allowed domain", assignment.AssignmentId))
+		}
+		if !slices.Contains(assignmentStatuses, assignment.Status) {
+			problems = append(problems, fmt.Sprintf("assignment %s has unknown status %q", assignment.AssignmentId, assignment.Status))
+		}
+		if _, err := a.labService.GetLabByIdAndType(ctx, "readinesslab", assignment.LabId); err != nil {
+			problems = append(problems, fmt.Sprintf("lab %s of assignment %s: %s", assignment.LabId, assignment.AssignmentId, err))
+		}
+	}
+	if len(problems) > 0 {
+		return 0, entity.NewValidationError("invalid_assignments", strings.Join(problems, "; "))
+	}
+
+	imported := 0
+	var err error
+	for _, assignment := range assignments {
+		assignment.PartitionKey = assignment.UserId
+		assignment.RowKey = assignment.LabId
+
+		if upsertErr := a.assignmentRepository.UpsertAssignment(ctx, assignment); upsertErr != nil {
+			logger.LogError(ctx, "Failed to import assignment",
+				"operation", "import_assignments",
+				"assignment_id", assignment.AssignmentId,
+				"error", upsertErr,
+			)
+			err = entity.NewStorageError("not able to save assignment "+assignment.AssignmentId, upsertErr)
+			break
+		}
+		imported++
+	}
+
+	createImportEvent(ctx, a.eventService, "Assignments", imported, len(assignments), err)
+
+	return imported, err
+}
@@ -5,7 +5,6 @@ import (
 	"actlabs-hub/internal/helper"
 	"actlabs-hub/internal/logger"
 	"context"
-	"errors"
 )
 
 type AuthService struct {
@@ -37,7 +36,7 @@ func (s *AuthService) CreateProfile(ctx context.Context, profile entity.Profile)
 			"user_principal", profile.UserPrincipal,
 			"display_name", profile.DisplayName,
 		)
-		return errors.New("profile is incomplete")
+		return entity.NewValidationError("invalid_profile", "profile is incomplete")
 	}
 
 	// if the user already exists, then update the profile with existing roles
@@ -66,7 +65,7 @@ func (s *AuthService) GetProfile(ctx context.Context, userPrincipal string) (ent
 			"user_principal", userPrincipal,
 			"error", err,
 		)
-		return entity.Profile{}, err
+		return entity.Profile{}, entity.NewStorageError("not able to get profile", err)
 	}
 
 	return profile, nil
@@ -89,12 +88,24 @@ func (s *AuthService) GetAllProfiles(ctx context.Context) ([]entity.Profile, err
 		logger.LogError(ctx, "failed to get all profiles",
 			"error", err,
 		)
-		return nil, err
+		return nil, entity.NewStorageError("not able to get profiles", err)
 	}
 
 	return profiles, nil
 }
 
+func (s *AuthService) ListProfiles(ctx context.Context, query entity.ListQuery) (entity.Page[entity.Profile], error) {
+	page, err := s.authRepository.ListProfiles(ctx, query)
+	if err != nil {
+		logger.LogError(ctx, "failed to list profiles",
+			"error", err,
+		)
+		return page, listError(err, "not able to get profiles")
+	}
+
+	return page, nil
+}
+
 func (s *AuthService) DeleteRole(ctx context.Context, userPrincipal string, role string) error {
 	logger.LogInfo(ctx, "deleting role from user",
 		"user_principal", userPrincipal,
@@ -3,9 +3,9 @@ package service
 import (
 	"actlabs-hub/internal/entity"
 	"actlabs-hub/internal/helper"
+	"actlabs-hub/internal/identity"
 	"actlabs-hub/internal/logger"
 	"context"
-	"errors"
 	"fmt"
 	"regexp"
 	"strings"
@@ -16,12 +16,14 @@ var validAliasPattern = regexp.MustCompile(`^[a-z-]+$`)
 type challengeService struct {
 	challengeRepository entity.ChallengeRepository
 	labService          entity.LabService
+	directory           *identity.Directory
 }
 
-func NewChallengeService(challengeRepository entity.ChallengeRepository, labService entity.LabService) entity.ChallengeService {
+func NewChallengeService(challengeRepository entity.ChallengeRepository, labService entity.LabService, directory *identity.Directory) entity.ChallengeService {
 	return &challengeService{
 		challengeRepository: challengeRepository,
 		labService:          labService,
+		directory:           directory,
 	}
 }
 
@@ -31,7 +33,7 @@ func (a *challengeService) GetAllLabsRedacted(ctx context.Context) ([]entity.Lab
 		logger.LogError(ctx, "failed to get challenge labs",
 			"error", err,
 		)
-		return nil, errors.New("not able to get challenge labs")
+		return nil, entity.NewStorageError("not able to get challenge labs", err)
 	}
 
 	return redactLabs(labs), nil
@@ -90,12 +92,24 @@ func (c *challengeService) GetAllChallenges(ctx context.Context) ([]entity.Chall
 		logger.LogError(ctx, "failed to get all challenges",
 			"error", err,
 		)
-		return challenges, errors.New("not able to get challenges")
+		return challenges, entity.NewStorageError("not able to get challenges", err)
 	}
 
 	return challenges, nil
 }
 
+func (c *challengeService) ListChallenges(ctx context.Context, query entity.ListQuery) (entity.Page[entity.Challenge], error) {
+	page, err := c.challengeRepository.ListChallenges(ctx, query)
+	if err != nil {
+		logger.LogError(ctx, "failed to list challenges",
+			"error", err,
+		)
+		return page, listError(err, "not able to get challenges")
+	}
+
+	return page, nil
+}
+
 func (c *challengeService) GetChallengeByUserIdAndLabId(ctx context.Context, userId string, labId string) (entity.Challenge, error) {
 	challenge, err := c.challengeRepository.GetChallengeByUserIdAndLabId(ctx, userId, labId)
 	if err != nil {
@@ -104,7 +118,7 @@ func (c *challengeService) GetChallengeByUserIdAndLabId(ctx context.Context, use
 			"lab_id", labId,
 			"error", err,
 		)
-		return challenge, fmt.Errorf("not able to get challenge for user id %s and lab id %s", userId, labId)
+		return challenge, lookupError(err, entity.NewNotFoundError("challenge_not_found", fmt.Sprintf("challenge not found for user id %s and lab id %s", userId, labId)), fmt.Sprintf("not able to get challenge for user id %s and lab id %s", userId, labId))
 	}
 
 	return challenge, nil
@@ -117,7 +131,7 @@ func (c *challengeService) GetChallengesByLabId(ctx context.Context, labId strin
 			"lab_id", labId,
 			"error", err,
 		)
-		return challenges, fmt.Errorf("not able to get challenges for lab id %s", labId)
+		return challenges, entity.NewStorageError(fmt.Sprintf("not able to get challenges for lab id %s", labId), err)
 	}
 
 	return challenges, nil
@@ -130,7 +144,7 @@ func (c *challengeService) GetChallengesByUserId(ctx context.Context, userId str
 			"user_id", userId,
 			"error", err,
 		)
-		return challenges, fmt.Errorf("not able to get challenges for user id %s", userId)
+		return challenges, entity.NewStorageError(fmt.Sprintf("not able to get challenges for user id %s", userId), err)
 	}
 	return challenges, nil
 }
@@ -161,7 +175,7 @@ func (c *challengeService) UpsertChallenges(ctx context.Context, challenges []en
 				"lab_id", challenge.LabId,
 				"error", err,
 			)
-			return fmt.Errorf("not able to upsert challenge for user id %s and lab id %s. may be all challenges not added", challenge.UserId, challenge.LabId)
+			return entity.NewStorageError(fmt.Sprintf("not able to upsert challenge for user id %s and lab id %s. may be all challenges not added", challenge.UserId, challenge.LabId), err)
 		}
 	}
 
@@ -171,7 +185,7 @@ func (c *challengeService) UpsertChallenges(ctx context.Context, challenges []en
 func (c *challengeService) CreateChallenges(ctx context.Context, userIds []string, labIds []string, createdBy string) error {
 
 	for _, userId := range userIds {
-		userId, err := normalizeUserId(userId)
+		userId, err := normalizeUserId(userId, c.directory)
 		if err != nil {
 			logger.LogError(ctx, "invalid user id domain",
 				"user_id", userId,
@@ -199,7 +213,7 @@ func (c *challengeService) CreateChallenges(ctx context.Context, userIds []strin
 					"lab_id", labId,
 					"error", err,
 				)
-				return fmt.Errorf("not able to create challenge for user id %s and lab id %s", userId, labId)
+				return entity.NewStorageError(fmt.Sprintf("not able to create challenge for user id %s and lab id %s", userId, labId), err)
 			}
 		}
 	}
@@ -215,7 +229,7 @@ func (c *challengeService) UpdateChallenge(ctx context.Context, userId string, l
 			"lab_id", labId,
 			"error", err,
 		)
-		return fmt.Errorf("not able to get challenges for user id %s", userId)
+		return entity.NewStorageError(fmt.Sprintf("not able to get challenges for user id %s", userId), err)
 	}
 
 	var challenge entity.Challenge
@@ -231,7 +245,7 @@ func (c *challengeService) UpdateChallenge(ctx context.Context, userId string, l
 			"user_id", userId,
 			"lab_id", labId,
 		)
-		return fmt.Errorf("challenge not found for user id %s and lab id %s", userId, labId)
+		return entity.NewNotFoundError("challenge_not_found", fmt.Sprintf("challenge not found for user id %s and lab id %s", userId, labId))
 	}
 
 	updated, err := applyStatusTransition(challenge, status, helper.GetTodaysDateTimeString())
@@ -250,31 +264,23 @@ func (c *challengeService) UpdateChallenge(ctx context.Context, userId string, l
 			"lab_id", labId,
 			"error", err,
 		)
-		return fmt.Errorf("not able to update challenge for user id %s and lab id %s", userId, labId)
+		return entity.NewStorageError(fmt.Sprintf("not able to update challenge for user id %s and lab id %s", userId, labId), err)
 	}
 
 	return nil
 }
 
-// normalizeUserId ensures the userId is a valid @microsoft.com email.
+// normalizeUserId ensures the userId is a valid email in one of the allowed domains.
 // The alias portion (before @) must contain only a-z and hyphens.
-// If the userId has no domain, @microsoft.com is appended.
-// If it has a non-@microsoft.com domain, an error is returned.
-func normalizeUserId(userId string) (string, error) {
-	var alias string
-	if !strings.Contains(userId, "@") {
-		alias = userId
-	} else if strings.HasSuffix(userId, "@microsoft.com") {
-		alias = strings.TrimSuffix(userId, "@microsoft.com")
-	} else {
-		return "", fmt.Errorf("invalid email domain for user id %s", userId)
-	}
-
+// If the userId has no domain, the default domain is appended.
+// If it has a domain that isn't allowed, an error is returned.
+func normalizeUserId(userId string, directory *identity.Directory) (string, error) {
+	alias, _, _ := strings.Cut(userId, "@")
 	if !validAliasPattern.MatchString(alias) {
 		return "", fmt.Errorf("invalid alias for user id %s", userId)
 	}
 
-	return alias + "@microsoft.com", nil
+	return directory.QualifyUserId(userId)
 }
 
 // applyStatusTransition is a pure function that applies a status transition to a challenge.
@@ -288,7 +294,7 @@ func applyStatusTransition(challenge entity.Challenge, status string, now string
 	case entity.ChallengeStatusCreated:
 		challenge.CreatedOn = now
 	default:
-		return challenge, fmt.Errorf("invalid status: %s", status)
+		return challenge, entity.NewValidationError("invalid_status", fmt.Sprintf("invalid status: %s", status))
 	}
 	challenge.Status = status
 	return challenge, nil
@@ -304,7 +310,7 @@ func (c *challengeService) DeleteChallenges(ctx context.Context, challengeIds []
 			logger.LogError(ctx, "invalid challenge id format",
 				"challenge_id", challengeId,
 			)
-			return fmt.Errorf("invalid challenge id format for challenge id %s", challengeId)
+			return entity.NewValidationError("invalid_challenge_id", fmt.Sprintf("invalid challenge id format for challenge id %s", challengeId))
 		}
 
 		userId := parts[0]
@@ -315,7 +321,10 @@ func (c *challengeService) DeleteChallenges(ctx context.Context, challengeIds []
 				"challenge_id", challengeId,
 				"error", err,
 			)
-			return fmt.Errorf("delete not allowed for challenge id %s", challengeId)
+			if entity.ErrorKindOf(err) != entity.ErrorKindForbidden {
+				return err
+			}
+			return entity.NewForbiddenError("challenge_delete_forbidden", fmt.Sprintf("delete not allowed for challenge id %s", challengeId))
 		}
 
 		if err := c.challengeRepository.DeleteChallenge(ctx, challengeId); err != nil {
@@ -323,7 +332,7 @@ func (c *challengeService) DeleteChallenges(ctx context.Context, challengeIds []
 				"challenge_id", challengeId,
 				"error", err,
 			)
-			return fmt.Errorf("not able to delete challenge for challenge id %s. stopped processing remaining challenges", challengeId)
+			return entity.NewStorageError(fmt.Sprintf("not able to delete challenge for challenge id %s. stopped processing remaining challenges", challengeId), err)
 		}
 	}
 
@@ -339,7 +348,7 @@ func (c *challengeService) IsDeleteAllowed(ctx context.Context, userId string, l
 			"challenge_id", userId+"+"+labId,
 			"error", err,
 		)
-		return fmt.Errorf("not able to get challenge for challenge id %s", userId+"+"+labId)
+		return lookupError(err, entity.NewNotFoundError("challenge_not_found", fmt.Sprintf("challenge not found for challenge id %s", userId+"+"+labId)), fmt.Sprintf("not able to get challenge for challenge id %s", userId+"+"+labId))
 	}
 
 	// The calling user is the one making the API request.
@@ -352,7 +361,7 @@ func (c *challengeService) IsDeleteAllowed(ctx context.Context, userId string, l
 			"lab_id", challenge.LabId,
 			"error", err,
 		)
-		return fmt.Errorf("not able to get lab for lab id %s", challenge.LabId)
+		return err
 	}
 
 	return isDeleteAllowed(callingUserId, challenge, lab)
@@ -385,5 +394,5 @@ func isDeleteAllowed(callingUserId string, challenge entity.Challenge, lab entit
 		return nil
 	}
 
-	return errors.New("delete not allowed for this lab")
+	return entity.NewForbiddenError("challenge_delete_forbidden", "delete not allowed for this lab")
 }
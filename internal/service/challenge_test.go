@@ -2,6 +2,7 @@ package service
 
 import (
 	"actlabs-hub/internal/entity"
+	"actlabs-hub/internal/identity"
 	"actlabs-hub/internal/logger"
 	"actlabs/labentity"
 	"context"
@@ -11,6 +12,11 @@ import (
 	"testing"
 )
 
+var testDirectory = identity.NewDirectory([]entity.Tenant{
+	{Domain: "microsoft.com", TenantId: "corp-tenant", ServerVersion: "V2"},
+	{Domain: "microsoft.com", TenantId: "fdpo-tenant", ServerVersion: "V3"},
+})
+
 func TestIsDeleteAllowed(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -202,6 +208,9 @@ type mockChallengeRepository struct {
 func (m *mockChallengeRepository) GetAllChallenges(ctx context.Context) ([]entity.Challenge, error) {
 	return m.challenges, m.err
 }
+func (m *mockChallengeRepository) ListChallenges(ctx context.Context, query entity.ListQuery) (entity.Page[entity.Challenge], error) {
+	return entity.Page[entity.Challenge]{Items: m.challenges}, m.err
+}
 func (m *mockChallengeRepository) GetChallengeByUserIdAndLabId(ctx context.Context, userId string, labId string) (entity.Challenge, error) {
 	return m.challenge, m.err
 }
@@ -281,6 +290,12 @@ func (m *mockLabService) GetLabByIdAndType(ctx context.Context, typeOfLab string
 func (m *mockLabService) GetLabs(ctx context.Context, typeOfLab string) ([]entity.LabType, error) {
 	return m.labs, m.err
 }
+func (m *mockLabService) GetAllLabs(ctx context.Context, typeOfLab string) ([]entity.LabType, error) {
+	return m.labs, m.err
+}
+func (m *mockLabService) ListLabs(ctx context.Context, typeOfLab string, userId string, query entity.ListQuery) (entity.Page[entity.LabType], error) {
+	return entity.Page[entity.LabType]{Items: m.labs}, m.err
+}
 func (m *mockLabService) GetLabVersions(ctx context.Context, typeOfLab string, labId string) ([]entity.LabType, error) {
 	return m.labs, m.err
 }
@@ -290,9 +305,19 @@ func (m *mockLabService) UpsertLab(ctx context.Context, lab entity.LabType) (ent
 func (m *mockLabService) DeleteLab(ctx context.Context, typeOfLab string, labId string) error {
 	return m.err
 }
-func (m *mockLabService) UpsertSupportingDocument(ctx context.Context, supportingDocument multipart.File) (string, error) {
+func (m *mockLabService) ImportLabs(ctx context.Context, labs []entity.LabType) (int, error) {
+	return len(labs), m.err
+}
+func (m *mockLabService) UpsertSupportingDocument(ctx context.Context, supportingDocument multipart.File, header *multipart.FileHeader, uploadedBy string) (string, error) {
 	return "", m.err
 }
+func (m *mockLabService) GetSupportingDocumentMetadata(ctx context.Context, supportingDocumentId string) (entity.SupportingDocument, error) {
+	return entity.SupportingDocument{}, m.err
+}
+func (m *mockLabService) CollectSupportingDocuments(ctx context.Context) (int, error) {
+	return 0, m.err
+}
+func (m *mockLabService) MonitorSupportingDocuments(ctx context.Context) {}
 func (m *mockLabService) DeleteSupportingDocument(ctx context.Context, supportingDocumentId string) error {
 	return m.err
 }
@@ -687,7 +712,7 @@ func TestNormalizeUserId(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := normalizeUserId(tt.input)
+			got, err := normalizeUserId(tt.input, testDirectory)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("normalizeUserId(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
 				return
@@ -702,12 +727,60 @@ func TestNormalizeUserId(t *testing.T) {
 	}
 }
 
+func TestNormalizeUserIdPartnerDomains(t *testing.T) {
+	directory := identity.NewDirectory([]entity.Tenant{
+		{Domain: "contoso.com", TenantId: "contoso-tenant", ServerVersion: "V2"},
+		{Domain: "fabrikam.com", TenantId: "fabrikam-tenant", ServerVersion: "V2"},
+	})
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "alias gets first configured domain",
+			input: "alice",
+			want:  "alice@contoso.com",
+		},
+		{
+			name:  "second configured domain is allowed",
+			input: "bob@fabrikam.com",
+			want:  "bob@fabrikam.com",
+		},
+		{
+			name:  "domain is matched case insensitively",
+			input: "bob@Fabrikam.COM",
+			want:  "bob@fabrikam.com",
+		},
+		{
+			name:    "unconfigured domain is rejected",
+			input:   "user@microsoft.com",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeUserId(tt.input, directory)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("normalizeUserId(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("normalizeUserId(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestCreateChallengesOrchestrator(t *testing.T) {
 	t.Run("skips invalid user ids", func(t *testing.T) {
 		svc := &challengeService{
 			challengeRepository: &mockChallengeRepository{
 				validateUser: true,
 			},
+			directory: testDirectory,
 		}
 		// "User" has uppercase, should be skipped; no upsert call, no error
 		err := svc.CreateChallenges(context.Background(), []string{"User"}, []string{"lab1"}, "creator@microsoft.com")
@@ -721,6 +794,7 @@ func TestCreateChallengesOrchestrator(t *testing.T) {
 			challengeRepository: &mockChallengeRepository{
 				validateUser: true,
 			},
+			directory: testDirectory,
 		}
 		err := svc.CreateChallenges(context.Background(), []string{"attacker@evil.com"}, []string{"lab1"}, "creator@microsoft.com")
 		if err != nil {
@@ -733,6 +807,7 @@ func TestCreateChallengesOrchestrator(t *testing.T) {
 			challengeRepository: &mockChallengeRepository{
 				validateUser: true,
 			},
+			directory: testDirectory,
 		}
 		err := svc.CreateChallenges(context.Background(), []string{"ashish"}, []string{"lab1"}, "creator@microsoft.com")
 		if err != nil {
@@ -745,6 +820,7 @@ func TestCreateChallengesOrchestrator(t *testing.T) {
 			challengeRepository: &mockChallengeRepository{
 				validateUser: true,
 			},
+			directory: testDirectory,
 		}
 		err := svc.CreateChallenges(context.Background(), []string{"user@microsoft.com"}, []string{"lab1"}, "creator@microsoft.com")
 		if err != nil {
@@ -758,6 +834,7 @@ func TestCreateChallengesOrchestrator(t *testing.T) {
 				validateUser: true,
 				upsertErr:    errors.New("upsert failed"),
 			},
+			directory: testDirectory,
 		}
 		err := svc.CreateChallenges(context.Background(), []string{"user@microsoft.com"}, []string{"lab1"}, "creator@microsoft.com")
 		if err == nil {
@@ -770,6 +847,7 @@ func TestCreateChallengesOrchestrator(t *testing.T) {
 			challengeRepository: &mockChallengeRepository{
 				validateUser: true,
 			},
+			directory: testDirectory,
 		}
 		err := svc.CreateChallenges(context.Background(), []string{"user-a", "user-b"}, []string{"lab1", "lab2"}, "creator@microsoft.com")
 		if err != nil {
@@ -782,6 +860,7 @@ func TestCreateChallengesOrchestrator(t *testing.T) {
 			challengeRepository: &mockChallengeRepository{
 				validateUser: true,
 			},
+			directory: testDirectory,
 		}
 		err := svc.CreateChallenges(context.Background(), []string{""}, []string{"lab1"}, "creator@microsoft.com")
 		if err != nil {
@@ -794,6 +873,7 @@ func TestCreateChallengesOrchestrator(t *testing.T) {
 			challengeRepository: &mockChallengeRepository{
 				validateUser: true,
 			},
+			directory: testDirectory,
 		}
 		err := svc.CreateChallenges(context.Background(), []string{"attacker@evil.com", "valid-user"}, []string{"lab1"}, "creator@microsoft.com")
 		if err != nil {
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+
+	"actlabs-hub/internal/entity"
+	"actlabs-hub/internal/logger"
+)
+
+type costService struct {
+	priceSheetRepository entity.PriceSheetRepository
+}
+
+func NewCostService(priceSheetRepository entity.PriceSheetRepository) entity.CostService {
+	return &costService{
+		priceSheetRepository: priceSheetRepository,
+	}
+}
+
+func (c *costService) EstimateLabCost(ctx context.Context, lab entity.LabType) (entity.CostEstimate, error) {
+	priceSheet, err := c.priceSheetRepository.GetPriceSheet(ctx)
+	if err != nil {
+		return entity.CostEstimate{}, err
+	}
+
+	// The template is read through its JSON form so the estimate only relies
+	// on the tfvar field names, not on the Go types of every resource.
+	templateJSON, err := json.Marshal(lab.Template)
+	if err != nil {
+		logger.LogError(ctx, "failed to marshal lab template for cost estimate",
+			"lab_id", lab.Id,
+			"error", err,
+		)
+		return entity.CostEstimate{}, err
+	}
+
+	template := map[string]any{}
+	if err := json.Unmarshal(templateJSON, &template); err != nil {
+		return entity.CostEstimate{}, err
+	}
+
+	return estimateTemplateCost(template, priceSheet), nil
+}
+
+func (c *costService) EstimateDeploymentCost(ctx context.Context, deployment entity.Deployment) (entity.CostEstimate, error) {
+	estimate, err := c.EstimateLabCost(ctx, deployment.DeploymentLab)
+	if err != nil {
+		return entity.CostEstimate{}, err
+	}
+
+	if deployment.DeploymentAutoDelete && deployment.DeploymentLifespan > 0 {
+		estimate.LifespanCost = roundCost(estimate.HourlyCost * float64(deployment.DeploymentLifespan) / 3600)
+	}
+
+	return estimate, nil
+}
+
+func estimateTemplateCost(template map[string]any, priceSheet entity.PriceSheet) entity.CostEstimate {
+	estimate := entity.CostEstimate{
+		Currency: priceSheet.Currency,
+		Items:    []entity.CostItem{},
+	}
+
+	add := func(resource string, sku string, quantity int, unitHourly float64) {
+		if quantity <= 0 {
+			return
+		}
+		hourly := unitHourly * float64(quantity)
+		estimate.Items = append(estimate.Items, entity.CostItem{
+			Resource:   resource,
+			Sku:        sku,
+			Quantity:   quantity,
+			HourlyCost: roundCost(hourly),
+		})
+		estimate.HourlyCost += hourly
+	}
+
+	for _, cluster := range templateObjects(template, "kubernetesClusters") {
+		add("kubernetesCluster", "", 1, priceSheet.KubernetesClusterHourly)
+
+		nodePool, _ := cluster["defaultNodePool"].(map[string]any)
+		vmSize := templateString(nodePool, "vmSize", priceSheet.DefaultNodeVmSize)
+		add("kubernetesNode", vmSize, nodeCount(nodePool), vmHourly(priceSheet, vmSize))
+
+		// The app gateway ingress controller addon deploys its own gateway.
+		if addons, ok := cluster["addons"].(map[string]any); ok && addons["appGateway"] == true {
+			add("appGateway", "", 1, priceSheet.AppGatewayHourly)
+		}
+	}
+
+	for _, cluster := range templateObjects(template, "aroClusters") {
+		add("aroCluster", "", 1, priceSheet.AroClusterHourly)
+
+		workerProfile, _ := cluster["workerProfile"].(map[string]any)
+		vmSize := templateString(workerProfile, "vmSize", priceSheet.DefaultAroWorkerVmSize)
+		count := templateInt(workerProfile, "count")
+		if count == 0 {
+			count = 3
+		}
+		add("aroWorkerNode", vmSize, count, vmHourly(priceSheet, vmSize))
+	}
+
+	for _, jumpserver := range templateObjects(template, "jumpservers") {
+		vmSize := templateString(jumpserver, "vmSize", priceSheet.DefaultJumpserverVmSize)
+		add("jumpserver", vmSize, 1, vmHourly(priceSheet, vmSize))
+	}
+
+	for _, firewall := range templateObjects(template, "firewalls") {
+		tier := templateString(firewall, "skuTier", "Standard")
+		hourly, ok := priceSheet.FirewallHourly[tier]
+		if !ok {
+			hourly = priceSheet.DefaultFirewallHourly
+		}
+		add("firewall", tier, 1, hourly)
+	}
+
+	add("appGateway", "", len(templateObjects(template, "appGateways")), priceSheet.AppGatewayHourly)
+	add("containerRegistry", "", len(templateObjects(template, "containerRegistries")), priceSheet.ContainerRegistryHourly)
+
+	estimate.HourlyCost = roundCost(estimate.HourlyCost)
+
+	return estimate
+}
+
+// nodeCount is the fixed node count, or the minimum when autoscaling.
+// Autoscaled pools are estimated at their floor.
+func nodeCount(nodePool map[string]any) int {
+	if count := templateInt(nodePool, "nodeCount"); count > 0 && nodePool["enableAutoScaling"] != true {
+		return count
+	}
+	if count := templateInt(nodePool, "minCount"); count > 0 {
+		return count
+	}
+	if count := templateInt(nodePool, "nodeCount"); count > 0 {
+		return count
+	}
+	return 1
+}
+
+func vmHourly(priceSheet entity.PriceSheet, vmSize string) float64 {
+	if hourly, ok := priceSheet.VmHourly[vmSize]; ok {
+		return hourly
+	}
+	return priceSheet.DefaultVmHourly
+}
+
+func templateObjects(template map[string]any, key string) []map[string]any {
+	items, _ := template[key].([]any)
+	objects := []map[string]any{}
+	for _, item := range items {
+		if object, ok := item.(map[string]any); ok {
+			objects = append(objects, object)
+		}
+	}
+	return objects
+}
+
+func templateString(object map[string]any, key string, fallback string) string {
+	if value, ok := object[key].(string); ok && value != "" {
+		return value
+	}
+	return fallback
+}
+
+func templateInt(object map[string]any, key string) int {
+	value, _ := object[key].(float64)
+	return int(value)
+}
+
+func roundCost(cost float64) float64 {
+	return math.Round(cost*10000) / 10000
+}
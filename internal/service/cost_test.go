@@ -0,0 +1,49 @@
+package service
+
+import (
+	"actlabs-hub/internal/entity"
+	"testing"
+)
+
+func TestEstimateTemplateCost(t *testing.T) {
+	priceSheet := entity.PriceSheet{
+		Currency:                "USD",
+		VmHourly:                map[string]float64{"Standard_D4s_v3": 0.2},
+		DefaultVmHourly:         0.1,
+		DefaultNodeVmSize:       "Standard_D2s_v3",
+		DefaultJumpserverVmSize: "Standard_B2s",
+		KubernetesClusterHourly: 0.1,
+		AppGatewayHourly:        0.25,
+		FirewallHourly:          map[string]float64{"Basic": 0.4},
+		DefaultFirewallHourly:   1,
+	}
+
+	template := map[string]any{
+		"kubernetesClusters": []any{
+			map[string]any{
+				"defaultNodePool": map[string]any{
+					"vmSize":            "Standard_D4s_v3",
+					"enableAutoScaling": true,
+					"minCount":          float64(2),
+					"nodeCount":         float64(5),
+				},
+				"addons": map[string]any{"appGateway": true},
+			},
+		},
+		"jumpservers": []any{map[string]any{}},
+		"firewalls":   []any{map[string]any{"skuTier": "Basic"}},
+	}
+
+	estimate := estimateTemplateCost(template, priceSheet)
+
+	// cluster 0.1 + 2 autoscaled nodes 0.4 + app gateway 0.25 + jumpserver 0.1 + firewall 0.4
+	if estimate.HourlyCost != 1.25 {
+		t.Errorf("expected hourly cost 1.25, got %v: %+v", estimate.HourlyCost, estimate.Items)
+	}
+	if len(estimate.Items) != 5 {
+		t.Errorf("expected 5 items, got %d: %+v", len(estimate.Items), estimate.Items)
+	}
+	if estimate.Currency != "USD" {
+		t.Errorf("expected currency USD, got %s", estimate.Currency)
+	}
+}
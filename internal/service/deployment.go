@@ -3,32 +3,61 @@ package service
 import (
 	"context"
 	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"actlabs-hub/internal/config"
 	"actlabs-hub/internal/entity"
+	"actlabs-hub/internal/health"
 	"actlabs-hub/internal/helper"
 	"actlabs-hub/internal/logger"
+	"actlabs-hub/internal/metrics"
 )
 
 type DeploymentService struct {
 	deploymentRepository entity.DeploymentRepository
 	serverService        entity.ServerService
 	eventService         entity.EventService
+	authService          entity.AuthService
+	notifier             entity.Notifier
+	quotaService         entity.QuotaService
+	costService          entity.CostService
+	serverStatusClient   entity.ServerStatusClient
+	tasks                *helper.TaskGroup
 	appConfig            *config.Config
+
+	// Set from the runtime settings, zero until they're applied.
+	pollingIntervalSeconds atomic.Int32
+	pollingIntervalChanged chan struct{}
 }
 
 func NewDeploymentService(
 	deploymentRepo entity.DeploymentRepository,
 	serverService entity.ServerService,
 	eventService entity.EventService,
+	authService entity.AuthService,
+	notifier entity.Notifier,
+	quotaService entity.QuotaService,
+	costService entity.CostService,
+	serverStatusClient entity.ServerStatusClient,
+	tasks *helper.TaskGroup,
 	appConfig *config.Config,
 ) entity.DeploymentService {
 	return &DeploymentService{
-		deploymentRepository: deploymentRepo,
-		serverService:        serverService,
-		eventService:         eventService,
-		appConfig:            appConfig,
+		deploymentRepository:   deploymentRepo,
+		serverService:          serverService,
+		eventService:           eventService,
+		authService:            authService,
+		notifier:               notifier,
+		quotaService:           quotaService,
+		costService:            costService,
+		serverStatusClient:     serverStatusClient,
+		tasks:                  tasks,
+		appConfig:              appConfig,
+		pollingIntervalChanged: make(chan struct{}, 1),
 	}
 }
 
@@ -57,13 +86,13 @@ func (d *DeploymentService) GetUserDeployments(ctx context.Context, usePrincipal
 	return deployments, err
 }
 
-func (d *DeploymentService) GetDeployment(ctx context.Context, usePrincipalName string, workspace string, subscriptionId string) (entity.Deployment, error) {
-	deployment, err := d.deploymentRepository.GetDeployment(ctx, usePrincipalName, workspace, subscriptionId)
+func (d *DeploymentService) GetDeployment(ctx context.Context, key entity.DeploymentKey) (entity.Deployment, error) {
+	deployment, err := d.deploymentRepository.GetDeployment(ctx, key)
 	if err != nil {
 		logger.LogError(ctx, "failed to get deployment",
-			"requested_user_id", usePrincipalName,
-			"workspace", workspace,
-			"subscription_id", subscriptionId,
+			"requested_user_id", key.UserId,
+			"workspace", key.Workspace,
+			"subscription_id", key.SubscriptionId,
 			"error", err,
 		)
 		return entity.Deployment{}, err
@@ -79,9 +108,38 @@ func (d *DeploymentService) UpsertDeployment(ctx context.Context, deployment ent
 			"subscription_id", deployment.DeploymentSubscriptionId,
 			"user_id", deployment.DeploymentUserId,
 		)
-		return fmt.Errorf("userId, workspace or subscription id cant be empty")
+		return entity.NewValidationError("invalid_deployment", "userId, workspace or subscription id cant be empty")
+	}
+
+	lifespan, err := d.quotaService.CheckDeployment(ctx, deployment)
+	if err != nil {
+		logger.LogWarning(ctx, "deployment rejected by quota",
+			"workspace", deployment.DeploymentWorkspace,
+			"subscription_id", deployment.DeploymentSubscriptionId,
+			"error", err,
+		)
+
+		// Create Event
+		if err := d.eventService.CreateEvent(ctx, entity.Event{
+			TimeStamp: time.Now().Format(time.RFC3339),
+			Type:      "Warning",
+			Reason:    "DeploymentQuotaExceeded",
+			Message:   fmt.Sprintf("Update of deployment of user %s for subscription %s with workspace %s rejected, %s.", deployment.DeploymentUserId, deployment.DeploymentSubscriptionId, deployment.DeploymentWorkspace, err.Error()),
+			Reporter:  "actlabs-hub",
+			Object:    deployment.DeploymentUserId,
+		}); err != nil {
+			logger.LogError(ctx, "failed to create warning event",
+				"workspace", deployment.DeploymentWorkspace,
+				"subscription_id", deployment.DeploymentSubscriptionId,
+				"error", err,
+			)
+		}
+
+		return err
 	}
 
+	d.estimateCost(ctx, &deployment)
+
 	if err := d.deploymentRepository.UpsertDeployment(ctx, deployment); err != nil {
 		logger.LogError(ctx, "failed to upsert deployment",
 			"workspace", deployment.DeploymentWorkspace,
@@ -124,14 +182,26 @@ func (d *DeploymentService) UpsertDeployment(ctx context.Context, deployment ent
 		)
 	}
 
+	if err := d.quotaService.RecordLifespan(ctx, deployment.DeploymentUserId, lifespan); err != nil {
+		logger.LogError(ctx, "failed to record deployment lifespan for quota",
+			"workspace", deployment.DeploymentWorkspace,
+			"subscription_id", deployment.DeploymentSubscriptionId,
+			"error", err,
+		)
+	}
+
 	// Add deployment operation entry
 	// sending a different context here cause http context will end early while this operation is still running.
-	go d.deploymentRepository.DeploymentOperationEntry(context.Background(), deployment)
+	d.tasks.Go("DeploymentOperationEntry", func() {
+		d.deploymentRepository.DeploymentOperationEntry(context.WithoutCancel(ctx), deployment)
+	})
 
 	return nil
 }
 
-func (d *DeploymentService) DeleteDeployment(ctx context.Context, userPrincipalName string, subscriptionId string, workspace string) error {
+func (d *DeploymentService) DeleteDeployment(ctx context.Context, key entity.DeploymentKey) error {
+	userPrincipalName, subscriptionId, workspace := key.UserId, key.SubscriptionId, key.Workspace
+
 	// default deployment cant be deleted.
 	if workspace == "default" {
 		logger.LogError(ctx, "default workspace cannot be deleted",
@@ -142,7 +212,7 @@ func (d *DeploymentService) DeleteDeployment(ctx context.Context, userPrincipalN
 		return nil
 	}
 
-	if err := d.deploymentRepository.DeleteDeployment(ctx, userPrincipalName, subscriptionId, workspace); err != nil {
+	if err := d.deploymentRepository.DeleteDeployment(ctx, key); err != nil {
 		logger.LogError(ctx, "failed to delete deployment",
 			"requested_user_id", userPrincipalName,
 			"workspace", workspace,
@@ -190,9 +260,255 @@ func (d *DeploymentService) DeleteDeployment(ctx context.Context, userPrincipalN
 	return nil
 }
 
+func (d *DeploymentService) GetDeploymentHistory(ctx context.Context, userPrincipalName string, subscriptionId string, workspace string, limit int32, nextToken string) (entity.DeploymentHistory, error) {
+	operations, next, err := d.deploymentRepository.GetDeploymentOperations(ctx, userPrincipalName, subscriptionId, workspace, limit, nextToken)
+	if err != nil {
+		logger.LogError(ctx, "failed to get deployment operations",
+			"requested_user_id", userPrincipalName,
+			"workspace", workspace,
+			"subscription_id", subscriptionId,
+			"error", err,
+		)
+		return entity.DeploymentHistory{}, err
+	}
+
+	return entity.DeploymentHistory{
+		UserId:         userPrincipalName,
+		SubscriptionId: subscriptionId,
+		Workspace:      workspace,
+		Operations:     operations,
+		Phases:         deploymentPhases(operations),
+		NextToken:      next,
+	}, nil
+}
+
+// deploymentPhases fills in the time since the previous operation and pairs
+// each "<Phase> In Progress" with the "<Phase> Completed" or "<Phase> Failed"
+// that follows it. A phase still running at the end of the page is left out.
+func deploymentPhases(operations []entity.DeploymentOperation) []entity.DeploymentPhase {
+	phases := []entity.DeploymentPhase{}
+	started := map[string]time.Time{}
+
+	var previous time.Time
+	for i, operation := range operations {
+		at, err := time.Parse(time.RFC3339Nano, operation.Timestamp)
+		if err != nil {
+			continue
+		}
+		if !previous.IsZero() {
+			operations[i].SincePreviousSeconds = int64(at.Sub(previous).Seconds())
+		}
+		previous = at
+
+		phase, state, found := strings.Cut(string(operation.Status), " ")
+		if !found {
+			continue
+		}
+
+		switch state {
+		case "In Progress":
+			started[phase] = at
+		case "Completed", "Failed":
+			startedAt, ok := started[phase]
+			if !ok {
+				continue
+			}
+			delete(started, phase)
+
+			phases = append(phases, entity.DeploymentPhase{
+				Phase:           phase,
+				Result:          operation.Status,
+				StartedAt:       startedAt.Format(time.RFC3339Nano),
+				EndedAt:         operation.Timestamp,
+				DurationSeconds: int64(at.Sub(startedAt).Seconds()),
+			})
+		}
+	}
+
+	return phases
+}
+
+func (d *DeploymentService) ExtendDeployment(ctx context.Context, userPrincipalName string, subscriptionId string, workspace string, duration time.Duration) (entity.Deployment, error) {
+	if duration <= 0 {
+		return entity.Deployment{}, entity.NewValidationError("invalid_duration", "extension duration must be positive")
+	}
+
+	deployments, err := d.deploymentRepository.GetUserDeployments(ctx, userPrincipalName)
+	if err != nil {
+		logger.LogError(ctx, "failed to get user deployments for extension",
+			"requested_user_id", userPrincipalName,
+			"error", err,
+		)
+		return entity.Deployment{}, entity.NewStorageError("not able to get deployments", err)
+	}
+
+	index := slices.IndexFunc(deployments, func(deployment entity.Deployment) bool {
+		return deployment.DeploymentSubscriptionId == subscriptionId && deployment.DeploymentWorkspace == workspace
+	})
+	if index == -1 {
+		return entity.Deployment{}, entity.ErrDeploymentNotFound
+	}
+	deployment := deployments[index]
+
+	if !deployment.DeploymentAutoDelete {
+		return entity.Deployment{}, entity.ErrDeploymentNotAutoDeleted
+	}
+
+	// Anything in progress gets its auto delete time when it completes.
+	if deployment.DeploymentStatus != entity.DeploymentCompleted && deployment.DeploymentStatus != entity.DeploymentFailed {
+		return entity.Deployment{}, entity.ErrDeploymentNotExtendable
+	}
+
+	// An expired deployment is already being destroyed.
+	autoDeleteTime := time.Unix(deployment.DeploymentAutoDeleteUnixTime, 0)
+	if deployment.DeploymentAutoDeleteUnixTime == 0 || !autoDeleteTime.After(time.Now()) {
+		return entity.Deployment{}, entity.ErrDeploymentExpired
+	}
+	autoDeleteTime = autoDeleteTime.Add(duration)
+
+	// The lifespan adds up every extension, so it caps how long the deployment
+	// lives in total rather than how far out it can be pushed each time.
+	maxLifespan := time.Duration(d.maxLifespanSeconds(ctx, userPrincipalName)) * time.Second
+	lifespan := time.Duration(deployment.DeploymentLifespan)*time.Second + duration
+
+	if lifespan > maxLifespan {
+		logger.LogWarning(ctx, "deployment extension exceeds maximum lifespan",
+			"requested_user_id", userPrincipalName,
+			"workspace", workspace,
+			"subscription_id", subscriptionId,
+			"lifespan_seconds", int64(lifespan.Seconds()),
+			"max_lifespan_seconds", int64(maxLifespan.Seconds()),
+		)
+		return entity.Deployment{}, fmt.Errorf("%w: at most %s in total, %s left", entity.ErrLifespanExceedsPolicy, maxLifespan, max(maxLifespan-time.Duration(deployment.DeploymentLifespan)*time.Second, 0))
+	}
+
+	if err := d.quotaService.CheckLifespan(ctx, userPrincipalName, int64(duration.Seconds())); err != nil {
+		return entity.Deployment{}, err
+	}
+
+	deployment.DeploymentLifespan += int64(duration.Seconds())
+	deployment.DeploymentAutoDeleteUnixTime = autoDeleteTime.Unix()
+	d.estimateCost(ctx, &deployment)
+
+	if err := d.deploymentRepository.UpsertDeployment(ctx, deployment); err != nil {
+		logger.LogError(ctx, "failed to upsert extended deployment",
+			"requested_user_id", userPrincipalName,
+			"workspace", workspace,
+			"subscription_id", subscriptionId,
+			"error", err,
+		)
+		return entity.Deployment{}, err
+	}
+
+	if err := d.quotaService.RecordLifespan(ctx, userPrincipalName, int64(duration.Seconds())); err != nil {
+		logger.LogError(ctx, "failed to record deployment lifespan for quota",
+			"workspace", workspace,
+			"subscription_id", subscriptionId,
+			"error", err,
+		)
+	}
+
+	// Create Event
+	if err := d.eventService.CreateEvent(ctx, entity.Event{
+		TimeStamp: time.Now().Format(time.RFC3339),
+		Type:      "Normal",
+		Reason:    "DeploymentExtended",
+		Message:   fmt.Sprintf("Deployment of user %s for subscription %s with workspace %s is extended by %s, it will be deleted at %s.", userPrincipalName, subscriptionId, workspace, duration, autoDeleteTime.UTC().Format(time.RFC3339)),
+		Reporter:  "actlabs-hub",
+		Object:    userPrincipalName,
+	}); err != nil {
+		logger.LogError(ctx, "failed to create success event",
+			"requested_user_id", userPrincipalName,
+			"workspace", workspace,
+			"subscription_id", subscriptionId,
+			"error", err,
+		)
+	}
+
+	// Add deployment operation entry
+	d.tasks.Go("DeploymentOperationEntry", func() {
+		d.deploymentRepository.DeploymentOperationEntry(context.WithoutCancel(ctx), deployment)
+	})
+
+	return deployment, nil
+}
+
+func (d *DeploymentService) DestroyDeployment(ctx context.Context, key entity.DeploymentKey) error {
+	deployment, err := d.deploymentRepository.GetDeployment(ctx, key)
+	if err != nil {
+		return lookupError(err, entity.ErrDeploymentNotFound, "not able to get deployment")
+	}
+
+	if err := d.deploymentRepository.AutoDestroyDeployment(ctx, key.UserId, deployment); err != nil {
+		return entity.NewUpstreamError("destroy_request_failed", "not able to request destroy from the actlabs server", err)
+	}
+
+	// Create Event
+	if err := d.eventService.CreateEvent(ctx, entity.Event{
+		TimeStamp: time.Now().Format(time.RFC3339),
+		Type:      "Normal",
+		Reason:    "DeploymentDestroyRequested",
+		Message:   fmt.Sprintf("Destroy of deployment of user %s for subscription %s with workspace %s is requested.", key.UserId, key.SubscriptionId, key.Workspace),
+		Reporter:  "actlabs-hub",
+		Object:    key.UserId,
+	}); err != nil {
+		logger.LogError(ctx, "failed to create success event",
+			"requested_user_id", key.UserId,
+			"workspace", key.Workspace,
+			"subscription_id", key.SubscriptionId,
+			"error", err,
+		)
+	}
+
+	return nil
+}
+
+// maxLifespanSeconds is the longest a deployment of the user may live in
+// total. Mentors and admins get the mentor limit, everyone else the user limit.
+func (d *DeploymentService) maxLifespanSeconds(ctx context.Context, userPrincipalName string) int64 {
+	profile, err := d.authService.GetProfile(ctx, userPrincipalName)
+	if err != nil {
+		logger.LogWarning(ctx, "failed to get profile for lifespan policy, using user limit",
+			"requested_user_id", userPrincipalName,
+			"error", err,
+		)
+		return d.appConfig.ActlabsHubDeploymentMaxLifespanSecondsUser
+	}
+
+	if helper.Contains(profile.Roles, "mentor") || helper.Contains(profile.Roles, "admin") {
+		return d.appConfig.ActlabsHubDeploymentMaxLifespanSecondsMentor
+	}
+
+	return d.appConfig.ActlabsHubDeploymentMaxLifespanSecondsUser
+}
+
+// ApplySettings picks up a new polling interval, MonitorAndAutoDestroyDeployments
+// switches to it right away.
+func (d *DeploymentService) ApplySettings(ctx context.Context, settings entity.RuntimeSettings) {
+	if d.pollingIntervalSeconds.Swap(settings.DeploymentsPollingIntervalSeconds) == settings.DeploymentsPollingIntervalSeconds {
+		return
+	}
+
+	select {
+	case d.pollingIntervalChanged <- struct{}{}:
+	default:
+	}
+}
+
+func (d *DeploymentService) pollingInterval() time.Duration {
+	seconds := d.pollingIntervalSeconds.Load()
+	if seconds <= 0 {
+		seconds = d.appConfig.ActlabsHubDeploymentsPollingIntervalSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
+
 func (d *DeploymentService) MonitorAndAutoDestroyDeployments(ctx context.Context) {
+	interval := d.pollingInterval()
+	health.ExpectHeartbeat("MonitorAndAutoDestroyDeployments", interval)
+
 	helper.Recoverer(ctx, 100, "MonitorAndAutoDestroyDeployments", func() {
-		ticker := time.NewTicker(time.Duration(d.appConfig.ActlabsHubDeploymentsPollingIntervalSeconds) * time.Second)
+		ticker := time.NewTicker(interval)
 		defer ticker.Stop()
 
 		for {
@@ -200,7 +516,16 @@ func (d *DeploymentService) MonitorAndAutoDestroyDeployments(ctx context.Context
 			case <-ctx.Done():
 				// Context was cancelled or the application finished, so stop the goroutine
 				return
+			case <-d.pollingIntervalChanged:
+				interval = d.pollingInterval()
+				ticker.Reset(interval)
+				health.ExpectHeartbeat("MonitorAndAutoDestroyDeployments", interval)
+				logger.LogInfo(ctx, "deployments polling interval changed",
+					"interval", interval.String(),
+				)
 			case <-ticker.C:
+				health.Beat("MonitorAndAutoDestroyDeployments")
+
 				// Every minute, check for servers to destroy
 				if err := d.PollDeploymentsToBeAutoDestroyed(ctx); err != nil {
 					logger.LogError(ctx, "failed to poll deployments for auto destruction",
@@ -221,6 +546,8 @@ func (d *DeploymentService) PollDeploymentsToBeAutoDestroyed(ctx context.Context
 		return err
 	}
 
+	sent, failed := 0, 0
+
 	for _, deployment := range allDeployments {
 		if !deployment.DeploymentAutoDelete {
 			continue
@@ -228,15 +555,340 @@ func (d *DeploymentService) PollDeploymentsToBeAutoDestroyed(ctx context.Context
 
 		currentEpochTime := time.Now().Unix()
 
+		if deployment.DeploymentAutoDeleteUnixTime >= currentEpochTime &&
+			(deployment.DeploymentStatus == entity.DeploymentCompleted ||
+				deployment.DeploymentStatus == entity.DeploymentFailed) {
+			d.warnIfExpiringSoon(ctx, deployment, deployment.DeploymentAutoDeleteUnixTime-currentEpochTime)
+			continue
+		}
+
 		if deployment.DeploymentAutoDeleteUnixTime < currentEpochTime &&
 			deployment.DeploymentAutoDeleteUnixTime != 0 &&
 			(deployment.DeploymentStatus == entity.DeploymentCompleted ||
 				deployment.DeploymentStatus == entity.DeploymentFailed) {
 
-			d.deploymentRepository.AutoDestroyDeployment(ctx, deployment.DeploymentUserId, deployment)
+			sent++
+			if err := d.deploymentRepository.AutoDestroyDeployment(ctx, deployment.DeploymentUserId, deployment); err != nil {
+				failed++
+			}
+
+			// Each destroy can take several retries, so a long poll beats per
+			// deployment rather than missing its heartbeat.
+			health.Beat("MonitorAndAutoDestroyDeployments")
 		}
 
 	}
 
+	metrics.RecordAutoDestroyPoll(len(allDeployments), sent, failed)
+
 	return nil
 }
+
+// warnIfExpiringSoon sends the expiry warning for the smallest configured
+// window the deployment is in, once per window and auto delete time.
+func (d *DeploymentService) warnIfExpiringSoon(ctx context.Context, deployment entity.Deployment, remainingSeconds int64) {
+	window := 0
+	for _, minutes := range d.appConfig.ActlabsHubDeploymentExpiryWarningMinutes {
+		if remainingSeconds <= int64(minutes)*60 && (window == 0 || minutes < window) {
+			window = minutes
+		}
+	}
+	if window == 0 {
+		return
+	}
+
+	ctx = logger.WithUserID(ctx, deployment.DeploymentUserId)
+
+	sent, err := d.deploymentRepository.MarkExpiryWarningSent(ctx, deployment, window)
+	if err != nil || !sent {
+		return
+	}
+
+	autoDeleteTime := time.Unix(deployment.DeploymentAutoDeleteUnixTime, 0).UTC().Format(time.RFC3339)
+	message := fmt.Sprintf("Deployment of user %s for subscription %s with workspace %s will be deleted in less than %d minutes, at %s. Extend it to keep it running.", deployment.DeploymentUserId, deployment.DeploymentSubscriptionId, deployment.DeploymentWorkspace, window, autoDeleteTime)
+
+	// Create Event
+	if err := d.eventService.CreateEvent(ctx, entity.Event{
+		TimeStamp: time.Now().Format(time.RFC3339),
+		Type:      "Warning",
+		Reason:    "DeploymentExpiringSoon",
+		Message:   message,
+		Reporter:  "actlabs-hub",
+		Object:    deployment.DeploymentUserId,
+	}); err != nil {
+		logger.LogError(ctx, "failed to create warning event",
+			"workspace", deployment.DeploymentWorkspace,
+			"subscription_id", deployment.DeploymentSubscriptionId,
+			"error", err,
+		)
+	}
+
+	if err := d.notifier.Notify(ctx, entity.Notification{
+		UserId:  deployment.DeploymentUserId,
+		Reason:  "DeploymentExpiringSoon",
+		Subject: fmt.Sprintf("Your deployment %s will be deleted in less than %d minutes", deployment.DeploymentWorkspace, window),
+		Message: message,
+	}); err != nil {
+		logger.LogError(ctx, "failed to notify user of expiring deployment",
+			"workspace", deployment.DeploymentWorkspace,
+			"subscription_id", deployment.DeploymentSubscriptionId,
+			"error", err,
+		)
+	}
+}
+
+// estimateCost sets the deployment's estimated costs. A failed estimate never
+// blocks the deployment, the costs are just left empty.
+func (d *DeploymentService) estimateCost(ctx context.Context, deployment *entity.Deployment) {
+	estimate, err := d.costService.EstimateDeploymentCost(ctx, *deployment)
+	if err != nil {
+		logger.LogWarning(ctx, "failed to estimate deployment cost",
+			"workspace", deployment.DeploymentWorkspace,
+			"subscription_id", deployment.DeploymentSubscriptionId,
+			"error", err,
+		)
+		deployment.DeploymentEstimatedHourlyCost = 0
+		deployment.DeploymentEstimatedCost = 0
+		return
+	}
+
+	deployment.DeploymentEstimatedHourlyCost = estimate.HourlyCost
+	deployment.DeploymentEstimatedCost = estimate.LifespanCost
+}
+
+// stuckDeploymentFailedStatuses maps each in progress status to the status a
+// deployment stuck in it is marked with.
+var stuckDeploymentFailedStatuses = map[entity.DeploymentStatus]entity.DeploymentStatus{
+	entity.InitInProgress:       entity.InitFailed,
+	entity.PlanInProgress:       entity.PlanFailed,
+	entity.DeploymentInProgress: entity.DeploymentFailed,
+	entity.DestroyInProgress:    entity.DestroyFailed,
+}
+
+func (d *DeploymentService) GetStuckDeployments(ctx context.Context) ([]entity.StuckDeployment, error) {
+	deployments, err := d.deploymentRepository.GetAllDeployments(ctx)
+	if err != nil {
+		logger.LogError(ctx, "failed to get all deployments for stuck deployment check",
+			"error", err,
+		)
+		return nil, err
+	}
+
+	threshold := time.Duration(d.appConfig.ActlabsHubStuckDeploymentThresholdMinutes) * time.Minute
+	now := time.Now()
+	stuckDeployments := []entity.StuckDeployment{}
+
+	for _, deployment := range deployments {
+		if _, ok := stuckDeploymentFailedStatuses[deployment.DeploymentStatus]; !ok {
+			continue
+		}
+
+		// One unreadable history shouldn't hide every other stuck deployment.
+		since, retries, err := d.statusSince(ctx, deployment)
+		if err != nil {
+			logger.LogError(ctx, "failed to get operations of deployment for stuck deployment check, skipping it",
+				"requested_user_id", deployment.DeploymentUserId,
+				"subscription_id", deployment.DeploymentSubscriptionId,
+				"workspace", deployment.DeploymentWorkspace,
+				"error", err,
+			)
+			continue
+		}
+
+		// Without history there's no telling how long it has been in progress.
+		if since.IsZero() || now.Sub(since) < threshold {
+			continue
+		}
+
+		stuckDeployments = append(stuckDeployments, entity.StuckDeployment{
+			Deployment:   deployment,
+			StatusSince:  since.Format(time.RFC3339),
+			StuckMinutes: int64(now.Sub(since).Minutes()),
+			Retries:      retries,
+		})
+	}
+
+	sort.Slice(stuckDeployments, func(i, j int) bool {
+		return stuckDeployments[i].StuckMinutes > stuckDeployments[j].StuckMinutes
+	})
+
+	return stuckDeployments, nil
+}
+
+// statusSince returns when the deployment's current status was last recorded
+// and how many times it was recorded again after it was first entered, which
+// is the number of retries.
+func (d *DeploymentService) statusSince(ctx context.Context, deployment entity.Deployment) (time.Time, int, error) {
+	operations := []entity.DeploymentOperation{}
+	nextToken := ""
+	for {
+		page, next, err := d.deploymentRepository.GetDeploymentOperations(ctx, deployment.DeploymentUserId, deployment.DeploymentSubscriptionId, deployment.DeploymentWorkspace, 1000, nextToken)
+		if err != nil {
+			return time.Time{}, 0, err
+		}
+		operations = append(operations, page...)
+		if next == "" {
+			break
+		}
+		nextToken = next
+	}
+
+	since := time.Time{}
+	retries := -1
+	for i := len(operations) - 1; i >= 0 && operations[i].Status == deployment.DeploymentStatus; i-- {
+		t, err := time.Parse(time.RFC3339Nano, operations[i].Timestamp)
+		if err != nil {
+			continue
+		}
+		if t.After(since) {
+			since = t
+		}
+		retries++
+	}
+
+	if retries < 0 {
+		retries = 0
+	}
+
+	return since, retries, nil
+}
+
+func (d *DeploymentService) ReconcileStuckDeployments(ctx context.Context) error {
+	stuckDeployments, err := d.GetStuckDeployments(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, stuckDeployment := range stuckDeployments {
+		d.reconcileStuckDeployment(ctx, stuckDeployment)
+	}
+
+	return nil
+}
+
+func (d *DeploymentService) reconcileStuckDeployment(ctx context.Context, stuckDeployment entity.StuckDeployment) {
+	deployment := stuckDeployment.Deployment
+
+	ctx = logger.WithUserID(ctx, deployment.DeploymentUserId)
+
+	// The status only says when the hub last heard of the operation, long
+	// applies are still running on the server. Without an answer from the
+	// server the deployment is left as it is.
+	running, err := d.serverStatusClient.IsOperationRunning(ctx, deployment.DeploymentUserId, deployment)
+	if err != nil {
+		logger.LogWarning(ctx, "not able to get operation status of stuck deployment, leaving it as is",
+			"workspace", deployment.DeploymentWorkspace,
+			"subscription_id", deployment.DeploymentSubscriptionId,
+			"error", err,
+		)
+		return
+	}
+	if running {
+		logger.LogInfo(ctx, "stuck deployment is still running on the actlabs server",
+			"workspace", deployment.DeploymentWorkspace,
+			"subscription_id", deployment.DeploymentSubscriptionId,
+			"stuck_minutes", stuckDeployment.StuckMinutes,
+		)
+		return
+	}
+
+	// The deployment may have moved on since it was listed.
+	current, err := d.deploymentRepository.GetDeployment(ctx, entity.NewDeploymentKey(deployment))
+	if err != nil {
+		if !isNotFound(err) {
+			logger.LogError(ctx, "failed to get stuck deployment",
+				"workspace", deployment.DeploymentWorkspace,
+				"subscription_id", deployment.DeploymentSubscriptionId,
+				"error", err,
+			)
+		}
+		return
+	}
+	if current.DeploymentStatus != deployment.DeploymentStatus {
+		return
+	}
+	deployment = current
+
+	// Only a destroy can be sent again as is, other operations need the user.
+	if deployment.DeploymentStatus == entity.DestroyInProgress && stuckDeployment.Retries < d.appConfig.ActlabsHubStuckDeploymentMaxRetries {
+		if err := d.deploymentRepository.AutoDestroyDeployment(ctx, deployment.DeploymentUserId, deployment); err == nil {
+			// Recording the status again restarts the clock and counts the retry.
+			if err := d.deploymentRepository.DeploymentOperationEntry(ctx, deployment); err != nil {
+				logger.LogError(ctx, "failed to record retry of stuck deployment",
+					"workspace", deployment.DeploymentWorkspace,
+					"subscription_id", deployment.DeploymentSubscriptionId,
+					"error", err,
+				)
+			}
+
+			d.createStuckDeploymentEvent(ctx, "Normal", "StuckDeploymentRetried", fmt.Sprintf("Deployment of user %s for subscription %s with workspace %s was in %s for %d minutes, destroy was sent again (retry %d of %d).", deployment.DeploymentUserId, deployment.DeploymentSubscriptionId, deployment.DeploymentWorkspace, deployment.DeploymentStatus, stuckDeployment.StuckMinutes, stuckDeployment.Retries+1, d.appConfig.ActlabsHubStuckDeploymentMaxRetries), deployment)
+			return
+		}
+
+		logger.LogError(ctx, "failed to retry destroy of stuck deployment",
+			"workspace", deployment.DeploymentWorkspace,
+			"subscription_id", deployment.DeploymentSubscriptionId,
+		)
+	}
+
+	stuckStatus := deployment.DeploymentStatus
+	deployment.DeploymentStatus = stuckDeploymentFailedStatuses[stuckStatus]
+
+	if err := d.deploymentRepository.UpsertDeployment(ctx, deployment); err != nil {
+		logger.LogError(ctx, "failed to mark stuck deployment failed",
+			"workspace", deployment.DeploymentWorkspace,
+			"subscription_id", deployment.DeploymentSubscriptionId,
+			"error", err,
+		)
+		return
+	}
+
+	d.tasks.Go("DeploymentOperationEntry", func() {
+		d.deploymentRepository.DeploymentOperationEntry(context.WithoutCancel(ctx), deployment)
+	})
+
+	d.createStuckDeploymentEvent(ctx, "Warning", "StuckDeploymentFailed", fmt.Sprintf("Deployment of user %s for subscription %s with workspace %s was in %s for %d minutes and is marked %s.", deployment.DeploymentUserId, deployment.DeploymentSubscriptionId, deployment.DeploymentWorkspace, stuckStatus, stuckDeployment.StuckMinutes, deployment.DeploymentStatus), deployment)
+}
+
+func (d *DeploymentService) createStuckDeploymentEvent(ctx context.Context, eventType string, reason string, message string, deployment entity.Deployment) {
+	// Create Event
+	if err := d.eventService.CreateEvent(ctx, entity.Event{
+		TimeStamp: time.Now().Format(time.RFC3339),
+		Type:      eventType,
+		Reason:    reason,
+		Message:   message,
+		Reporter:  "actlabs-hub",
+		Object:    deployment.DeploymentUserId,
+	}); err != nil {
+		logger.LogError(ctx, "failed to create event for stuck deployment",
+			"workspace", deployment.DeploymentWorkspace,
+			"subscription_id", deployment.DeploymentSubscriptionId,
+			"error", err,
+		)
+	}
+}
+
+func (d *DeploymentService) MonitorStuckDeployments(ctx context.Context) {
+	interval := time.Duration(d.appConfig.ActlabsHubStuckDeploymentsPollingIntervalSeconds) * time.Second
+	health.ExpectHeartbeat("MonitorStuckDeployments", interval)
+
+	helper.Recoverer(ctx, 100, "MonitorStuckDeployments", func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				health.Beat("MonitorStuckDeployments")
+
+				if err := d.ReconcileStuckDeployments(ctx); err != nil {
+					logger.LogError(ctx, "failed to reconcile stuck deployments",
+						"error", err,
+					)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,332 @@
+package service
+
+import (
+	"actlabs-hub/internal/config"
+	"actlabs-hub/internal/entity"
+	"actlabs-hub/internal/helper"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+func TestDeploymentPhases(t *testing.T) {
+	operations := []entity.DeploymentOperation{
+		{Status: entity.InitInProgress, Timestamp: "2024-05-01T10:00:00Z"},
+		{Status: entity.InitCompleted, Timestamp: "2024-05-01T10:00:30Z"},
+		{Status: entity.PlanInProgress, Timestamp: "2024-05-01T10:00:31Z"},
+		{Status: entity.PlanCompleted, Timestamp: "2024-05-01T10:01:31Z"},
+		{Status: entity.DeploymentInProgress, Timestamp: "2024-05-01T10:01:32Z"},
+		{Status: entity.DeploymentFailed, Timestamp: "2024-05-01T10:11:32Z"},
+		{Status: entity.DestroyInProgress, Timestamp: "2024-05-01T11:00:00Z"},
+	}
+
+	phases := deploymentPhases(operations)
+
+	want := []entity.DeploymentPhase{
+		{Phase: "Init", Result: entity.InitCompleted, DurationSeconds: 30},
+		{Phase: "Plan", Result: entity.PlanCompleted, DurationSeconds: 60},
+		{Phase: "Deployment", Result: entity.DeploymentFailed, DurationSeconds: 600},
+	}
+
+	if len(phases) != len(want) {
+		t.Fatalf("expected %d phases, got %d: %+v", len(want), len(phases), phases)
+	}
+	for i := range want {
+		if phases[i].Phase != want[i].Phase || phases[i].Result != want[i].Result || phases[i].DurationSeconds != want[i].DurationSeconds {
+			t.Errorf("phase %d: expected %+v, got %+v", i, want[i], phases[i])
+		}
+	}
+
+	if operations[1].SincePreviousSeconds != 30 || operations[6].SincePreviousSeconds != 2908 {
+		t.Errorf("unexpected time since previous operation: %+v", operations)
+	}
+}
+
+type mockStuckDeploymentRepository struct {
+	entity.DeploymentRepository
+	deployments []entity.Deployment
+	operations  map[string][]entity.DeploymentOperation
+	unreadable  string
+}
+
+func (m *mockStuckDeploymentRepository) GetAllDeployments(ctx context.Context) ([]entity.Deployment, error) {
+	return m.deployments, nil
+}
+
+func (m *mockStuckDeploymentRepository) GetDeploymentOperations(ctx context.Context, userId string, subscriptionId string, workspace string, limit int32, nextToken string) ([]entity.DeploymentOperation, string, error) {
+	if workspace == m.unreadable {
+		return nil, "", errors.New("table unavailable")
+	}
+	return m.operations[workspace], "", nil
+}
+
+func TestGetStuckDeployments(t *testing.T) {
+	now := time.Now()
+	at := func(minutesAgo int) string {
+		return now.Add(-time.Duration(minutesAgo) * time.Minute).Format(time.RFC3339Nano)
+	}
+
+	repo := &mockStuckDeploymentRepository{
+		deployments: []entity.Deployment{
+			{DeploymentWorkspace: "stuck", DeploymentStatus: entity.DestroyInProgress},
+			{DeploymentWorkspace: "recent", DeploymentStatus: entity.DeploymentInProgress},
+			{DeploymentWorkspace: "completed", DeploymentStatus: entity.DeploymentCompleted},
+			{DeploymentWorkspace: "no-history", DeploymentStatus: entity.PlanInProgress},
+			{DeploymentWorkspace: "unreadable", DeploymentStatus: entity.DeploymentInProgress},
+		},
+		unreadable: "unreadable",
+		operations: map[string][]entity.DeploymentOperation{
+			"stuck": {
+				{Status: entity.DeploymentCompleted, Timestamp: at(300)},
+				{Status: entity.DestroyInProgress, Timestamp: at(200)},
+				{Status: entity.DestroyInProgress, Timestamp: at(90)},
+			},
+			"recent": {
+				{Status: entity.DeploymentInProgress, Timestamp: at(10)},
+			},
+			"completed": {
+				{Status: entity.DeploymentCompleted, Timestamp: at(300)},
+			},
+		},
+	}
+
+	svc := &DeploymentService{
+		deploymentRepository: repo,
+		appConfig:            &config.Config{ActlabsHubStuckDeploymentThresholdMinutes: 60},
+	}
+
+	stuckDeployments, err := svc.GetStuckDeployments(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(stuckDeployments) != 1 || stuckDeployments[0].Deployment.DeploymentWorkspace != "stuck" {
+		t.Fatalf("expected only the stuck deployment, got %+v", stuckDeployments)
+	}
+	if stuckDeployments[0].Retries != 1 || stuckDeployments[0].StuckMinutes != 90 {
+		t.Errorf("expected 1 retry and 90 minutes, got %+v", stuckDeployments[0])
+	}
+}
+
+type mockDestroyDeploymentRepository struct {
+	entity.DeploymentRepository
+	deployment entity.Deployment
+	destroyed  []string
+}
+
+func (m *mockDestroyDeploymentRepository) GetDeployment(ctx context.Context, key entity.DeploymentKey) (entity.Deployment, error) {
+	if key != entity.NewDeploymentKey(m.deployment) {
+		return entity.Deployment{}, &azcore.ResponseError{StatusCode: http.StatusNotFound}
+	}
+	return m.deployment, nil
+}
+
+func (m *mockDestroyDeploymentRepository) AutoDestroyDeployment(ctx context.Context, userPrincipalName string, deployment entity.Deployment) error {
+	m.destroyed = append(m.destroyed, deployment.DeploymentWorkspace)
+	return nil
+}
+
+func TestDestroyDeployment(t *testing.T) {
+	deployment := entity.Deployment{DeploymentUserId: "user@contoso.com", DeploymentSubscriptionId: "sub", DeploymentWorkspace: "ws"}
+	repo := &mockDestroyDeploymentRepository{deployment: deployment}
+	events := &mockEventService{}
+	svc := &DeploymentService{deploymentRepository: repo, eventService: events}
+
+	if err := svc.DestroyDeployment(context.Background(), entity.NewDeploymentKey(deployment)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repo.destroyed) != 1 || repo.destroyed[0] != "ws" {
+		t.Errorf("expected destroy of ws to be requested, got %v", repo.destroyed)
+	}
+	if len(events.reasons) != 1 || events.reasons[0] != "DeploymentDestroyRequested" {
+		t.Errorf("expected a DeploymentDestroyRequested event, got %v", events.reasons)
+	}
+
+	missing := entity.DeploymentKey{UserId: "user@contoso.com", SubscriptionId: "sub", Workspace: "missing"}
+	if err := svc.DestroyDeployment(context.Background(), missing); !errors.Is(err, entity.ErrDeploymentNotFound) {
+		t.Errorf("expected ErrDeploymentNotFound, got %v", err)
+	}
+}
+
+type mockReconcileDeploymentRepository struct {
+	mockDestroyDeploymentRepository
+	upserted []entity.Deployment
+}
+
+func (m *mockReconcileDeploymentRepository) UpsertDeployment(ctx context.Context, deployment entity.Deployment) error {
+	m.upserted = append(m.upserted, deployment)
+	return nil
+}
+
+func (m *mockReconcileDeploymentRepository) DeploymentOperationEntry(ctx context.Context, deployment entity.Deployment) error {
+	return nil
+}
+
+type mockServerStatusClient struct {
+	entity.ServerStatusClient
+	running bool
+	err     error
+}
+
+func (m *mockServerStatusClient) IsOperationRunning(ctx context.Context, userPrincipalName string, deployment entity.Deployment) (bool, error) {
+	return m.running, m.err
+}
+
+func TestReconcileStuckDeployment(t *testing.T) {
+	stuck := entity.Deployment{DeploymentUserId: "user@contoso.com", DeploymentSubscriptionId: "sub", DeploymentWorkspace: "ws", DeploymentStatus: entity.DeploymentInProgress}
+
+	cases := []struct {
+		name         string
+		current      entity.DeploymentStatus
+		statusClient *mockServerStatusClient
+		wantFailed   bool
+	}{
+		{name: "still running on the server", current: entity.DeploymentInProgress, statusClient: &mockServerStatusClient{running: true}},
+		{name: "server not answering", current: entity.DeploymentInProgress, statusClient: &mockServerStatusClient{err: errors.New("timeout")}},
+		{name: "finished since listed", current: entity.DeploymentCompleted, statusClient: &mockServerStatusClient{}},
+		{name: "not running", current: entity.DeploymentInProgress, statusClient: &mockServerStatusClient{}, wantFailed: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			current := stuck
+			current.DeploymentStatus = c.current
+			repo := &mockReconcileDeploymentRepository{mockDestroyDeploymentRepository: mockDestroyDeploymentRepository{deployment: current}}
+			svc := &DeploymentService{
+				deploymentRepository: repo,
+				eventService:         &mockEventService{},
+				serverStatusClient:   c.statusClient,
+				tasks:                helper.NewTaskGroup(),
+				appConfig:            &config.Config{},
+			}
+
+			svc.reconcileStuckDeployment(context.Background(), entity.StuckDeployment{Deployment: stuck, StuckMinutes: 300})
+			svc.tasks.Wait(context.Background())
+
+			if !c.wantFailed {
+				if len(repo.upserted) != 0 {
+					t.Errorf("expected the deployment to be left as is, got %+v", repo.upserted)
+				}
+				return
+			}
+			if len(repo.upserted) != 1 || repo.upserted[0].DeploymentStatus != entity.DeploymentFailed {
+				t.Errorf("expected the deployment to be marked failed, got %+v", repo.upserted)
+			}
+		})
+	}
+}
+
+type mockExtendDeploymentRepository struct {
+	entity.DeploymentRepository
+	deployment entity.Deployment
+}
+
+func (m *mockExtendDeploymentRepository) GetUserDeployments(ctx context.Context, userPrincipalName string) ([]entity.Deployment, error) {
+	return []entity.Deployment{m.deployment}, nil
+}
+
+func (m *mockExtendDeploymentRepository) UpsertDeployment(ctx context.Context, deployment entity.Deployment) error {
+	m.deployment = deployment
+	return nil
+}
+
+func (m *mockExtendDeploymentRepository) DeploymentOperationEntry(ctx context.Context, deployment entity.Deployment) error {
+	return nil
+}
+
+type mockExtendAuthService struct {
+	entity.AuthService
+}
+
+func (m *mockExtendAuthService) GetProfile(ctx context.Context, userPrincipalName string) (entity.Profile, error) {
+	return entity.Profile{UserPrincipal: userPrincipalName, Roles: []string{"user"}}, nil
+}
+
+type mockExtendQuotaService struct {
+	entity.QuotaService
+}
+
+func (m *mockExtendQuotaService) CheckLifespan(ctx context.Context, userId string, lifespanSeconds int64) error {
+	return nil
+}
+
+func (m *mockExtendQuotaService) RecordLifespan(ctx context.Context, userId string, lifespanSeconds int64) error {
+	return nil
+}
+
+type mockExtendCostService struct {
+	entity.CostService
+}
+
+func (m *mockExtendCostService) EstimateDeploymentCost(ctx context.Context, deployment entity.Deployment) (entity.CostEstimate, error) {
+	return entity.CostEstimate{}, nil
+}
+
+func newExtendDeploymentService(deployment entity.Deployment) (*DeploymentService, *mockExtendDeploymentRepository) {
+	repo := &mockExtendDeploymentRepository{deployment: deployment}
+	return &DeploymentService{
+		deploymentRepository: repo,
+		eventService:         &mockEventService{},
+		authService:          &mockExtendAuthService{},
+		quotaService:         &mockExtendQuotaService{},
+		costService:          &mockExtendCostService{},
+		appConfig:            &config.Config{ActlabsHubDeploymentMaxLifespanSecondsUser: 4 * 3600},
+	}, repo
+}
+
+func TestExtendDeploymentCapsTotalLifespan(t *testing.T) {
+	// Deployed for 2 hours, with 1 hour left.
+	svc, repo := newExtendDeploymentService(entity.Deployment{
+		DeploymentUserId:             "user@contoso.com",
+		DeploymentSubscriptionId:     "sub",
+		DeploymentWorkspace:          "ws",
+		DeploymentStatus:             entity.DeploymentCompleted,
+		DeploymentAutoDelete:         true,
+		DeploymentLifespan:           2 * 3600,
+		DeploymentAutoDeleteUnixTime: time.Now().Add(time.Hour).Unix(),
+	})
+
+	// Each extension is well within the limit from now, but they add up.
+	for range 2 {
+		if _, err := svc.ExtendDeployment(context.Background(), "user@contoso.com", "sub", "ws", time.Hour); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if repo.deployment.DeploymentLifespan != 4*3600 {
+		t.Errorf("expected a 4 hour lifespan, got %d seconds", repo.deployment.DeploymentLifespan)
+	}
+
+	if _, err := svc.ExtendDeployment(context.Background(), "user@contoso.com", "sub", "ws", time.Minute); !errors.Is(err, entity.ErrLifespanExceedsPolicy) {
+		t.Errorf("expected ErrLifespanExceedsPolicy once the total lifespan is used up, got %v", err)
+	}
+}
+
+func TestExtendDeploymentRejectsExpiredAndInProgress(t *testing.T) {
+	deployment := entity.Deployment{
+		DeploymentUserId:         "user@contoso.com",
+		DeploymentSubscriptionId: "sub",
+		DeploymentWorkspace:      "ws",
+		DeploymentAutoDelete:     true,
+		DeploymentLifespan:       3600,
+	}
+
+	expired := deployment
+	expired.DeploymentStatus = entity.DeploymentCompleted
+	expired.DeploymentAutoDeleteUnixTime = time.Now().Add(-time.Minute).Unix()
+	svc, _ := newExtendDeploymentService(expired)
+	if _, err := svc.ExtendDeployment(context.Background(), "user@contoso.com", "sub", "ws", time.Hour); !errors.Is(err, entity.ErrDeploymentExpired) {
+		t.Errorf("expected ErrDeploymentExpired, got %v", err)
+	}
+
+	inProgress := deployment
+	inProgress.DeploymentStatus = entity.DestroyInProgress
+	inProgress.DeploymentAutoDeleteUnixTime = time.Now().Add(time.Hour).Unix()
+	svc, _ = newExtendDeploymentService(inProgress)
+	if _, err := svc.ExtendDeployment(context.Background(), "user@contoso.com", "sub", "ws", time.Hour); !errors.Is(err, entity.ErrDeploymentNotExtendable) {
+		t.Errorf("expected ErrDeploymentNotExtendable, got %v", err)
+	}
+}
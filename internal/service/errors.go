@@ -0,0 +1,34 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+
+	"actlabs-hub/internal/entity"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// isNotFound is true when storage answered 404.
+func isNotFound(err error) bool {
+	var responseErr *azcore.ResponseError
+	return errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusNotFound
+}
+
+// lookupError is notFound when storage answered 404, otherwise a storage
+// error with message.
+func lookupError(err error, notFound *entity.Error, message string) error {
+	if isNotFound(err) {
+		return notFound
+	}
+	return entity.NewStorageError(message, err)
+}
+
+// listError passes a bad nextToken through as is, anything else is a storage
+// error with message.
+func listError(err error, message string) error {
+	if errors.Is(err, entity.ErrInvalidNextToken) {
+		return err
+	}
+	return entity.NewStorageError(message, err)
+}
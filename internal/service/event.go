@@ -5,7 +5,9 @@ import (
 	"actlabs-hub/internal/helper"
 	"actlabs-hub/internal/logger"
 	"context"
-	"errors"
+	"fmt"
+	"strings"
+	"time"
 )
 
 type eventService struct {
@@ -34,7 +36,7 @@ func (es *eventService) CreateEvent(ctx context.Context, event entity.Event) err
 	userID := logger.GetUserID(ctx)
 	if userID == "" {
 		logger.LogError(ctx, "user_id not found in context for event creation")
-		return errors.New("unauthorized: user_id not found in context")
+		return entity.NewUnauthenticatedError("unauthenticated", "unauthorized: user_id not found in context")
 	}
 
 	event.PartitionKey = userID
@@ -51,3 +53,28 @@ func (es *eventService) CreateEvent(ctx context.Context, event entity.Event) err
 
 	return nil
 }
+
+// createImportEvent records that imported of total items of kind, e.g.
+// "Labs", were imported, and why the rest weren't.
+func createImportEvent(ctx context.Context, eventService entity.EventService, kind string, imported int, total int, err error) {
+	event := entity.Event{
+		TimeStamp: time.Now().Format(time.RFC3339),
+		Type:      "Normal",
+		Reason:    kind + "Imported",
+		Message:   fmt.Sprintf("%d %s imported.", imported, strings.ToLower(kind)),
+		Reporter:  "actlabs-hub",
+		Object:    logger.GetUserID(ctx),
+	}
+	if err != nil {
+		event.Type = "Warning"
+		event.Reason = kind + "ImportFailed"
+		event.Message = fmt.Sprintf("%d of %d %s imported, %s.", imported, total, strings.ToLower(kind), err)
+	}
+
+	if err := eventService.CreateEvent(ctx, event); err != nil {
+		logger.LogError(ctx, "failed to create import event",
+			"kind", kind,
+			"error", err,
+		)
+	}
+}
@@ -4,13 +4,18 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"mime/multipart"
+	"net/http"
+	"slices"
 	"strings"
+	"time"
 
+	"actlabs-hub/internal/config"
 	"actlabs-hub/internal/entity"
+	"actlabs-hub/internal/health"
 	"actlabs-hub/internal/helper"
 	"actlabs-hub/internal/logger"
 
@@ -19,18 +24,22 @@ import (
 
 type labService struct {
 	labRepository entity.LabRepository
+	eventService  entity.EventService
+	appConfig     *config.Config
 }
 
-func NewLabService(repo entity.LabRepository) entity.LabService {
+func NewLabService(repo entity.LabRepository, eventService entity.EventService, appConfig *config.Config) entity.LabService {
 	return &labService{
 		labRepository: repo,
+		eventService:  eventService,
+		appConfig:     appConfig,
 	}
 }
 
 func (l *labService) GetAllPrivateLabs(ctx context.Context, typeOfLab string) ([]entity.LabType, error) {
 	if typeOfLab != "challengelab" {
 		logger.LogError(ctx, "only challenge labs are allowed via this function", "typeOfLab", typeOfLab)
-		return []entity.LabType{}, errors.New("only challenge labs are allowed via this function")
+		return []entity.LabType{}, entity.NewValidationError("invalid_lab_type", "only challenge labs are allowed via this function")
 	}
 
 	labs, err := l.GetLabs(ctx, typeOfLab)
@@ -47,22 +56,32 @@ func (l *labService) GetPrivateLabs(ctx context.Context, typeOfLab string, userI
 		return labs, err
 	}
 
-	var filteredLabs []entity.LabType
+	return filterPrivateLabs(labs, userId), nil
+}
+
+// filterPrivateLabs keeps the labs userId owns, edits or views.
+func filterPrivateLabs(labs []entity.LabType, userId string) []entity.LabType {
+	filteredLabs := []entity.LabType{}
 
 	for _, lab := range labs {
-		if helper.Contains(lab.Owners, userId) || helper.Contains(lab.Editors, userId) || helper.Contains(lab.Viewers, userId) {
+		if canViewPrivateLab(lab, userId) {
 			filteredLabs = append(filteredLabs, lab)
 		}
 	}
 
-	return filteredLabs, nil
+	return filteredLabs
+}
+
+// canViewPrivateLab is true for the lab's owners, editors and viewers.
+func canViewPrivateLab(lab entity.LabType, userId string) bool {
+	return helper.Contains(lab.Owners, userId) || helper.Contains(lab.Editors, userId) || helper.Contains(lab.Viewers, userId)
 }
 
 func (l *labService) GetPrivateLab(ctx context.Context, typeOfLab string, labId string) (entity.LabType, error) {
 	lab, err := l.labRepository.GetLab(ctx, typeOfLab, labId)
 	if err != nil {
 		logger.LogError(ctx, "not able to get lab", "labId", labId, "typeOfLab", typeOfLab, "error", err.Error())
-		return entity.LabType{}, fmt.Errorf("not able to get lab")
+		return entity.LabType{}, lookupError(err, entity.ErrLabNotFound, "not able to get lab")
 	}
 
 	return lab, nil
@@ -84,7 +103,7 @@ func (l *labService) GetProtectedLab(ctx context.Context, typeOfLab string, labI
 			return lab, nil
 		}
 	}
-	return entity.LabType{}, errors.New("lab not found")
+	return entity.LabType{}, entity.ErrLabNotFound
 }
 
 func (l *labService) GetProtectedLabs(ctx context.Context, typeOfLab string, userId string, requestIsWithSecret bool) ([]entity.LabType, error) {
@@ -99,6 +118,13 @@ func (l *labService) GetProtectedLabs(ctx context.Context, typeOfLab string, use
 		return labs, nil
 	}
 
+	redactProtectedLabs(labs, userId)
+	return labs, nil
+}
+
+// redactProtectedLabs hides the description, supporting document and script
+// of RBAC enforced labs userId isn't an owner, editor or viewer of.
+func redactProtectedLabs(labs []entity.LabType, userId string) {
 	for i := range labs {
 
 		if labs[i].RbacEnforcedProtectedLab && !helper.Contains(labs[i].Owners, userId) && !helper.Contains(labs[i].Editors, userId) && !helper.Contains(labs[i].Viewers, userId) {
@@ -107,48 +133,135 @@ func (l *labService) GetProtectedLabs(ctx context.Context, typeOfLab string, use
 			labs[i].ExtendScript = ""
 		}
 	}
-
-	return labs, nil
 }
 
 func (l *labService) GetLabByIdAndType(ctx context.Context, typeOfLab string, labId string) (entity.LabType, error) {
 	lab, err := l.labRepository.GetLab(ctx, typeOfLab, labId)
 	if err != nil {
 		logger.LogError(ctx, "not able to get lab", "labId", labId, "typeOfLab", typeOfLab, "error", err.Error())
-		return entity.LabType{}, fmt.Errorf("not able to get lab")
+		return entity.LabType{}, lookupError(err, entity.ErrLabNotFound, "not able to get lab")
 	}
 
 	return lab, nil
 }
 
 func (l *labService) GetLabs(ctx context.Context, typeOfLab string) ([]entity.LabType, error) {
-	logger.LogInfo(ctx, "getting labs", "typeOfLab", typeOfLab)
+	page, err := l.listLabs(ctx, typeOfLab, entity.ListQuery{}, nil)
+	return page.Items, err
+}
+
+func (l *labService) GetAllLabs(ctx context.Context, typeOfLab string) ([]entity.LabType, error) {
+	blobs, err := l.labRepository.ListBlobs(ctx, typeOfLab)
+	if err != nil {
+		logger.LogError(ctx, "not able to get list of blobs", "typeOfLab", typeOfLab, "error", err.Error())
+		return nil, entity.NewStorageError("not able to get labs", err)
+	}
 
 	labs := []entity.LabType{}
+	for _, element := range blobs {
+		if !element.IsCurrentVersion {
+			continue
+		}
+
+		lab, err := l.labRepository.GetLab(ctx, typeOfLab, element.Name) //name is labId
+		if err != nil {
+			logger.LogError(ctx, "not able to get blob from given url", "labId", element.Name, "typeOfLab", typeOfLab, "error", err.Error())
+			return nil, entity.NewStorageError("not able to get lab "+element.Name, err)
+		}
+		AddCategoryToLabIfMissing(ctx, l, &lab)
+		labs = append(labs, lab)
+	}
+
+	return labs, nil
+}
+
+func (l *labService) ListLabs(ctx context.Context, typeOfLab string, userId string, query entity.ListQuery) (entity.Page[entity.LabType], error) {
+	var visible func(lab entity.LabType) bool
+
+	switch {
+	case slices.Contains(entity.PrivateLab, typeOfLab):
+		// Filtered while paging, so pages are full and tokens only name the
+		// user's own labs.
+		visible = func(lab entity.LabType) bool {
+			return canViewPrivateLab(lab, userId)
+		}
+	case slices.Contains(entity.PublicLab, typeOfLab):
+	case slices.Contains(entity.ProtectedLabs, typeOfLab):
+	default:
+		return entity.Page[entity.LabType]{}, entity.NewValidationError("invalid_lab_type", "Invalid lab type: "+typeOfLab)
+	}
+
+	page, err := l.listLabs(ctx, typeOfLab, query, visible)
+	if err != nil {
+		return page, err
+	}
+
+	if slices.Contains(entity.ProtectedLabs, typeOfLab) {
+		redactProtectedLabs(page.Items, userId)
+	}
+
+	return page, nil
+}
+
+// listLabs pages through the current versions of the labs of typeOfLab by
+// blob name, reading labs until the page is full of labs visible returns
+// true for, or all labs when visible is nil. The next token is the last blob
+// name of the page.
+func (l *labService) listLabs(ctx context.Context, typeOfLab string, query entity.ListQuery, visible func(lab entity.LabType) bool) (entity.Page[entity.LabType], error) {
+	logger.LogInfo(ctx, "getting labs", "typeOfLab", typeOfLab)
+
+	page := entity.Page[entity.LabType]{Items: []entity.LabType{}}
+
+	after := ""
+	if query.NextToken != "" {
+		name, err := base64.RawURLEncoding.DecodeString(query.NextToken)
+		if err != nil {
+			return page, fmt.Errorf("%w: %w", entity.ErrInvalidNextToken, err)
+		}
+		after = string(name)
+	}
 
 	blobs, err := l.labRepository.ListBlobs(ctx, typeOfLab)
 	if err != nil {
 		logger.LogError(ctx, "not able to get list of blobs", "typeOfLab", typeOfLab, "error", err.Error())
-		return labs, err
+		return page, entity.NewStorageError("not able to get labs", err)
 	}
 
 	logger.LogDebug(ctx, "listed labs", "type", typeOfLab, "count", len(blobs))
 
+	names := []string{}
 	for _, element := range blobs {
-		if element.IsCurrentVersion {
-			lab, err := l.labRepository.GetLab(ctx, typeOfLab, element.Name) //element.Name is labId
-			if err != nil {
-				logger.LogError(ctx, "not able to get blob from given url", "labId", element.Name, "typeOfLab", typeOfLab, "error", err.Error())
-				continue
-			}
-			AddCategoryToLabIfMissing(ctx, l, &lab)
-			labs = append(labs, lab)
+		if element.IsCurrentVersion && element.Name > after {
+			names = append(names, element.Name)
 		}
 	}
+	slices.Sort(names)
 
-	logger.LogDebug(ctx, "current versions of labs", "type", typeOfLab, "count", len(labs))
+	last := ""
+	for _, name := range names {
+		lab, err := l.labRepository.GetLab(ctx, typeOfLab, name) //name is labId
+		if err != nil {
+			logger.LogError(ctx, "not able to get blob from given url", "labId", name, "typeOfLab", typeOfLab, "error", err.Error())
+			continue
+		}
+		if visible != nil && !visible(lab) {
+			continue
+		}
 
-	return labs, nil
+		// Another lab for the page means there's a next one.
+		if query.Limit > 0 && len(page.Items) == int(query.Limit) {
+			page.NextToken = base64.RawURLEncoding.EncodeToString([]byte(last))
+			break
+		}
+
+		AddCategoryToLabIfMissing(ctx, l, &lab)
+		page.Items = append(page.Items, lab)
+		last = name
+	}
+
+	logger.LogDebug(ctx, "current versions of labs", "type", typeOfLab, "count", len(page.Items))
+
+	return page, nil
 }
 
 func (l *labService) UpsertPrivateLab(ctx context.Context, lab entity.LabType) (entity.LabType, error) {
@@ -158,7 +271,7 @@ func (l *labService) UpsertPrivateLab(ctx context.Context, lab entity.LabType) (
 	}
 
 	if !ok {
-		return lab, fmt.Errorf("user is not either owner or editor which is required to edit the private lab")
+		return lab, entity.NewForbiddenError("lab_edit_forbidden", "user is not either owner or editor which is required to edit the private lab")
 	}
 
 	return l.UpsertLab(ctx, lab)
@@ -171,7 +284,7 @@ func (l *labService) UpsertPublicLab(ctx context.Context, lab entity.LabType) (e
 	}
 
 	if !ok {
-		return lab, fmt.Errorf("user is not either owner or editor which is required to edit the public lab")
+		return lab, entity.NewForbiddenError("lab_edit_forbidden", "user is not either owner or editor which is required to edit the public lab")
 	}
 	return l.UpsertLab(ctx, lab)
 }
@@ -183,7 +296,7 @@ func (l *labService) UpsertProtectedLab(ctx context.Context, lab entity.LabType,
 	}
 
 	if lab.RbacEnforcedProtectedLab && !helper.Contains(lab.Owners, userId) && !helper.Contains(lab.Editors, userId) {
-		return lab, errors.New("only the owner or an editor can modify this RBAC-enforced lab, please contact the owner or editor for access or further details")
+		return lab, entity.NewForbiddenError("lab_edit_forbidden", "only the owner or an editor can modify this RBAC-enforced lab, please contact the owner or editor for access or further details")
 	}
 	return l.UpsertLab(ctx, lab)
 }
@@ -192,11 +305,11 @@ func (l *labService) UpsertLab(ctx context.Context, lab entity.LabType) (entity.
 
 	ok, err := l.ValidateAddingEditorsOrViewers(ctx, lab)
 	if err != nil {
-		return lab, &json.MarshalerError{}
+		return lab, err
 	}
 
 	if !ok {
-		return lab, errors.New("user is not an owner and there are changes in owners, editors, or viewers")
+		return lab, entity.NewForbiddenError("lab_edit_forbidden", "user is not an owner and there are changes in owners, editors, or viewers")
 	}
 
 	l.NewLabThings(ctx, &lab)
@@ -206,7 +319,7 @@ func (l *labService) UpsertLab(ctx context.Context, lab entity.LabType) (entity.
 	if lab.SupportingDocumentId != "" {
 		if !l.DoesSupportingDocumentExist(ctx, lab.SupportingDocumentId) {
 			logger.LogError(ctx, "Supporting document doesn't exist", "SupportingDocumentId", lab.SupportingDocumentId)
-			return lab, errors.New("supporting document doesn't exist")
+			return lab, entity.NewValidationError("supporting_document_not_found", "supporting document doesn't exist")
 		}
 	}
 
@@ -218,12 +331,61 @@ func (l *labService) UpsertLab(ctx context.Context, lab entity.LabType) (entity.
 
 	if err := l.labRepository.UpsertLab(ctx, lab.Id, string(val), lab.Type); err != nil {
 		logger.LogError(ctx, "not able to save lab", "labName", lab.Name, "labId", lab.Id, "typeOfLab", lab.Type, "owners", strings.Join(lab.Owners, ", "), "editors", strings.Join(lab.Editors, ", "), "error", err.Error())
-		return lab, fmt.Errorf("not able to save lab")
+		return lab, entity.NewStorageError("not able to save lab", err)
+	}
+
+	if lab.SupportingDocumentId != "" {
+		l.linkSupportingDocument(ctx, lab)
 	}
 
 	return lab, nil
 }
 
+func (l *labService) ImportLabs(ctx context.Context, labs []entity.LabType) (int, error) {
+	types := slices.Concat(entity.PrivateLab, entity.PublicLab, entity.ProtectedLabs)
+
+	problems := []string{}
+	for i, lab := range labs {
+		if lab.Id == "" || !slices.Contains(types, lab.Type) {
+			problems = append(problems, fmt.Sprintf("lab %d (%q) needs an id and one of the types %s", i, lab.Name, strings.Join(types, ", ")))
+			continue
+		}
+		if lab.SupportingDocumentId != "" && !l.DoesSupportingDocumentExist(ctx, lab.SupportingDocumentId) {
+			problems = append(problems, fmt.Sprintf("supporting document %s of lab %s doesn't exist", lab.SupportingDocumentId, lab.Id))
+		}
+	}
+	if len(problems) > 0 {
+		return 0, entity.NewValidationError("invalid_labs", strings.Join(problems, "; "))
+	}
+
+	imported := 0
+	var err error
+	for _, lab := range labs {
+		l.AssignOwnerToOrphanLab(ctx, &lab)
+
+		val, marshalErr := json.Marshal(lab)
+		if marshalErr != nil {
+			err = marshalErr
+			break
+		}
+
+		if upsertErr := l.labRepository.UpsertLab(ctx, lab.Id, string(val), lab.Type); upsertErr != nil {
+			logger.LogError(ctx, "not able to import lab", "labId", lab.Id, "typeOfLab", lab.Type, "error", upsertErr.Error())
+			err = entity.NewStorageError("not able to save lab "+lab.Id, upsertErr)
+			break
+		}
+
+		if lab.SupportingDocumentId != "" {
+			l.linkSupportingDocument(ctx, lab)
+		}
+		imported++
+	}
+
+	createImportEvent(ctx, l.eventService, "Labs", imported, len(labs), err)
+
+	return imported, err
+}
+
 func (l *labService) DeletePrivateLab(ctx context.Context, typeOfLab string, labId string, userId string) error {
 	ok, err := l.IsDeleteAllowed(ctx, typeOfLab, labId, userId)
 	if err != nil {
@@ -231,12 +393,12 @@ func (l *labService) DeletePrivateLab(ctx context.Context, typeOfLab string, lab
 	}
 
 	if !ok {
-		return errors.New("only owner can delete the lab")
+		return entity.NewForbiddenError("lab_delete_forbidden", "only owner can delete the lab")
 	}
 
 	if err := l.DeleteLab(ctx, typeOfLab, labId); err != nil {
 		logger.LogError(ctx, "not able to delete lab", "labId", labId, "typeOfLab", typeOfLab, "error", err.Error())
-		return fmt.Errorf("not able to delete lab")
+		return entity.NewStorageError("not able to delete lab", err)
 	}
 
 	return nil
@@ -250,7 +412,7 @@ func (l *labService) DeletePublicLab(ctx context.Context, typeOfLab string, labI
 
 	if !ok {
 		logger.LogError(ctx, "Only owner can delete the lab", "labId", labId)
-		return errors.New("only owner can delete the lab")
+		return entity.NewForbiddenError("lab_delete_forbidden", "only owner can delete the lab")
 	}
 
 	return l.DeleteLab(ctx, typeOfLab, labId)
@@ -265,7 +427,7 @@ func (l *labService) DeleteLab(ctx context.Context, typeOfLab string, labId stri
 	lab, err := l.labRepository.GetLab(ctx, typeOfLab, labId)
 	if err != nil {
 		logger.LogError(ctx, "not able to get lab", "labId", labId, "error", err.Error())
-		return err
+		return lookupError(err, entity.ErrLabNotFound, "not able to get lab")
 	}
 
 	if lab.SupportingDocumentId != "" {
@@ -279,7 +441,7 @@ func (l *labService) DeleteLab(ctx context.Context, typeOfLab string, labId stri
 
 	if err := l.labRepository.DeleteLab(ctx, typeOfLab, labId); err != nil {
 		logger.LogError(ctx, "not able to delete lab", "error", err.Error())
-		return err
+		return entity.NewStorageError("not able to delete lab", err)
 	}
 	return nil
 }
@@ -288,11 +450,11 @@ func (l *labService) GetPrivateLabVersions(ctx context.Context, typeOfLab string
 	existingLab, err := l.labRepository.GetLab(ctx, typeOfLab, labId)
 	if err != nil {
 		logger.LogError(ctx, "Not able to get the current version of lab.", "error", err.Error())
-		return []entity.LabType{}, err
+		return []entity.LabType{}, lookupError(err, entity.ErrLabNotFound, "not able to get lab")
 	}
 	if !helper.Contains(existingLab.Owners, userId) && !helper.Contains(existingLab.Editors, userId) && !helper.Contains(existingLab.Viewers, userId) {
 		logger.LogError(ctx, "User doesn't have access to view Lab", "labName", existingLab.Name)
-		return []entity.LabType{}, errors.New("user does not have access to view lab")
+		return []entity.LabType{}, entity.NewForbiddenError("lab_access_denied", "user does not have access to view lab")
 	}
 	return l.GetLabVersions(ctx, typeOfLab, labId)
 }
@@ -309,23 +471,62 @@ func (l *labService) GetLabVersions(ctx context.Context, typeOfLab string, labId
 	labs, err := l.labRepository.GetLabWithVersions(ctx, typeOfLab, labId)
 	if err != nil {
 		logger.LogError(ctx, "Not able to get list of blobs", "error", err.Error())
-		return []entity.LabType{}, err
+		return []entity.LabType{}, lookupError(err, entity.ErrLabNotFound, "not able to get lab versions")
 	}
 
 	return labs, nil
 }
 
 // Supporting Documents
-func (l *labService) UpsertSupportingDocument(ctx context.Context, supportingDocument multipart.File) (string, error) {
-	supportingDocumentId, err := l.labRepository.UpsertSupportingDocument(ctx, supportingDocument)
+func (l *labService) UpsertSupportingDocument(ctx context.Context, supportingDocument multipart.File, header *multipart.FileHeader, uploadedBy string) (string, error) {
+	if header.Size > l.appConfig.ActlabsHubSupportingDocumentMaxSizeBytes {
+		logger.LogWarning(ctx, "supporting document rejected, too large", "fileName", header.Filename, "size", header.Size)
+		return "", fmt.Errorf("%w: %d bytes, at most %d bytes are allowed", entity.ErrSupportingDocumentTooLarge, header.Size, l.appConfig.ActlabsHubSupportingDocumentMaxSizeBytes)
+	}
+
+	// The type is sniffed from the content, the uploaded content type can't be trusted.
+	head := make([]byte, 512)
+	n, err := io.ReadFull(supportingDocument, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		logger.LogError(ctx, "not able to read supporting document", "error", err.Error())
+		return "", err
+	}
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head[:n]))
+
+	if !l.isSupportingDocumentTypeAllowed(contentType) {
+		logger.LogWarning(ctx, "supporting document rejected, type not allowed", "fileName", header.Filename, "contentType", contentType)
+		return "", fmt.Errorf("%w: %s", entity.ErrSupportingDocumentTypeNotAllowed, contentType)
+	}
+
+	if _, err := supportingDocument.Seek(0, io.SeekStart); err != nil {
+		logger.LogError(ctx, "not able to rewind supporting document", "error", err.Error())
+		return "", err
+	}
+
+	supportingDocumentId, err := l.labRepository.UpsertSupportingDocument(ctx, supportingDocument, entity.SupportingDocument{
+		FileName:    header.Filename,
+		ContentType: contentType,
+		Size:        header.Size,
+		UploadedBy:  uploadedBy,
+		UploadedAt:  time.Now().UTC().Format(time.RFC3339),
+	})
 	if err != nil {
 		logger.LogError(ctx, "not able to save supporting document", "error", err.Error())
-		return "", err
+		return "", entity.NewStorageError("not able to save supporting document", err)
 	}
 
 	return supportingDocumentId, nil
 }
 
+func (l *labService) isSupportingDocumentTypeAllowed(contentType string) bool {
+	for _, allowed := range l.appConfig.ActlabsHubSupportingDocumentAllowedTypes {
+		if strings.EqualFold(strings.TrimSpace(allowed), contentType) {
+			return true
+		}
+	}
+	return false
+}
+
 func (l *labService) DeleteSupportingDocument(ctx context.Context, supportingDocumentId string) error {
 
 	if !l.DoesSupportingDocumentExist(ctx, supportingDocumentId) {
@@ -336,7 +537,7 @@ func (l *labService) DeleteSupportingDocument(ctx context.Context, supportingDoc
 
 	if err := l.labRepository.DeleteSupportingDocument(ctx, supportingDocumentId); err != nil {
 		logger.LogError(ctx, "not able to delete supporting document", "error", err.Error())
-		return err
+		return entity.NewStorageError("not able to delete supporting document", err)
 	}
 
 	return nil
@@ -346,12 +547,114 @@ func (l *labService) GetSupportingDocument(ctx context.Context, supportingDocume
 	supportingDocument, err := l.labRepository.GetSupportingDocument(ctx, supportingDocumentId)
 	if err != nil {
 		logger.LogError(ctx, "not able to get supporting document", "error", err.Error())
-		return nil, err
+		return nil, lookupError(err, entity.ErrSupportingDocumentNotFound, "not able to get supporting document")
 	}
 
 	return supportingDocument, nil
 }
 
+func (l *labService) GetSupportingDocumentMetadata(ctx context.Context, supportingDocumentId string) (entity.SupportingDocument, error) {
+	supportingDocument, err := l.labRepository.GetSupportingDocumentMetadata(ctx, supportingDocumentId)
+	if err != nil {
+		logger.LogError(ctx, "not able to get supporting document metadata", "supportingDocumentId", supportingDocumentId, "error", err.Error())
+		return entity.SupportingDocument{}, lookupError(err, entity.ErrSupportingDocumentNotFound, "not able to get supporting document metadata")
+	}
+
+	return supportingDocument, nil
+}
+
+// linkSupportingDocument records the lab as referencing its supporting document.
+func (l *labService) linkSupportingDocument(ctx context.Context, lab entity.LabType) {
+	supportingDocument, err := l.labRepository.GetSupportingDocumentMetadata(ctx, lab.SupportingDocumentId)
+	if err != nil {
+		logger.LogError(ctx, "not able to get supporting document metadata to link lab", "supportingDocumentId", lab.SupportingDocumentId, "labId", lab.Id, "error", err.Error())
+		return
+	}
+
+	if helper.Contains(supportingDocument.LabIds, lab.Id) {
+		return
+	}
+
+	if err := l.labRepository.SetSupportingDocumentLabIds(ctx, lab.SupportingDocumentId, append(supportingDocument.LabIds, lab.Id)); err != nil {
+		logger.LogError(ctx, "not able to link lab to supporting document", "supportingDocumentId", lab.SupportingDocumentId, "labId", lab.Id, "error", err.Error())
+	}
+}
+
+func (l *labService) CollectSupportingDocuments(ctx context.Context) (int, error) {
+	referenced := map[string]bool{}
+
+	for _, typeOfLab := range slices.Concat(entity.PrivateLab, entity.PublicLab, entity.ProtectedLabs) {
+		// Without every lab there's no telling which documents are orphaned,
+		// so one unreadable lab stops the run.
+		labs, err := l.GetAllLabs(ctx, typeOfLab)
+		if err != nil {
+			logger.LogError(ctx, "not able to get labs to collect supporting documents", "typeOfLab", typeOfLab, "error", err.Error())
+			return 0, err
+		}
+
+		for _, lab := range labs {
+			if lab.SupportingDocumentId != "" {
+				referenced[lab.SupportingDocumentId] = true
+			}
+		}
+	}
+
+	supportingDocuments, err := l.labRepository.ListSupportingDocuments(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().Add(-time.Duration(l.appConfig.ActlabsHubSupportingDocumentsGCGraceHours) * time.Hour)
+	deleted := 0
+
+	for _, supportingDocument := range supportingDocuments {
+		if referenced[supportingDocument.Id] {
+			continue
+		}
+
+		uploadedAt, err := time.Parse(time.RFC3339, supportingDocument.UploadedAt)
+		if err != nil || uploadedAt.After(cutoff) {
+			continue
+		}
+
+		if err := l.labRepository.DeleteSupportingDocument(ctx, supportingDocument.Id); err != nil {
+			logger.LogError(ctx, "not able to delete orphaned supporting document", "supportingDocumentId", supportingDocument.Id, "error", err.Error())
+			continue
+		}
+
+		logger.LogInfo(ctx, "deleted orphaned supporting document", "supportingDocumentId", supportingDocument.Id, "fileName", supportingDocument.FileName, "uploadedBy", supportingDocument.UploadedBy)
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+func (l *labService) MonitorSupportingDocuments(ctx context.Context) {
+	interval := time.Duration(l.appConfig.ActlabsHubSupportingDocumentsGCIntervalSeconds) * time.Second
+	health.ExpectHeartbeat("MonitorSupportingDocuments", interval)
+
+	helper.Recoverer(ctx, 100, "MonitorSupportingDocuments", func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				health.Beat("MonitorSupportingDocuments")
+
+				deleted, err := l.CollectSupportingDocuments(ctx)
+				if err != nil {
+					logger.LogError(ctx, "failed to collect supporting documents", "error", err.Error())
+					continue
+				}
+				logger.LogInfo(ctx, "collected supporting documents", "deleted", deleted)
+			}
+		}
+	})
+}
+
 func (l *labService) DoesSupportingDocumentExist(ctx context.Context, supportingDocumentId string) bool {
 	exists := l.labRepository.DoesSupportingDocumentExist(ctx, supportingDocumentId)
 	logger.LogDebug(ctx, "Supporting document exists", "supportingDocumentId", supportingDocumentId, "exists", exists)
@@ -378,8 +681,7 @@ func (l *labService) AssignOwnerToOrphanLab(ctx context.Context, lab *entity.Lab
 			lab.Owners = append(lab.Owners, lab.UpdatedBy)
 		}
 		if lab.CreatedBy == "" && lab.UpdatedBy == "" {
-			lab.Owners = append(lab.Owners, "ashisverma@microsoft.com")
-			lab.Owners = append(lab.Owners, "ericlucier@microsoft.com")
+			lab.Owners = append(lab.Owners, l.appConfig.ActlabsHubDefaultLabOwners...)
 		}
 		logger.LogDebug(ctx, "Updating Owners", "owners", strings.Join(lab.Owners, ", "))
 	}
@@ -394,8 +696,7 @@ func (l *labService) AssignOwnerToOrphanLab(ctx context.Context, lab *entity.Lab
 			lab.Owners = append(lab.Owners, lab.UpdatedBy)
 		}
 		if lab.CreatedBy == "" && lab.UpdatedBy == "" {
-			lab.Owners = append(lab.Owners, "ashisverma@microsoft.com")
-			lab.Owners = append(lab.Owners, "ericlucier@microsoft.com")
+			lab.Owners = append(lab.Owners, l.appConfig.ActlabsHubDefaultLabOwners...)
 		}
 		logger.LogDebug(ctx, "Updating Owners", "owners", strings.Join(lab.Owners, ", "))
 	}
@@ -410,7 +711,7 @@ func (l *labService) ValidateAddingEditorsOrViewers(ctx context.Context, lab ent
 	existingLab, err := l.labRepository.GetLab(ctx, lab.Type, lab.Id)
 	if err != nil {
 		logger.LogError(ctx, "error getting current version of lab", "labName", lab.Name, "labId", lab.Id, "typeOfLab", lab.Type, "owners", strings.Join(lab.Owners, ", "), "editors", strings.Join(lab.Editors, ", "), "error", err.Error())
-		return false, fmt.Errorf("error getting current version of lab")
+		return false, lookupError(err, entity.ErrLabNotFound, "error getting current version of lab")
 	}
 
 	if len(existingLab.Owners) == 0 || (len(existingLab.Owners) == 1 && existingLab.Owners[0] == "") {
@@ -421,7 +722,7 @@ func (l *labService) ValidateAddingEditorsOrViewers(ctx context.Context, lab ent
 	if !helper.Contains(existingLab.Owners, lab.UpdatedBy) {
 		if !helper.SlicesAreEqual(existingLab.Owners, lab.Owners) || !helper.SlicesAreEqual(existingLab.Editors, lab.Editors) || !helper.SlicesAreEqual(existingLab.Viewers, lab.Viewers) {
 			logger.LogError(ctx, "user is not owner and there are changes in either owners, editors or viewers which is not allowed", "labName", lab.Name, "labId", lab.Id, "typeOfLab", lab.Type, "updatedBy", lab.UpdatedBy, "owners", strings.Join(lab.Owners, ", "), "editors", strings.Join(lab.Editors, ", "), "viewers", strings.Join(lab.Viewers, ", "))
-			return false, fmt.Errorf("user is not owner and there are changes in owners, editors, or viewers")
+			return false, entity.NewForbiddenError("lab_edit_forbidden", "user is not owner and there are changes in owners, editors, or viewers")
 		}
 	}
 
@@ -438,7 +739,7 @@ func (l *labService) IsUpsertAllowed(ctx context.Context, lab entity.LabType) (b
 	existingLab, err := l.labRepository.GetLab(ctx, lab.Type, lab.Id)
 	if err != nil {
 		logger.LogError(ctx, "error getting current version of lab", "labName", lab.Name, "labId", lab.Id, "typeOfLab", lab.Type, "owners", strings.Join(lab.Owners, ", "), "error", err.Error())
-		return false, err
+		return false, lookupError(err, entity.ErrLabNotFound, "error getting current version of lab")
 	}
 
 	if len(existingLab.Owners) == 0 || (len(existingLab.Owners) == 1 && existingLab.Owners[0] == "") {
@@ -448,7 +749,7 @@ func (l *labService) IsUpsertAllowed(ctx context.Context, lab entity.LabType) (b
 
 	if !helper.Contains(existingLab.Owners, lab.UpdatedBy) && !helper.Contains(existingLab.Editors, lab.UpdatedBy) {
 		logger.LogError(ctx, "user is not either owner or editor which is required to edit the lab", "labName", lab.Name, "labId", lab.Id, "typeOfLab", lab.Type, "owners", strings.Join(lab.Owners, ", "), "editors", strings.Join(lab.Editors, ", "))
-		return false, fmt.Errorf("user is not owner or editor") // user not either owner or editor. edit not allowed.
+		return false, entity.NewForbiddenError("lab_edit_forbidden", "user is not owner or editor") // user not either owner or editor. edit not allowed.
 	}
 
 	return true, nil
@@ -458,7 +759,7 @@ func (l *labService) IsDeleteAllowed(ctx context.Context, typeOfLab string, labI
 	existingLab, err := l.labRepository.GetLab(ctx, typeOfLab, labId)
 	if err != nil {
 		logger.LogError(ctx, "error getting current version of lab", "labId", labId, "typeOfLab", typeOfLab, "error", err.Error())
-		return false, fmt.Errorf("error getting current version of lab")
+		return false, lookupError(err, entity.ErrLabNotFound, "error getting current version of lab")
 	}
 
 	if !helper.Contains(existingLab.Owners, userId) {
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"actlabs-hub/internal/config"
+	"actlabs-hub/internal/entity"
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+)
+
+type mockSupportingDocumentLabRepository struct {
+	entity.LabRepository
+	labs                map[string][]entity.LabType
+	supportingDocuments []entity.SupportingDocument
+	deleted             []string
+	getLabErr           error
+}
+
+func (m *mockSupportingDocumentLabRepository) ListBlobs(ctx context.Context, typeOfLab string) ([]entity.Blob, error) {
+	blobs := []entity.Blob{}
+	for _, lab := range m.labs[typeOfLab] {
+		blobs = append(blobs, entity.Blob{Name: lab.Id, IsCurrentVersion: true})
+	}
+	return blobs, nil
+}
+
+func (m *mockSupportingDocumentLabRepository) GetLab(ctx context.Context, typeOfLab string, labId string) (entity.LabType, error) {
+	if m.getLabErr != nil {
+		return entity.LabType{}, m.getLabErr
+	}
+	for _, lab := range m.labs[typeOfLab] {
+		if lab.Id == labId {
+			return lab, nil
+		}
+	}
+	return entity.LabType{}, nil
+}
+
+func (m *mockSupportingDocumentLabRepository) ListSupportingDocuments(ctx context.Context) ([]entity.SupportingDocument, error) {
+	return m.supportingDocuments, nil
+}
+
+func (m *mockSupportingDocumentLabRepository) DeleteSupportingDocument(ctx context.Context, supportingDocumentId string) error {
+	m.deleted = append(m.deleted, supportingDocumentId)
+	return nil
+}
+
+func TestCollectSupportingDocuments(t *testing.T) {
+	old := time.Now().Add(-48 * time.Hour).UTC().Format(time.RFC3339)
+	recent := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+
+	repository := &mockSupportingDocumentLabRepository{
+		labs: map[string][]entity.LabType{
+			"readinesslab": {{Id: "lab-1", Type: "readinesslab", Category: "protected", SupportingDocumentId: "referenced"}},
+		},
+		supportingDocuments: []entity.SupportingDocument{
+			{Id: "referenced", UploadedAt: old},
+			{Id: "orphaned", UploadedAt: old},
+			{Id: "recent", UploadedAt: recent},
+			{Id: "unknown-age"},
+		},
+	}
+
+	svc := &labService{
+		labRepository: repository,
+		appConfig:     &config.Config{ActlabsHubSupportingDocumentsGCGraceHours: 24},
+	}
+
+	deleted, err := svc.CollectSupportingDocuments(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if deleted != 1 || len(repository.deleted) != 1 || repository.deleted[0] != "orphaned" {
+		t.Errorf("expected only the orphaned document to be deleted, got %v", repository.deleted)
+	}
+}
+
+func TestCollectSupportingDocumentsStopsOnUnreadableLab(t *testing.T) {
+	old := time.Now().Add(-48 * time.Hour).UTC().Format(time.RFC3339)
+
+	repository := &mockSupportingDocumentLabRepository{
+		labs: map[string][]entity.LabType{
+			"readinesslab": {{Id: "lab-1", Type: "readinesslab", Category: "protected", SupportingDocumentId: "referenced"}},
+		},
+		supportingDocuments: []entity.SupportingDocument{
+			{Id: "referenced", UploadedAt: old},
+		},
+		getLabErr: errors.New("blob unavailable"),
+	}
+
+	svc := &labService{
+		labRepository: repository,
+		appConfig:     &config.Config{ActlabsHubSupportingDocumentsGCGraceHours: 24},
+	}
+
+	if _, err := svc.CollectSupportingDocuments(context.Background()); err == nil {
+		t.Error("expected an error when a lab can't be read")
+	}
+	if len(repository.deleted) != 0 {
+		t.Errorf("expected no documents to be deleted, got %v", repository.deleted)
+	}
+}
+
+func TestListLabsPagesByLabId(t *testing.T) {
+	repository := &mockSupportingDocumentLabRepository{
+		labs: map[string][]entity.LabType{
+			"privatelab": {
+				{Id: "lab-c", Type: "privatelab", Category: "private", Owners: []string{"user@example.com"}},
+				{Id: "lab-a", Type: "privatelab", Category: "private", Owners: []string{"user@example.com"}},
+				{Id: "lab-b", Type: "privatelab", Category: "private", Owners: []string{"someone@example.com"}},
+			},
+		},
+	}
+	svc := &labService{labRepository: repository, appConfig: &config.Config{}}
+
+	// lab-b isn't the user's, so it neither takes a place on the page nor
+	// ends up in a token.
+	page, err := svc.ListLabs(context.Background(), "privatelab", "user@example.com", entity.ListQuery{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 2 || page.Items[0].Id != "lab-a" || page.Items[1].Id != "lab-c" || page.NextToken != "" {
+		t.Fatalf("expected lab-a and lab-c on a single page, got %+v", page)
+	}
+
+	page, err = svc.ListLabs(context.Background(), "privatelab", "user@example.com", entity.ListQuery{Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].Id != "lab-a" || page.NextToken != base64.RawURLEncoding.EncodeToString([]byte("lab-a")) {
+		t.Fatalf("expected lab-a and a token naming it, got %+v", page)
+	}
+
+	page, err = svc.ListLabs(context.Background(), "privatelab", "user@example.com", entity.ListQuery{Limit: 1, NextToken: page.NextToken})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].Id != "lab-c" || page.NextToken != "" {
+		t.Fatalf("expected lab-c on the last page, got %+v", page)
+	}
+
+	if _, err := svc.ListLabs(context.Background(), "privatelab", "user@example.com", entity.ListQuery{NextToken: "not base64!"}); entity.ErrorKindOf(err) != entity.ErrorKindValidation {
+		t.Errorf("expected a validation error for a bad token, got %v", err)
+	}
+}
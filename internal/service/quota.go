@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"actlabs-hub/internal/config"
+	"actlabs-hub/internal/entity"
+	"actlabs-hub/internal/logger"
+)
+
+// startingDeploymentStatuses count against the concurrent deployments quota.
+// Destroys are never limited, they free up resources.
+var startingDeploymentStatuses = []entity.DeploymentStatus{
+	entity.InitInProgress,
+	entity.PlanInProgress,
+	entity.DeploymentInProgress,
+}
+
+type quotaService struct {
+	quotaRepository      entity.QuotaRepository
+	deploymentRepository entity.DeploymentRepository
+	appConfig            *config.Config
+}
+
+func NewQuotaService(
+	quotaRepository entity.QuotaRepository,
+	deploymentRepository entity.DeploymentRepository,
+	appConfig *config.Config,
+) entity.QuotaService {
+	return &quotaService{
+		quotaRepository:      quotaRepository,
+		deploymentRepository: deploymentRepository,
+		appConfig:            appConfig,
+	}
+}
+
+func (q *quotaService) GetQuotaUsage(ctx context.Context, userId string) (entity.DeploymentQuotaUsage, error) {
+	quota, overridden, err := q.quota(ctx, userId)
+	if err != nil {
+		return entity.DeploymentQuotaUsage{}, err
+	}
+
+	deployments, err := q.deploymentRepository.GetUserDeployments(ctx, userId)
+	if err != nil {
+		logger.LogError(ctx, "failed to get user deployments for quota usage",
+			"requested_user_id", userId,
+			"error", err,
+		)
+		return entity.DeploymentQuotaUsage{}, err
+	}
+
+	lifespanSeconds, err := q.quotaRepository.GetWeeklyLifespan(ctx, userId, currentWeek())
+	if err != nil {
+		return entity.DeploymentQuotaUsage{}, err
+	}
+
+	usage := entity.DeploymentQuotaUsage{
+		UserId:                userId,
+		Quota:                 quota,
+		Overridden:            overridden,
+		Workspaces:            len(deployments),
+		LifespanHoursThisWeek: float64(lifespanSeconds) / 3600,
+	}
+	for _, deployment := range deployments {
+		if slices.Contains(startingDeploymentStatuses, deployment.DeploymentStatus) {
+			usage.ConcurrentDeployments++
+		}
+	}
+
+	return usage, nil
+}
+
+func (q *quotaService) CheckDeployment(ctx context.Context, deployment entity.Deployment) (int64, error) {
+	userId := deployment.DeploymentUserId
+
+	deployments, err := q.deploymentRepository.GetUserDeployments(ctx, userId)
+	if err != nil {
+		// Without the user's deployments there's no telling whether the
+		// lifespan was already handed out, so none is recorded.
+		logger.LogWarning(ctx, "failed to get user deployments for quota check, allowing deployment",
+			"requested_user_id", userId,
+			"error", err,
+		)
+		return 0, nil
+	}
+
+	var existing *entity.Deployment
+	concurrent := 0
+	for i, d := range deployments {
+		if d.DeploymentSubscriptionId == deployment.DeploymentSubscriptionId && d.DeploymentWorkspace == deployment.DeploymentWorkspace {
+			existing = &deployments[i]
+			continue
+		}
+		if slices.Contains(startingDeploymentStatuses, d.DeploymentStatus) {
+			concurrent++
+		}
+	}
+
+	// The lifespan is handed out when the deployment starts deploying.
+	lifespan := int64(0)
+	if deployment.DeploymentAutoDelete &&
+		deployment.DeploymentStatus == entity.DeploymentInProgress &&
+		(existing == nil || existing.DeploymentStatus != entity.DeploymentInProgress) {
+		lifespan = deployment.DeploymentLifespan
+	}
+
+	// Updates within the starting statuses report what the server is already
+	// doing, rejecting them would only make the hub disagree with it. A
+	// workspace created earlier that starts deploying now is checked like a
+	// new one, except it's already counted against the workspaces.
+	starting := slices.Contains(startingDeploymentStatuses, deployment.DeploymentStatus)
+	if existing != nil && (!starting || slices.Contains(startingDeploymentStatuses, existing.DeploymentStatus)) {
+		return lifespan, nil
+	}
+
+	quota, _, err := q.quota(ctx, userId)
+	if err != nil {
+		logger.LogWarning(ctx, "failed to get quota, allowing deployment",
+			"requested_user_id", userId,
+			"error", err,
+		)
+		return lifespan, nil
+	}
+
+	if existing == nil && quota.MaxWorkspaces > 0 && len(deployments) >= quota.MaxWorkspaces {
+		return 0, &entity.QuotaExceededError{
+			Limit:     entity.QuotaLimitWorkspaces,
+			Max:       int64(quota.MaxWorkspaces),
+			Current:   float64(len(deployments)),
+			Requested: 1,
+		}
+	}
+
+	if starting && quota.MaxConcurrentDeployments > 0 && concurrent >= quota.MaxConcurrentDeployments {
+		return 0, &entity.QuotaExceededError{
+			Limit:     entity.QuotaLimitConcurrentDeployments,
+			Max:       int64(quota.MaxConcurrentDeployments),
+			Current:   float64(concurrent),
+			Requested: 1,
+		}
+	}
+
+	if lifespan > 0 {
+		if err := q.CheckLifespan(ctx, userId, lifespan); err != nil {
+			return 0, err
+		}
+	}
+
+	return lifespan, nil
+}
+
+func (q *quotaService) CheckLifespan(ctx context.Context, userId string, lifespanSeconds int64) error {
+	quota, _, err := q.quota(ctx, userId)
+	if err != nil {
+		logger.LogWarning(ctx, "failed to get quota, allowing lifespan",
+			"requested_user_id", userId,
+			"error", err,
+		)
+		return nil
+	}
+
+	if quota.MaxLifespanHoursPerWeek <= 0 {
+		return nil
+	}
+
+	used, err := q.quotaRepository.GetWeeklyLifespan(ctx, userId, currentWeek())
+	if err != nil {
+		logger.LogWarning(ctx, "failed to get weekly lifespan used, allowing lifespan",
+			"requested_user_id", userId,
+			"error", err,
+		)
+		return nil
+	}
+
+	if used+lifespanSeconds > quota.MaxLifespanHoursPerWeek*3600 {
+		return &entity.QuotaExceededError{
+			Limit:     entity.QuotaLimitLifespanHoursPerWeek,
+			Max:       quota.MaxLifespanHoursPerWeek,
+			Current:   float64(used) / 3600,
+			Requested: float64(lifespanSeconds) / 3600,
+		}
+	}
+
+	return nil
+}
+
+func (q *quotaService) RecordLifespan(ctx context.Context, userId string, lifespanSeconds int64) error {
+	if lifespanSeconds <= 0 {
+		return nil
+	}
+	return q.quotaRepository.AddWeeklyLifespan(ctx, userId, currentWeek(), lifespanSeconds)
+}
+
+func (q *quotaService) GetQuotaOverrides(ctx context.Context) ([]entity.DeploymentQuotaOverride, error) {
+	overrides, err := q.quotaRepository.GetQuotaOverrides(ctx)
+	if err != nil {
+		return nil, entity.NewStorageError("not able to get quota overrides", err)
+	}
+	return overrides, nil
+}
+
+func (q *quotaService) UpsertQuotaOverride(ctx context.Context, override entity.DeploymentQuotaOverride) error {
+	if override.UserId == "" {
+		return entity.NewValidationError("invalid_quota", "userId is required")
+	}
+	if override.Quota.MaxConcurrentDeployments < 0 || override.Quota.MaxWorkspaces < 0 || override.Quota.MaxLifespanHoursPerWeek < 0 {
+		return entity.NewValidationError("invalid_quota", "quota limits can't be negative")
+	}
+
+	logger.LogInfo(ctx, "upserting quota override",
+		"requested_user_id", override.UserId,
+		"max_concurrent_deployments", override.Quota.MaxConcurrentDeployments,
+		"max_workspaces", override.Quota.MaxWorkspaces,
+		"max_lifespan_hours_per_week", override.Quota.MaxLifespanHoursPerWeek,
+	)
+
+	return q.quotaRepository.UpsertQuotaOverride(ctx, override)
+}
+
+func (q *quotaService) DeleteQuotaOverride(ctx context.Context, userId string) error {
+	logger.LogInfo(ctx, "deleting quota override",
+		"requested_user_id", userId,
+	)
+
+	return q.quotaRepository.DeleteQuotaOverride(ctx, userId)
+}
+
+// quota is the user's override, or the default quota.
+func (q *quotaService) quota(ctx context.Context, userId string) (entity.DeploymentQuota, bool, error) {
+	quota, ok, err := q.quotaRepository.GetQuotaOverride(ctx, userId)
+	if err != nil {
+		return entity.DeploymentQuota{}, false, err
+	}
+	if ok {
+		return quota, true, nil
+	}
+	return q.appConfig.ActlabsHubDefaultDeploymentQuota, false, nil
+}
+
+// currentWeek is the ISO week lifespan quotas are counted in, e.g. "2024-W18".
+func currentWeek() string {
+	year, week := time.Now().UTC().ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
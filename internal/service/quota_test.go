@@ -0,0 +1,177 @@
+package service
+
+import (
+	"actlabs-hub/internal/config"
+	"actlabs-hub/internal/entity"
+	"context"
+	"errors"
+	"testing"
+)
+
+type mockQuotaRepository struct {
+	entity.QuotaRepository
+	override       *entity.DeploymentQuota
+	weeklyLifespan int64
+	err            error
+}
+
+func (m *mockQuotaRepository) GetQuotaOverride(ctx context.Context, userId string) (entity.DeploymentQuota, bool, error) {
+	if m.err != nil {
+		return entity.DeploymentQuota{}, false, m.err
+	}
+	if m.override == nil {
+		return entity.DeploymentQuota{}, false, nil
+	}
+	return *m.override, true, nil
+}
+
+func (m *mockQuotaRepository) GetWeeklyLifespan(ctx context.Context, userId string, week string) (int64, error) {
+	return m.weeklyLifespan, nil
+}
+
+type mockQuotaDeploymentRepository struct {
+	entity.DeploymentRepository
+	deployments []entity.Deployment
+}
+
+func (m *mockQuotaDeploymentRepository) GetUserDeployments(ctx context.Context, userPrincipalName string) ([]entity.Deployment, error) {
+	return m.deployments, nil
+}
+
+func TestCheckDeployment(t *testing.T) {
+	defaultQuota := entity.DeploymentQuota{
+		MaxConcurrentDeployments: 1,
+		MaxWorkspaces:            2,
+		MaxLifespanHoursPerWeek:  10,
+	}
+
+	deploying := entity.Deployment{
+		DeploymentUserId:         "user@microsoft.com",
+		DeploymentSubscriptionId: "sub",
+		DeploymentWorkspace:      "ws1",
+		DeploymentStatus:         entity.DeploymentInProgress,
+	}
+
+	tests := []struct {
+		name           string
+		override       *entity.DeploymentQuota
+		weeklyLifespan int64
+		quotaErr       error
+		existing       []entity.Deployment
+		deployment     entity.Deployment
+		wantLimit      string
+		wantLifespan   int64
+	}{
+		{
+			name:       "new workspace over max workspaces",
+			existing:   []entity.Deployment{{DeploymentWorkspace: "a"}, {DeploymentWorkspace: "b"}},
+			deployment: deploying,
+			wantLimit:  entity.QuotaLimitWorkspaces,
+		},
+		{
+			name:       "existing workspace is not counted again",
+			existing:   []entity.Deployment{{DeploymentSubscriptionId: "sub", DeploymentWorkspace: "ws1", DeploymentStatus: entity.DeploymentInProgress}, {DeploymentWorkspace: "b"}},
+			deployment: deploying,
+		},
+		{
+			name:       "another deployment in progress",
+			existing:   []entity.Deployment{{DeploymentWorkspace: "a", DeploymentStatus: entity.PlanInProgress}},
+			deployment: deploying,
+			wantLimit:  entity.QuotaLimitConcurrentDeployments,
+		},
+		{
+			name:       "destroy is never limited",
+			existing:   []entity.Deployment{{DeploymentWorkspace: "a", DeploymentStatus: entity.PlanInProgress}, {DeploymentSubscriptionId: "sub", DeploymentWorkspace: "b"}},
+			deployment: entity.Deployment{DeploymentSubscriptionId: "sub", DeploymentWorkspace: "b", DeploymentStatus: entity.DestroyInProgress},
+		},
+		{
+			name:           "lifespan over weekly quota",
+			weeklyLifespan: 9 * 3600,
+			deployment: entity.Deployment{
+				DeploymentSubscriptionId: "sub",
+				DeploymentWorkspace:      "ws1",
+				DeploymentStatus:         entity.DeploymentInProgress,
+				DeploymentAutoDelete:     true,
+				DeploymentLifespan:       2 * 3600,
+			},
+			wantLimit: entity.QuotaLimitLifespanHoursPerWeek,
+		},
+		{
+			name:       "created workspace starting over concurrent deployments",
+			existing:   []entity.Deployment{{DeploymentSubscriptionId: "sub", DeploymentWorkspace: "ws1", DeploymentStatus: entity.DeploymentNotStarted}, {DeploymentWorkspace: "a", DeploymentStatus: entity.DeploymentInProgress}},
+			deployment: deploying,
+			wantLimit:  entity.QuotaLimitConcurrentDeployments,
+		},
+		{
+			name:           "created workspace starting over weekly lifespan",
+			weeklyLifespan: 9 * 3600,
+			existing:       []entity.Deployment{{DeploymentSubscriptionId: "sub", DeploymentWorkspace: "ws1", DeploymentStatus: entity.DeploymentNotStarted}, {DeploymentWorkspace: "b"}},
+			deployment: entity.Deployment{
+				DeploymentSubscriptionId: "sub",
+				DeploymentWorkspace:      "ws1",
+				DeploymentStatus:         entity.DeploymentInProgress,
+				DeploymentAutoDelete:     true,
+				DeploymentLifespan:       2 * 3600,
+			},
+			wantLimit: entity.QuotaLimitLifespanHoursPerWeek,
+		},
+		{
+			name:           "updates within starting statuses are never rejected",
+			weeklyLifespan: 9 * 3600,
+			existing:       []entity.Deployment{{DeploymentSubscriptionId: "sub", DeploymentWorkspace: "ws1", DeploymentStatus: entity.PlanInProgress}, {DeploymentWorkspace: "a", DeploymentStatus: entity.PlanInProgress}},
+			deployment: entity.Deployment{
+				DeploymentSubscriptionId: "sub",
+				DeploymentWorkspace:      "ws1",
+				DeploymentStatus:         entity.DeploymentInProgress,
+				DeploymentAutoDelete:     true,
+				DeploymentLifespan:       2 * 3600,
+			},
+			wantLifespan: 2 * 3600,
+		},
+		{
+			name:       "quota storage error allows the deployment",
+			quotaErr:   errors.New("table unavailable"),
+			existing:   []entity.Deployment{{DeploymentWorkspace: "a"}, {DeploymentWorkspace: "b"}},
+			deployment: deploying,
+		},
+		{
+			name:     "override raises the limits",
+			override: &entity.DeploymentQuota{MaxConcurrentDeployments: 5, MaxWorkspaces: 5, MaxLifespanHoursPerWeek: 100},
+			existing: []entity.Deployment{{DeploymentWorkspace: "a", DeploymentStatus: entity.PlanInProgress}, {DeploymentWorkspace: "b"}},
+			deployment: entity.Deployment{
+				DeploymentSubscriptionId: "sub",
+				DeploymentWorkspace:      "ws1",
+				DeploymentStatus:         entity.DeploymentInProgress,
+				DeploymentAutoDelete:     true,
+				DeploymentLifespan:       2 * 3600,
+			},
+			wantLifespan: 2 * 3600,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewQuotaService(
+				&mockQuotaRepository{override: tt.override, weeklyLifespan: tt.weeklyLifespan, err: tt.quotaErr},
+				&mockQuotaDeploymentRepository{deployments: tt.existing},
+				&config.Config{ActlabsHubDefaultDeploymentQuota: defaultQuota},
+			)
+
+			lifespan, err := svc.CheckDeployment(context.Background(), tt.deployment)
+
+			var quotaErr *entity.QuotaExceededError
+			if tt.wantLimit != "" {
+				if !errors.As(err, &quotaErr) || quotaErr.Limit != tt.wantLimit {
+					t.Fatalf("expected quota error for %s, got %v", tt.wantLimit, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if lifespan != tt.wantLifespan {
+				t.Errorf("expected lifespan %d, got %d", tt.wantLifespan, lifespan)
+			}
+		})
+	}
+}
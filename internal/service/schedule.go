@@ -0,0 +1,351 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"actlabs-hub/internal/config"
+	"actlabs-hub/internal/entity"
+	"actlabs-hub/internal/health"
+	"actlabs-hub/internal/helper"
+	"actlabs-hub/internal/logger"
+
+	"github.com/google/uuid"
+)
+
+type scheduleService struct {
+	scheduleRepository   entity.ScheduleRepository
+	deploymentRepository entity.DeploymentRepository
+	deploymentService    entity.DeploymentService
+	labService           entity.LabService
+	serverService        entity.ServerService
+	eventService         entity.EventService
+	appConfig            *config.Config
+}
+
+func NewScheduleService(
+	scheduleRepository entity.ScheduleRepository,
+	deploymentRepository entity.DeploymentRepository,
+	deploymentService entity.DeploymentService,
+	labService entity.LabService,
+	serverService entity.ServerService,
+	eventService entity.EventService,
+	appConfig *config.Config,
+) entity.ScheduleService {
+	return &scheduleService{
+		scheduleRepository:   scheduleRepository,
+		deploymentRepository: deploymentRepository,
+		deploymentService:    deploymentService,
+		labService:           labService,
+		serverService:        serverService,
+		eventService:         eventService,
+		appConfig:            appConfig,
+	}
+}
+
+func (s *scheduleService) GetSchedules(ctx context.Context) ([]entity.Schedule, error) {
+	return s.scheduleRepository.GetSchedules(ctx)
+}
+
+func (s *scheduleService) GetSchedule(ctx context.Context, scheduleId string) (entity.Schedule, error) {
+	return s.scheduleRepository.GetSchedule(ctx, scheduleId)
+}
+
+func (s *scheduleService) CreateSchedule(ctx context.Context, schedule entity.Schedule) (entity.Schedule, error) {
+	if len(schedule.UserIds) == 0 || schedule.Workspace == "" || schedule.Lab.Id == "" || schedule.Lab.Type == "" {
+		return entity.Schedule{}, fmt.Errorf("%w: users, workspace and lab id and type are required", entity.ErrInvalidSchedule)
+	}
+
+	startTime, err := time.Parse(time.RFC3339, schedule.StartTime)
+	if err != nil {
+		return entity.Schedule{}, fmt.Errorf("%w: start time must be RFC3339", entity.ErrInvalidSchedule)
+	}
+
+	endTime, err := time.Parse(time.RFC3339, schedule.EndTime)
+	if err != nil {
+		return entity.Schedule{}, fmt.Errorf("%w: end time must be RFC3339", entity.ErrInvalidSchedule)
+	}
+
+	if !endTime.After(startTime) || !endTime.After(time.Now()) {
+		return entity.Schedule{}, fmt.Errorf("%w: end time must be after the start time and in the future", entity.ErrInvalidSchedule)
+	}
+
+	// The lab deployed is the saved one, not whatever came with the request.
+	lab, err := s.labService.GetLabByIdAndType(ctx, schedule.Lab.Type, schedule.Lab.Id)
+	if err != nil {
+		return entity.Schedule{}, err
+	}
+	schedule.Lab = lab
+
+	schedule.Id = uuid.NewString()
+	schedule.Status = entity.ScheduleScheduled
+	schedule.Runs = []entity.ScheduleRun{}
+
+	if err := s.scheduleRepository.UpsertSchedule(ctx, schedule); err != nil {
+		return entity.Schedule{}, err
+	}
+
+	s.createScheduleEvent(ctx, schedule, "Normal", "ScheduleCreated", fmt.Sprintf("Schedule %s deploys lab %s to workspace %s of %d users from %s to %s.", schedule.Id, schedule.Lab.Name, schedule.Workspace, len(schedule.UserIds), schedule.StartTime, schedule.EndTime))
+
+	return schedule, nil
+}
+
+func (s *scheduleService) DeleteSchedule(ctx context.Context, scheduleId string) error {
+	schedule, err := s.scheduleRepository.GetSchedule(ctx, scheduleId)
+	if err != nil {
+		return err
+	}
+
+	if schedule.Status == entity.ScheduleDeployed {
+		s.runAction(ctx, &schedule, entity.ScheduleActionDestroy)
+	}
+
+	if err := s.scheduleRepository.DeleteSchedule(ctx, scheduleId); err != nil {
+		return err
+	}
+
+	s.createScheduleEvent(ctx, schedule, "Normal", "ScheduleDeleted", fmt.Sprintf("Schedule %s for lab %s and workspace %s was deleted.", schedule.Id, schedule.Lab.Name, schedule.Workspace))
+
+	return nil
+}
+
+func (s *scheduleService) RunSchedules(ctx context.Context) error {
+	schedules, err := s.scheduleRepository.GetSchedules(ctx)
+	if err != nil {
+		logger.LogError(ctx, "failed to get schedules to run",
+			"error", err,
+		)
+		return err
+	}
+
+	now := time.Now()
+
+	for _, schedule := range schedules {
+		startTime, _ := time.Parse(time.RFC3339, schedule.StartTime)
+		endTime, _ := time.Parse(time.RFC3339, schedule.EndTime)
+
+		scheduleCtx := logger.WithUserID(ctx, schedule.CreatedBy)
+
+		switch {
+		case schedule.Status == entity.ScheduleScheduled && !now.Before(endTime):
+			// The hub was down for the whole window, there's nothing to deploy for.
+			schedule.Status = entity.ScheduleCompleted
+			s.createScheduleEvent(scheduleCtx, schedule, "Warning", "ScheduleMissed", fmt.Sprintf("Schedule %s for lab %s and workspace %s ended before it was run.", schedule.Id, schedule.Lab.Name, schedule.Workspace))
+		case schedule.Status == entity.ScheduleScheduled && !now.Before(startTime):
+			s.runAction(scheduleCtx, &schedule, entity.ScheduleActionDeploy)
+			schedule.Status = entity.ScheduleDeployed
+		case schedule.Status == entity.ScheduleDeployed && !now.Before(endTime):
+			// Failed destroys stay pending and are retried on the next run.
+			if s.runAction(scheduleCtx, &schedule, entity.ScheduleActionDestroy) {
+				schedule.Status = entity.ScheduleCompleted
+			}
+		default:
+			continue
+		}
+
+		if err := s.scheduleRepository.UpsertSchedule(scheduleCtx, schedule); err != nil {
+			logger.LogError(scheduleCtx, "failed to save schedule after run",
+				"schedule_id", schedule.Id,
+				"error", err,
+			)
+		}
+	}
+
+	return nil
+}
+
+// runAction deploys or destroys the schedule's lab for each of its users,
+// recording a run per user and one event for the whole schedule. Only labs
+// the schedule deployed and hasn't destroyed yet are destroyed. It returns
+// false if it failed for any user.
+func (s *scheduleService) runAction(ctx context.Context, schedule *entity.Schedule, action string) bool {
+	failedUsers := []string{}
+
+	userIds := schedule.UserIds
+	if action == entity.ScheduleActionDestroy {
+		userIds = pendingDestroys(*schedule)
+		if len(userIds) == 0 {
+			return true
+		}
+	}
+
+	for _, userId := range userIds {
+		var err error
+		if action == entity.ScheduleActionDeploy {
+			err = s.deployForUser(ctx, *schedule, userId)
+		} else {
+			err = s.destroyForUser(ctx, *schedule, userId)
+		}
+
+		run := entity.ScheduleRun{
+			UserId:    userId,
+			Action:    action,
+			Time:      time.Now().Format(time.RFC3339),
+			Succeeded: err == nil,
+		}
+		if err != nil {
+			logger.LogError(ctx, "failed to run schedule for user",
+				"schedule_id", schedule.Id,
+				"action", action,
+				"requested_user_id", userId,
+				"error", err,
+			)
+			run.Error = err.Error()
+			failedUsers = append(failedUsers, userId)
+		}
+
+		schedule.Runs = append(schedule.Runs, run)
+	}
+
+	if len(failedUsers) > 0 {
+		s.createScheduleEvent(ctx, *schedule, "Warning", "ScheduleRunFailed", fmt.Sprintf("Schedule %s failed to %s lab %s in workspace %s for %d of %d users: %s.", schedule.Id, action, schedule.Lab.Name, schedule.Workspace, len(failedUsers), len(userIds), strings.Join(failedUsers, ", ")))
+		return false
+	}
+
+	s.createScheduleEvent(ctx, *schedule, "Normal", "ScheduleRunSucceeded", fmt.Sprintf("Schedule %s started to %s lab %s in workspace %s for %d users.", schedule.Id, action, schedule.Lab.Name, schedule.Workspace, len(userIds)))
+	return true
+}
+
+// pendingDestroys are the users the schedule deployed the lab for and hasn't
+// destroyed it for yet.
+func pendingDestroys(schedule entity.Schedule) []string {
+	deployed := map[string]bool{}
+	for _, run := range schedule.Runs {
+		if !run.Succeeded {
+			continue
+		}
+		switch run.Action {
+		case entity.ScheduleActionDeploy:
+			deployed[run.UserId] = true
+		case entity.ScheduleActionDestroy:
+			delete(deployed, run.UserId)
+		}
+	}
+
+	userIds := []string{}
+	for _, userId := range schedule.UserIds {
+		if deployed[userId] {
+			userIds = append(userIds, userId)
+		}
+	}
+	return userIds
+}
+
+func (s *scheduleService) deployForUser(ctx context.Context, schedule entity.Schedule, userId string) error {
+	server, err := s.serverService.GetServer(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if server.SubscriptionId == "" || server.Status == entity.ServerStatusUnregistered {
+		return entity.ErrServerNotRegistered
+	}
+
+	endTime, _ := time.Parse(time.RFC3339, schedule.EndTime)
+
+	// The user's own deployment in the workspace is never replaced.
+	deployment := entity.Deployment{
+		DeploymentUserId:         userId,
+		DeploymentSubscriptionId: server.SubscriptionId,
+		DeploymentWorkspace:      schedule.Workspace,
+	}
+	if _, err := s.deploymentRepository.GetDeployment(ctx, entity.NewDeploymentKey(deployment)); err == nil {
+		return entity.ErrScheduleWorkspaceInUse
+	} else if !isNotFound(err) {
+		return entity.NewStorageError("not able to check for an existing deployment", err)
+	}
+
+	// The schedule destroys the deployment at its end time. It's auto deleted
+	// then too, in case the schedule's destroy fails.
+	deployment.DeploymentStatus = entity.InitInProgress
+	deployment.DeploymentLab = schedule.Lab
+	deployment.DeploymentLifespan = int64(time.Until(endTime).Seconds())
+	deployment.DeploymentAutoDelete = true
+	deployment.DeploymentAutoDeleteUnixTime = endTime.Unix()
+	deployment.DeploymentId = entity.NewDeploymentKey(deployment).RowKey()
+
+	userCtx := logger.WithUserID(ctx, userId)
+	if err := s.deploymentService.UpsertDeployment(userCtx, deployment); err != nil {
+		return err
+	}
+
+	if err := s.deploymentRepository.DeployDeployment(ctx, userId, deployment); err != nil {
+		// The server never started, so the deployment shouldn't look in progress.
+		deployment.DeploymentStatus = entity.InitFailed
+		if err := s.deploymentService.UpsertDeployment(userCtx, deployment); err != nil {
+			logger.LogError(ctx, "failed to mark scheduled deployment failed",
+				"schedule_id", schedule.Id,
+				"requested_user_id", userId,
+				"error", err,
+			)
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *scheduleService) destroyForUser(ctx context.Context, schedule entity.Schedule, userId string) error {
+	server, err := s.serverService.GetServer(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	deployment, err := s.deploymentRepository.GetDeployment(ctx, entity.DeploymentKey{
+		UserId:         userId,
+		SubscriptionId: server.SubscriptionId,
+		Workspace:      schedule.Workspace,
+	})
+	if isNotFound(err) {
+		// Already gone, there's nothing left to destroy.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.deploymentRepository.AutoDestroyDeployment(ctx, userId, deployment)
+}
+
+func (s *scheduleService) createScheduleEvent(ctx context.Context, schedule entity.Schedule, eventType string, reason string, message string) {
+	// Create Event
+	if err := s.eventService.CreateEvent(ctx, entity.Event{
+		TimeStamp: time.Now().Format(time.RFC3339),
+		Type:      eventType,
+		Reason:    reason,
+		Message:   message,
+		Reporter:  "actlabs-hub",
+		Object:    schedule.Id,
+	}); err != nil {
+		logger.LogError(ctx, "failed to create schedule event",
+			"schedule_id", schedule.Id,
+			"error", err,
+		)
+	}
+}
+
+func (s *scheduleService) MonitorSchedules(ctx context.Context) {
+	interval := time.Duration(s.appConfig.ActlabsHubSchedulesPollingIntervalSeconds) * time.Second
+	health.ExpectHeartbeat("MonitorSchedules", interval)
+
+	helper.Recoverer(ctx, 100, "MonitorSchedules", func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				health.Beat("MonitorSchedules")
+
+				if err := s.RunSchedules(ctx); err != nil {
+					logger.LogError(ctx, "failed to run schedules",
+						"error", err,
+					)
+				}
+			}
+		}
+	})
+}
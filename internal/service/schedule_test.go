@@ -0,0 +1,249 @@
+package service
+
+import (
+	"actlabs-hub/internal/entity"
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+type mockScheduleRepository struct {
+	entity.ScheduleRepository
+	schedules []entity.Schedule
+	saved     map[string]entity.Schedule
+}
+
+func (m *mockScheduleRepository) GetSchedules(ctx context.Context) ([]entity.Schedule, error) {
+	return m.schedules, nil
+}
+
+func (m *mockScheduleRepository) UpsertSchedule(ctx context.Context, schedule entity.Schedule) error {
+	m.saved[schedule.Id] = schedule
+	return nil
+}
+
+type mockScheduleServerService struct {
+	entity.ServerService
+}
+
+func (m *mockScheduleServerService) GetServer(ctx context.Context, userPrincipalName string) (entity.Server, error) {
+	if userPrincipalName == "unregistered@microsoft.com" {
+		return entity.Server{Status: entity.ServerStatusUnregistered}, nil
+	}
+	return entity.Server{SubscriptionId: "sub", Status: entity.ServerStatusRunning}, nil
+}
+
+type mockScheduleDeploymentService struct {
+	entity.DeploymentService
+}
+
+func (m *mockScheduleDeploymentService) UpsertDeployment(ctx context.Context, deployment entity.Deployment) error {
+	return nil
+}
+
+type mockScheduleDeploymentRepository struct {
+	entity.DeploymentRepository
+	existing   []string
+	deployed   []string
+	destroyed  []string
+	destroyErr error
+}
+
+func (m *mockScheduleDeploymentRepository) DeployDeployment(ctx context.Context, userPrincipalName string, deployment entity.Deployment) error {
+	m.deployed = append(m.deployed, userPrincipalName)
+	return nil
+}
+
+func (m *mockScheduleDeploymentRepository) GetDeployment(ctx context.Context, key entity.DeploymentKey) (entity.Deployment, error) {
+	if !slices.Contains(m.existing, key.UserId) {
+		return entity.Deployment{}, &azcore.ResponseError{StatusCode: http.StatusNotFound}
+	}
+	return entity.Deployment{DeploymentUserId: key.UserId}, nil
+}
+
+func (m *mockScheduleDeploymentRepository) AutoDestroyDeployment(ctx context.Context, userPrincipalName string, deployment entity.Deployment) error {
+	m.destroyed = append(m.destroyed, userPrincipalName)
+	return m.destroyErr
+}
+
+type mockEventService struct {
+	entity.EventService
+	reasons []string
+}
+
+func (m *mockEventService) CreateEvent(ctx context.Context, event entity.Event) error {
+	m.reasons = append(m.reasons, event.Reason)
+	return nil
+}
+
+func TestRunSchedules(t *testing.T) {
+	now := time.Now()
+	at := func(d time.Duration) string {
+		return now.Add(d).Format(time.RFC3339)
+	}
+
+	scheduleRepo := &mockScheduleRepository{
+		schedules: []entity.Schedule{
+			{Id: "start", Status: entity.ScheduleScheduled, UserIds: []string{"user@microsoft.com", "unregistered@microsoft.com"}, StartTime: at(-time.Minute), EndTime: at(time.Hour)},
+			{Id: "end", Status: entity.ScheduleDeployed, UserIds: []string{"deployed@microsoft.com"}, StartTime: at(-2 * time.Hour), EndTime: at(-time.Minute), Runs: []entity.ScheduleRun{
+				{UserId: "deployed@microsoft.com", Action: entity.ScheduleActionDeploy, Succeeded: true},
+			}},
+			{Id: "missed", Status: entity.ScheduleScheduled, UserIds: []string{"user@microsoft.com"}, StartTime: at(-2 * time.Hour), EndTime: at(-time.Hour)},
+			{Id: "later", Status: entity.ScheduleScheduled, UserIds: []string{"user@microsoft.com"}, StartTime: at(time.Hour), EndTime: at(2 * time.Hour)},
+		},
+		saved: map[string]entity.Schedule{},
+	}
+	deploymentRepo := &mockScheduleDeploymentRepository{existing: []string{"deployed@microsoft.com"}, destroyErr: errors.New("server not reachable")}
+	eventService := &mockEventService{}
+
+	svc := NewScheduleService(scheduleRepo, deploymentRepo, &mockScheduleDeploymentService{}, nil, &mockScheduleServerService{}, eventService, nil)
+
+	if err := svc.RunSchedules(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, ok := scheduleRepo.saved["later"]; ok {
+		t.Errorf("schedule that hasn't started should not be saved")
+	}
+	if got := scheduleRepo.saved["missed"].Status; got != entity.ScheduleCompleted {
+		t.Errorf("expected missed schedule to be completed, got %s", got)
+	}
+
+	started := scheduleRepo.saved["start"]
+	if started.Status != entity.ScheduleDeployed || len(started.Runs) != 2 || !started.Runs[0].Succeeded || started.Runs[1].Succeeded {
+		t.Errorf("expected deployed schedule with one failed run, got %+v", started)
+	}
+	if len(deploymentRepo.deployed) != 1 {
+		t.Errorf("expected 1 deploy, got %v", deploymentRepo.deployed)
+	}
+
+	// A failed destroy keeps the schedule deployed so it's retried.
+	ended := scheduleRepo.saved["end"]
+	if ended.Status != entity.ScheduleDeployed || len(ended.Runs) != 2 || ended.Runs[1].Error == "" {
+		t.Errorf("expected still deployed schedule with failed destroy run, got %+v", ended)
+	}
+
+	want := []string{"ScheduleRunFailed", "ScheduleRunFailed", "ScheduleMissed"}
+	if len(eventService.reasons) != len(want) {
+		t.Fatalf("expected events %v, got %v", want, eventService.reasons)
+	}
+	for i := range want {
+		if eventService.reasons[i] != want[i] {
+			t.Errorf("expected events %v, got %v", want, eventService.reasons)
+		}
+	}
+}
+
+type mockScheduleDeploymentCapture struct {
+	entity.DeploymentService
+	upserted []entity.Deployment
+}
+
+func (m *mockScheduleDeploymentCapture) UpsertDeployment(ctx context.Context, deployment entity.Deployment) error {
+	m.upserted = append(m.upserted, deployment)
+	return nil
+}
+
+func TestRunSchedulesDeploysOnlyToFreeWorkspaces(t *testing.T) {
+	now := time.Now()
+	end := now.Add(time.Hour).Format(time.RFC3339)
+
+	scheduleRepo := &mockScheduleRepository{
+		schedules: []entity.Schedule{
+			{Id: "start", Status: entity.ScheduleScheduled, UserIds: []string{"free@microsoft.com", "busy@microsoft.com"}, StartTime: now.Add(-time.Minute).Format(time.RFC3339), EndTime: end},
+		},
+		saved: map[string]entity.Schedule{},
+	}
+	deploymentRepo := &mockScheduleDeploymentRepository{existing: []string{"busy@microsoft.com"}}
+	deploymentService := &mockScheduleDeploymentCapture{}
+
+	svc := NewScheduleService(scheduleRepo, deploymentRepo, deploymentService, nil, &mockScheduleServerService{}, &mockEventService{}, nil)
+
+	if err := svc.RunSchedules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(deploymentService.upserted) != 1 || deploymentService.upserted[0].DeploymentUserId != "free@microsoft.com" {
+		t.Fatalf("expected only the free workspace to be deployed, got %+v", deploymentService.upserted)
+	}
+	if !deploymentService.upserted[0].DeploymentAutoDelete {
+		t.Error("expected the scheduled deployment to be auto deleted as a backstop")
+	}
+
+	runs := scheduleRepo.saved["start"].Runs
+	if len(runs) != 2 || runs[1].Succeeded || runs[1].Error != entity.ErrScheduleWorkspaceInUse.Error() {
+		t.Errorf("expected a workspace in use run for the busy user, got %+v", runs)
+	}
+
+	// Later only the lab the schedule deployed is destroyed.
+	if got := pendingDestroys(scheduleRepo.saved["start"]); len(got) != 1 || got[0] != "free@microsoft.com" {
+		t.Errorf("expected only the free user's lab to be destroyed, got %v", got)
+	}
+}
+
+func TestRunSchedulesCompletesOnceDestroysSucceed(t *testing.T) {
+	schedule := entity.Schedule{
+		Id: "end", Status: entity.ScheduleDeployed, UserIds: []string{"a@microsoft.com", "b@microsoft.com"},
+		StartTime: time.Now().Add(-2 * time.Hour).Format(time.RFC3339), EndTime: time.Now().Add(-time.Minute).Format(time.RFC3339),
+		Runs: []entity.ScheduleRun{
+			{UserId: "a@microsoft.com", Action: entity.ScheduleActionDeploy, Succeeded: true},
+			{UserId: "b@microsoft.com", Action: entity.ScheduleActionDeploy, Succeeded: true},
+			{UserId: "a@microsoft.com", Action: entity.ScheduleActionDestroy, Succeeded: true},
+			{UserId: "b@microsoft.com", Action: entity.ScheduleActionDestroy, Error: "server not reachable"},
+		},
+	}
+	scheduleRepo := &mockScheduleRepository{schedules: []entity.Schedule{schedule}, saved: map[string]entity.Schedule{}}
+	deploymentRepo := &mockScheduleDeploymentRepository{existing: []string{"b@microsoft.com"}}
+
+	svc := NewScheduleService(scheduleRepo, deploymentRepo, &mockScheduleDeploymentService{}, nil, &mockScheduleServerService{}, &mockEventService{}, nil)
+
+	if err := svc.RunSchedules(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(deploymentRepo.destroyed) != 1 || deploymentRepo.destroyed[0] != "b@microsoft.com" {
+		t.Errorf("expected only the pending destroy to be retried, got %v", deploymentRepo.destroyed)
+	}
+	if got := scheduleRepo.saved["end"].Status; got != entity.ScheduleCompleted {
+		t.Errorf("expected the schedule to complete, got %s", got)
+	}
+}
+
+type mockScheduleLabService struct {
+	entity.LabService
+	lab entity.LabType
+}
+
+func (m *mockScheduleLabService) GetLabByIdAndType(ctx context.Context, typeOfLab string, labId string) (entity.LabType, error) {
+	if labId != m.lab.Id || typeOfLab != m.lab.Type {
+		return entity.LabType{}, entity.ErrLabNotFound
+	}
+	return m.lab, nil
+}
+
+func TestCreateScheduleUsesSavedLab(t *testing.T) {
+	saved := entity.LabType{Id: "lab-1", Type: "readinesslab", Name: "Saved", ExtendScript: "saved-script"}
+	scheduleRepo := &mockScheduleRepository{saved: map[string]entity.Schedule{}}
+
+	svc := NewScheduleService(scheduleRepo, nil, nil, &mockScheduleLabService{lab: saved}, nil, &mockEventService{}, nil)
+
+	schedule, err := svc.CreateSchedule(context.Background(), entity.Schedule{
+		UserIds:   []string{"user@microsoft.com"},
+		Workspace: "default",
+		Lab:       entity.LabType{Id: "lab-1", Type: "readinesslab", ExtendScript: "injected"},
+		StartTime: time.Now().Add(time.Hour).Format(time.RFC3339),
+		EndTime:   time.Now().Add(2 * time.Hour).Format(time.RFC3339),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if schedule.Lab.ExtendScript != "saved-script" || scheduleRepo.saved[schedule.Id].Lab.ExtendScript != "saved-script" {
+		t.Errorf("expected the saved lab to be scheduled, got %+v", schedule.Lab)
+	}
+}
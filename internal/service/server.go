@@ -2,14 +2,17 @@ package service
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"slices"
+	"sort"
 	"strings"
 	"time"
 
 	"actlabs-hub/internal/config"
 	"actlabs-hub/internal/entity"
+	"actlabs-hub/internal/health"
 	"actlabs-hub/internal/helper"
+	"actlabs-hub/internal/identity"
 	"actlabs-hub/internal/logger"
 )
 
@@ -17,32 +20,54 @@ type serverService struct {
 	serverRepository entity.ServerRepository
 	appConfig        *config.Config
 	eventService     entity.EventService
+	directory        *identity.Directory
+	// deploymentRepository is only read, to join deployments in the summary.
+	deploymentRepository entity.DeploymentRepository
+	// authorizationChecker is the repository itself, except locally where
+	// there's no access to role assignments.
+	authorizationChecker entity.ServerAuthorizationChecker
 }
 
 func NewServerService(
 	serverRepository entity.ServerRepository,
 	appConfig *config.Config,
 	eventService entity.EventService,
+	directory *identity.Directory,
+	authorizationChecker entity.ServerAuthorizationChecker,
+	deploymentRepository entity.DeploymentRepository,
 ) entity.ServerService {
 	return &serverService{
-		serverRepository: serverRepository,
-		appConfig:        appConfig,
-		eventService:     eventService,
+		serverRepository:     serverRepository,
+		appConfig:            appConfig,
+		eventService:         eventService,
+		directory:            directory,
+		authorizationChecker: authorizationChecker,
+		deploymentRepository: deploymentRepository,
 	}
 }
 
 func (s *serverService) RegisterSubscription(ctx context.Context, server entity.Server) error {
-	server.UserPrincipalName = server.UserAlias + "@microsoft.com"
+	userPrincipalName, tenant, err := s.directory.ResolveUser(server.UserAlias, server.TenantID)
+	if err != nil {
+		logger.LogError(ctx, "user is not allowed to register a server",
+			"user_alias", server.UserAlias,
+			"tenant_id", server.TenantID,
+			"error", err,
+		)
+		return err
+	}
+
+	server.UserPrincipalName = userPrincipalName
+	server.UserAlias = strings.Split(userPrincipalName, "@")[0]
 	server.PartitionKey = "actlabs"
 	server.RowKey = server.UserPrincipalName
-	server.Version = "V2"
+	server.Version = tenant.ServerVersion
 	server.Region = "West Central US"
 
-	// if tenant is fdpo, set version to V3.
+	// V3 servers (FDPO) use the FDPO principal.
 	// set FdpoUserPrincipalId to userPrincipalId.
 	// set UserPrincipalId to empty.
-	if server.TenantID == s.appConfig.FdpoTenantID {
-		server.Version = "V3"
+	if server.Version == "V3" {
 		server.FdpoUserPrincipalId = server.UserPrincipalId
 		server.UserPrincipalId = ""
 	}
@@ -76,7 +101,7 @@ func (s *serverService) Unregister(ctx context.Context, userPrincipalName string
 			"error", err,
 		)
 
-		return fmt.Errorf("resources were destroyed, but got error deleting server from db")
+		return entity.NewStorageError("resources were destroyed, but got error deleting server from db", err)
 	}
 
 	return nil
@@ -101,13 +126,13 @@ func (s *serverService) GetServer(ctx context.Context, userPrincipalName string)
 			"error", err,
 		)
 
-		if strings.Contains(err.Error(), "404 Not Found") {
+		if isNotFound(err) {
 			s.ServerDefaults(&server)
 			server.Status = entity.ServerStatusUnregistered
 			return server, nil
 		}
 
-		return server, errors.New("not able to get server status from database")
+		return server, entity.NewStorageError("not able to get server status from database", err)
 	}
 
 	// update endpoint to accommodate new changes.
@@ -119,7 +144,7 @@ func (s *serverService) GetServer(ctx context.Context, userPrincipalName string)
 func (s *serverService) UpdateActivityStatus(ctx context.Context, userPrincipalName string) error {
 	server, err := s.GetServerFromDatabase(ctx, userPrincipalName)
 	if err != nil {
-		return errors.New("error getting server from database")
+		return err
 	}
 
 	server.LastUserActivityTime = time.Now().Format(time.RFC3339)
@@ -137,10 +162,13 @@ func (s *serverService) GetServerFromDatabase(ctx context.Context, userPrincipal
 		logger.LogError(ctx, "failed to get server from database",
 			"error", err,
 		)
-		return server, fmt.Errorf(
-			"not able to find server for %s in database, is it registered?",
-			userPrincipalName,
-		)
+		if isNotFound(err) {
+			return server, entity.NewNotFoundError(
+				entity.ErrServerNotRegistered.Code,
+				fmt.Sprintf("not able to find server for %s in database, is it registered?", userPrincipalName),
+			)
+		}
+		return server, entity.NewStorageError("not able to get server from database", err)
 	}
 
 	return server, nil
@@ -152,12 +180,24 @@ func (s *serverService) GetAllServers(ctx context.Context) ([]entity.Server, err
 		logger.LogError(ctx, "failed to get all servers from database",
 			"error", err,
 		)
-		return servers, fmt.Errorf("not able to find servers in database")
+		return servers, entity.NewStorageError("not able to find servers in database", err)
 	}
 
 	return servers, nil
 }
 
+func (s *serverService) ListServers(ctx context.Context, query entity.ListQuery) (entity.Page[entity.Server], error) {
+	page, err := s.serverRepository.ListServersFromDatabase(ctx, query)
+	if err != nil {
+		logger.LogError(ctx, "failed to list servers from database",
+			"error", err,
+		)
+		return page, listError(err, "not able to find servers in database")
+	}
+
+	return page, nil
+}
+
 func (s *serverService) UpsertServerInDatabase(ctx context.Context, server entity.Server) error {
 	// Update server in database.
 	if err := s.serverRepository.UpsertServerInDatabase(ctx, server); err != nil {
@@ -181,7 +221,8 @@ func (s *serverService) Validate(ctx context.Context, server entity.Server) erro
 			"fdpo_user_principal_id", server.FdpoUserPrincipalId,
 			"subscription_id", server.SubscriptionId,
 		)
-		return errors.New(
+		return entity.NewValidationError(
+			"invalid_server",
 			"userPrincipalName, userPrincipalId or FdpoUserPrincipalId, and subscriptionId are all required",
 		)
 	}
@@ -195,21 +236,21 @@ func (s *serverService) Validate(ctx context.Context, server entity.Server) erro
 		return nil
 	}
 
-	ok, err := s.serverRepository.IsUserAuthorized(ctx, server)
+	ok, err := s.authorizationChecker.IsUserAuthorized(ctx, server)
 	if err != nil {
 		logger.LogError(ctx, "failed to verify user authorization for subscription",
 			"subscription_id", server.SubscriptionId,
 			"server_version", server.Version,
 			"error", err,
 		)
-		return errors.New("failed to verify if user is the owner or contributor of subscription")
+		return entity.NewUpstreamError("authorization_check_failed", "failed to verify if user is the owner or contributor of subscription", err)
 	}
 	if !ok {
 		logger.LogError(ctx, "user is not the owner or contributor of subscription",
 			"subscription_id", server.SubscriptionId,
 			"server_version", server.Version,
 		)
-		return errors.New("insufficient permissions")
+		return entity.NewForbiddenError("insufficient_permissions", "insufficient permissions")
 	}
 
 	return nil
@@ -246,3 +287,336 @@ func (s *serverService) ServerDefaults(server *entity.Server) {
 
 	server.Endpoint = s.appConfig.ActlabsServerEndpointExternal
 }
+
+func (s *serverService) MonitorServerAuthorization(ctx context.Context) {
+	interval := time.Duration(s.appConfig.ActlabsHubServerVerificationIntervalSeconds) * time.Second
+	health.ExpectHeartbeat("MonitorServerAuthorization", interval)
+
+	helper.Recoverer(ctx, 100, "MonitorServerAuthorization", func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				// Context was cancelled or the application finished, so stop the goroutine
+				return
+			case <-ticker.C:
+				health.Beat("MonitorServerAuthorization")
+
+				if _, err := s.VerifyServers(ctx); err != nil {
+					logger.LogError(ctx, "failed to verify servers",
+						"error", err,
+					)
+				}
+			}
+		}
+	})
+}
+
+func (s *serverService) VerifyServers(ctx context.Context) ([]entity.ServerVerificationResult, error) {
+	servers, err := s.serverRepository.GetAllServersFromDatabase(ctx)
+	if err != nil {
+		logger.LogError(ctx, "failed to get all servers for verification",
+			"error", err,
+		)
+		return nil, err
+	}
+
+	results := []entity.ServerVerificationResult{}
+	for _, server := range servers {
+		result := s.verifyServer(ctx, server)
+
+		if err := s.serverRepository.SaveServerVerificationResult(ctx, result); err != nil {
+			logger.LogError(ctx, "failed to save server verification result",
+				"subscription_id", server.SubscriptionId,
+				"error", err,
+			)
+		}
+
+		results = append(results, result)
+	}
+
+	logger.LogInfo(ctx, "verified servers",
+		"server_count", len(results),
+	)
+
+	return results, nil
+}
+
+// verifyServer checks a single server and suspends or restores it. Errors
+// from the checker are recorded but don't change the server; they are more
+// likely to be transient than a real loss of access.
+func (s *serverService) verifyServer(ctx context.Context, server entity.Server) entity.ServerVerificationResult {
+	ctx = logger.WithUserID(ctx, server.UserPrincipalName)
+
+	result := verificationResult(server)
+
+	authorized, err := s.authorizationChecker.IsUserAuthorized(ctx, server)
+	if err != nil {
+		logger.LogError(ctx, "failed to verify user authorization for subscription",
+			"subscription_id", server.SubscriptionId,
+			"server_version", server.Version,
+			"error", err,
+		)
+		result.Error = err.Error()
+		return result
+	}
+	result.Authorized = authorized
+
+	// Still suspended, or nothing to restore.
+	if authorized != server.Suspended {
+		return result
+	}
+
+	// The server may have changed since it was listed, e.g. it was deployed
+	// or another instance already verified it.
+	server, err = s.serverRepository.GetServerFromDatabase(ctx, server.PartitionKey, server.RowKey)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if authorized != server.Suspended {
+		result = verificationResult(server)
+		result.Authorized = authorized
+		return result
+	}
+
+	if !authorized {
+		server.Suspended = true
+		server.SuspendedFromStatus = server.Status
+		server.Status = entity.ServerStatusUnknown
+	} else {
+		// Access is back, put the server back to where it was unless its
+		// status changed while it was suspended.
+		if server.Status == entity.ServerStatusUnknown && server.SuspendedFromStatus != "" {
+			server.Status = server.SuspendedFromStatus
+		}
+		server.Suspended = false
+		server.SuspendedFromStatus = ""
+	}
+
+	if err := s.UpsertServerInDatabase(ctx, server); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	if !authorized {
+		logger.LogWarning(ctx, "user lost access to subscription, server suspended",
+			"subscription_id", server.SubscriptionId,
+			"server_version", server.Version,
+		)
+		s.createServerEvent(ctx, server, "Warning", "ServerAuthorizationLost",
+			"user no longer has the required role on subscription "+server.SubscriptionId+", server suspended")
+	} else {
+		logger.LogInfo(ctx, "user regained access to subscription, server restored",
+			"subscription_id", server.SubscriptionId,
+			"server_status", server.Status,
+		)
+		s.createServerEvent(ctx, server, "Normal", "ServerAuthorizationRestored",
+			"user has the required role on subscription "+server.SubscriptionId+" again, server restored")
+	}
+
+	result.Suspended = server.Suspended
+	result.Status = server.Status
+	return result
+}
+
+// verificationResult describes the server as it is, before it's verified.
+// PreviousStatus is the status it had before it was suspended, if it is.
+func verificationResult(server entity.Server) entity.ServerVerificationResult {
+	result := entity.ServerVerificationResult{
+		UserPrincipalName: server.UserPrincipalName,
+		SubscriptionId:    server.SubscriptionId,
+		PreviousStatus:    server.Status,
+		Status:            server.Status,
+		Suspended:         server.Suspended,
+		CheckedAt:         time.Now().Format(time.RFC3339),
+	}
+	if server.Suspended {
+		result.PreviousStatus = server.SuspendedFromStatus
+	}
+	return result
+}
+
+func (s *serverService) GetServersHealth(ctx context.Context) ([]entity.ServerHealth, error) {
+	servers, err := s.GetAllServers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := s.serverRepository.GetServerVerificationResults(ctx)
+	if err != nil {
+		logger.LogError(ctx, "failed to get server verification results",
+			"error", err,
+		)
+		return nil, entity.NewStorageError("not able to get server verification results", err)
+	}
+
+	health := []entity.ServerHealth{}
+	for _, server := range servers {
+		serverHealth := entity.ServerHealth{
+			UserPrincipalName: server.UserPrincipalName,
+			SubscriptionId:    server.SubscriptionId,
+			TenantID:          server.TenantID,
+			Version:           server.Version,
+			Status:            server.Status,
+		}
+		if result, ok := results[server.UserPrincipalName]; ok {
+			serverHealth.LastVerification = &result
+		}
+		health = append(health, serverHealth)
+	}
+
+	return health, nil
+}
+
+// transitionalServerStatuses are the statuses a server should only pass
+// through. A server staying in one of them is likely stuck.
+var transitionalServerStatuses = []entity.ServerStatus{
+	entity.ServerStatusDeploying,
+	entity.ServerStatusStopping,
+	entity.ServerStatusUpdating,
+}
+
+func (s *serverService) GetServersSummary(ctx context.Context, options entity.ServerSummaryOptions) (entity.ServerSummary, error) {
+	summary := entity.ServerSummary{
+		ByStatus:     map[entity.ServerStatus]int{},
+		ByVersion:    map[string]int{},
+		ByRegion:     map[string]int{},
+		ByTenant:     map[string]int{},
+		IdleServers:  []entity.ServerSummaryEntry{},
+		StuckServers: []entity.ServerSummaryEntry{},
+		TopUsers:     []entity.UserDeploymentCount{},
+	}
+
+	servers, err := s.GetAllServers(ctx)
+	if err != nil {
+		return summary, err
+	}
+
+	deployments, err := s.deploymentRepository.GetAllDeployments(ctx)
+	if err != nil {
+		logger.LogError(ctx, "failed to get all deployments for server summary",
+			"error", err,
+		)
+		return summary, entity.NewStorageError("not able to get deployments", err)
+	}
+
+	now := time.Now()
+	serversByUser := map[string]entity.Server{}
+
+	for _, server := range servers {
+		serversByUser[server.UserPrincipalName] = server
+
+		summary.TotalServers++
+		summary.ByStatus[server.Status]++
+		summary.ByVersion[server.Version]++
+		summary.ByRegion[server.Region]++
+		summary.ByTenant[server.TenantID]++
+
+		// Only running servers cost money while idle.
+		if server.Status == entity.ServerStatusRunning {
+			if since, ok := summarySince(server.LastUserActivityTime); ok && now.Sub(since) > options.IdleThreshold {
+				summary.IdleServers = append(summary.IdleServers, newServerSummaryEntry(server, since, now))
+			}
+		}
+
+		if slices.Contains(transitionalServerStatuses, server.Status) {
+			if since, ok := summarySince(server.LastModifiedTime); ok && now.Sub(since) > options.StuckThreshold {
+				summary.StuckServers = append(summary.StuckServers, newServerSummaryEntry(server, since, now))
+			}
+		}
+	}
+
+	deploymentCounts := map[string]int{}
+	deploymentCosts := map[string]float64{}
+	for _, deployment := range deployments {
+		deploymentCounts[deployment.DeploymentUserId]++
+
+		// Only deployed labs have resources that cost money.
+		if slices.Contains(deployedStatuses, deployment.DeploymentStatus) {
+			deploymentCosts[deployment.DeploymentUserId] += deployment.DeploymentEstimatedHourlyCost
+			summary.EstimatedHourlyCost += deployment.DeploymentEstimatedHourlyCost
+		}
+	}
+
+	for userPrincipalName, count := range deploymentCounts {
+		server := serversByUser[userPrincipalName]
+		summary.TopUsers = append(summary.TopUsers, entity.UserDeploymentCount{
+			UserPrincipalName:   userPrincipalName,
+			Deployments:         count,
+			ServerStatus:        server.Status,
+			ServerRegion:        server.Region,
+			EstimatedHourlyCost: roundCost(deploymentCosts[userPrincipalName]),
+		})
+	}
+	summary.EstimatedHourlyCost = roundCost(summary.EstimatedHourlyCost)
+
+	sort.Slice(summary.TopUsers, func(i, j int) bool {
+		if summary.TopUsers[i].Deployments != summary.TopUsers[j].Deployments {
+			return summary.TopUsers[i].Deployments > summary.TopUsers[j].Deployments
+		}
+		return summary.TopUsers[i].UserPrincipalName < summary.TopUsers[j].UserPrincipalName
+	})
+	if options.TopUsers > 0 && len(summary.TopUsers) > options.TopUsers {
+		summary.TopUsers = summary.TopUsers[:options.TopUsers]
+	}
+
+	// Longest first, these are the ones to look at.
+	sort.Slice(summary.IdleServers, func(i, j int) bool {
+		return summary.IdleServers[i].DurationMinutes > summary.IdleServers[j].DurationMinutes
+	})
+	sort.Slice(summary.StuckServers, func(i, j int) bool {
+		return summary.StuckServers[i].DurationMinutes > summary.StuckServers[j].DurationMinutes
+	})
+
+	return summary, nil
+}
+
+// deployedStatuses are the statuses in which a deployment's resources exist.
+var deployedStatuses = []entity.DeploymentStatus{
+	entity.DeploymentInProgress,
+	entity.DeploymentCompleted,
+}
+
+// summarySince parses an RFC3339 time stored on a server. Servers that never
+// had the time set are skipped rather than reported as idle or stuck forever.
+func summarySince(value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	since, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return since, true
+}
+
+func newServerSummaryEntry(server entity.Server, since time.Time, now time.Time) entity.ServerSummaryEntry {
+	return entity.ServerSummaryEntry{
+		UserPrincipalName: server.UserPrincipalName,
+		SubscriptionId:    server.SubscriptionId,
+		Status:            server.Status,
+		Region:            server.Region,
+		Version:           server.Version,
+		Since:             since.Format(time.RFC3339),
+		DurationMinutes:   int64(now.Sub(since).Minutes()),
+	}
+}
+
+func (s *serverService) createServerEvent(ctx context.Context, server entity.Server, eventType string, reason string, message string) {
+	if err := s.eventService.CreateEvent(ctx, entity.Event{
+		Type:      eventType,
+		Reason:    reason,
+		Message:   message,
+		Reporter:  "actlabs-hub",
+		Object:    server.UserPrincipalName,
+		TimeStamp: time.Now().Format(time.RFC3339),
+	}); err != nil {
+		logger.LogError(ctx, "failed to create server event",
+			"reason", reason,
+			"error", err,
+		)
+	}
+}
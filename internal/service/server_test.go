@@ -0,0 +1,72 @@
+package service
+
+import (
+	"actlabs-hub/internal/entity"
+	"context"
+	"testing"
+)
+
+type mockVerifyServerRepository struct {
+	entity.ServerRepository
+	server entity.Server
+}
+
+func (m *mockVerifyServerRepository) GetServerFromDatabase(ctx context.Context, partitionKey string, rowKey string) (entity.Server, error) {
+	return m.server, nil
+}
+
+func (m *mockVerifyServerRepository) UpsertServerInDatabase(ctx context.Context, server entity.Server) error {
+	m.server = server
+	return nil
+}
+
+type mockAuthorizationChecker struct {
+	authorized bool
+}
+
+func (m *mockAuthorizationChecker) IsUserAuthorized(ctx context.Context, server entity.Server) (bool, error) {
+	return m.authorized, nil
+}
+
+func TestVerifyServerSuspendsAndRestores(t *testing.T) {
+	server := entity.Server{PartitionKey: "actlabs", RowKey: "user@contoso.com", UserPrincipalName: "user@contoso.com", Status: entity.ServerStatusRunning}
+	repo := &mockVerifyServerRepository{server: server}
+	checker := &mockAuthorizationChecker{}
+	events := &mockEventService{}
+	svc := &serverService{serverRepository: repo, eventService: events, authorizationChecker: checker}
+
+	result := svc.verifyServer(context.Background(), server)
+	if !result.Suspended || !repo.server.Suspended || repo.server.Status != entity.ServerStatusUnknown || repo.server.SuspendedFromStatus != entity.ServerStatusRunning {
+		t.Fatalf("expected the server to be suspended, got %+v and %+v", result, repo.server)
+	}
+
+	// Access comes back. The status to restore is read from the server, not
+	// from anything kept outside it.
+	checker.authorized = true
+	result = svc.verifyServer(context.Background(), repo.server)
+	if result.Suspended || repo.server.Suspended || repo.server.Status != entity.ServerStatusRunning {
+		t.Fatalf("expected the server to be restored to Running, got %+v and %+v", result, repo.server)
+	}
+
+	if len(events.reasons) != 2 || events.reasons[0] != "ServerAuthorizationLost" || events.reasons[1] != "ServerAuthorizationRestored" {
+		t.Errorf("expected lost and restored events, got %v", events.reasons)
+	}
+}
+
+func TestVerifyServerRereadsBeforeSuspending(t *testing.T) {
+	listed := entity.Server{PartitionKey: "actlabs", RowKey: "user@contoso.com", UserPrincipalName: "user@contoso.com", Status: entity.ServerStatusRunning}
+
+	// Another instance suspended it after it was listed.
+	current := listed
+	current.Status = entity.ServerStatusUnknown
+	current.Suspended = true
+	current.SuspendedFromStatus = entity.ServerStatusRunning
+	repo := &mockVerifyServerRepository{server: current}
+	events := &mockEventService{}
+	svc := &serverService{serverRepository: repo, eventService: events, authorizationChecker: &mockAuthorizationChecker{}}
+
+	result := svc.verifyServer(context.Background(), listed)
+	if !result.Suspended || repo.server != current || len(events.reasons) != 0 {
+		t.Errorf("expected the suspended server to be left alone, got %+v, %+v and %v", result, repo.server, events.reasons)
+	}
+}
@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"actlabs-hub/internal/config"
+	"actlabs-hub/internal/entity"
+	"actlabs-hub/internal/logger"
+)
+
+// watchRetryInterval is how long to wait before watching for settings
+// changes again after losing the connection to Redis.
+const watchRetryInterval = 5 * time.Second
+
+type settingsSubscriber struct {
+	name  string
+	apply func(ctx context.Context, settings entity.RuntimeSettings)
+}
+
+type settingsService struct {
+	settingsRepository entity.SettingsRepository
+	eventService       entity.EventService
+	defaults           entity.RuntimeSettings
+
+	mu          sync.Mutex
+	current     entity.RuntimeSettings
+	subscribers []settingsSubscriber
+}
+
+func NewSettingsService(
+	settingsRepository entity.SettingsRepository,
+	eventService entity.EventService,
+	appConfig *config.Config,
+) entity.SettingsService {
+	// Until an admin changes them, the settings are what the hub started with.
+	defaults := entity.RuntimeSettings{
+		DeploymentsPollingIntervalSeconds: appConfig.ActlabsHubDeploymentsPollingIntervalSeconds,
+		CorsAllowOrigins:                  strings.Split(appConfig.CorsAllowOrigins, ","),
+		LogLevel:                          appConfig.ActlabsHubLogLevel,
+		RateLimit: entity.RateLimitSettings{
+			WindowSeconds:     appConfig.ActlabsHubRateLimitWindowSeconds,
+			MaxRequests:       appConfig.ActlabsHubRateLimitMaxRequests,
+			APIKeyMaxRequests: appConfig.ActlabsHubAPIKeyRateLimitMaxRequests,
+		},
+	}
+
+	return &settingsService{
+		settingsRepository: settingsRepository,
+		eventService:       eventService,
+		defaults:           defaults,
+		current:            defaults,
+	}
+}
+
+func (s *settingsService) GetSettings(ctx context.Context) (entity.RuntimeSettings, error) {
+	settings, ok, err := s.settingsRepository.GetSettings(ctx)
+	if err != nil {
+		return entity.RuntimeSettings{}, entity.NewStorageError("not able to get settings", err)
+	}
+	if !ok {
+		return s.defaults, nil
+	}
+
+	return settings, nil
+}
+
+func (s *settingsService) UpdateSettings(ctx context.Context, settings entity.RuntimeSettings) (entity.RuntimeSettings, error) {
+	if err := validateSettings(settings); err != nil {
+		return entity.RuntimeSettings{}, err
+	}
+
+	previous, err := s.GetSettings(ctx)
+	if err != nil {
+		return entity.RuntimeSettings{}, err
+	}
+
+	if err := s.settingsRepository.UpsertSettings(ctx, settings); err != nil {
+		return entity.RuntimeSettings{}, entity.NewStorageError("not able to save settings", err)
+	}
+
+	// Other instances pick the change up from WatchSettings.
+	s.apply(ctx, settings)
+
+	changes := settingsChanges(previous, settings)
+	if len(changes) == 0 {
+		return settings, nil
+	}
+
+	if err := s.eventService.CreateEvent(ctx, entity.Event{
+		TimeStamp: time.Now().Format(time.RFC3339),
+		Type:      "Normal",
+		Reason:    "RuntimeSettingsUpdated",
+		Message:   fmt.Sprintf("Runtime settings changed: %s.", strings.Join(changes, ", ")),
+		Reporter:  "actlabs-hub",
+		Object:    logger.GetUserID(ctx),
+	}); err != nil {
+		logger.LogError(ctx, "failed to create settings updated event",
+			"error", err,
+		)
+	}
+
+	return settings, nil
+}
+
+func (s *settingsService) LoadSettings(ctx context.Context) error {
+	settings, err := s.GetSettings(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.apply(ctx, settings)
+	return nil
+}
+
+func (s *settingsService) Subscribe(ctx context.Context, name string, apply func(ctx context.Context, settings entity.RuntimeSettings)) {
+	s.mu.Lock()
+	s.subscribers = append(s.subscribers, settingsSubscriber{name: name, apply: apply})
+	current := s.current
+	s.mu.Unlock()
+
+	apply(ctx, current)
+}
+
+func (s *settingsService) WatchSettings(ctx context.Context) {
+	for {
+		// Changes saved while not subscribed were missed, so the saved
+		// settings are read again on every subscribe.
+		err := s.settingsRepository.WatchSettings(ctx, func() {
+			if err := s.LoadSettings(ctx); err != nil {
+				logger.LogError(ctx, "failed to load runtime settings after subscribing",
+					"error", err,
+				)
+			}
+		}, func(settings entity.RuntimeSettings) {
+			s.apply(ctx, settings)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+
+		logger.LogError(ctx, "stopped watching runtime settings, retrying",
+			"error", err,
+		)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(watchRetryInterval):
+		}
+	}
+}
+
+// apply hands settings to every subscriber, unless they're already applied.
+func (s *settingsService) apply(ctx context.Context, settings entity.RuntimeSettings) {
+	s.mu.Lock()
+	if reflect.DeepEqual(s.current, settings) {
+		s.mu.Unlock()
+		return
+	}
+	s.current = settings
+	subscribers := slices.Clone(s.subscribers)
+	s.mu.Unlock()
+
+	for _, subscriber := range subscribers {
+		logger.LogInfo(ctx, "applying runtime settings",
+			"subscriber", subscriber.name,
+		)
+		subscriber.apply(ctx, settings)
+	}
+}
+
+// validateSettings reports every invalid setting at once.
+func validateSettings(settings entity.RuntimeSettings) error {
+	problems := []string{}
+
+	if settings.DeploymentsPollingIntervalSeconds <= 0 {
+		problems = append(problems, "deploymentsPollingIntervalSeconds must be greater than 0")
+	}
+
+	if len(settings.CorsAllowOrigins) == 0 {
+		problems = append(problems, "corsAllowOrigins must list at least one origin")
+	}
+	for _, origin := range settings.CorsAllowOrigins {
+		if origin != "*" && !strings.HasPrefix(origin, "http://") && !strings.HasPrefix(origin, "https://") {
+			problems = append(problems, fmt.Sprintf("corsAllowOrigins entry %q must be * or start with http:// or https://", origin))
+		}
+	}
+
+	if _, err := logger.ParseLevel(settings.LogLevel); err != nil {
+		problems = append(problems, fmt.Sprintf("logLevel %q must be DEBUG, INFO, WARN or ERROR", settings.LogLevel))
+	}
+
+	if settings.RateLimit.WindowSeconds <= 0 || settings.RateLimit.MaxRequests <= 0 || settings.RateLimit.APIKeyMaxRequests <= 0 {
+		problems = append(problems, "rateLimit windowSeconds, maxRequests and apiKeyMaxRequests must be greater than 0")
+	}
+
+	if len(problems) > 0 {
+		return entity.NewValidationError("invalid_settings", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// settingsChanges describes each setting that differs between previous and
+// next.
+func settingsChanges(previous entity.RuntimeSettings, next entity.RuntimeSettings) []string {
+	changes := []string{}
+
+	if previous.DeploymentsPollingIntervalSeconds != next.DeploymentsPollingIntervalSeconds {
+		changes = append(changes, fmt.Sprintf("deploymentsPollingIntervalSeconds from %d to %d", previous.DeploymentsPollingIntervalSeconds, next.DeploymentsPollingIntervalSeconds))
+	}
+	if !slices.Equal(previous.CorsAllowOrigins, next.CorsAllowOrigins) {
+		changes = append(changes, fmt.Sprintf("corsAllowOrigins from %v to %v", previous.CorsAllowOrigins, next.CorsAllowOrigins))
+	}
+	if previous.LogLevel != next.LogLevel {
+		changes = append(changes, fmt.Sprintf("logLevel from %s to %s", previous.LogLevel, next.LogLevel))
+	}
+	if previous.RateLimit != next.RateLimit {
+		changes = append(changes, fmt.Sprintf("rateLimit from %+v to %+v", previous.RateLimit, next.RateLimit))
+	}
+
+	return changes
+}
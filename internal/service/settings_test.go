@@ -0,0 +1,165 @@
+package service
+
+import (
+	"actlabs-hub/internal/config"
+	"actlabs-hub/internal/entity"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type mockSettingsRepository struct {
+	entity.SettingsRepository
+	saved *entity.RuntimeSettings
+}
+
+func (m *mockSettingsRepository) GetSettings(ctx context.Context) (entity.RuntimeSettings, bool, error) {
+	if m.saved == nil {
+		return entity.RuntimeSettings{}, false, nil
+	}
+	return *m.saved, true, nil
+}
+
+func (m *mockSettingsRepository) UpsertSettings(ctx context.Context, settings entity.RuntimeSettings) error {
+	m.saved = &settings
+	return nil
+}
+
+func newTestSettingsService(repository entity.SettingsRepository, events entity.EventService) entity.SettingsService {
+	return NewSettingsService(repository, events, &config.Config{
+		ActlabsHubDeploymentsPollingIntervalSeconds: 30,
+		CorsAllowOrigins:                     "http://localhost:3000",
+		ActlabsHubLogLevel:                   "INFO",
+		ActlabsHubRateLimitWindowSeconds:     60,
+		ActlabsHubRateLimitMaxRequests:       100,
+		ActlabsHubAPIKeyRateLimitMaxRequests: 200,
+	})
+}
+
+func TestUpdateSettingsAppliesAndRecordsChange(t *testing.T) {
+	repository := &mockSettingsRepository{}
+	events := &mockEventService{}
+	settingsService := newTestSettingsService(repository, events)
+
+	applied := []entity.RuntimeSettings{}
+	settingsService.Subscribe(context.Background(), "test", func(ctx context.Context, settings entity.RuntimeSettings) {
+		applied = append(applied, settings)
+	})
+
+	settings, err := settingsService.GetSettings(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings.LogLevel = "DEBUG"
+
+	if _, err := settingsService.UpdateSettings(context.Background(), settings); err != nil {
+		t.Fatal(err)
+	}
+
+	if repository.saved == nil || repository.saved.LogLevel != "DEBUG" {
+		t.Errorf("expected the settings to be saved, got %v", repository.saved)
+	}
+	if len(applied) != 2 || applied[0].LogLevel != "INFO" || applied[1].LogLevel != "DEBUG" {
+		t.Errorf("expected the subscriber to get the defaults then the change, got %v", applied)
+	}
+	if len(events.reasons) != 1 || events.reasons[0] != "RuntimeSettingsUpdated" {
+		t.Errorf("expected one RuntimeSettingsUpdated event, got %v", events.reasons)
+	}
+
+	// Saving the same settings again changes nothing.
+	if _, err := settingsService.UpdateSettings(context.Background(), settings); err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 2 || len(events.reasons) != 1 {
+		t.Errorf("expected no more applies or events, got %d applies and %v", len(applied), events.reasons)
+	}
+}
+
+func TestUpdateSettingsReportsEveryInvalidSetting(t *testing.T) {
+	repository := &mockSettingsRepository{}
+	settingsService := newTestSettingsService(repository, &mockEventService{})
+
+	_, err := settingsService.UpdateSettings(context.Background(), entity.RuntimeSettings{
+		CorsAllowOrigins: []string{"localhost:3000"},
+		LogLevel:         "LOUD",
+	})
+
+	var entityErr *entity.Error
+	if !errors.As(err, &entityErr) || entityErr.Kind != entity.ErrorKindValidation {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	for _, want := range []string{"deploymentsPollingIntervalSeconds", "localhost:3000", "logLevel", "rateLimit"} {
+		if !strings.Contains(entityErr.Message, want) {
+			t.Errorf("expected %q in %q", want, entityErr.Message)
+		}
+	}
+	if repository.saved != nil {
+		t.Errorf("expected invalid settings not to be saved")
+	}
+}
+
+func TestLoadSettingsAppliesSavedSettings(t *testing.T) {
+	saved := entity.RuntimeSettings{
+		DeploymentsPollingIntervalSeconds: 120,
+		CorsAllowOrigins:                  []string{"https://actlabs.example.com"},
+		LogLevel:                          "WARN",
+		RateLimit:                         entity.RateLimitSettings{WindowSeconds: 30, MaxRequests: 10, APIKeyMaxRequests: 20},
+	}
+	settingsService := newTestSettingsService(&mockSettingsRepository{saved: &saved}, &mockEventService{})
+
+	if err := settingsService.LoadSettings(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var applied entity.RuntimeSettings
+	settingsService.Subscribe(context.Background(), "test", func(ctx context.Context, settings entity.RuntimeSettings) {
+		applied = settings
+	})
+
+	if applied.LogLevel != "WARN" || applied.DeploymentsPollingIntervalSeconds != 120 {
+		t.Errorf("expected the subscriber to start with the saved settings, got %+v", applied)
+	}
+}
+
+type mockWatchSettingsRepository struct {
+	mockSettingsRepository
+	cancel context.CancelFunc
+}
+
+// WatchSettings saves a change as if another instance made it while not
+// subscribed, then subscribes.
+func (m *mockWatchSettingsRepository) WatchSettings(ctx context.Context, subscribed func(), changed func(settings entity.RuntimeSettings)) error {
+	saved := *m.saved
+	saved.LogLevel = "ERROR"
+	m.saved = &saved
+
+	subscribed()
+	m.cancel()
+	return nil
+}
+
+func TestWatchSettingsReloadsOnSubscribe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	saved := entity.RuntimeSettings{
+		DeploymentsPollingIntervalSeconds: 30,
+		CorsAllowOrigins:                  []string{"http://localhost:3000"},
+		LogLevel:                          "INFO",
+		RateLimit:                         entity.RateLimitSettings{WindowSeconds: 60, MaxRequests: 100, APIKeyMaxRequests: 200},
+	}
+	repository := &mockWatchSettingsRepository{mockSettingsRepository: mockSettingsRepository{saved: &saved}, cancel: cancel}
+	settingsService := newTestSettingsService(repository, &mockEventService{})
+
+	var applied entity.RuntimeSettings
+	settingsService.Subscribe(ctx, "test", func(ctx context.Context, settings entity.RuntimeSettings) {
+		applied = settings
+	})
+
+	settingsService.WatchSettings(ctx)
+
+	if applied.LogLevel != "ERROR" {
+		t.Errorf("expected the change missed while not subscribed to be applied, got %q", applied.LogLevel)
+	}
+}
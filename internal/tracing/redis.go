@@ -0,0 +1,58 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RedisHook starts a span for every Redis command and pipeline. Keys aren't
+// recorded, they carry user IDs.
+type RedisHook struct{}
+
+func (RedisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (RedisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		ctx, span := Start(ctx, "redis "+strings.ToUpper(cmd.Name()),
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemRedis,
+				semconv.DBOperationName(cmd.Name()),
+			),
+		)
+
+		err := next(ctx, cmd)
+		if err == redis.Nil {
+			// A cache miss, not a failure.
+			End(span, nil)
+			return err
+		}
+
+		End(span, err)
+		return err
+	}
+}
+
+func (RedisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		ctx, span := Start(ctx, "redis pipeline",
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.DBSystemRedis),
+		)
+
+		err := next(ctx, cmds)
+		if err == redis.Nil {
+			End(span, nil)
+			return err
+		}
+
+		End(span, err)
+		return err
+	}
+}
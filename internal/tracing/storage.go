@@ -0,0 +1,51 @@
+package tracing
+
+import (
+	"fmt"
+	"net/http"
+
+	"actlabs-hub/internal/helper"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// StoragePolicy returns an Azure SDK policy that starts a span for every
+// request the client sends, named after the repository method that sent it.
+// storage is "table" or "blob".
+func StoragePolicy(storage string) policy.Policy {
+	return storagePolicy{storage: storage}
+}
+
+type storagePolicy struct {
+	storage string
+}
+
+func (p storagePolicy) Do(req *policy.Request) (*http.Response, error) {
+	raw := req.Raw()
+
+	ctx, span := Start(raw.Context(), helper.RepositoryMethod(),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("storage", p.storage),
+			semconv.HTTPRequestMethodKey.String(raw.Method),
+			semconv.ServerAddress(raw.URL.Host),
+			semconv.URLPath(raw.URL.Path),
+		),
+	)
+
+	resp, err := req.WithContext(ctx).Next()
+	if err == nil {
+		span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+		if resp.StatusCode >= http.StatusBadRequest {
+			err = fmt.Errorf("%s %s: %s", raw.Method, raw.URL.Path, resp.Status)
+			End(span, err)
+			return resp, nil
+		}
+	}
+
+	End(span, err)
+	return resp, err
+}
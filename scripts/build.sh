@@ -44,6 +44,12 @@ if [ $? -ne 0 ]; then
   exit 1
 fi
 
+go build -o actlabs-hubctl ./cmd/actlabs-hubctl
+if [ $? -ne 0 ]; then
+  echo "Failed to build actlabs-hubctl"
+  exit 1
+fi
+
 docker build --no-cache --progress=plain -t actlabs.azurecr.io/actlabs-hub:${TAG} .
 if [ $? -ne 0 ]; then
   echo "Failed to build docker image"
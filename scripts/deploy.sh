@@ -74,6 +74,8 @@ az containerapp create \
   "ACTLABS_HUB_DEPLOYMENTS_TABLE_NAME=$ACTLABS_HUB_DEPLOYMENTS_TABLE_NAME" \
   "ACTLABS_HUB_EVENTS_TABLE_NAME=$ACTLABS_HUB_EVENTS_TABLE_NAME" \
   "ACTLABS_HUB_DEPLOYMENT_OPERATIONS_TABLE_NAME=$ACTLABS_HUB_DEPLOYMENT_OPERATIONS_TABLE_NAME" \
+  "ACTLABS_HUB_QUOTAS_TABLE_NAME=$ACTLABS_HUB_QUOTAS_TABLE_NAME" \
+  "ACTLABS_HUB_SCHEDULES_TABLE_NAME=$ACTLABS_HUB_SCHEDULES_TABLE_NAME" \
   "ACTLABS_HUB_CLIENT_ID=$ACTLABS_HUB_CLIENT_ID" \
   "ACTLABS_HUB_USE_MSI=$ACTLABS_HUB_USE_MSI" \
   "PORT=$ACTLABS_HUB_PORT" \